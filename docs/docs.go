@@ -15,25 +15,97 @@ const docTemplate = `{
     "host": "{{.Host}}",
     "basePath": "{{.BasePath}}",
     "paths": {
-        "/book": {
+        "/apikey": {
             "get": {
-                "description": "GetAllBooks retrieves the detail and available copies of a book title",
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "GetAllAPIKeys retrieves the issued and revoked api keys in the order issued, without the keys themselves",
                 "produces": [
                     "application/json"
                 ],
-                "summary": "GetAllBooks fetches the book details",
+                "summary": "GetAllAPIKeys fetches the api keys",
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
                             "type": "array",
                             "items": {
-                                "$ref": "#/definitions/model.BookDetails"
+                                "$ref": "#/definitions/model.APIKey"
                             }
                         }
                     },
-                    "404": {
-                        "description": "Not Found",
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/model.CustomError"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/model.CustomError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/model.CustomError"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "IssueAPIKey issues a key for an integrating system to call the api with in the X-API-Key header, within its scopes and its quota of requests a minute. The key is returned once, only its hash is stored",
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "IssueAPIKey issues an api key",
+                "parameters": [
+                    {
+                        "description": "API Key Request",
+                        "name": "apiKeyRequest",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/model.APIKeyRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/model.IssuedAPIKey"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/model.CustomError"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/model.CustomError"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/model.CustomError"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
                         "schema": {
                             "$ref": "#/definitions/model.CustomError"
                         }
@@ -47,18 +119,23 @@ const docTemplate = `{
                 }
             }
         },
-        "/book/{title}": {
-            "get": {
-                "description": "GetBook retrieves the detail and available copies of a book title",
+        "/apikey/{id}": {
+            "delete": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "RevokeAPIKey revokes an api key, requests made with it fail with 401 from then on",
                 "produces": [
                     "application/json"
                 ],
-                "summary": "GetBook fetches the book details",
+                "summary": "RevokeAPIKey revokes an api key",
                 "parameters": [
                     {
-                        "type": "string",
-                        "description": "Title of the book",
-                        "name": "title",
+                        "type": "integer",
+                        "description": "API key id",
+                        "name": "id",
                         "in": "path",
                         "required": true
                     }
@@ -67,7 +144,25 @@ const docTemplate = `{
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/model.BookDetails"
+                            "$ref": "#/definitions/model.APIKey"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/model.CustomError"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/model.CustomError"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/model.CustomError"
                         }
                     },
                     "404": {
@@ -76,6 +171,12 @@ const docTemplate = `{
                             "$ref": "#/definitions/model.CustomError"
                         }
                     },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/model.CustomError"
+                        }
+                    },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
@@ -85,25 +186,89 @@ const docTemplate = `{
                 }
             }
         },
-        "/loan": {
+        "/book": {
             "get": {
-                "description": "GetAllLoans retrieves the detail of all loans",
+                "security": [
+                    {
+                        "BearerAuth": []
+                    },
+                    {
+                        "APIKeyAuth": []
+                    }
+                ],
+                "description": "GetAllBooks retrieves a page of the books passing the filters, next_cursor continues the listing and is missed on the last page",
                 "produces": [
                     "application/json"
                 ],
-                "summary": "GetAllLoans fetches the all loan details",
+                "summary": "GetAllBooks fetches the book details",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "No of books in the page (default 50, max 100)",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "next_cursor of the previous page",
+                        "name": "cursor",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "id",
+                            "title",
+                            "publication_year"
+                        ],
+                        "type": "string",
+                        "description": "Sort key",
+                        "name": "sort",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "asc",
+                            "desc"
+                        ],
+                        "type": "string",
+                        "description": "Sort order",
+                        "name": "order",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Part of the title",
+                        "name": "title",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Part of any author",
+                        "name": "author",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Books with or without available copies",
+                        "name": "available",
+                        "in": "query"
+                    }
+                ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "type": "array",
-                            "items": {
-                                "$ref": "#/definitions/model.LoanDetails"
-                            }
+                            "$ref": "#/definitions/model.BookPage"
                         }
                     },
-                    "404": {
-                        "description": "Not Found",
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/model.CustomError"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
                         "schema": {
                             "$ref": "#/definitions/model.CustomError"
                         }
@@ -117,19 +282,27 @@ const docTemplate = `{
                 }
             },
             "post": {
-                "description": "LoanBook borrows a book from store (loan period: 4 weeks) and returns the details of a loan",
+                "security": [
+                    {
+                        "BearerAuth": []
+                    },
+                    {
+                        "APIKeyAuth": []
+                    }
+                ],
+                "description": "AddBook adds a book with its bibliographic details and the given number of copies to the catalog",
                 "produces": [
                     "application/json"
                 ],
-                "summary": "LoanBook borrows a book from store",
+                "summary": "AddBook adds a book to the catalog",
                 "parameters": [
                     {
-                        "description": "Loan Request",
-                        "name": "loanRequest",
+                        "description": "Book Request",
+                        "name": "bookRequest",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/model.LoanRequest"
+                            "$ref": "#/definitions/model.BookRequest"
                         }
                     }
                 ],
@@ -137,7 +310,7 @@ const docTemplate = `{
                     "201": {
                         "description": "Created",
                         "schema": {
-                            "$ref": "#/definitions/model.LoanDetails"
+                            "$ref": "#/definitions/model.BookDetails"
                         }
                     },
                     "400": {
@@ -146,8 +319,20 @@ const docTemplate = `{
                             "$ref": "#/definitions/model.CustomError"
                         }
                     },
-                    "404": {
-                        "description": "Not Found",
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/model.CustomError"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/model.CustomError"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
                         "schema": {
                             "$ref": "#/definitions/model.CustomError"
                         }
@@ -161,27 +346,41 @@ const docTemplate = `{
                 }
             }
         },
-        "/loan/extend/{id}": {
-            "post": {
-                "description": "ExtendLoan extends the loan of a book",
+        "/book/id/{id}": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    },
+                    {
+                        "APIKeyAuth": []
+                    }
+                ],
+                "description": "GetBookByID retrieves the detail and available copies of a book by its id",
                 "produces": [
                     "application/json"
                 ],
-                "summary": "ExtendLoan extends the loan of a book",
+                "summary": "GetBookByID fetches the book details",
                 "parameters": [
                     {
                         "type": "integer",
-                        "description": "Loan id",
+                        "description": "Book id",
                         "name": "id",
                         "in": "path",
                         "required": true
                     }
                 ],
                 "responses": {
-                    "202": {
-                        "description": "Accepted",
+                    "200": {
+                        "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/model.LoanDetails"
+                            "$ref": "#/definitions/model.BookDetails"
+                        },
+                        "headers": {
+                            "ETag": {
+                                "type": "string",
+                                "description": "Version of the book"
+                            }
                         }
                     },
                     "400": {
@@ -190,36 +389,59 @@ const docTemplate = `{
                             "$ref": "#/definitions/model.CustomError"
                         }
                     },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/model.CustomError"
+                        }
+                    },
                     "404": {
                         "description": "Not Found",
                         "schema": {
                             "$ref": "#/definitions/model.CustomError"
                         }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/model.CustomError"
+                        }
                     }
                 }
             }
         },
-        "/loan/return/{id}": {
-            "post": {
-                "description": "ReturnBook returns the book",
+        "/book/id/{id}/copy": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    },
+                    {
+                        "APIKeyAuth": []
+                    }
+                ],
+                "description": "GetBookCopies retrieves the barcode, shelf location, condition and status of every copy of a book",
                 "produces": [
                     "application/json"
                 ],
-                "summary": "ReturnBook returns the book",
+                "summary": "GetBookCopies fetches the copies of a book",
                 "parameters": [
                     {
                         "type": "integer",
-                        "description": "Loan id",
+                        "description": "Book id",
                         "name": "id",
                         "in": "path",
                         "required": true
                     }
                 ],
                 "responses": {
-                    "202": {
-                        "description": "Accepted",
+                    "200": {
+                        "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/model.LoanDetails"
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/model.BookCopy"
+                            }
                         }
                     },
                     "400": {
@@ -228,89 +450,2902 @@ const docTemplate = `{
                             "$ref": "#/definitions/model.CustomError"
                         }
                     },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/model.CustomError"
+                        }
+                    },
                     "404": {
                         "description": "Not Found",
                         "schema": {
                             "$ref": "#/definitions/model.CustomError"
                         }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/model.CustomError"
+                        }
                     }
                 }
-            }
-        }
-    },
-    "definitions": {
-        "model.BookDetails": {
-            "type": "object",
-            "properties": {
-                "available_copies": {
-                    "description": "No of available copies of the book that can be loaned",
-                    "type": "integer",
-                    "example": 10
-                },
-                "title": {
-                    "description": "Unique Identifier for the book",
-                    "type": "string",
-                    "example": "alchemist"
-                }
-            }
-        },
-        "model.CustomError": {
-            "type": "object",
-            "properties": {
-                "code": {
-                    "type": "integer"
-                },
-                "details": {
-                    "type": "string"
-                },
-                "error": {
-                    "type": "string"
-                }
-            }
-        },
-        "model.LoanDetails": {
-            "type": "object",
-            "properties": {
-                "id": {
-                    "description": "auto generated at the backend",
-                    "type": "integer"
-                },
-                "loan_date": {
-                    "description": "Date when the book was borrowed, unix epoch format. relavant for api calls",
-                    "type": "integer"
-                },
-                "name_of_borrower": {
-                    "description": "Name of borrower",
-                    "type": "string"
-                },
-                "return_date": {
-                    "description": "Date when the book should be returned, unix epoch format. relavant for api calls",
-                    "type": "integer"
-                },
+            },
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    },
+                    {
+                        "APIKeyAuth": []
+                    }
+                ],
+                "description": "AddBookCopy adds a physical copy to a book, the barcode is generated when missed",
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "AddBookCopy adds a copy of a book",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Book id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Book Copy Request",
+                        "name": "bookCopyRequest",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/model.BookCopyRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/model.BookCopy"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/model.CustomError"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/model.CustomError"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/model.CustomError"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/model.CustomError"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/model.CustomError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/model.CustomError"
+                        }
+                    }
+                }
+            }
+        },
+        "/book/search": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    },
+                    {
+                        "APIKeyAuth": []
+                    }
+                ],
+                "description": "SearchBooks ranks the books matching all words of the query across title, authors, subjects and description, highlighting the matches with \u003cmark\u003e tags",
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "SearchBooks searches the catalog",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Search query",
+                        "name": "q",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Max no of results (default 20, max 100)",
+                        "name": "limit",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/model.BookSearchResult"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/model.CustomError"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/model.CustomError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/model.CustomError"
+                        }
+                    }
+                }
+            }
+        },
+        "/book/{id}": {
+            "put": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    },
+                    {
+                        "APIKeyAuth": []
+                    }
+                ],
+                "description": "UpdateBook replaces the bibliographic details of a book, copies are managed separately",
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "UpdateBook updates a book in the catalog",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Book id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "ETag of the book the update is based on",
+                        "name": "If-Match",
+                        "in": "header"
+                    },
+                    {
+                        "description": "Book Request",
+                        "name": "bookRequest",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/model.BookRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/model.BookDetails"
+                        },
+                        "headers": {
+                            "ETag": {
+                                "type": "string",
+                                "description": "Version of the book"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/model.CustomError"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/model.CustomError"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/model.CustomError"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/model.CustomError"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/model.CustomError"
+                        }
+                    },
+                    "412": {
+                        "description": "Precondition Failed",
+                        "schema": {
+                            "$ref": "#/definitions/model.CustomError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/model.CustomError"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    },
+                    {
+                        "APIKeyAuth": []
+                    }
+                ],
+                "description": "DeleteBook removes a book from the catalog, refused while the book has active loans",
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "DeleteBook removes a book from the catalog",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Book id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK"
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/model.CustomError"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/model.CustomError"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/model.CustomError"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/model.CustomError"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/model.CustomError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/model.CustomError"
+                        }
+                    }
+                }
+            },
+            "patch": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    },
+                    {
+                        "APIKeyAuth": []
+                    }
+                ],
+                "description": "UpdateBookCopies adds copies with generated barcodes for a positive delta, withdraws available copies for a negative one",
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "UpdateBookCopies adds or withdraws copies of a book",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Book id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "ETag of the book the update is based on",
+                        "name": "If-Match",
+                        "in": "header"
+                    },
+                    {
+                        "description": "Book Copies Request",
+                        "name": "bookCopiesRequest",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/model.BookCopiesRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/model.BookDetails"
+                        },
+                        "headers": {
+                            "ETag": {
+                                "type": "string",
+                                "description": "Version of the book"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/model.CustomError"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/model.CustomError"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/model.CustomError"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/model.CustomError"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/model.CustomError"
+                        }
+                    },
+                    "412": {
+                        "description": "Precondition Failed",
+                        "schema": {
+                            "$ref": "#/definitions/model.CustomError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/model.CustomError"
+                        }
+                    }
+                }
+            }
+        },
+        "/book/{title}": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    },
+                    {
+                        "APIKeyAuth": []
+                    }
+                ],
+                "description": "GetBook retrieves the detail and available copies of a book title, the oldest one when several books share it",
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "GetBook fetches the book details",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Title of the book",
+                        "name": "title",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/model.BookDetails"
+                        },
+                        "headers": {
+                            "ETag": {
+                                "type": "string",
+                                "description": "Version of the book"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/model.CustomError"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/model.CustomError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/model.CustomError"
+                        }
+                    }
+                }
+            }
+        },
+        "/copy/{barcode}": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    },
+                    {
+                        "APIKeyAuth": []
+                    }
+                ],
+                "description": "GetBookCopy retrieves the shelf location, condition and status of a copy",
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "GetBookCopy fetches a copy by its barcode",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Barcode of the copy",
+                        "name": "barcode",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/model.BookCopy"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/model.CustomError"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/model.CustomError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/model.CustomError"
+                        }
+                    }
+                }
+            },
+            "patch": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    },
+                    {
+                        "APIKeyAuth": []
+                    }
+                ],
+                "description": "UpdateBookCopy updates the shelf location, condition and status of a copy, empty fields are left unchanged",
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "UpdateBookCopy updates a copy",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Barcode of the copy",
+                        "name": "barcode",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Book Copy Request",
+                        "name": "bookCopyRequest",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/model.BookCopyRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/model.BookCopy"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/model.CustomError"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/model.CustomError"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/model.CustomError"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/model.CustomError"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/model.CustomError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/model.CustomError"
+                        }
+                    }
+                }
+            }
+        },
+        "/fine/{id}/pay": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    },
+                    {
+                        "APIKeyAuth": []
+                    }
+                ],
+                "description": "PayFine pays the amount of an accruing or unpaid fine, the outstanding amount when missed. An unpaid fine gets paid once nothing is outstanding, paying more than outstanding fails with 409",
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "PayFine pays a fine",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Fine id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Fine Payment Request",
+                        "name": "finePaymentRequest",
+                        "in": "body",
+                        "schema": {
+                            "$ref": "#/definitions/model.FinePaymentRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/model.Fine"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/model.CustomError"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/model.CustomError"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/model.CustomError"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/model.CustomError"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/model.CustomError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/model.CustomError"
+                        }
+                    }
+                }
+            }
+        },
+        "/fine/{id}/waive": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    },
+                    {
+                        "APIKeyAuth": []
+                    }
+                ],
+                "description": "WaiveFine waives an accruing or unpaid fine, nothing more is owed for it and it stops accruing",
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "WaiveFine waives a fine",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Fine id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/model.Fine"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/model.CustomError"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/model.CustomError"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/model.CustomError"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/model.CustomError"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/model.CustomError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/model.CustomError"
+                        }
+                    }
+                }
+            }
+        },
+        "/hold": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    },
+                    {
+                        "APIKeyAuth": []
+                    }
+                ],
+                "description": "PlaceHold queues a member for a book with no copy available. A returned copy is set aside for the longest waiting hold, which gets ready and expires when not borrowed within the pickup window. A hold refused by the borrowing policy fails with 403 and a reason code: member_inactive, hold_limit_reached or duplicate_title",
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "PlaceHold places a hold on a book",
+                "parameters": [
+                    {
+                        "description": "Hold Request",
+                        "name": "holdRequest",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/model.HoldRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/model.Hold"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/model.CustomError"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/model.CustomError"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/model.CustomError"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/model.CustomError"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/model.CustomError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/model.CustomError"
+                        }
+                    }
+                }
+            }
+        },
+        "/hold/{id}": {
+            "delete": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    },
+                    {
+                        "APIKeyAuth": []
+                    }
+                ],
+                "description": "CancelHold cancels a waiting or ready hold, the copy set aside for a ready hold passes on to the next waiting hold",
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "CancelHold cancels a hold",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Hold id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/model.Hold"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/model.CustomError"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/model.CustomError"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/model.CustomError"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/model.CustomError"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/model.CustomError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/model.CustomError"
+                        }
+                    }
+                }
+            }
+        },
+        "/loan": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    },
+                    {
+                        "APIKeyAuth": []
+                    }
+                ],
+                "description": "GetAllLoans retrieves a page of the loans passing the filters, next_cursor continues the listing and is missed on the last page. Dates are unix epoch format",
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "GetAllLoans fetches the loan details",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "No of loans in the page (default 50, max 100)",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "next_cursor of the previous page",
+                        "name": "cursor",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "id",
+                            "name_of_borrower",
+                            "loan_date",
+                            "return_date"
+                        ],
+                        "type": "string",
+                        "description": "Sort key",
+                        "name": "sort",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "asc",
+                            "desc"
+                        ],
+                        "type": "string",
+                        "description": "Sort order",
+                        "name": "order",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "active",
+                            "overdue",
+                            "closed"
+                        ],
+                        "type": "string",
+                        "description": "Loan status",
+                        "name": "status",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "ID of the borrowing member",
+                        "name": "member_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Name of borrower",
+                        "name": "borrower",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Part of the title",
+                        "name": "title",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Loans not returned past their return date",
+                        "name": "overdue",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Loaned at or after",
+                        "name": "loaned_from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Loaned at or before",
+                        "name": "loaned_to",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "To be returned at or after",
+                        "name": "due_from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "To be returned at or before",
+                        "name": "due_to",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/model.LoanPage"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/model.CustomError"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/model.CustomError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/model.CustomError"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    },
+                    {
+                        "APIKeyAuth": []
+                    }
+                ],
+                "description": "LoanBook lends a book to a member for the loan period of the member tier and book category, and returns the details of a loan. A loan refused by the borrowing policy fails with 403 and a reason code: member_inactive, loan_limit_reached, duplicate_title, overdue_items or unpaid_fines",
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "LoanBook borrows a book from store",
+                "parameters": [
+                    {
+                        "description": "Loan Request",
+                        "name": "loanRequest",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/model.LoanRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/model.LoanDetails"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/model.CustomError"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/model.CustomError"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/model.CustomError"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/model.CustomError"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/model.CustomError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/model.CustomError"
+                        }
+                    }
+                }
+            }
+        },
+        "/loan/extend/{id}": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    },
+                    {
+                        "APIKeyAuth": []
+                    }
+                ],
+                "description": "ExtendLoan extends the return date of a loan by the extension period of the member tier and book category. Refused with 403 and the reason overdue_items once overdue, or extension_limit_reached once extended the most times",
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "ExtendLoan extends the loan of a book",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Loan id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "ETag of the loan the extension is based on",
+                        "name": "If-Match",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "202": {
+                        "description": "Accepted",
+                        "schema": {
+                            "$ref": "#/definitions/model.LoanDetails"
+                        },
+                        "headers": {
+                            "ETag": {
+                                "type": "string",
+                                "description": "Version of the loan"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/model.CustomError"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/model.CustomError"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/model.CustomError"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/model.CustomError"
+                        }
+                    },
+                    "412": {
+                        "description": "Precondition Failed",
+                        "schema": {
+                            "$ref": "#/definitions/model.CustomError"
+                        }
+                    }
+                }
+            }
+        },
+        "/loan/return/{id}": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    },
+                    {
+                        "APIKeyAuth": []
+                    }
+                ],
+                "description": "ReturnBook returns the book closing the loan, the fine of an overdue loan stops accruing and becomes unpaid",
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "ReturnBook returns the book",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Loan id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "ETag of the loan the return is based on",
+                        "name": "If-Match",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "202": {
+                        "description": "Accepted",
+                        "schema": {
+                            "$ref": "#/definitions/model.LoanDetails"
+                        },
+                        "headers": {
+                            "ETag": {
+                                "type": "string",
+                                "description": "Version of the loan"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/model.CustomError"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/model.CustomError"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/model.CustomError"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/model.CustomError"
+                        }
+                    },
+                    "412": {
+                        "description": "Precondition Failed",
+                        "schema": {
+                            "$ref": "#/definitions/model.CustomError"
+                        }
+                    }
+                }
+            }
+        },
+        "/loan/{id}": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    },
+                    {
+                        "APIKeyAuth": []
+                    }
+                ],
+                "description": "GetLoan retrieves a loan by its id whether active, overdue or closed",
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "GetLoan fetches a loan",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Loan id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/model.LoanDetails"
+                        },
+                        "headers": {
+                            "ETag": {
+                                "type": "string",
+                                "description": "Version of the loan"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/model.CustomError"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/model.CustomError"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/model.CustomError"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/model.CustomError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/model.CustomError"
+                        }
+                    }
+                }
+            }
+        },
+        "/loan/{id}/history": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    },
+                    {
+                        "APIKeyAuth": []
+                    }
+                ],
+                "description": "GetLoanHistory retrieves the transitions of a loan in the order made: created, extended, overdue and returned, each with the actor, the time and the values before and after. The actor is named by the X-Actor header of the request, system for background jobs",
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "GetLoanHistory fetches the history of a loan",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Loan id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/model.LoanEvent"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/model.CustomError"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/model.CustomError"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/model.CustomError"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/model.CustomError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/model.CustomError"
+                        }
+                    }
+                }
+            }
+        },
+        "/log/level": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "GetLogLevel retrieves the level the app logs at",
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "GetLogLevel fetches the log level",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/model.LogLevel"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/model.CustomError"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/model.CustomError"
+                        }
+                    }
+                }
+            },
+            "put": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "SetLogLevel changes the level the app logs at till it's changed again or the app restarts, the level is named or numbered from -1 for debug",
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "SetLogLevel changes the log level",
+                "parameters": [
+                    {
+                        "description": "Log Level",
+                        "name": "logLevel",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/model.LogLevel"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/model.LogLevel"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/model.CustomError"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/model.CustomError"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/model.CustomError"
+                        }
+                    }
+                }
+            }
+        },
+        "/member": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    },
+                    {
+                        "APIKeyAuth": []
+                    }
+                ],
+                "description": "GetAllMembers retrieves a page of the members passing the filters, next_cursor continues the listing and is missed on the last page",
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "GetAllMembers fetches the members",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "No of members in the page (default 50, max 100)",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "next_cursor of the previous page",
+                        "name": "cursor",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "id",
+                            "name"
+                        ],
+                        "type": "string",
+                        "description": "Sort key",
+                        "name": "sort",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "asc",
+                            "desc"
+                        ],
+                        "type": "string",
+                        "description": "Sort order",
+                        "name": "order",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Part of the name",
+                        "name": "name",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "standard",
+                            "student",
+                            "premium"
+                        ],
+                        "type": "string",
+                        "description": "Membership tier",
+                        "name": "tier",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "active",
+                            "suspended",
+                            "expired"
+                        ],
+                        "type": "string",
+                        "description": "Member status",
+                        "name": "status",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/model.MemberPage"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/model.CustomError"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/model.CustomError"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/model.CustomError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/model.CustomError"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    },
+                    {
+                        "APIKeyAuth": []
+                    }
+                ],
+                "description": "AddMember registers a member allowed to borrow books, the card number is generated when missed",
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "AddMember registers a member",
+                "parameters": [
+                    {
+                        "description": "Member Request",
+                        "name": "memberRequest",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/model.MemberRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/model.Member"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/model.CustomError"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/model.CustomError"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/model.CustomError"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/model.CustomError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/model.CustomError"
+                        }
+                    }
+                }
+            }
+        },
+        "/member/{id}": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    },
+                    {
+                        "APIKeyAuth": []
+                    }
+                ],
+                "description": "GetMember retrieves a member by its id",
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "GetMember fetches a member",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Member id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/model.Member"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/model.CustomError"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/model.CustomError"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/model.CustomError"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/model.CustomError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/model.CustomError"
+                        }
+                    }
+                }
+            },
+            "put": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    },
+                    {
+                        "APIKeyAuth": []
+                    }
+                ],
+                "description": "UpdateMember updates the details of a member, empty fields are left unchanged. Members other than active can't borrow",
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "UpdateMember updates a member",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Member id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Member Request",
+                        "name": "memberRequest",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/model.MemberRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/model.Member"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/model.CustomError"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/model.CustomError"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/model.CustomError"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/model.CustomError"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/model.CustomError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/model.CustomError"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    },
+                    {
+                        "APIKeyAuth": []
+                    }
+                ],
+                "description": "DeleteMember removes a member, refused while the member has active loans. Past loans keep the name of the member",
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "DeleteMember removes a member",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Member id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK"
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/model.CustomError"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/model.CustomError"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/model.CustomError"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/model.CustomError"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/model.CustomError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/model.CustomError"
+                        }
+                    }
+                }
+            }
+        },
+        "/member/{id}/fines": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    },
+                    {
+                        "APIKeyAuth": []
+                    }
+                ],
+                "description": "GetMemberFines retrieves the fines of a member in the order fined along with the total owed. Fines accrue daily while a loan is overdue and get finalised on return",
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "GetMemberFines fetches the fines of a member",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Member id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/model.MemberFines"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/model.CustomError"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/model.CustomError"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/model.CustomError"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/model.CustomError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/model.CustomError"
+                        }
+                    }
+                }
+            }
+        },
+        "/member/{id}/holds": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    },
+                    {
+                        "APIKeyAuth": []
+                    }
+                ],
+                "description": "GetMemberHolds retrieves all the holds placed by a member in the order placed",
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "GetMemberHolds fetches the holds of a member",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Member id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/model.Hold"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/model.CustomError"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/model.CustomError"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/model.CustomError"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/model.CustomError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/model.CustomError"
+                        }
+                    }
+                }
+            }
+        },
+        "/member/{id}/loans": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    },
+                    {
+                        "APIKeyAuth": []
+                    }
+                ],
+                "description": "GetMemberLoans retrieves the active and past loans of a member in the order loaned",
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "GetMemberLoans fetches the loans of a member",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Member id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/model.LoanDetails"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/model.CustomError"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/model.CustomError"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/model.CustomError"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/model.CustomError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/model.CustomError"
+                        }
+                    }
+                }
+            }
+        }
+    },
+    "definitions": {
+        "model.APIKey": {
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "description": "Date of issue, unix epoch format",
+                    "type": "integer",
+                    "example": 1712850000
+                },
+                "id": {
+                    "description": "auto generated at the backend",
+                    "type": "integer",
+                    "example": 1
+                },
+                "name": {
+                    "description": "unique name of the integrating system",
+                    "type": "string",
+                    "example": "kiosk-east"
+                },
+                "prefix": {
+                    "description": "first characters of the key telling the keys apart",
+                    "type": "string",
+                    "example": "lib_Xq3v"
+                },
+                "requests_per_minute": {
+                    "description": "quota of the key, 0 for the default quota",
+                    "type": "integer",
+                    "example": 120
+                },
+                "revoked_at": {
+                    "description": "Date of revocation, 0 while usable",
+                    "type": "integer",
+                    "example": 1712936400
+                },
+                "scopes": {
+                    "description": "resources the key may read or write",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "catalog:read",
+                        "circulation:write"
+                    ]
+                }
+            }
+        },
+        "model.APIKeyRequest": {
+            "type": "object",
+            "properties": {
+                "name": {
+                    "description": "binding: required",
+                    "type": "string",
+                    "example": "kiosk-east"
+                },
+                "requests_per_minute": {
+                    "description": "0 for the default quota",
+                    "type": "integer",
+                    "example": 120
+                },
+                "scopes": {
+                    "description": "catalog, members or circulation with :read or :write",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "catalog:read",
+                        "circulation:write"
+                    ]
+                }
+            }
+        },
+        "model.BookCopiesRequest": {
+            "type": "object",
+            "properties": {
+                "delta": {
+                    "description": "No of copies to add with generated barcodes (positive) or available copies to withdraw (negative)",
+                    "type": "integer",
+                    "example": 2
+                }
+            }
+        },
+        "model.BookCopy": {
+            "type": "object",
+            "properties": {
+                "acquisition_date": {
+                    "description": "Date when the copy was acquired, unix epoch format",
+                    "type": "integer",
+                    "example": 1700000000
+                },
+                "barcode": {
+                    "description": "Unique identifier printed on the copy",
+                    "type": "string",
+                    "example": "1-0001"
+                },
+                "book_id": {
+                    "description": "ID of the book the copy belongs to",
+                    "type": "integer",
+                    "example": 1
+                },
+                "condition": {
+                    "description": "new | good | worn | damaged",
+                    "type": "string",
+                    "example": "good"
+                },
+                "id": {
+                    "description": "auto generated at the backend",
+                    "type": "integer",
+                    "example": 1
+                },
+                "shelf_location": {
+                    "description": "where the copy is shelved",
+                    "type": "string",
+                    "example": "FIC-COE-A2"
+                },
+                "status": {
+                    "description": "available | on_loan | in_repair | lost | withdrawn",
+                    "type": "string",
+                    "example": "available"
+                }
+            }
+        },
+        "model.BookCopyRequest": {
+            "type": "object",
+            "properties": {
+                "acquisition_date": {
+                    "description": "unix epoch format, defaults to now on add",
+                    "type": "integer",
+                    "example": 1700000000
+                },
+                "barcode": {
+                    "description": "generated when missed on add",
+                    "type": "string",
+                    "example": "1-0001"
+                },
+                "condition": {
+                    "description": "new | good | worn | damaged",
+                    "type": "string",
+                    "example": "good"
+                },
+                "shelf_location": {
+                    "description": "where the copy is shelved",
+                    "type": "string",
+                    "example": "FIC-COE-A2"
+                },
+                "status": {
+                    "description": "available | in_repair | lost | withdrawn, only honoured on update",
+                    "type": "string",
+                    "example": "in_repair"
+                }
+            }
+        },
+        "model.BookDetails": {
+            "type": "object",
+            "properties": {
+                "authors": {
+                    "description": "authors of the book",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "Paulo Coelho"
+                    ]
+                },
+                "available_copies": {
+                    "description": "No of copies on the shelf that can be loaned, derived from copies",
+                    "type": "integer",
+                    "example": 10
+                },
+                "category": {
+                    "description": "lending category, picks the loan period and extension rules",
+                    "type": "string",
+                    "example": "reference"
+                },
+                "description": {
+                    "description": "summary of the book",
+                    "type": "string",
+                    "example": "A shepherd's journey"
+                },
+                "edition": {
+                    "description": "edition statement",
+                    "type": "string",
+                    "example": "25th anniversary"
+                },
+                "id": {
+                    "description": "auto generated at the backend, unique identifier for the book",
+                    "type": "integer",
+                    "example": 1
+                },
+                "isbn": {
+                    "description": "ISBN-10 or ISBN-13, unique when given",
+                    "type": "string",
+                    "example": "9780062315007"
+                },
+                "language": {
+                    "description": "language of the edition",
+                    "type": "string",
+                    "example": "en"
+                },
+                "publication_year": {
+                    "description": "year the edition was published",
+                    "type": "integer",
+                    "example": 1988
+                },
+                "publisher": {
+                    "description": "publisher of the edition",
+                    "type": "string",
+                    "example": "HarperOne"
+                },
+                "subjects": {
+                    "description": "genres or subjects of the book",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "fiction"
+                    ]
+                },
+                "title": {
+                    "description": "title of the book, different books may share it",
+                    "type": "string",
+                    "example": "alchemist"
+                },
+                "total_copies": {
+                    "description": "No of copies not withdrawn, derived from copies",
+                    "type": "integer",
+                    "example": 12
+                },
+                "version": {
+                    "description": "bumped by every update of the book, served as the ETag",
+                    "type": "integer",
+                    "example": 1
+                }
+            }
+        },
+        "model.BookPage": {
+            "type": "object",
+            "properties": {
+                "books": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/model.BookDetails"
+                    }
+                },
+                "next_cursor": {
+                    "description": "missed on the last page",
+                    "type": "string",
+                    "example": "eyJzIjoiaWQiLCJpIjo1MH0"
+                }
+            }
+        },
+        "model.BookRequest": {
+            "type": "object",
+            "properties": {
+                "authors": {
+                    "description": "authors of the book",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "Paulo Coelho"
+                    ]
+                },
+                "category": {
+                    "description": "lending category, picks the loan period and extension rules",
+                    "type": "string",
+                    "example": "reference"
+                },
+                "copies": {
+                    "description": "No of copies to create with generated barcodes, only honoured on add",
+                    "type": "integer",
+                    "example": 10
+                },
+                "description": {
+                    "description": "summary of the book",
+                    "type": "string",
+                    "example": "A shepherd's journey"
+                },
+                "edition": {
+                    "description": "edition statement",
+                    "type": "string",
+                    "example": "25th anniversary"
+                },
+                "isbn": {
+                    "description": "ISBN-10 or ISBN-13",
+                    "type": "string",
+                    "example": "9780062315007"
+                },
+                "language": {
+                    "description": "language of the edition",
+                    "type": "string",
+                    "example": "en"
+                },
+                "publication_year": {
+                    "description": "year the edition was published",
+                    "type": "integer",
+                    "example": 1988
+                },
+                "publisher": {
+                    "description": "publisher of the edition",
+                    "type": "string",
+                    "example": "HarperOne"
+                },
+                "subjects": {
+                    "description": "genres or subjects of the book",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "fiction"
+                    ]
+                },
+                "title": {
+                    "description": "title of the book",
+                    "type": "string",
+                    "example": "alchemist"
+                }
+            }
+        },
+        "model.BookSearchResult": {
+            "type": "object",
+            "properties": {
+                "book": {
+                    "description": "matched book",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/model.BookDetails"
+                        }
+                    ]
+                },
+                "highlights": {
+                    "description": "matched fields with the matches wrapped in \u003cmark\u003e tags",
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "string"
+                    },
+                    "example": {
+                        "title": "The \u003cmark\u003eAlchemist\u003c/mark\u003e"
+                    }
+                },
+                "score": {
+                    "description": "relevance of the match, higher is better",
+                    "type": "number",
+                    "example": 0.75
+                }
+            }
+        },
+        "model.CustomError": {
+            "type": "object",
+            "properties": {
+                "code": {
+                    "type": "integer"
+                },
+                "details": {
+                    "type": "string"
+                },
+                "error": {
+                    "type": "string"
+                },
+                "reason": {
+                    "description": "reason code of a refused loan",
+                    "type": "string",
+                    "example": "loan_limit_reached"
+                }
+            }
+        },
+        "model.Fine": {
+            "type": "object",
+            "properties": {
+                "accrued_at": {
+                    "description": "Date when the amount got last updated, unix epoch format",
+                    "type": "integer",
+                    "example": 1700000000
+                },
+                "amount_in_cents": {
+                    "description": "fine accrued so far, final once returned",
+                    "type": "integer",
+                    "example": 250
+                },
+                "id": {
+                    "description": "auto generated at the backend",
+                    "type": "integer",
+                    "example": 1
+                },
+                "loan_id": {
+                    "description": "ID of the overdue loan",
+                    "type": "integer",
+                    "example": 1
+                },
+                "member_id": {
+                    "description": "ID of the fined member",
+                    "type": "integer",
+                    "example": 1
+                },
+                "paid_in_cents": {
+                    "description": "part of the amount paid",
+                    "type": "integer",
+                    "example": 100
+                },
+                "settled_at": {
+                    "description": "Date when the fine got paid or waived, unix epoch format",
+                    "type": "integer",
+                    "example": 1700000000
+                },
+                "status": {
+                    "description": "accruing | unpaid | paid | waived",
+                    "type": "string",
+                    "example": "unpaid"
+                },
+                "title": {
+                    "description": "title of the loaned book",
+                    "type": "string",
+                    "example": "alchemist"
+                }
+            }
+        },
+        "model.FinePaymentRequest": {
+            "type": "object",
+            "properties": {
+                "amount_in_cents": {
+                    "description": "amount paid, the outstanding amount when missed",
+                    "type": "integer",
+                    "example": 100
+                }
+            }
+        },
+        "model.Hold": {
+            "type": "object",
+            "properties": {
+                "barcode": {
+                    "description": "barcode of the copy set aside once ready",
+                    "type": "string",
+                    "example": "1-0001"
+                },
+                "book_id": {
+                    "description": "ID of the held book",
+                    "type": "integer",
+                    "example": 1
+                },
+                "copy_id": {
+                    "description": "ID of the copy set aside once ready",
+                    "type": "integer",
+                    "example": 1
+                },
+                "expires_at": {
+                    "description": "Date till the copy is set aside, unix epoch format",
+                    "type": "integer",
+                    "example": 1700259200
+                },
+                "id": {
+                    "description": "auto generated at the backend",
+                    "type": "integer",
+                    "example": 1
+                },
+                "member_id": {
+                    "description": "ID of the waiting member",
+                    "type": "integer",
+                    "example": 1
+                },
+                "placed_at": {
+                    "description": "Date when the hold was placed, unix epoch format",
+                    "type": "integer",
+                    "example": 1700000000
+                },
+                "ready_at": {
+                    "description": "Date when a copy got set aside, unix epoch format",
+                    "type": "integer",
+                    "example": 1700000000
+                },
+                "status": {
+                    "description": "waiting | ready | fulfilled | cancelled | expired",
+                    "type": "string",
+                    "example": "waiting"
+                },
+                "title": {
+                    "description": "title of the book",
+                    "type": "string",
+                    "example": "alchemist"
+                }
+            }
+        },
+        "model.HoldRequest": {
+            "type": "object",
+            "properties": {
+                "book_id": {
+                    "description": "ID of the book, takes precedence over title",
+                    "type": "integer",
+                    "example": 1
+                },
+                "member_id": {
+                    "description": "ID of the waiting member",
+                    "type": "integer",
+                    "example": 1
+                },
+                "title": {
+                    "description": "title of the book, must be unambiguous when book_id is absent",
+                    "type": "string",
+                    "example": "alchemist"
+                }
+            }
+        },
+        "model.IssuedAPIKey": {
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "description": "Date of issue, unix epoch format",
+                    "type": "integer",
+                    "example": 1712850000
+                },
+                "id": {
+                    "description": "auto generated at the backend",
+                    "type": "integer",
+                    "example": 1
+                },
+                "key": {
+                    "type": "string",
+                    "example": "lib_Xq3vZb8b6yqkS0Ub1m3nJ2z0u1dF4oP0Ia1iW3cR7sE"
+                },
+                "name": {
+                    "description": "unique name of the integrating system",
+                    "type": "string",
+                    "example": "kiosk-east"
+                },
+                "prefix": {
+                    "description": "first characters of the key telling the keys apart",
+                    "type": "string",
+                    "example": "lib_Xq3v"
+                },
+                "requests_per_minute": {
+                    "description": "quota of the key, 0 for the default quota",
+                    "type": "integer",
+                    "example": 120
+                },
+                "revoked_at": {
+                    "description": "Date of revocation, 0 while usable",
+                    "type": "integer",
+                    "example": 1712936400
+                },
+                "scopes": {
+                    "description": "resources the key may read or write",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "catalog:read",
+                        "circulation:write"
+                    ]
+                }
+            }
+        },
+        "model.LoanDetails": {
+            "type": "object",
+            "properties": {
+                "barcode": {
+                    "description": "barcode of the loaned copy",
+                    "type": "string"
+                },
+                "book_id": {
+                    "description": "ID of the loaned book",
+                    "type": "integer"
+                },
+                "copy_id": {
+                    "description": "ID of the loaned copy",
+                    "type": "integer"
+                },
+                "extensions": {
+                    "description": "No of times the loan got extended",
+                    "type": "integer"
+                },
+                "id": {
+                    "description": "auto generated at the backend",
+                    "type": "integer"
+                },
+                "loan_date": {
+                    "description": "Date when the book was borrowed, unix epoch format. relavant for api calls",
+                    "type": "integer"
+                },
+                "member_id": {
+                    "description": "ID of the borrowing member",
+                    "type": "integer"
+                },
+                "name_of_borrower": {
+                    "description": "Name of the member when borrowed",
+                    "type": "string"
+                },
+                "return_date": {
+                    "description": "Date when the book should be returned, unix epoch format. relavant for api calls",
+                    "type": "integer"
+                },
                 "status": {
-                    "description": "active | closed",
+                    "description": "active | overdue | closed",
                     "type": "string"
                 },
                 "title": {
                     "description": "title of the book",
                     "type": "string"
+                },
+                "version": {
+                    "description": "bumped by every transition of the loan, served as the ETag",
+                    "type": "integer"
+                }
+            }
+        },
+        "model.LoanEvent": {
+            "type": "object",
+            "properties": {
+                "actor": {
+                    "description": "who made the transition, system for background jobs",
+                    "type": "string",
+                    "example": "librarian-1"
+                },
+                "at": {
+                    "description": "Date when the transition happened, unix epoch format",
+                    "type": "integer",
+                    "example": 1700000000
+                },
+                "id": {
+                    "description": "auto generated at the backend",
+                    "type": "integer",
+                    "example": 1
+                },
+                "loan_id": {
+                    "description": "ID of the loan",
+                    "type": "integer",
+                    "example": 1
+                },
+                "new": {
+                    "description": "values after the transition",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/model.LoanState"
+                        }
+                    ]
+                },
+                "old": {
+                    "description": "values before the transition, missed on creation",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/model.LoanState"
+                        }
+                    ]
+                },
+                "type": {
+                    "description": "created | extended | overdue | returned",
+                    "type": "string",
+                    "example": "extended"
+                }
+            }
+        },
+        "model.LoanPage": {
+            "type": "object",
+            "properties": {
+                "loans": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/model.LoanDetails"
+                    }
+                },
+                "next_cursor": {
+                    "description": "missed on the last page",
+                    "type": "string",
+                    "example": "eyJzIjoiaWQiLCJpIjo1MH0"
                 }
             }
         },
         "model.LoanRequest": {
             "type": "object",
             "properties": {
-                "name_of_borrower": {
-                    "description": "binding: required",
+                "barcode": {
+                    "description": "barcode of the copy to loan, any available copy when missed",
                     "type": "string",
-                    "example": "john"
+                    "example": "1-0001"
+                },
+                "book_id": {
+                    "description": "ID of the book, takes precedence over title",
+                    "type": "integer",
+                    "example": 1
+                },
+                "member_id": {
+                    "description": "binding: required",
+                    "type": "integer",
+                    "example": 1
                 },
                 "title": {
-                    "description": "title of the book",
+                    "description": "title of the book, must be unambiguous when book_id is absent",
                     "type": "string",
                     "example": "alchemist"
                 }
             }
+        },
+        "model.LoanState": {
+            "type": "object",
+            "properties": {
+                "extensions": {
+                    "type": "integer",
+                    "example": 1
+                },
+                "return_date": {
+                    "type": "integer",
+                    "example": 1700000000
+                },
+                "status": {
+                    "type": "string",
+                    "example": "active"
+                }
+            }
+        },
+        "model.LogLevel": {
+            "type": "object",
+            "properties": {
+                "level": {
+                    "description": "debug, info, warn, error, dpanic, panic or fatal",
+                    "type": "string",
+                    "example": "info"
+                }
+            }
+        },
+        "model.Member": {
+            "type": "object",
+            "properties": {
+                "card_number": {
+                    "description": "unique library card number",
+                    "type": "string",
+                    "example": "LIB-000001"
+                },
+                "email": {
+                    "description": "unique when given",
+                    "type": "string",
+                    "example": "john@example.com"
+                },
+                "id": {
+                    "description": "auto generated at the backend",
+                    "type": "integer",
+                    "example": 1
+                },
+                "joined_at": {
+                    "description": "Date when the member registered, unix epoch format",
+                    "type": "integer",
+                    "example": 1700000000
+                },
+                "name": {
+                    "description": "full name of the member",
+                    "type": "string",
+                    "example": "John Doe"
+                },
+                "status": {
+                    "description": "active | suspended | expired, only active members borrow",
+                    "type": "string",
+                    "example": "active"
+                },
+                "tier": {
+                    "description": "standard | student | premium",
+                    "type": "string",
+                    "example": "standard"
+                }
+            }
+        },
+        "model.MemberFines": {
+            "type": "object",
+            "properties": {
+                "fines": {
+                    "description": "fines of the member in the order fined",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/model.Fine"
+                    }
+                },
+                "member_id": {
+                    "type": "integer",
+                    "example": 1
+                },
+                "outstanding_in_cents": {
+                    "description": "total owed by the member",
+                    "type": "integer",
+                    "example": 150
+                }
+            }
+        },
+        "model.MemberPage": {
+            "type": "object",
+            "properties": {
+                "members": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/model.Member"
+                    }
+                },
+                "next_cursor": {
+                    "description": "missed on the last page",
+                    "type": "string",
+                    "example": "eyJzIjoiaWQiLCJpIjo1MH0"
+                }
+            }
+        },
+        "model.MemberRequest": {
+            "type": "object",
+            "properties": {
+                "card_number": {
+                    "description": "generated when missed on register",
+                    "type": "string",
+                    "example": "LIB-000001"
+                },
+                "email": {
+                    "description": "unique when given",
+                    "type": "string",
+                    "example": "john@example.com"
+                },
+                "name": {
+                    "description": "mandatory on register",
+                    "type": "string",
+                    "example": "John Doe"
+                },
+                "status": {
+                    "description": "active | suspended | expired, active by default",
+                    "type": "string",
+                    "example": "active"
+                },
+                "tier": {
+                    "description": "standard | student | premium, standard by default",
+                    "type": "string",
+                    "example": "standard"
+                }
+            }
+        }
+    },
+    "securityDefinitions": {
+        "APIKeyAuth": {
+            "description": "Key of an integrating system, held to its scopes and quota",
+            "type": "apiKey",
+            "name": "X-API-Key",
+            "in": "header"
+        },
+        "BearerAuth": {
+            "description": "Bearer JWT of a librarian or a member, required once AuthEnabled",
+            "type": "apiKey",
+            "name": "Authorization",
+            "in": "header"
         }
     }
 }`
@@ -0,0 +1,23 @@
+package audit
+
+import (
+	"context"
+
+	"github.com/test/library-app/internal/constants"
+)
+
+// actorKey keys the actor in a context
+type actorKey struct{}
+
+// WithActor returns a copy of the context carrying the actor making the changes
+func WithActor(ctx context.Context, actor string) context.Context {
+	return context.WithValue(ctx, actorKey{}, actor)
+}
+
+// Actor gives the actor carried by the context, the system when none
+func Actor(ctx context.Context) string {
+	if actor, ok := ctx.Value(actorKey{}).(string); ok && actor != "" {
+		return actor
+	}
+	return constants.ActorSystem
+}
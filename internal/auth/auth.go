@@ -0,0 +1,202 @@
+package auth
+
+import (
+	"context"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"slices"
+	"strconv"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/test/library-app/internal/config"
+	"github.com/test/library-app/internal/constants"
+	"github.com/test/library-app/internal/model"
+)
+
+// ErrUnauthenticated refuses a token that can't be verified or grants no role
+var ErrUnauthenticated = errors.New("unauthenticated")
+
+// Principal is the caller authenticated by a token or an api key
+type Principal struct {
+	Subject  string        // sub claim, names the actor of the changes
+	Role     string        // librarian | member
+	MemberID int           // member the caller is, set for members only
+	APIKey   *model.APIKey // key of the integrating system calling, nil for tokens
+}
+
+// IsLibrarian reports whether the principal may act on any loan and change the catalog
+func (p *Principal) IsLibrarian() bool {
+	return p.Role == constants.RoleLibrarian
+}
+
+// principalKey keys the principal in a context
+type principalKey struct{}
+
+// WithPrincipal returns a copy of the context carrying the authenticated principal
+func WithPrincipal(ctx context.Context, p *Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, p)
+}
+
+// PrincipalFrom gives the principal carried by the context, nil when authentication is disabled
+func PrincipalFrom(ctx context.Context) *Principal {
+	p, _ := ctx.Value(principalKey{}).(*Principal)
+	return p
+}
+
+// Authenticator verifies the bearer tokens with the keys of the config
+type Authenticator struct {
+	secret    []byte                    // HS256 shared secret
+	publicKey *rsa.PublicKey            // RS256 key of tokens with no kid or a kid missed in the key set
+	keySet    map[string]*rsa.PublicKey // RS256 keys of the JWKS file by their kid
+	parser    *jwt.Parser
+}
+
+// NewAuthenticator loads the keys of the config, at least one of JWTSecret, JWTPublicKeyFile or JWKSFile is needed
+func NewAuthenticator() (*Authenticator, error) {
+	a := &Authenticator{keySet: make(map[string]*rsa.PublicKey)}
+	methods := make([]string, 0, 2)
+	if config.AuthConfig.JWTSecret != "" {
+		a.secret = []byte(config.AuthConfig.JWTSecret)
+		methods = append(methods, jwt.SigningMethodHS256.Alg())
+	}
+	if path := config.AuthConfig.JWTPublicKeyFile; path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read the public key file %s: %w", path, err)
+		}
+		if a.publicKey, err = jwt.ParseRSAPublicKeyFromPEM(data); err != nil {
+			return nil, fmt.Errorf("failed to parse the public key file %s: %w", path, err)
+		}
+	}
+	if path := config.AuthConfig.JWKSFile; path != "" {
+		if err := a.loadKeySet(path); err != nil {
+			return nil, err
+		}
+	}
+	if a.publicKey != nil || len(a.keySet) > 0 {
+		methods = append(methods, jwt.SigningMethodRS256.Alg())
+	}
+	if len(methods) == 0 {
+		return nil, errors.New("authentication needs JWTSecret, JWTPublicKeyFile or JWKSFile")
+	}
+	options := []jwt.ParserOption{jwt.WithValidMethods(methods), jwt.WithExpirationRequired()}
+	if config.AuthConfig.JWTIssuer != "" {
+		options = append(options, jwt.WithIssuer(config.AuthConfig.JWTIssuer))
+	}
+	if config.AuthConfig.JWTAudience != "" {
+		options = append(options, jwt.WithAudience(config.AuthConfig.JWTAudience))
+	}
+	a.parser = jwt.NewParser(options...)
+	return a, nil
+}
+
+// loadKeySet reads the RSA keys of a JSON Web Key Set file
+func (a *Authenticator) loadKeySet(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read the JWKS file %s: %w", path, err)
+	}
+	var set struct {
+		Keys []struct {
+			Kty string `json:"kty"`
+			Kid string `json:"kid"`
+			Use string `json:"use"`
+			N   string `json:"n"`
+			E   string `json:"e"`
+		} `json:"keys"`
+	}
+	if err := json.Unmarshal(data, &set); err != nil {
+		return fmt.Errorf("failed to decode the JWKS file %s: %w", path, err)
+	}
+	for _, key := range set.Keys {
+		// encryption keys and other key types aren't for verifying
+		if key.Kty != "RSA" || (key.Use != "" && key.Use != "sig") {
+			continue
+		}
+		n, err := base64.RawURLEncoding.DecodeString(key.N)
+		if err != nil {
+			return fmt.Errorf("invalid modulus of key %s in the JWKS file %s: %w", key.Kid, path, err)
+		}
+		e, err := base64.RawURLEncoding.DecodeString(key.E)
+		if err != nil {
+			return fmt.Errorf("invalid exponent of key %s in the JWKS file %s: %w", key.Kid, path, err)
+		}
+		a.keySet[key.Kid] = &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}
+	}
+	return nil
+}
+
+// key picks the key verifying the token by its algorithm and kid
+func (a *Authenticator) key(token *jwt.Token) (any, error) {
+	if token.Method.Alg() == jwt.SigningMethodHS256.Alg() {
+		return a.secret, nil
+	}
+	if kid, ok := token.Header["kid"].(string); ok {
+		if key, ok := a.keySet[kid]; ok {
+			return key, nil
+		}
+	}
+	if a.publicKey == nil {
+		return nil, fmt.Errorf("no key to verify the token signed by kid %v", token.Header["kid"])
+	}
+	return a.publicKey, nil
+}
+
+// Authenticate verifies the token and maps its claims to the principal,
+// librarian roles take precedence over member roles and members need a member ID
+func (a *Authenticator) Authenticate(tokenString string) (*Principal, error) {
+	claims := jwt.MapClaims{}
+	if _, err := a.parser.ParseWithClaims(tokenString, claims, a.key); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrUnauthenticated, err)
+	}
+	subject, _ := claims.GetSubject()
+	if subject == "" {
+		return nil, fmt.Errorf("%w: token has no subject", ErrUnauthenticated)
+	}
+	roles := claimStrings(claims[config.AuthConfig.RoleClaim])
+	if slices.ContainsFunc(roles, func(role string) bool { return slices.Contains(config.AuthConfig.LibrarianRoles, role) }) {
+		return &Principal{Subject: subject, Role: constants.RoleLibrarian}, nil
+	}
+	if slices.ContainsFunc(roles, func(role string) bool { return slices.Contains(config.AuthConfig.MemberRoles, role) }) {
+		memberID := claimInt(claims[config.AuthConfig.MemberClaim])
+		if memberID <= 0 {
+			return nil, fmt.Errorf("%w: member token has no %s claim", ErrUnauthenticated, config.AuthConfig.MemberClaim)
+		}
+		return &Principal{Subject: subject, Role: constants.RoleMember, MemberID: memberID}, nil
+	}
+	return nil, fmt.Errorf("%w: token grants no role", ErrUnauthenticated)
+}
+
+// claimStrings reads a claim given as a string or a list of strings
+func claimStrings(claim any) []string {
+	switch val := claim.(type) {
+	case string:
+		return []string{val}
+	case []any:
+		values := make([]string, 0, len(val))
+		for _, item := range val {
+			if s, ok := item.(string); ok {
+				values = append(values, s)
+			}
+		}
+		return values
+	}
+	return nil
+}
+
+// claimInt reads a claim given as a number or a numeric string, 0 otherwise
+func claimInt(claim any) int {
+	switch val := claim.(type) {
+	case float64:
+		return int(val)
+	case string:
+		n, _ := strconv.Atoi(val)
+		return n
+	}
+	return 0
+}
@@ -0,0 +1,152 @@
+package authtest
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/test/library-app/internal/auth"
+	"github.com/test/library-app/internal/config"
+	"github.com/test/library-app/internal/constants"
+	"github.com/test/library-app/internal/model"
+)
+
+func TestMain(m *testing.M) {
+	// loading configuration
+	config.LoadConfig()
+	m.Run()
+}
+
+// sign signs the claims with the key, expiring in an hour unless set
+func sign(t *testing.T, method jwt.SigningMethod, key any, kid string, claims jwt.MapClaims) string {
+	if _, ok := claims["exp"]; !ok {
+		claims["exp"] = time.Now().Add(time.Hour).Unix()
+	}
+	token := jwt.NewWithClaims(method, claims)
+	if kid != "" {
+		token.Header["kid"] = kid
+	}
+	signed, err := token.SignedString(key)
+	assert.Nil(t, err)
+	return signed
+}
+
+func TestAuthenticateHS256(t *testing.T) {
+	config.AuthConfig.JWTSecret = "library-secret"
+	config.AuthConfig.JWTIssuer = "library-idp"
+	defer func() {
+		config.AuthConfig.JWTSecret = ""
+		config.AuthConfig.JWTIssuer = ""
+	}()
+	authn, err := auth.NewAuthenticator()
+	assert.Nil(t, err)
+	secret := []byte("library-secret")
+
+	// success case: librarian roles win over member roles
+	token := sign(t, jwt.SigningMethodHS256, secret, "", jwt.MapClaims{"sub": "anna", "iss": "library-idp", "role": []string{"member", "librarian"}})
+	principal, err := authn.Authenticate(token)
+	assert.Nil(t, err)
+	assert.Equal(t, "anna", principal.Subject)
+	assert.True(t, principal.IsLibrarian())
+
+	// success case: a member with the member ID given as a string
+	token = sign(t, jwt.SigningMethodHS256, secret, "", jwt.MapClaims{"sub": "ben", "iss": "library-idp", "role": "member", "member_id": "7"})
+	principal, err = authn.Authenticate(token)
+	assert.Nil(t, err)
+	assert.Equal(t, constants.RoleMember, principal.Role)
+	assert.Equal(t, 7, principal.MemberID)
+
+	// failure cases: member with no member ID, no role, another issuer, expired, another secret, no expiry
+	refused := []string{
+		sign(t, jwt.SigningMethodHS256, secret, "", jwt.MapClaims{"sub": "ben", "iss": "library-idp", "role": "member"}),
+		sign(t, jwt.SigningMethodHS256, secret, "", jwt.MapClaims{"sub": "ben", "iss": "library-idp", "role": "guest"}),
+		sign(t, jwt.SigningMethodHS256, secret, "", jwt.MapClaims{"sub": "anna", "iss": "other-idp", "role": "librarian"}),
+		sign(t, jwt.SigningMethodHS256, secret, "", jwt.MapClaims{"sub": "anna", "iss": "library-idp", "role": "librarian", "exp": time.Now().Add(-time.Minute).Unix()}),
+		sign(t, jwt.SigningMethodHS256, []byte("other-secret"), "", jwt.MapClaims{"sub": "anna", "iss": "library-idp", "role": "librarian"}),
+		jwtWithoutExpiry(t, secret),
+	}
+	for _, token := range refused {
+		_, err = authn.Authenticate(token)
+		assert.ErrorIs(t, err, auth.ErrUnauthenticated)
+	}
+}
+
+// jwtWithoutExpiry signs a librarian token that never expires
+func jwtWithoutExpiry(t *testing.T, secret []byte) string {
+	signed, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{"sub": "anna", "iss": "library-idp", "role": "librarian"}).SignedString(secret)
+	assert.Nil(t, err)
+	return signed
+}
+
+func TestAuthenticateJWKS(t *testing.T) {
+	current, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.Nil(t, err)
+	retired, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.Nil(t, err)
+	jwk := func(kid string, key *rsa.PrivateKey) map[string]string {
+		return map[string]string{
+			"kty": "RSA",
+			"kid": kid,
+			"use": "sig",
+			"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+		}
+	}
+	data, _ := json.Marshal(map[string]any{"keys": []map[string]string{jwk("2024", retired), jwk("2025", current)}})
+	config.AuthConfig.JWKSFile = filepath.Join(t.TempDir(), "jwks.json")
+	defer func() { config.AuthConfig.JWKSFile = "" }()
+	assert.Nil(t, os.WriteFile(config.AuthConfig.JWKSFile, data, 0o600))
+	authn, err := auth.NewAuthenticator()
+	assert.Nil(t, err)
+
+	// success case: the key is picked by the kid of the token
+	for kid, key := range map[string]*rsa.PrivateKey{"2024": retired, "2025": current} {
+		principal, err := authn.Authenticate(sign(t, jwt.SigningMethodRS256, key, kid, jwt.MapClaims{"sub": "anna", "role": "librarian"}))
+		assert.Nil(t, err)
+		assert.Equal(t, "anna", principal.Subject)
+	}
+
+	// failure case: signed by the key of another kid
+	_, err = authn.Authenticate(sign(t, jwt.SigningMethodRS256, retired, "2025", jwt.MapClaims{"sub": "anna", "role": "librarian"}))
+	assert.ErrorIs(t, err, auth.ErrUnauthenticated)
+
+	// failure case: HS256 isn't accepted with RSA keys only
+	_, err = authn.Authenticate(sign(t, jwt.SigningMethodHS256, []byte("secret"), "", jwt.MapClaims{"sub": "anna", "role": "librarian"}))
+	assert.ErrorIs(t, err, auth.ErrUnauthenticated)
+}
+
+func TestNewAuthenticatorWithoutKeys(t *testing.T) {
+	// failure case: nothing to verify the tokens with
+	_, err := auth.NewAuthenticator()
+	assert.NotNil(t, err)
+}
+
+func TestAPIKeyScopes(t *testing.T) {
+	key, prefix, hash, err := auth.NewAPIKey()
+	assert.Nil(t, err)
+	assert.True(t, strings.HasPrefix(key, prefix))
+	assert.Equal(t, hash, auth.HashAPIKey(key))
+	assert.True(t, auth.ValidScope("circulation:write"))
+	assert.False(t, auth.ValidScope("circulation"))
+	assert.False(t, auth.ValidScope("loans:read"))
+
+	// success case: a write scope allows reading too
+	principal := auth.APIKeyPrincipal(&model.APIKey{Name: "kiosk-east", Scopes: []string{"catalog:read", "circulation:write"}})
+	assert.Equal(t, "apikey:kiosk-east", principal.Subject)
+	assert.True(t, principal.Allows(constants.ScopeCatalog, false))
+	assert.True(t, principal.Allows(constants.ScopeCirculation, false))
+	assert.True(t, principal.Allows(constants.ScopeCirculation, true))
+
+	// failure cases: writing a read scope, a resource out of the scopes
+	assert.False(t, principal.Allows(constants.ScopeCatalog, true))
+	assert.False(t, principal.Allows(constants.ScopeMembers, false))
+}
@@ -2,61 +2,160 @@ package config
 
 import (
 	"log"
+	"os"
 
 	"github.com/kelseyhightower/envconfig"
 )
 
 type CommonConfiguration struct {
-	AppName           string `default:"library-app"`
-	ServicePort       int    `default:"3000"`
-	ReadTimeoutInSec  int    `default:"15"`
-	WriteTimeoutInSec int    `default:"15"`
-	IdleTimeoutInSec  int    `deault:"60"`
-	StoreType         string `default:"local"` // local | postgres
+	AppName                string `default:"library-app"`
+	ServicePort            int    `default:"3000"`
+	ReadTimeoutInSec       int    `default:"15"`
+	WriteTimeoutInSec      int    `default:"15"`
+	IdleTimeoutInSec       int    `default:"60"`
+	StoreType              string `default:"local"` // local | postgres | sqlite
+	HoldExpiryInSec        int    `default:"60"`    // interval of expiring the ready holds not picked up
+	OverdueCheckInSec      int    `default:"3600"`  // interval of marking the overdue loans and accruing their fines
+	IdempotencyKeyTTLInSec int    `default:"86400"` // No of seconds the response of a request is replayed to the retries with its Idempotency-Key
+	IdempotencyExpiryInSec int    `default:"3600"`  // interval of dropping the expired idempotency keys
+	APIKeyQuotaPerMinute   int    `default:"600"`   // No of requests a minute allowed to the api keys issued with no quota of their own
+	CirculationStatsInSec  int    `default:"60"`    // interval of refreshing the gauges of the open and overdue loans and unavailable titles
+	HealthTimeoutInSec     int    `default:"2"`     // No of seconds a health check may take before it's failed
 }
 
 type LogConfiguration struct {
-	Level    string `default:"-1"`
-	Format   string `default:"_2 Jan 2006 15:04:05.000"`
-	Encoding string `default:"console"`
+	Level              string `default:"info"` // debug, info, warn, error or their number from -1, changed at runtime through the api
+	Format             string `default:"_2 Jan 2006 15:04:05.000"`
+	Encoding           string `default:"console"`     // console | json
+	Mode               string `default:"development"` // development | production, production logs the stack traces of errors only
+	SamplingInitial    int    `default:"0"`           // No of entries of a message logged each second before sampling, no sampling when 0
+	SamplingThereafter int    `default:"0"`           // every Nth entry of the message logged past the initial ones, none when 0
 }
 
 type PostgresConfiguration struct {
-	Host           string `default:"localhost:5432"`
-	PGUserName     string `default:"postgres"`
-	Password       string `default:"postgres"`
-	DBName         string `default:"postgresdb"`
-	BooksTableName string `default:"books"`
-	LoansTableName string `default:"loans"`
+	Host                     string `default:"localhost:5432"`
+	PGUserName               string `default:"postgres"`
+	Password                 string `default:"postgres" secret:"true"`
+	DBName                   string `default:"postgresdb"`
+	BooksTableName           string `default:"books"`
+	CopiesTableName          string `default:"book_copies"`
+	LoansTableName           string `default:"loans"`
+	MembersTableName         string `default:"members"`
+	HoldsTableName           string `default:"holds"`
+	FinesTableName           string `default:"fines"`
+	LoanEventsTableName      string `default:"loan_events"`
+	IdempotencyKeysTableName string `default:"idempotency_keys"`
+	APIKeysTableName         string `default:"api_keys"`
+	MigrationsTableName      string `default:"schema_migrations"` // tracks the applied schema migrations
+	MigrateOnStart           bool   `default:"true"`              // applies pending migrations on start, otherwise refuses to start while any are pending
+}
+
+type LocalConfiguration struct {
+	DataDir             string `default:""`     // keeps a snapshot and a write-ahead log of the local store under the directory, in memory only when empty
+	SnapshotEveryWrites int    `default:"1000"` // No of logged writes compacted into a new snapshot
+}
+
+type SQLiteConfiguration struct {
+	SQLitePath string `default:"library.db"` // database file, created along with its schema when missed
+}
+
+type AuthConfiguration struct {
+	AuthEnabled      bool     `default:"false"` // requires a bearer token on the api, every request acts as a librarian otherwise but the log level and api key endpoints stay unreachable
+	JWTSecret        string   `secret:"true"`   // HS256 shared secret
+	JWTPublicKeyFile string   // PEM file of the RS256 public key
+	JWKSFile         string   // JSON Web Key Set file of the RS256 public keys picked by the kid of the token
+	JWTIssuer        string   // iss the tokens must carry when set
+	JWTAudience      string   // aud the tokens must carry when set
+	RoleClaim        string   `default:"role"`      // claim with the role or the list of roles
+	MemberClaim      string   `default:"member_id"` // claim with the member ID of members
+	LibrarianRoles   []string `default:"librarian"` // values of the role claim mapped to librarians
+	MemberRoles      []string `default:"member"`    // values of the role claim mapped to members
+}
+
+type TracingConfiguration struct {
+	TracingEnabled   bool              `default:"false"`          // exports the spans of the requests, the store operations and the queries
+	OTLPEndpoint     string            `default:"localhost:4318"` // host:port of the OTLP/HTTP collector
+	OTLPInsecure     bool              `default:"true"`           // sends the spans over http rather than https
+	OTLPHeaders      map[string]string `secret:"true"`            // headers sent along with the spans as key:value,key:value
+	TraceSampleRatio float64           `default:"1"`              // share of the traces started here sampled, the traces of the callers follow their decision
+}
+
+type PolicyConfiguration struct {
+	MaxLoansStandard      int   `default:"5"`  // No of books a standard member may hold at once
+	MaxLoansStudent       int   `default:"3"`  // No of books a student member may hold at once
+	MaxLoansPremium       int   `default:"10"` // No of books a premium member may hold at once
+	BlockOnOverdue        bool  `default:"true"`
+	MaxUnpaidFinesInCents int64 `default:"0"` // members owing more fines than this are blocked from borrowing
+	LoanPeriodInDays      int   `default:"28"`
+	ExtensionInDays       int   `default:"21"`   // No of days an extension adds to the return date
+	MaxExtensions         int   `default:"2"`    // No of times a loan may be extended
+	MaxHolds              int   `default:"5"`    // No of holds a member may have waiting or ready at once
+	HoldPickupInDays      int   `default:"3"`    // No of days a copy is set aside for a ready hold
+	FinePerDayInCents     int64 `default:"25"`   // fine accrued per overdue day
+	FineGraceInDays       int   `default:"0"`    // No of overdue days not fined
+	MaxFineInCents        int64 `default:"1000"` // cap of the fine of a loan, 0 for none
+	// overrides by member tier and by book category as tier:value,tier:value, the category wins over the tier
+	LoanPeriodInDaysByTier     map[string]int
+	LoanPeriodInDaysByCategory map[string]int
+	ExtensionInDaysByTier      map[string]int
+	ExtensionInDaysByCategory  map[string]int
+	MaxExtensionsByTier        map[string]int
+	MaxExtensionsByCategory    map[string]int
 }
 
 var (
 	CommonConfig   CommonConfiguration
 	LogConfig      LogConfiguration
 	PostgresConfig PostgresConfiguration
+	LocalConfig    LocalConfiguration
+	SQLiteConfig   SQLiteConfiguration
+	AuthConfig     AuthConfiguration
+	TracingConfig  TracingConfiguration
+	PolicyConfig   PolicyConfiguration
 )
 
+// sections are the settings of the app by their section of the config file, loaded in this order
+var sections = []struct {
+	name string
+	spec any
+}{
+	{"common", &CommonConfig},
+	{"log", &LogConfig},
+	{"postgres", &PostgresConfig},
+	{"local", &LocalConfig},
+	{"sqlite", &SQLiteConfig},
+	{"auth", &AuthConfig},
+	{"tracing", &TracingConfig},
+	{"policy", &PolicyConfig},
+}
+
+// LoadConfig loads the settings from the env, the env vars win over the config file named by CONFIG_FILE
+// and the file over the defaults. The secrets are read from the files named by their _FILE env vars.
+// The settings are validated once loaded, the secrets aren't logged
 func LoadConfig() error {
-	// loading common config
-	if err := envconfig.Process("", &CommonConfig); err != nil {
-		log.Printf("Failed to load common config env %v\n", err)
+	file, err := readConfigFile(os.Getenv(ConfigFileEnv))
+	if err != nil {
+		log.Printf("Failed to read config file %v\n", err)
 		return err
 	}
-	log.Printf("CommonConfig: %+v\n", CommonConfig)
-
-	// loading log config
-	if err := envconfig.Process("", &LogConfig); err != nil {
-		log.Printf("Failed to load common log env %v\n", err)
-		return err
+	for _, section := range sections {
+		if err := envconfig.Process("", section.spec); err != nil {
+			log.Printf("Failed to load %s config env %v\n", section.name, err)
+			return err
+		}
+		if err := applySecretFiles(section.spec); err != nil {
+			log.Printf("Failed to load %s config secrets %v\n", section.name, err)
+			return err
+		}
+		if err := applyConfigFile(section.name, section.spec, file); err != nil {
+			log.Printf("Failed to load %s config file %v\n", section.name, err)
+			return err
+		}
+		log.Printf("%s config: %s\n", section.name, Redacted(section.spec))
 	}
-	log.Printf("LogConfig: %+v\n", LogConfig)
-
-	// loading postgres config
-	if err := envconfig.Process("", &PostgresConfig); err != nil {
-		log.Printf("Failed to load common log env %v\n", err)
+	if err := Validate(); err != nil {
+		log.Printf("Invalid config %v\n", err)
 		return err
 	}
-	log.Printf("PostgresConfig: %+v\n", PostgresConfig)
-
 	return nil
 }
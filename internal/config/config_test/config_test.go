@@ -1,6 +1,9 @@
 package configtest
 
 import (
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -11,3 +14,137 @@ func TestLoadConfig(t *testing.T) {
 	err := config.LoadConfig()
 	assert.Nil(t, err)
 }
+
+// writeFile writes the content to a file of the name in a temp dir, returns its path
+func writeFile(t *testing.T, name, content string) string {
+	path := filepath.Join(t.TempDir(), name)
+	assert.Nil(t, os.WriteFile(path, []byte(content), 0o600))
+	return path
+}
+
+func TestLoadConfigFile(t *testing.T) {
+	// reloading once the env is restored
+	t.Cleanup(func() { config.LoadConfig() })
+
+	// success case: the YAML file wins over the defaults, the env over the file
+	t.Setenv("CONFIG_FILE", writeFile(t, "library.yaml", `
+common:
+  servicePort: 8080
+  storeType: sqlite
+  readTimeoutInSec: 30
+log:
+  level: debug
+postgres:
+  password: from-file
+policy:
+  loanPeriodInDaysByTier:
+    premium: 42
+auth:
+  librarianRoles: [librarian, admin]
+`))
+	t.Setenv("READTIMEOUTINSEC", "20")
+	assert.Nil(t, config.LoadConfig())
+	assert.Equal(t, 8080, config.CommonConfig.ServicePort)
+	assert.Equal(t, "sqlite", config.CommonConfig.StoreType)
+	assert.Equal(t, 20, config.CommonConfig.ReadTimeoutInSec)
+	assert.Equal(t, 15, config.CommonConfig.WriteTimeoutInSec)
+	assert.Equal(t, 60, config.CommonConfig.IdleTimeoutInSec)
+	assert.Equal(t, "debug", config.LogConfig.Level)
+	assert.Equal(t, "from-file", config.PostgresConfig.Password)
+	assert.Equal(t, map[string]int{"premium": 42}, config.PolicyConfig.LoanPeriodInDaysByTier)
+	assert.Equal(t, []string{"librarian", "admin"}, config.AuthConfig.LibrarianRoles)
+
+	// success case: TOML file
+	t.Setenv("CONFIG_FILE", writeFile(t, "library.toml", `
+[common]
+ServicePort = 9090
+
+[tracing]
+TraceSampleRatio = 0.25
+`))
+	assert.Nil(t, config.LoadConfig())
+	assert.Equal(t, 9090, config.CommonConfig.ServicePort)
+	assert.Equal(t, 0.25, config.TracingConfig.TraceSampleRatio)
+
+	// failure case: unknown setting
+	t.Setenv("CONFIG_FILE", writeFile(t, "library.yaml", "common:\n  servicePrt: 8080\n"))
+	err := config.LoadConfig()
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "unknown setting common.servicePrt")
+
+	// failure case: unknown section
+	t.Setenv("CONFIG_FILE", writeFile(t, "library.yaml", "commons:\n  servicePort: 8080\n"))
+	assert.NotNil(t, config.LoadConfig())
+
+	// failure case: setting of another type
+	t.Setenv("CONFIG_FILE", writeFile(t, "library.yaml", "common:\n  servicePort: eighty\n"))
+	assert.NotNil(t, config.LoadConfig())
+
+	// failure case: unknown format
+	t.Setenv("CONFIG_FILE", writeFile(t, "library.json", `{}`))
+	assert.NotNil(t, config.LoadConfig())
+}
+
+func TestSecretFiles(t *testing.T) {
+	// reloading once the env is restored
+	t.Cleanup(func() { config.LoadConfig() })
+
+	// success case: the secret is read from the file, the trailing newline trimmed
+	t.Setenv("PASSWORD_FILE", writeFile(t, "password", "s3cr3t\n"))
+	assert.Nil(t, config.LoadConfig())
+	assert.Equal(t, "s3cr3t", config.PostgresConfig.Password)
+
+	// success case: the file of the secret wins over the config file
+	t.Setenv("CONFIG_FILE", writeFile(t, "library.yaml", "postgres:\n  password: from-file\n"))
+	assert.Nil(t, config.LoadConfig())
+	assert.Equal(t, "s3cr3t", config.PostgresConfig.Password)
+
+	// failure case: both the env var and its file set
+	t.Setenv("PASSWORD", "other")
+	assert.NotNil(t, config.LoadConfig())
+
+	// failure case: missed file
+	os.Unsetenv("PASSWORD")
+	t.Setenv("PASSWORD_FILE", filepath.Join(t.TempDir(), "missed"))
+	assert.NotNil(t, config.LoadConfig())
+}
+
+func TestValidate(t *testing.T) {
+	// reloading once the env is restored
+	t.Cleanup(func() { config.LoadConfig() })
+
+	// failure case: every invalid setting is reported at once
+	t.Setenv("SERVICEPORT", "70000")
+	t.Setenv("STORETYPE", "mongo")
+	t.Setenv("WRITETIMEOUTINSEC", "0")
+	t.Setenv("TRACESAMPLERATIO", "2")
+	err := config.LoadConfig()
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "ServicePort must be between 1 and 65535, got 70000")
+	assert.Contains(t, err.Error(), `StoreType must be local, postgres or sqlite, got "mongo"`)
+	assert.Contains(t, err.Error(), "WriteTimeoutInSec must be positive, got 0")
+	assert.Contains(t, err.Error(), "TraceSampleRatio must be between 0 and 1, got 2")
+
+	// failure case: no key to verify the tokens with
+	os.Unsetenv("SERVICEPORT")
+	os.Unsetenv("STORETYPE")
+	os.Unsetenv("WRITETIMEOUTINSEC")
+	os.Unsetenv("TRACESAMPLERATIO")
+	t.Setenv("AUTHENABLED", "true")
+	err = config.LoadConfig()
+	assert.NotNil(t, err)
+	assert.True(t, strings.HasPrefix(err.Error(), "JWTSecret, JWTPublicKeyFile or JWKSFile must be set"))
+}
+
+func TestRedacted(t *testing.T) {
+	cfg := config.PostgresConfiguration{Host: "db:5432", Password: "s3cr3t"}
+
+	// success case: the secrets are masked, the rest shown
+	dump := config.Redacted(&cfg)
+	assert.NotContains(t, dump, "s3cr3t")
+	assert.Contains(t, dump, "Password:[REDACTED]")
+	assert.Contains(t, dump, "Host:db:5432")
+
+	// success case: unset secrets are shown empty
+	assert.Contains(t, config.Redacted(config.AuthConfiguration{}), "JWTSecret: ")
+}
@@ -0,0 +1,151 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+
+	"github.com/pelletier/go-toml/v2"
+	"gopkg.in/yaml.v3"
+)
+
+// ConfigFileEnv names the env var of the config file, a YAML or a TOML file by its extension
+const ConfigFileEnv = "CONFIG_FILE"
+
+// secretFileSuffix suffixes the env var of a secret to name the file holding it, PASSWORD_FILE for PASSWORD
+const secretFileSuffix = "_FILE"
+
+// readConfigFile reads the settings of the config file by their section, none when the path is empty
+func readConfigFile(path string) (map[string]any, error) {
+	settings := make(map[string]any)
+	if path == "" {
+		return settings, nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, &settings)
+	case ".toml":
+		err = toml.Unmarshal(data, &settings)
+	default:
+		return nil, fmt.Errorf("config file %s must be .yaml, .yml or .toml", path)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("parsing config file %s: %w", path, err)
+	}
+	// a section missing from the app is most likely a typo
+	for name := range settings {
+		if !knownSection(name) {
+			return nil, fmt.Errorf("unknown section %s in config file %s", name, path)
+		}
+	}
+	return settings, nil
+}
+
+// knownSection reports whether the config has the section of the name
+func knownSection(name string) bool {
+	for _, section := range sections {
+		if strings.EqualFold(section.name, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// envKey gives the env var of a setting as envconfig names it with no prefix
+func envKey(field reflect.StructField) string {
+	return strings.ToUpper(field.Name)
+}
+
+// envSet reports whether the setting is set through its env var or the file of its secret
+func envSet(field reflect.StructField) bool {
+	if _, ok := os.LookupEnv(envKey(field)); ok {
+		return true
+	}
+	_, ok := os.LookupEnv(envKey(field) + secretFileSuffix)
+	return ok
+}
+
+// applyConfigFile sets the settings of the section found in the config file, matching their names case insensitively.
+// The settings set through the env are left as they are
+func applyConfigFile(name string, spec any, file map[string]any) error {
+	var values map[string]any
+	for section, settings := range file {
+		if !strings.EqualFold(section, name) {
+			continue
+		}
+		var ok bool
+		if values, ok = settings.(map[string]any); !ok {
+			return fmt.Errorf("section %s must be a table of settings", name)
+		}
+	}
+	v := reflect.ValueOf(spec).Elem()
+	t := v.Type()
+	for key, value := range values {
+		field, ok := t.FieldByNameFunc(func(fieldName string) bool { return strings.EqualFold(fieldName, key) })
+		if !ok {
+			return fmt.Errorf("unknown setting %s.%s", name, key)
+		}
+		if envSet(field) {
+			continue
+		}
+		// the value is decoded into the type of the setting whatever the format of the file
+		raw, err := yaml.Marshal(value)
+		if err != nil {
+			return fmt.Errorf("invalid setting %s.%s: %w", name, key, err)
+		}
+		target := reflect.New(field.Type)
+		if err := yaml.Unmarshal(raw, target.Interface()); err != nil {
+			return fmt.Errorf("invalid setting %s.%s: %w", name, key, err)
+		}
+		v.FieldByIndex(field.Index).Set(target.Elem())
+	}
+	return nil
+}
+
+// applySecretFiles sets the string settings from the files named by their _FILE env vars, the trailing newline trimmed.
+// Setting both the env var and its _FILE is refused as ambiguous
+func applySecretFiles(spec any) error {
+	v := reflect.ValueOf(spec).Elem()
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		key := envKey(field)
+		path, ok := os.LookupEnv(key + secretFileSuffix)
+		if !ok {
+			continue
+		}
+		if _, ok := os.LookupEnv(key); ok {
+			return fmt.Errorf("both %s and %s%s are set", key, key, secretFileSuffix)
+		}
+		if field.Type.Kind() != reflect.String {
+			return fmt.Errorf("%s%s is supported by string settings only", key, secretFileSuffix)
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("reading %s%s: %w", key, secretFileSuffix, err)
+		}
+		v.Field(i).SetString(strings.TrimRight(string(data), "\r\n"))
+	}
+	return nil
+}
+
+// Redacted formats the settings as %+v does with the values of the secret settings masked once set
+func Redacted(spec any) string {
+	v := reflect.Indirect(reflect.ValueOf(spec))
+	t := v.Type()
+	fields := make([]string, 0, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		value := fmt.Sprintf("%v", v.Field(i).Interface())
+		if t.Field(i).Tag.Get("secret") == "true" && !v.Field(i).IsZero() {
+			value = "[REDACTED]"
+		}
+		fields = append(fields, t.Field(i).Name+":"+value)
+	}
+	return "{" + strings.Join(fields, " ") + "}"
+}
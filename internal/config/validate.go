@@ -0,0 +1,79 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/test/library-app/internal/constants"
+)
+
+// Validate validates the settings loaded, reporting every invalid one at once
+func Validate() error {
+	var errs []error
+	invalid := func(format string, vals ...interface{}) {
+		errs = append(errs, fmt.Errorf(format, vals...))
+	}
+
+	if CommonConfig.ServicePort < 1 || CommonConfig.ServicePort > 65535 {
+		invalid("ServicePort must be between 1 and 65535, got %d", CommonConfig.ServicePort)
+	}
+	if !slices.Contains([]string{constants.LocalStore, constants.PostgresStore, constants.SQLiteStore}, CommonConfig.StoreType) {
+		invalid("StoreType must be local, postgres or sqlite, got %q", CommonConfig.StoreType)
+	}
+	timeouts := []struct {
+		name  string
+		value int
+	}{
+		{"ReadTimeoutInSec", CommonConfig.ReadTimeoutInSec},
+		{"WriteTimeoutInSec", CommonConfig.WriteTimeoutInSec},
+		{"IdleTimeoutInSec", CommonConfig.IdleTimeoutInSec},
+		{"HealthTimeoutInSec", CommonConfig.HealthTimeoutInSec},
+		{"IdempotencyKeyTTLInSec", CommonConfig.IdempotencyKeyTTLInSec},
+	}
+	for _, timeout := range timeouts {
+		if timeout.value <= 0 {
+			invalid("%s must be positive, got %d", timeout.name, timeout.value)
+		}
+	}
+	// the intervals of the jobs disable them when not positive
+	if CommonConfig.APIKeyQuotaPerMinute < 0 {
+		invalid("APIKeyQuotaPerMinute must not be negative, got %d", CommonConfig.APIKeyQuotaPerMinute)
+	}
+
+	if !slices.Contains([]string{"console", "json"}, LogConfig.Encoding) {
+		invalid("Encoding must be console or json, got %q", LogConfig.Encoding)
+	}
+	if !slices.Contains([]string{constants.LogModeDevelopment, constants.LogModeProduction}, LogConfig.Mode) {
+		invalid("Mode must be development or production, got %q", LogConfig.Mode)
+	}
+	if LogConfig.SamplingInitial < 0 || LogConfig.SamplingThereafter < 0 {
+		invalid("SamplingInitial and SamplingThereafter must not be negative, got %d and %d", LogConfig.SamplingInitial, LogConfig.SamplingThereafter)
+	}
+
+	if CommonConfig.StoreType == constants.PostgresStore && PostgresConfig.Host == "" {
+		invalid("Host must be set with the postgres store")
+	}
+	if CommonConfig.StoreType == constants.SQLiteStore && SQLiteConfig.SQLitePath == "" {
+		invalid("SQLitePath must be set with the sqlite store")
+	}
+
+	if AuthConfig.AuthEnabled && AuthConfig.JWTSecret == "" && AuthConfig.JWTPublicKeyFile == "" && AuthConfig.JWKSFile == "" {
+		invalid("JWTSecret, JWTPublicKeyFile or JWKSFile must be set once AuthEnabled")
+	}
+
+	if TracingConfig.TracingEnabled && TracingConfig.OTLPEndpoint == "" {
+		invalid("OTLPEndpoint must be set once TracingEnabled")
+	}
+	if TracingConfig.TraceSampleRatio < 0 || TracingConfig.TraceSampleRatio > 1 {
+		invalid("TraceSampleRatio must be between 0 and 1, got %v", TracingConfig.TraceSampleRatio)
+	}
+
+	if PolicyConfig.LoanPeriodInDays <= 0 {
+		invalid("LoanPeriodInDays must be positive, got %d", PolicyConfig.LoanPeriodInDays)
+	}
+	if PolicyConfig.HoldPickupInDays <= 0 {
+		invalid("HoldPickupInDays must be positive, got %d", PolicyConfig.HoldPickupInDays)
+	}
+	return errors.Join(errs...)
+}
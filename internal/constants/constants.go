@@ -4,10 +4,195 @@ package constants
 const (
 	LocalStore    = "local"
 	PostgresStore = "postgres"
+	SQLiteStore   = "sqlite"
 )
 
 // Loan status
 const (
-	Active = "active"
-	Closed = "closed"
+	Active  = "active"
+	Overdue = "overdue" // not returned by the return date, accrues a fine
+	Closed  = "closed"
+)
+
+// Loan event types, one is recorded on each transition of a loan
+const (
+	LoanCreated  = "created"
+	LoanExtended = "extended"
+	LoanOverdue  = "overdue"
+	LoanReturned = "returned"
+)
+
+// Actors of the loan events
+const (
+	ActorHeader    = "X-Actor"   // names the actor of a request
+	ActorAnonymous = "anonymous" // requests not naming the actor
+	ActorSystem    = "system"    // background jobs
+)
+
+// Request IDs correlating the log entries of a request, taken from the caller or generated
+const (
+	RequestIDHeader    = "X-Request-ID"
+	MaxRequestIDLength = 128
+)
+
+// Conditional requests, the version of a book or a loan is served as its ETag
+const (
+	ETagHeader    = "ETag"
+	IfMatchHeader = "If-Match" // refuses changes with 412 once the version moved on
+)
+
+// Idempotent requests, retries of a mutating request with the same key replay its response
+const (
+	IdempotencyKeyHeader     = "Idempotency-Key"
+	IdempotentReplayedHeader = "Idempotent-Replayed" // marks a replayed response
+	MaxIdempotencyKeyLength  = 255
+	MaxIdempotentBodyBytes   = 1 << 20 // size of the body of a request read to fingerprint it
+)
+
+// API keys of the integrating systems, a key may write the resources of its write scopes
+// and read the ones of its read or write scopes
+const (
+	APIKeyHeader      = "X-API-Key"
+	APIKeyPrefix      = "lib_"
+	ScopeCatalog      = "catalog"     // books and copies
+	ScopeMembers      = "members"     // members
+	ScopeCirculation  = "circulation" // loans, holds and fines
+	ScopeRead         = "read"
+	ScopeWrite        = "write"
+	RateLimitHeader   = "X-RateLimit-Limit"
+	RateLimitLeft     = "X-RateLimit-Remaining"
+	RetryAfterHeader  = "Retry-After"
+	APIKeyActorPrefix = "apikey:" // actor of the changes made with a key, followed by its name
+)
+
+// Roles of the authenticated callers
+const (
+	RoleLibrarian = "librarian" // changes the catalog and acts on any loan
+	RoleMember    = "member"    // views the catalog and acts on own loans, holds and fines
+)
+
+// Fine status
+const (
+	FineAccruing = "accruing" // grows each day the loan stays overdue
+	FineUnpaid   = "unpaid"   // finalised on return
+	FinePaid     = "paid"
+	FineWaived   = "waived"
+)
+
+// Copy status
+const (
+	CopyAvailable = "available"
+	CopyOnLoan    = "on_loan"
+	CopyOnHold    = "on_hold" // set aside for the member of a ready hold
+	CopyInRepair  = "in_repair"
+	CopyLost      = "lost"
+	CopyWithdrawn = "withdrawn"
+)
+
+// Hold status, waiting holds queue up per book in the order placed
+const (
+	HoldWaiting   = "waiting"
+	HoldReady     = "ready" // a copy is set aside till the hold expires
+	HoldFulfilled = "fulfilled"
+	HoldCancelled = "cancelled"
+	HoldExpired   = "expired"
+)
+
+// Member status, only active members borrow
+const (
+	MemberActive    = "active"
+	MemberSuspended = "suspended"
+	MemberExpired   = "expired"
+)
+
+// Reasons a loan is refused by the borrowing policy
+const (
+	ReasonMemberInactive   = "member_inactive"
+	ReasonLoanLimitReached = "loan_limit_reached"
+	ReasonDuplicateTitle   = "duplicate_title"
+	ReasonOverdueItems     = "overdue_items"
+	ReasonUnpaidFines      = "unpaid_fines"
+	// refuses extending a loan
+	ReasonExtensionLimitReached = "extension_limit_reached"
+	// refuses placing a hold
+	ReasonHoldLimitReached = "hold_limit_reached"
+)
+
+// Membership tier
+const (
+	TierStandard = "standard"
+	TierStudent  = "student"
+	TierPremium  = "premium"
+	// DefaultTier given when missed
+	DefaultTier = TierStandard
+)
+
+// Copy condition
+const (
+	ConditionNew     = "new"
+	ConditionGood    = "good"
+	ConditionWorn    = "worn"
+	ConditionDamaged = "damaged"
+	// DefaultCondition given when missed
+	DefaultCondition = ConditionGood
+)
+
+// Searchable book fields, in descending order of weight
+const (
+	SearchFieldTitle       = "title"
+	SearchFieldAuthors     = "authors"
+	SearchFieldSubjects    = "subjects"
+	SearchFieldDescription = "description"
+)
+
+// Highlight tags wrapping the matches of a search
+const (
+	HighlightStart = "<mark>"
+	HighlightStop  = "</mark>"
+)
+
+// No of search results returned when the limit is missed, and at most
+const (
+	DefaultSearchLimit = 20
+	MaxSearchLimit     = 100
+)
+
+// No of listed items in a page when the limit is missed, and at most
+const (
+	DefaultPageLimit = 50
+	MaxPageLimit     = 100
+)
+
+// No of copies a request adds or withdraws at most, the copies are created one by one under the lock of the store
+const MaxCopiesChange = 1000
+
+// Sort keys of listings
+const (
+	SortByID              = "id"
+	SortByTitle           = "title"
+	SortByPublicationYear = "publication_year"
+	SortByName            = "name"
+	SortByBorrower        = "name_of_borrower"
+	SortByLoanDate        = "loan_date"
+	SortByReturnDate      = "return_date"
+)
+
+// Sort order
+const (
+	OrderAsc  = "asc"
+	OrderDesc = "desc"
+)
+
+// Status of the health report and of its checks
+const (
+	HealthOK           = "ok"
+	HealthFailed       = "failed"
+	HealthUnavailable  = "unavailable"
+	HealthShuttingDown = "shutting_down"
+)
+
+// Modes of the logger
+const (
+	LogModeDevelopment = "development"
+	LogModeProduction  = "production"
 )
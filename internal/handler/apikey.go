@@ -0,0 +1,203 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/test/library-app/internal/auth"
+	"github.com/test/library-app/internal/logger"
+	"github.com/test/library-app/internal/model"
+)
+
+// validateAPIKeyRequest validates a request to issue an api key, returns the reason when invalid
+func validateAPIKeyRequest(req *model.APIKeyRequest) string {
+	req.Name = strings.TrimSpace(req.Name)
+	if req.Name == "" {
+		return "Name missed in the request"
+	}
+	if len(req.Scopes) == 0 {
+		return "Scopes missed in the request"
+	}
+	for _, scope := range req.Scopes {
+		if !auth.ValidScope(scope) {
+			return "Scope " + scope + " must be catalog, members or circulation followed by :read or :write"
+		}
+	}
+	if req.RequestsPerMinute < 0 {
+		return "RequestsPerMinute must not be negative"
+	}
+	return ""
+}
+
+// IssueAPIKey godoc
+//
+//	@Summary 		IssueAPIKey issues an api key
+//	@Description 	IssueAPIKey issues a key for an integrating system to call the api with in the X-API-Key header, within its scopes and its quota of requests a minute. The key is returned once, only its hash is stored
+//	@Param			apiKeyRequest	body	model.APIKeyRequest	true	"API Key Request"
+//	@Consume 		json	model.APIKeyRequest
+//	@Produce 		json
+//	@Success 		201	{object}	model.IssuedAPIKey
+//	@Failure 		400	{object}	model.CustomError
+//	@Failure 		401	{object}	model.CustomError
+//	@Failure 		403	{object}	model.CustomError
+//	@Failure 		409	{object}	model.CustomError
+//	@Failure 		500	{object}	model.CustomError
+//	@Security 		BearerAuth
+//	@Router 		/apikey	[post]
+//
+// IssueAPIKey issues an api key
+func (h *Handler) IssueAPIKey(c *gin.Context) {
+	var keyReq model.APIKeyRequest
+	if err := c.ShouldBindJSON(&keyReq); err != nil {
+		logger.FromContext(c).Errorf("Failed to unamrshal the request body: %v", err)
+		customError := &model.CustomError{
+			Error: "invalid request body",
+			Code:  http.StatusBadRequest,
+		}
+		c.JSON(http.StatusBadRequest, customError)
+		return
+	}
+	if msg := validateAPIKeyRequest(&keyReq); msg != "" {
+		logger.FromContext(c).Errorf("invalid request to issue an api key: %s", msg)
+		customError := &model.CustomError{
+			Error: msg,
+			Code:  http.StatusBadRequest,
+		}
+		c.JSON(http.StatusBadRequest, customError)
+		return
+	}
+	key, prefix, hash, err := auth.NewAPIKey()
+	if err != nil {
+		logger.FromContext(c).Errorf("generating api key failed. Error: %v", err)
+		customError := &model.CustomError{
+			Error: err.Error(),
+			Code:  http.StatusInternalServerError,
+		}
+		c.JSON(http.StatusInternalServerError, customError)
+		return
+	}
+	issued := &model.IssuedAPIKey{
+		APIKey: model.APIKey{
+			Name:              keyReq.Name,
+			Prefix:            prefix,
+			Hash:              hash,
+			Scopes:            keyReq.Scopes,
+			RequestsPerMinute: keyReq.RequestsPerMinute,
+			CreatedAt:         time.Now().Unix(),
+		},
+		Key: key,
+	}
+	if _, err := h.repo.AddAPIKey(c, &issued.APIKey); err != nil {
+		// if the name is taken by another key
+		if errors.Is(err, model.ErrAlreadyExists) {
+			customError := &model.CustomError{
+				Error: err.Error(),
+				Code:  http.StatusConflict,
+			}
+			c.JSON(http.StatusConflict, customError)
+			return
+		}
+		// rest of all errors falls under this category
+		logger.FromContext(c).Errorf("issuing api key %s failed. Error: %v", keyReq.Name, err)
+		customError := &model.CustomError{
+			Error: err.Error(),
+			Code:  http.StatusInternalServerError,
+		}
+		c.JSON(http.StatusInternalServerError, customError)
+		return
+	}
+	c.JSON(http.StatusCreated, issued)
+}
+
+// GetAllAPIKeys godoc
+//
+//	@Summary 		GetAllAPIKeys fetches the api keys
+//	@Description 	GetAllAPIKeys retrieves the issued and revoked api keys in the order issued, without the keys themselves
+//	@Produce 		json
+//	@Success 		200	{object}	[]model.APIKey
+//	@Failure 		401	{object}	model.CustomError
+//	@Failure 		403	{object}	model.CustomError
+//	@Failure 		500	{object}	model.CustomError
+//	@Security 		BearerAuth
+//	@Router 		/apikey	[get]
+//
+// GetAllAPIKeys retrieves the api keys
+func (h *Handler) GetAllAPIKeys(c *gin.Context) {
+	keys, err := h.repo.GetAllAPIKeys(c)
+	if err != nil {
+		// rest of all errors falls under this category
+		logger.FromContext(c).Errorf("fetching api keys failed. Error: %v", err)
+		customError := &model.CustomError{
+			Error: err.Error(),
+			Code:  http.StatusInternalServerError,
+		}
+		c.JSON(http.StatusInternalServerError, customError)
+		return
+	}
+	c.JSON(http.StatusOK, keys)
+}
+
+// RevokeAPIKey godoc
+//
+//	@Summary 		RevokeAPIKey revokes an api key
+//	@Description 	RevokeAPIKey revokes an api key, requests made with it fail with 401 from then on
+//	@Param			id	path	int	true	"API key id"
+//	@Produce 		json
+//	@Success 		200	{object}	model.APIKey
+//	@Failure 		400	{object}	model.CustomError
+//	@Failure 		401	{object}	model.CustomError
+//	@Failure 		403	{object}	model.CustomError
+//	@Failure 		404	{object}	model.CustomError
+//	@Failure 		409	{object}	model.CustomError
+//	@Failure 		500	{object}	model.CustomError
+//	@Security 		BearerAuth
+//	@Router 		/apikey/{id}	[delete]
+//
+// RevokeAPIKey revokes an api key
+func (h *Handler) RevokeAPIKey(c *gin.Context) {
+	id := c.Param("id")
+	idInt, err := strconv.Atoi(id)
+	if err != nil {
+		logger.FromContext(c).Errorf("invalid id %s to revoke api key", id)
+		customError := &model.CustomError{
+			Error: "id is mandatory",
+			Code:  http.StatusBadRequest,
+		}
+		c.JSON(http.StatusBadRequest, customError)
+		return
+	}
+	key, err := h.repo.RevokeAPIKey(c, idInt, time.Now())
+	if err != nil {
+		// if notfound needs to return the specific error code and details
+		if errors.Is(err, model.ErrNotFound) {
+			customError := &model.CustomError{
+				Error: err.Error(),
+				Code:  http.StatusNotFound,
+			}
+			c.JSON(http.StatusNotFound, customError)
+			return
+		}
+		// revoked already
+		if errors.Is(err, model.ErrConflict) {
+			customError := &model.CustomError{
+				Error: err.Error(),
+				Code:  http.StatusConflict,
+			}
+			c.JSON(http.StatusConflict, customError)
+			return
+		}
+		// rest of all errors falls under this category
+		logger.FromContext(c).Errorf("revoking api key %d failed. Error: %v", idInt, err)
+		customError := &model.CustomError{
+			Error: err.Error(),
+			Code:  http.StatusInternalServerError,
+		}
+		c.JSON(http.StatusInternalServerError, customError)
+		return
+	}
+	c.JSON(http.StatusOK, key)
+}
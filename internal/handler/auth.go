@@ -0,0 +1,235 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/test/library-app/internal/auth"
+	"github.com/test/library-app/internal/constants"
+	"github.com/test/library-app/internal/logger"
+	"github.com/test/library-app/internal/model"
+	"go.uber.org/zap"
+)
+
+// Authenticate verifies the api key or the bearer token of the request and carries its principal into the request context,
+// requests are refused with 401 when the token is missed or invalid. Api keys are verified and held to their scopes
+// whether authentication is enabled or not, it lets every other request through when disabled
+func (h *Handler) Authenticate(c *gin.Context) {
+	if key := c.GetHeader(constants.APIKeyHeader); key != "" {
+		h.authenticateAPIKey(c, key)
+		return
+	}
+	if h.authn == nil {
+		c.Next()
+		return
+	}
+	token, ok := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer ")
+	if !ok || strings.TrimSpace(token) == "" {
+		c.Header("WWW-Authenticate", `Bearer realm="library-app"`)
+		customError := &model.CustomError{
+			Error: "bearer token missed in the request",
+			Code:  http.StatusUnauthorized,
+		}
+		c.AbortWithStatusJSON(http.StatusUnauthorized, customError)
+		return
+	}
+	principal, err := h.authn.Authenticate(strings.TrimSpace(token))
+	if err != nil {
+		logger.FromContext(c).Errorf("authenticating request failed. Error: %v", err)
+		c.Header("WWW-Authenticate", `Bearer realm="library-app", error="invalid_token"`)
+		customError := &model.CustomError{
+			Error: "invalid bearer token",
+			Code:  http.StatusUnauthorized,
+		}
+		c.AbortWithStatusJSON(http.StatusUnauthorized, customError)
+		return
+	}
+	c.Request = c.Request.WithContext(auth.WithPrincipal(c.Request.Context(), principal))
+	if principal.MemberID != 0 {
+		logWith(c, zap.Int("member_id", principal.MemberID))
+	}
+	c.Next()
+}
+
+// authenticateAPIKey authenticates a request made with an api key, refused with 401 when the key is unknown or revoked
+// and with 403 when the route is out of the scopes of the key
+func (h *Handler) authenticateAPIKey(c *gin.Context, key string) {
+	apiKey, err := h.repo.GetAPIKeyByHash(c, auth.HashAPIKey(key))
+	if err != nil && !errors.Is(err, model.ErrNotFound) {
+		logger.FromContext(c).Errorf("fetching api key failed. Error: %v", err)
+		customError := &model.CustomError{
+			Error: err.Error(),
+			Code:  http.StatusInternalServerError,
+		}
+		c.AbortWithStatusJSON(http.StatusInternalServerError, customError)
+		return
+	}
+	if err != nil || apiKey.RevokedAt != 0 {
+		logger.FromContext(c).Errorf("request made with an unknown or revoked api key")
+		customError := &model.CustomError{
+			Error: "invalid api key",
+			Code:  http.StatusUnauthorized,
+		}
+		c.AbortWithStatusJSON(http.StatusUnauthorized, customError)
+		return
+	}
+	principal := auth.APIKeyPrincipal(apiKey)
+	resource := routeResource(c.FullPath())
+	write := c.Request.Method != http.MethodGet && c.Request.Method != http.MethodHead && c.Request.Method != http.MethodOptions
+	if resource == "" || !principal.Allows(resource, write) {
+		logger.FromContext(c).Errorf("api key %d refused %s %s", apiKey.ID, c.Request.Method, c.FullPath())
+		customError := &model.CustomError{
+			Error: "request is out of the scopes of the api key",
+			Code:  http.StatusForbidden,
+		}
+		c.AbortWithStatusJSON(http.StatusForbidden, customError)
+		return
+	}
+	c.Request = c.Request.WithContext(auth.WithPrincipal(c.Request.Context(), principal))
+	c.Next()
+}
+
+// routeResource gives the resource of a route the scopes of the api keys refer to, empty for the routes api keys can't call.
+// The loans, holds and fines of a member are circulation
+func routeResource(path string) string {
+	segments := strings.Split(strings.Trim(path, "/"), "/")
+	switch segments[len(segments)-1] {
+	case "loans", "holds", "fines":
+		return constants.ScopeCirculation
+	}
+	for _, segment := range segments {
+		switch segment {
+		case "book", "copy":
+			return constants.ScopeCatalog
+		case "member":
+			return constants.ScopeMembers
+		case "loan", "hold", "fine":
+			return constants.ScopeCirculation
+		}
+	}
+	return ""
+}
+
+// Admin refuses the requests of members and api keys with 403, it guards the administration of the api keys.
+// Unlike the rest of the api it requires an authenticated librarian, refusing anonymous requests with 401 even while authentication is disabled
+func (h *Handler) Admin(c *gin.Context) {
+	principal := auth.PrincipalFrom(c.Request.Context())
+	if principal == nil {
+		logger.FromContext(c).Errorf("anonymous request refused %s %s", c.Request.Method, c.FullPath())
+		c.Header("WWW-Authenticate", `Bearer realm="library-app"`)
+		customError := &model.CustomError{
+			Error: "request is allowed to authenticated librarians only",
+			Code:  http.StatusUnauthorized,
+		}
+		c.AbortWithStatusJSON(http.StatusUnauthorized, customError)
+		return
+	}
+	if !principal.IsLibrarian() || principal.APIKey != nil {
+		logger.FromContext(c).Errorf("%s refused %s %s", principal.Subject, c.Request.Method, c.FullPath())
+		customError := &model.CustomError{
+			Error: "request is allowed to librarians only",
+			Code:  http.StatusForbidden,
+		}
+		c.AbortWithStatusJSON(http.StatusForbidden, customError)
+		return
+	}
+	c.Next()
+}
+
+// Librarian refuses the requests of members with 403, it guards the catalog changes and the member administration
+func (h *Handler) Librarian(c *gin.Context) {
+	if principal := auth.PrincipalFrom(c.Request.Context()); principal != nil && !principal.IsLibrarian() {
+		logger.FromContext(c).Errorf("member %d refused %s %s", principal.MemberID, c.Request.Method, c.FullPath())
+		customError := &model.CustomError{
+			Error: "request is allowed to librarians only",
+			Code:  http.StatusForbidden,
+		}
+		c.AbortWithStatusJSON(http.StatusForbidden, customError)
+		return
+	}
+	c.Next()
+}
+
+// memberScope gives the member a member principal is restricted to, 0 for librarians and unauthenticated requests
+func memberScope(c *gin.Context) int {
+	if principal := auth.PrincipalFrom(c.Request.Context()); principal != nil && !principal.IsLibrarian() {
+		return principal.MemberID
+	}
+	return 0
+}
+
+// allowMember answers 403 and reports false when a member acts on another member
+func allowMember(c *gin.Context, memberID int) bool {
+	logWith(c, zap.Int("member_id", memberID))
+	if scope := memberScope(c); scope != 0 && scope != memberID {
+		logger.FromContext(c).Errorf("member %d refused to act on member %d", scope, memberID)
+		customError := &model.CustomError{
+			Error: "member may act on own account only",
+			Code:  http.StatusForbidden,
+		}
+		c.JSON(http.StatusForbidden, customError)
+		return false
+	}
+	return true
+}
+
+// allowLoan answers and reports false when a member acts on the loan of another member,
+// a missed loan is answered with 404 as the handlers do
+func (h *Handler) allowLoan(c *gin.Context, loanID int) bool {
+	logWith(c, zap.Int("loan_id", loanID))
+	if memberScope(c) == 0 {
+		return true
+	}
+	loan, err := h.repo.GetLoan(c, loanID)
+	if err != nil {
+		// if notfound needs to return the specific error code and details
+		if errors.Is(err, model.ErrNotFound) {
+			customError := &model.CustomError{
+				Error: err.Error(),
+				Code:  http.StatusNotFound,
+			}
+			c.JSON(http.StatusNotFound, customError)
+			return false
+		}
+		// rest of all errors falls under this category
+		logger.FromContext(c).Errorf("fetching loan %d failed. Error: %v", loanID, err)
+		customError := &model.CustomError{
+			Error: err.Error(),
+			Code:  http.StatusInternalServerError,
+		}
+		c.JSON(http.StatusInternalServerError, customError)
+		return false
+	}
+	return allowMember(c, loan.MemberID)
+}
+
+// allowHold answers and reports false when a member cancels a hold not among their own
+func (h *Handler) allowHold(c *gin.Context, holdID int) bool {
+	scope := memberScope(c)
+	if scope == 0 {
+		return true
+	}
+	holds, err := h.repo.GetMemberHolds(c, scope)
+	if err != nil {
+		logger.FromContext(c).Errorf("fetching holds of member %d failed. Error: %v", scope, err)
+		customError := &model.CustomError{
+			Error: err.Error(),
+			Code:  http.StatusInternalServerError,
+		}
+		c.JSON(http.StatusInternalServerError, customError)
+		return false
+	}
+	if !slices.ContainsFunc(holds, func(hold *model.Hold) bool { return hold.ID == holdID }) {
+		logger.FromContext(c).Errorf("member %d refused to cancel hold %d", scope, holdID)
+		customError := &model.CustomError{
+			Error: "member may act on own account only",
+			Code:  http.StatusForbidden,
+		}
+		c.JSON(http.StatusForbidden, customError)
+		return false
+	}
+	return true
+}
@@ -0,0 +1,31 @@
+package handler
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/test/library-app/internal/constants"
+)
+
+// etag formats the version of a book or a loan as a strong entity tag
+func etag(version int) string {
+	return `"` + strconv.Itoa(version) + `"`
+}
+
+// ifMatchVersion reads the version the request is conditioned on, 0 when the header is missed or is *.
+// Weak tags and lists never match the single strong tag served, so they are reported as invalid
+func ifMatchVersion(c *gin.Context) (int, bool) {
+	header := strings.TrimSpace(c.GetHeader(constants.IfMatchHeader))
+	if header == "" || header == "*" {
+		return 0, true
+	}
+	if len(header) < 3 || header[0] != '"' || header[len(header)-1] != '"' {
+		return 0, false
+	}
+	version, err := strconv.Atoi(header[1 : len(header)-1])
+	if err != nil || version <= 0 {
+		return 0, false
+	}
+	return version, true
+}
@@ -0,0 +1,213 @@
+package handler
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/test/library-app/internal/logger"
+	"github.com/test/library-app/internal/model"
+)
+
+// GetMemberFines godoc
+//
+//	@Summary 		GetMemberFines fetches the fines of a member
+//	@Description 	GetMemberFines retrieves the fines of a member in the order fined along with the total owed. Fines accrue daily while a loan is overdue and get finalised on return
+//	@Param			id	path	int	true	"Member id"
+//	@Produce 		json
+//	@Success 		200	{object}	model.MemberFines
+//	@Failure 		400	{object}	model.CustomError
+//	@Failure 		401	{object}	model.CustomError
+//	@Failure 		403	{object}	model.CustomError
+//	@Failure 		404	{object}	model.CustomError
+//	@Failure 		500	{object}	model.CustomError
+//	@Security 		BearerAuth
+//	@Security 		APIKeyAuth
+//	@Router 		/member/{id}/fines	[get]
+//
+// GetMemberFines retrieves the fines of a member
+func (h *Handler) GetMemberFines(c *gin.Context) {
+	id := c.Param("id")
+	idInt, err := strconv.Atoi(id)
+	if err != nil {
+		logger.FromContext(c).Errorf("invalid id %s to fetch fines", id)
+		customError := &model.CustomError{
+			Error: "id is mandatory",
+			Code:  http.StatusBadRequest,
+		}
+		c.JSON(http.StatusBadRequest, customError)
+		return
+	}
+	if !allowMember(c, idInt) {
+		return
+	}
+	fines, err := h.repo.GetMemberFines(c, idInt)
+	if err != nil {
+		// if notfound needs to return the specific error code and details
+		if errors.Is(err, model.ErrNotFound) {
+			customError := &model.CustomError{
+				Error: err.Error(),
+				Code:  http.StatusNotFound,
+			}
+			c.JSON(http.StatusNotFound, customError)
+			return
+		}
+		logger.FromContext(c).Errorf("fetching fines of member %d failed. Error: %v", idInt, err)
+		// rest of all errors falls under this category
+		customError := &model.CustomError{
+			Error: err.Error(),
+			Code:  http.StatusInternalServerError,
+		}
+		c.JSON(http.StatusInternalServerError, customError)
+		return
+	}
+	c.JSON(http.StatusOK, fines)
+}
+
+// PayFine godoc
+//
+//	@Summary 		PayFine pays a fine
+//	@Description 	PayFine pays the amount of an accruing or unpaid fine, the outstanding amount when missed. An unpaid fine gets paid once nothing is outstanding, paying more than outstanding fails with 409
+//	@Param			id					path	int							true	"Fine id"
+//	@Param			finePaymentRequest	body	model.FinePaymentRequest	false	"Fine Payment Request"
+//	@Consume 		json	model.FinePaymentRequest
+//	@Produce 		json
+//	@Success 		200	{object}	model.Fine
+//	@Failure 		400	{object}	model.CustomError
+//	@Failure 		401	{object}	model.CustomError
+//	@Failure 		403	{object}	model.CustomError
+//	@Failure 		404	{object}	model.CustomError
+//	@Failure 		409	{object}	model.CustomError
+//	@Failure 		500	{object}	model.CustomError
+//	@Security 		BearerAuth
+//	@Security 		APIKeyAuth
+//	@Router 		/fine/{id}/pay	[post]
+//
+// PayFine pays a fine
+func (h *Handler) PayFine(c *gin.Context) {
+	id := c.Param("id")
+	idInt, err := strconv.Atoi(id)
+	if err != nil {
+		logger.FromContext(c).Errorf("invalid id %s to pay fine", id)
+		customError := &model.CustomError{
+			Error: "id is mandatory",
+			Code:  http.StatusBadRequest,
+		}
+		c.JSON(http.StatusBadRequest, customError)
+		return
+	}
+	// the body is optional, paying the outstanding amount
+	var paymentReq model.FinePaymentRequest
+	if err := c.ShouldBindJSON(&paymentReq); err != nil && !errors.Is(err, io.EOF) {
+		logger.FromContext(c).Errorf("Failed to unamrshal the request body: %v", err)
+		customError := &model.CustomError{
+			Error: "invalid request body",
+			Code:  http.StatusBadRequest,
+		}
+		c.JSON(http.StatusBadRequest, customError)
+		return
+	}
+	if paymentReq.AmountInCents < 0 {
+		logger.FromContext(c).Errorf("invalid amount %d to pay fine %d", paymentReq.AmountInCents, idInt)
+		customError := &model.CustomError{
+			Error: "AmountInCents can't be negative",
+			Code:  http.StatusBadRequest,
+		}
+		c.JSON(http.StatusBadRequest, customError)
+		return
+	}
+	fine, err := h.repo.PayFine(c, idInt, paymentReq.AmountInCents)
+	if err != nil {
+		// if notfound needs to return the specific error code and details
+		if errors.Is(err, model.ErrNotFound) {
+			customError := &model.CustomError{
+				Error: err.Error(),
+				Code:  http.StatusNotFound,
+			}
+			c.JSON(http.StatusNotFound, customError)
+			return
+		}
+		// fine already settled or paying more than outstanding
+		if errors.Is(err, model.ErrConflict) {
+			customError := &model.CustomError{
+				Error: err.Error(),
+				Code:  http.StatusConflict,
+			}
+			c.JSON(http.StatusConflict, customError)
+			return
+		}
+		// rest of all errors falls under this category
+		logger.FromContext(c).Errorf("paying fine %d failed. Error: %v", idInt, err)
+		customError := &model.CustomError{
+			Error: err.Error(),
+			Code:  http.StatusInternalServerError,
+		}
+		c.JSON(http.StatusInternalServerError, customError)
+		return
+	}
+	c.JSON(http.StatusOK, fine)
+}
+
+// WaiveFine godoc
+//
+//	@Summary 		WaiveFine waives a fine
+//	@Description 	WaiveFine waives an accruing or unpaid fine, nothing more is owed for it and it stops accruing
+//	@Param			id	path	int	true	"Fine id"
+//	@Produce 		json
+//	@Success 		200	{object}	model.Fine
+//	@Failure 		400	{object}	model.CustomError
+//	@Failure 		401	{object}	model.CustomError
+//	@Failure 		403	{object}	model.CustomError
+//	@Failure 		404	{object}	model.CustomError
+//	@Failure 		409	{object}	model.CustomError
+//	@Failure 		500	{object}	model.CustomError
+//	@Security 		BearerAuth
+//	@Security 		APIKeyAuth
+//	@Router 		/fine/{id}/waive	[post]
+//
+// WaiveFine waives a fine
+func (h *Handler) WaiveFine(c *gin.Context) {
+	id := c.Param("id")
+	idInt, err := strconv.Atoi(id)
+	if err != nil {
+		logger.FromContext(c).Errorf("invalid id %s to waive fine", id)
+		customError := &model.CustomError{
+			Error: "id is mandatory",
+			Code:  http.StatusBadRequest,
+		}
+		c.JSON(http.StatusBadRequest, customError)
+		return
+	}
+	fine, err := h.repo.WaiveFine(c, idInt)
+	if err != nil {
+		// if notfound needs to return the specific error code and details
+		if errors.Is(err, model.ErrNotFound) {
+			customError := &model.CustomError{
+				Error: err.Error(),
+				Code:  http.StatusNotFound,
+			}
+			c.JSON(http.StatusNotFound, customError)
+			return
+		}
+		// fine already paid or waived
+		if errors.Is(err, model.ErrConflict) {
+			customError := &model.CustomError{
+				Error: err.Error(),
+				Code:  http.StatusConflict,
+			}
+			c.JSON(http.StatusConflict, customError)
+			return
+		}
+		// rest of all errors falls under this category
+		logger.FromContext(c).Errorf("waiving fine %d failed. Error: %v", idInt, err)
+		customError := &model.CustomError{
+			Error: err.Error(),
+			Code:  http.StatusInternalServerError,
+		}
+		c.JSON(http.StatusInternalServerError, customError)
+		return
+	}
+	c.JSON(http.StatusOK, fine)
+}
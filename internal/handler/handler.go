@@ -2,60 +2,889 @@ package handler
 
 import (
 	"errors"
+	"fmt"
 	"net/http"
+	"slices"
 	"strconv"
+	"strings"
 	"time"
+	"unicode"
 
 	"github.com/gin-gonic/gin"
+	"github.com/test/library-app/internal/auth"
 	"github.com/test/library-app/internal/constants"
+	"github.com/test/library-app/internal/health"
 	"github.com/test/library-app/internal/logger"
 	"github.com/test/library-app/internal/model"
 	"github.com/test/library-app/internal/store"
 )
 
 type Handler struct {
-	repo store.Store
+	repo   store.Store
+	authn  *auth.Authenticator // verifies the bearer tokens, nil when authentication is disabled
+	quotas *quotas             // counts the requests of the api keys
+	checks *health.Registry    // checks reported by the health endpoint
 }
 
-// Initializes requests handler
-func NewHandler(s store.Store) *Handler {
+// Initializes requests handler, authn is nil when authentication is disabled
+func NewHandler(s store.Store, authn *auth.Authenticator, checks *health.Registry) *Handler {
 	return &Handler{
-		repo: s,
+		repo:   s,
+		authn:  authn,
+		quotas: newQuotas(),
+		checks: checks,
 	}
 }
 
+// Live tells the app is up, whatever the state of its dependencies
 func (h *Handler) Live(c *gin.Context) {
 	c.String(http.StatusOK, "")
 }
 
+// Health runs the registered checks and reports each of them, the app is ready to serve only when all passed.
+// It's refused with 503 once any check failed or the app started shutting down
 func (h *Handler) Health(c *gin.Context) {
-	c.String(http.StatusOK, "")
+	report := h.checks.Run(c.Request.Context())
+	if report.Status != constants.HealthOK {
+		logger.FromContext(c).Warnf("app isn't ready, health is %s", report.Status)
+		c.JSON(http.StatusServiceUnavailable, report)
+		return
+	}
+	c.JSON(http.StatusOK, report)
+}
+
+// GetAllBooks godoc
+//
+//	@Summary 		GetAllBooks fetches the book details
+//	@Description 	GetAllBooks retrieves a page of the books passing the filters, next_cursor continues the listing and is missed on the last page
+//	@Param			limit		query	int		false	"No of books in the page (default 50, max 100)"
+//	@Param			cursor		query	string	false	"next_cursor of the previous page"
+//	@Param			sort		query	string	false	"Sort key"	Enums(id, title, publication_year)
+//	@Param			order		query	string	false	"Sort order"	Enums(asc, desc)
+//	@Param			title		query	string	false	"Part of the title"
+//	@Param			author		query	string	false	"Part of any author"
+//	@Param			available	query	bool	false	"Books with or without available copies"
+//	@Produce 		json
+//	@Success 		200	{object}	model.BookPage
+//	@Failure 		400	{object}	model.CustomError
+//	@Failure 		401	{object}	model.CustomError
+//	@Failure 		500	{object}	model.CustomError
+//	@Security 		BearerAuth
+//	@Security 		APIKeyAuth
+//	@Router 		/book	[get]
+//
+// GetAllBooks retrieves a page of the books in store
+func (h *Handler) GetAllBooks(c *gin.Context) {
+	var query model.BookQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		logger.FromContext(c).Errorf("invalid query to list books. Error: %v", err)
+		customError := &model.CustomError{
+			Error: err.Error(),
+			Code:  http.StatusBadRequest,
+		}
+		c.JSON(http.StatusBadRequest, customError)
+		return
+	}
+	if msg := validatePageQuery(&query.PageQuery, constants.SortByID, constants.SortByTitle, constants.SortByPublicationYear); msg != "" {
+		logger.FromContext(c).Errorf("invalid query to list books: %s", msg)
+		customError := &model.CustomError{
+			Error: msg,
+			Code:  http.StatusBadRequest,
+		}
+		c.JSON(http.StatusBadRequest, customError)
+		return
+	}
+	page, err := h.repo.GetAllBookDetails(c, &query)
+	if err != nil {
+		// rest of all errors falls under this category
+		customError := &model.CustomError{
+			Error: err.Error(),
+			Code:  http.StatusInternalServerError,
+		}
+		c.JSON(http.StatusInternalServerError, customError)
+		return
+	}
+	c.JSON(http.StatusOK, page)
+}
+
+// GetAllLoans godoc
+//
+//	@Summary 		GetAllLoans fetches the loan details
+//	@Description 	GetAllLoans retrieves a page of the loans passing the filters, next_cursor continues the listing and is missed on the last page. Dates are unix epoch format
+//	@Param			limit		query	int		false	"No of loans in the page (default 50, max 100)"
+//	@Param			cursor		query	string	false	"next_cursor of the previous page"
+//	@Param			sort		query	string	false	"Sort key"	Enums(id, name_of_borrower, loan_date, return_date)
+//	@Param			order		query	string	false	"Sort order"	Enums(asc, desc)
+//	@Param			status		query	string	false	"Loan status"	Enums(active, overdue, closed)
+//	@Param			member_id	query	int		false	"ID of the borrowing member"
+//	@Param			borrower	query	string	false	"Name of borrower"
+//	@Param			title		query	string	false	"Part of the title"
+//	@Param			overdue		query	bool	false	"Loans not returned past their return date"
+//	@Param			loaned_from	query	int		false	"Loaned at or after"
+//	@Param			loaned_to	query	int		false	"Loaned at or before"
+//	@Param			due_from	query	int		false	"To be returned at or after"
+//	@Param			due_to		query	int		false	"To be returned at or before"
+//	@Produce 		json
+//	@Success 		200	{object}	model.LoanPage
+//	@Failure 		400	{object}	model.CustomError
+//	@Failure 		401	{object}	model.CustomError
+//	@Failure 		500	{object}	model.CustomError
+//	@Security 		BearerAuth
+//	@Security 		APIKeyAuth
+//	@Router 		/loan	[get]
+//
+// GetAllLoans retrieves a page of the loans from store
+func (h *Handler) GetAllLoans(c *gin.Context) {
+	var query model.LoanQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		logger.FromContext(c).Errorf("invalid query to list loans. Error: %v", err)
+		customError := &model.CustomError{
+			Error: err.Error(),
+			Code:  http.StatusBadRequest,
+		}
+		c.JSON(http.StatusBadRequest, customError)
+		return
+	}
+	msg := validatePageQuery(&query.PageQuery, constants.SortByID, constants.SortByBorrower, constants.SortByLoanDate, constants.SortByReturnDate)
+	if msg == "" && query.Status != "" && query.Status != constants.Active && query.Status != constants.Overdue && query.Status != constants.Closed {
+		msg = "status must be active, overdue or closed"
+	}
+	if msg != "" {
+		logger.FromContext(c).Errorf("invalid query to list loans: %s", msg)
+		customError := &model.CustomError{
+			Error: msg,
+			Code:  http.StatusBadRequest,
+		}
+		c.JSON(http.StatusBadRequest, customError)
+		return
+	}
+	// members list their own loans only
+	if scope := memberScope(c); scope != 0 {
+		query.MemberID = scope
+	}
+	page, err := h.repo.GetAllLoans(c, &query)
+	if err != nil {
+		// rest of all errors falls under this category
+		customError := &model.CustomError{
+			Error: err.Error(),
+			Code:  http.StatusInternalServerError,
+		}
+		c.JSON(http.StatusInternalServerError, customError)
+		return
+	}
+	c.JSON(http.StatusOK, page)
+}
+
+// validatePageQuery validates the paging of a listing sortable by the sort keys and decodes its cursor,
+// returns the reason when invalid
+func validatePageQuery(query *model.PageQuery, sortKeys ...string) string {
+	if query.Limit == 0 {
+		query.Limit = constants.DefaultPageLimit
+	}
+	if query.Limit < 0 || query.Limit > constants.MaxPageLimit {
+		return fmt.Sprintf("limit must be between 1 and %d", constants.MaxPageLimit)
+	}
+	if query.SortBy == "" {
+		query.SortBy = constants.SortByID
+	}
+	if !slices.Contains(sortKeys, query.SortBy) {
+		return fmt.Sprintf("sort must be one of %s", strings.Join(sortKeys, ", "))
+	}
+	if query.Order == "" {
+		query.Order = constants.OrderAsc
+	}
+	if query.Order != constants.OrderAsc && query.Order != constants.OrderDesc {
+		return "order must be asc or desc"
+	}
+	if query.Cursor != "" {
+		cursor, err := model.DecodeCursor(query.Cursor)
+		if err != nil || cursor.SortBy != query.SortBy || cursor.Desc != (query.Order == constants.OrderDesc) {
+			return "cursor doesn't continue this sort order"
+		}
+		query.After = cursor
+	}
+	return ""
+}
+
+// GetBook godoc
+//
+//	@Summary 		GetBook fetches the book details
+//	@Description 	GetBook retrieves the detail and available copies of a book title, the oldest one when several books share it
+//	@Param			title	path	string	true	"Title of the book"
+//	@Produce 		json
+//	@Success 		200	{object}	model.BookDetails
+//	@Header 		200	{string}	ETag	"Version of the book"
+//	@Failure 		401	{object}	model.CustomError
+//	@Failure 		404	{object}	model.CustomError
+//	@Failure 		500	{object}	model.CustomError
+//	@Security 		BearerAuth
+//	@Security 		APIKeyAuth
+//	@Router 		/book/{title}	[get]
+//
+// GetBook retrieves the detail and available copies of a book title
+func (h *Handler) GetBook(c *gin.Context) {
+	title := c.Param("title")
+	if len(title) == 0 {
+		logger.FromContext(c).Errorf("title is mandatory")
+		customError := &model.CustomError{
+			Error: "title is mandatory",
+			Code:  http.StatusBadRequest,
+		}
+		c.JSON(http.StatusBadRequest, customError)
+		return
+	}
+	det, err := h.repo.GetBookDetails(c, title)
+	if err != nil {
+		// if notfound needs to return the specific error code and details
+		if errors.Is(err, model.ErrNotFound) {
+			customError := &model.CustomError{
+				Error: err.Error(),
+				Code:  http.StatusNotFound,
+			}
+			c.JSON(http.StatusNotFound, customError)
+			return
+		}
+		logger.FromContext(c).Errorf("fetching title %s failed. Error: %v", title, err)
+		// rest of all errors falls under this category
+		customError := &model.CustomError{
+			Error: err.Error(),
+			Code:  http.StatusInternalServerError,
+		}
+		c.JSON(http.StatusInternalServerError, customError)
+		return
+	}
+	c.Header(constants.ETagHeader, etag(det.Version))
+	c.JSON(http.StatusOK, det)
+}
+
+// SearchBooks godoc
+//
+//	@Summary 		SearchBooks searches the catalog
+//	@Description 	SearchBooks ranks the books matching all words of the query across title, authors, subjects and description, highlighting the matches with <mark> tags
+//	@Param			q		query	string	true	"Search query"
+//	@Param			limit	query	int		false	"Max no of results (default 20, max 100)"
+//	@Produce 		json
+//	@Success 		200	{array}		model.BookSearchResult
+//	@Failure 		400	{object}	model.CustomError
+//	@Failure 		401	{object}	model.CustomError
+//	@Failure 		500	{object}	model.CustomError
+//	@Security 		BearerAuth
+//	@Security 		APIKeyAuth
+//	@Router 		/book/search	[get]
+//
+// SearchBooks searches the catalog
+func (h *Handler) SearchBooks(c *gin.Context) {
+	query := strings.TrimSpace(c.Query("q"))
+	if len(query) == 0 {
+		logger.FromContext(c).Errorf("q is mandatory to search books")
+		customError := &model.CustomError{
+			Error: "q is mandatory",
+			Code:  http.StatusBadRequest,
+		}
+		c.JSON(http.StatusBadRequest, customError)
+		return
+	}
+	limit := constants.DefaultSearchLimit
+	if l := c.Query("limit"); l != "" {
+		var err error
+		limit, err = strconv.Atoi(l)
+		if err != nil || limit <= 0 || limit > constants.MaxSearchLimit {
+			logger.FromContext(c).Errorf("invalid limit %s to search books", l)
+			customError := &model.CustomError{
+				Error: fmt.Sprintf("limit must be between 1 and %d", constants.MaxSearchLimit),
+				Code:  http.StatusBadRequest,
+			}
+			c.JSON(http.StatusBadRequest, customError)
+			return
+		}
+	}
+	results, err := h.repo.SearchBooks(c, query, limit)
+	if err != nil {
+		logger.FromContext(c).Errorf("searching books with %s failed. Error: %v", query, err)
+		customError := &model.CustomError{
+			Error: err.Error(),
+			Code:  http.StatusInternalServerError,
+		}
+		c.JSON(http.StatusInternalServerError, customError)
+		return
+	}
+	c.JSON(http.StatusOK, results)
+}
+
+// GetBookByID godoc
+//
+//	@Summary 		GetBookByID fetches the book details
+//	@Description 	GetBookByID retrieves the detail and available copies of a book by its id
+//	@Param			id	path	int	true	"Book id"
+//	@Produce 		json
+//	@Success 		200	{object}	model.BookDetails
+//	@Header 		200	{string}	ETag	"Version of the book"
+//	@Failure 		400	{object}	model.CustomError
+//	@Failure 		401	{object}	model.CustomError
+//	@Failure 		404	{object}	model.CustomError
+//	@Failure 		500	{object}	model.CustomError
+//	@Security 		BearerAuth
+//	@Security 		APIKeyAuth
+//	@Router 		/book/id/{id}	[get]
+//
+// GetBookByID retrieves the detail and available copies of a book by its id
+func (h *Handler) GetBookByID(c *gin.Context) {
+	id := c.Param("id")
+	idInt, err := strconv.Atoi(id)
+	if err != nil {
+		logger.FromContext(c).Errorf("invalid id %s to fetch book", id)
+		customError := &model.CustomError{
+			Error: "id is mandatory",
+			Code:  http.StatusBadRequest,
+		}
+		c.JSON(http.StatusBadRequest, customError)
+		return
+	}
+	det, err := h.repo.GetBookDetailsByID(c, idInt)
+	if err != nil {
+		// if notfound needs to return the specific error code and details
+		if errors.Is(err, model.ErrNotFound) {
+			customError := &model.CustomError{
+				Error: err.Error(),
+				Code:  http.StatusNotFound,
+			}
+			c.JSON(http.StatusNotFound, customError)
+			return
+		}
+		logger.FromContext(c).Errorf("fetching book %d failed. Error: %v", idInt, err)
+		// rest of all errors falls under this category
+		customError := &model.CustomError{
+			Error: err.Error(),
+			Code:  http.StatusInternalServerError,
+		}
+		c.JSON(http.StatusInternalServerError, customError)
+		return
+	}
+	c.Header(constants.ETagHeader, etag(det.Version))
+	c.JSON(http.StatusOK, det)
+}
+
+// validateBookRequest checks the mandatory fields and formats of a book request
+func validateBookRequest(req *model.BookRequest) string {
+	if req.Title == "" {
+		return "Title missed in the request"
+	}
+	if req.Copies < 0 || req.Copies > constants.MaxCopiesChange {
+		return fmt.Sprintf("Copies must be between 0 and %d", constants.MaxCopiesChange)
+	}
+	if req.ISBN != "" {
+		isbn := strings.ReplaceAll(req.ISBN, "-", "")
+		if len(isbn) != 10 && len(isbn) != 13 {
+			return "ISBN must have 10 or 13 digits"
+		}
+		for i, r := range isbn {
+			// ISBN-10 check digit may be X
+			if !unicode.IsDigit(r) && !(len(isbn) == 10 && i == 9 && r == 'X') {
+				return "ISBN must have 10 or 13 digits"
+			}
+		}
+		req.ISBN = isbn
+	}
+	if req.PublicationYear < 0 || req.PublicationYear > time.Now().Year()+1 {
+		return "PublicationYear is out of range"
+	}
+	return ""
+}
+
+// bookFromRequest maps a validated book request to the book details kept in store
+func bookFromRequest(req *model.BookRequest) *model.BookDetails {
+	return &model.BookDetails{
+		ISBN:            req.ISBN,
+		Title:           req.Title,
+		Authors:         req.Authors,
+		Publisher:       req.Publisher,
+		PublicationYear: req.PublicationYear,
+		Language:        req.Language,
+		Subjects:        req.Subjects,
+		Edition:         req.Edition,
+		Description:     req.Description,
+		Category:        strings.ToLower(strings.TrimSpace(req.Category)),
+		TotalCopies:     req.Copies,
+	}
+}
+
+// AddBook godoc
+//
+//	@Summary 		AddBook adds a book to the catalog
+//	@Description 	AddBook adds a book with its bibliographic details and the given number of copies to the catalog
+//	@Param			bookRequest	body	model.BookRequest	true "Book Request"
+//	@Consume 		json	model.BookRequest
+//	@Produce 		json
+//	@Success 		201	{object}	model.BookDetails
+//	@Failure 		400	{object}	model.CustomError
+//	@Failure 		401	{object}	model.CustomError
+//	@Failure 		403	{object}	model.CustomError
+//	@Failure 		409	{object}	model.CustomError
+//	@Failure 		500	{object}	model.CustomError
+//	@Security 		BearerAuth
+//	@Security 		APIKeyAuth
+//	@Router 		/book	[post]
+//
+// AddBook adds a book to the catalog
+func (h *Handler) AddBook(c *gin.Context) {
+	var bookReq model.BookRequest
+	if err := c.ShouldBindJSON(&bookReq); err != nil {
+		logger.FromContext(c).Errorf("Failed to unamrshal the request body: %v", err)
+		customError := &model.CustomError{
+			Error: "invalid request body",
+			Code:  http.StatusBadRequest,
+		}
+		c.JSON(http.StatusBadRequest, customError)
+		return
+	}
+	if msg := validateBookRequest(&bookReq); msg != "" {
+		logger.FromContext(c).Errorf("invalid request to add a book: %s", msg)
+		customError := &model.CustomError{
+			Error: msg,
+			Code:  http.StatusBadRequest,
+		}
+		c.JSON(http.StatusBadRequest, customError)
+		return
+	}
+	book := bookFromRequest(&bookReq)
+	_, err := h.repo.AddBook(c, book)
+	if err != nil {
+		// if the isbn already exists needs to return the specific error code and details
+		if errors.Is(err, model.ErrAlreadyExists) {
+			customError := &model.CustomError{
+				Error: err.Error(),
+				Code:  http.StatusConflict,
+			}
+			c.JSON(http.StatusConflict, customError)
+			return
+		}
+		// rest of all errors falls under this category
+		logger.FromContext(c).Errorf("adding title %s failed. Error: %v", bookReq.Title, err)
+		customError := &model.CustomError{
+			Error: err.Error(),
+			Code:  http.StatusInternalServerError,
+		}
+		c.JSON(http.StatusInternalServerError, customError)
+		return
+	}
+	c.JSON(http.StatusCreated, book)
+}
+
+// UpdateBook godoc
+//
+//	@Summary 		UpdateBook updates a book in the catalog
+//	@Description 	UpdateBook replaces the bibliographic details of a book, copies are managed separately
+//	@Param			id			path	int					true	"Book id"
+//	@Param			If-Match	header	string				false	"ETag of the book the update is based on"
+//	@Param			bookRequest	body	model.BookRequest	true	"Book Request"
+//	@Consume 		json	model.BookRequest
+//	@Produce 		json
+//	@Success 		200	{object}	model.BookDetails
+//	@Header 		200	{string}	ETag	"Version of the book"
+//	@Failure 		400	{object}	model.CustomError
+//	@Failure 		401	{object}	model.CustomError
+//	@Failure 		403	{object}	model.CustomError
+//	@Failure 		404	{object}	model.CustomError
+//	@Failure 		409	{object}	model.CustomError
+//	@Failure 		412	{object}	model.CustomError
+//	@Failure 		500	{object}	model.CustomError
+//	@Security 		BearerAuth
+//	@Security 		APIKeyAuth
+//	@Router 		/book/{id}	[put]
+//
+// UpdateBook updates a book in the catalog
+func (h *Handler) UpdateBook(c *gin.Context) {
+	id := c.Param("id")
+	idInt, err := strconv.Atoi(id)
+	if err != nil {
+		logger.FromContext(c).Errorf("invalid id %s to update book", id)
+		customError := &model.CustomError{
+			Error: "id is mandatory",
+			Code:  http.StatusBadRequest,
+		}
+		c.JSON(http.StatusBadRequest, customError)
+		return
+	}
+	version, ok := ifMatchVersion(c)
+	if !ok {
+		logger.FromContext(c).Errorf("invalid If-Match %s to update book %d", c.GetHeader(constants.IfMatchHeader), idInt)
+		customError := &model.CustomError{
+			Error: "If-Match must be a strong ETag",
+			Code:  http.StatusPreconditionFailed,
+		}
+		c.JSON(http.StatusPreconditionFailed, customError)
+		return
+	}
+	var bookReq model.BookRequest
+	if err := c.ShouldBindJSON(&bookReq); err != nil {
+		logger.FromContext(c).Errorf("Failed to unamrshal the request body: %v", err)
+		customError := &model.CustomError{
+			Error: "invalid request body",
+			Code:  http.StatusBadRequest,
+		}
+		c.JSON(http.StatusBadRequest, customError)
+		return
+	}
+	if msg := validateBookRequest(&bookReq); msg != "" {
+		logger.FromContext(c).Errorf("invalid request to update book %d: %s", idInt, msg)
+		customError := &model.CustomError{
+			Error: msg,
+			Code:  http.StatusBadRequest,
+		}
+		c.JSON(http.StatusBadRequest, customError)
+		return
+	}
+	book, err := h.repo.UpdateBook(c, idInt, version, bookFromRequest(&bookReq))
+	if err != nil {
+		// if notfound needs to return the specific error code and details
+		if errors.Is(err, model.ErrNotFound) {
+			customError := &model.CustomError{
+				Error: err.Error(),
+				Code:  http.StatusNotFound,
+			}
+			c.JSON(http.StatusNotFound, customError)
+			return
+		}
+		// if the isbn is taken by another book
+		if errors.Is(err, model.ErrAlreadyExists) {
+			customError := &model.CustomError{
+				Error: err.Error(),
+				Code:  http.StatusConflict,
+			}
+			c.JSON(http.StatusConflict, customError)
+			return
+		}
+		// changed since the version the request is conditioned on
+		if errors.Is(err, model.ErrPreconditionFailed) {
+			customError := &model.CustomError{
+				Error: err.Error(),
+				Code:  http.StatusPreconditionFailed,
+			}
+			c.JSON(http.StatusPreconditionFailed, customError)
+			return
+		}
+		// rest of all errors falls under this category
+		logger.FromContext(c).Errorf("updating book %d failed. Error: %v", idInt, err)
+		customError := &model.CustomError{
+			Error: err.Error(),
+			Code:  http.StatusInternalServerError,
+		}
+		c.JSON(http.StatusInternalServerError, customError)
+		return
+	}
+	c.Header(constants.ETagHeader, etag(book.Version))
+	c.JSON(http.StatusOK, book)
+}
+
+// UpdateBookCopies godoc
+//
+//	@Summary 		UpdateBookCopies adds or withdraws copies of a book
+//	@Description 	UpdateBookCopies adds copies with generated barcodes for a positive delta, withdraws available copies for a negative one
+//	@Param			id					path	int							true	"Book id"
+//	@Param			If-Match			header	string						false	"ETag of the book the update is based on"
+//	@Param			bookCopiesRequest	body	model.BookCopiesRequest		true	"Book Copies Request"
+//	@Consume 		json	model.BookCopiesRequest
+//	@Produce 		json
+//	@Success 		200	{object}	model.BookDetails
+//	@Header 		200	{string}	ETag	"Version of the book"
+//	@Failure 		400	{object}	model.CustomError
+//	@Failure 		401	{object}	model.CustomError
+//	@Failure 		403	{object}	model.CustomError
+//	@Failure 		404	{object}	model.CustomError
+//	@Failure 		409	{object}	model.CustomError
+//	@Failure 		412	{object}	model.CustomError
+//	@Failure 		500	{object}	model.CustomError
+//	@Security 		BearerAuth
+//	@Security 		APIKeyAuth
+//	@Router 		/book/{id}	[patch]
+//
+// UpdateBookCopies adds or withdraws copies of a book
+func (h *Handler) UpdateBookCopies(c *gin.Context) {
+	id := c.Param("id")
+	idInt, err := strconv.Atoi(id)
+	if err != nil {
+		logger.FromContext(c).Errorf("invalid id %s to update book copies", id)
+		customError := &model.CustomError{
+			Error: "id is mandatory",
+			Code:  http.StatusBadRequest,
+		}
+		c.JSON(http.StatusBadRequest, customError)
+		return
+	}
+	version, ok := ifMatchVersion(c)
+	if !ok {
+		logger.FromContext(c).Errorf("invalid If-Match %s to update copies of book %d", c.GetHeader(constants.IfMatchHeader), idInt)
+		customError := &model.CustomError{
+			Error: "If-Match must be a strong ETag",
+			Code:  http.StatusPreconditionFailed,
+		}
+		c.JSON(http.StatusPreconditionFailed, customError)
+		return
+	}
+	var copiesReq model.BookCopiesRequest
+	if err := c.ShouldBindJSON(&copiesReq); err != nil {
+		logger.FromContext(c).Errorf("Failed to unamrshal the request body: %v", err)
+		customError := &model.CustomError{
+			Error: "invalid request body",
+			Code:  http.StatusBadRequest,
+		}
+		c.JSON(http.StatusBadRequest, customError)
+		return
+	}
+	// a zero delta changes nothing but the version
+	if copiesReq.Delta == 0 || copiesReq.Delta < -constants.MaxCopiesChange || copiesReq.Delta > constants.MaxCopiesChange {
+		logger.FromContext(c).Errorf("invalid delta %d to update copies of book %d", copiesReq.Delta, idInt)
+		customError := &model.CustomError{
+			Error: fmt.Sprintf("Delta must be between -%d and %d, not 0", constants.MaxCopiesChange, constants.MaxCopiesChange),
+			Code:  http.StatusBadRequest,
+		}
+		c.JSON(http.StatusBadRequest, customError)
+		return
+	}
+	book, err := h.repo.UpdateBookCopies(c, idInt, version, copiesReq.Delta)
+	if err != nil {
+		// if notfound needs to return the specific error code and details
+		if errors.Is(err, model.ErrNotFound) {
+			customError := &model.CustomError{
+				Error: err.Error(),
+				Code:  http.StatusNotFound,
+			}
+			c.JSON(http.StatusNotFound, customError)
+			return
+		}
+		// withdrawing more copies than available
+		if errors.Is(err, model.ErrConflict) {
+			customError := &model.CustomError{
+				Error: err.Error(),
+				Code:  http.StatusConflict,
+			}
+			c.JSON(http.StatusConflict, customError)
+			return
+		}
+		// changed since the version the request is conditioned on
+		if errors.Is(err, model.ErrPreconditionFailed) {
+			customError := &model.CustomError{
+				Error: err.Error(),
+				Code:  http.StatusPreconditionFailed,
+			}
+			c.JSON(http.StatusPreconditionFailed, customError)
+			return
+		}
+		// rest of all errors falls under this category
+		logger.FromContext(c).Errorf("updating copies of book %d failed. Error: %v", idInt, err)
+		customError := &model.CustomError{
+			Error: err.Error(),
+			Code:  http.StatusInternalServerError,
+		}
+		c.JSON(http.StatusInternalServerError, customError)
+		return
+	}
+	c.Header(constants.ETagHeader, etag(book.Version))
+	c.JSON(http.StatusOK, book)
+}
+
+// DeleteBook godoc
+//
+//	@Summary 		DeleteBook removes a book from the catalog
+//	@Description 	DeleteBook removes a book from the catalog, refused while the book has active loans
+//	@Param			id	path	int	true	"Book id"
+//	@Produce 		json
+//	@Success 		200
+//	@Failure 		400	{object}	model.CustomError
+//	@Failure 		401	{object}	model.CustomError
+//	@Failure 		403	{object}	model.CustomError
+//	@Failure 		404	{object}	model.CustomError
+//	@Failure 		409	{object}	model.CustomError
+//	@Failure 		500	{object}	model.CustomError
+//	@Security 		BearerAuth
+//	@Security 		APIKeyAuth
+//	@Router 		/book/{id}	[delete]
+//
+// DeleteBook removes a book from the catalog
+func (h *Handler) DeleteBook(c *gin.Context) {
+	id := c.Param("id")
+	idInt, err := strconv.Atoi(id)
+	if err != nil {
+		logger.FromContext(c).Errorf("invalid id %s to delete book", id)
+		customError := &model.CustomError{
+			Error: "id is mandatory",
+			Code:  http.StatusBadRequest,
+		}
+		c.JSON(http.StatusBadRequest, customError)
+		return
+	}
+	err = h.repo.DeleteBook(c, idInt)
+	if err != nil {
+		// if notfound needs to return the specific error code and details
+		if errors.Is(err, model.ErrNotFound) {
+			customError := &model.CustomError{
+				Error: err.Error(),
+				Code:  http.StatusNotFound,
+			}
+			c.JSON(http.StatusNotFound, customError)
+			return
+		}
+		// book still has active loans
+		if errors.Is(err, model.ErrConflict) {
+			customError := &model.CustomError{
+				Error: err.Error(),
+				Code:  http.StatusConflict,
+			}
+			c.JSON(http.StatusConflict, customError)
+			return
+		}
+		// rest of all errors falls under this category
+		logger.FromContext(c).Errorf("deleting book %d failed. Error: %v", idInt, err)
+		customError := &model.CustomError{
+			Error: err.Error(),
+			Code:  http.StatusInternalServerError,
+		}
+		c.JSON(http.StatusInternalServerError, customError)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "book deleted"})
+}
+
+// GetBookCopies godoc
+//
+//	@Summary 		GetBookCopies fetches the copies of a book
+//	@Description 	GetBookCopies retrieves the barcode, shelf location, condition and status of every copy of a book
+//	@Param			id	path	int	true	"Book id"
+//	@Produce 		json
+//	@Success 		200	{array}		model.BookCopy
+//	@Failure 		400	{object}	model.CustomError
+//	@Failure 		401	{object}	model.CustomError
+//	@Failure 		404	{object}	model.CustomError
+//	@Failure 		500	{object}	model.CustomError
+//	@Security 		BearerAuth
+//	@Security 		APIKeyAuth
+//	@Router 		/book/id/{id}/copy	[get]
+//
+// GetBookCopies retrieves the copies of a book
+func (h *Handler) GetBookCopies(c *gin.Context) {
+	id := c.Param("id")
+	idInt, err := strconv.Atoi(id)
+	if err != nil {
+		logger.FromContext(c).Errorf("invalid id %s to fetch copies", id)
+		customError := &model.CustomError{
+			Error: "id is mandatory",
+			Code:  http.StatusBadRequest,
+		}
+		c.JSON(http.StatusBadRequest, customError)
+		return
+	}
+	copies, err := h.repo.GetBookCopies(c, idInt)
+	if err != nil {
+		// if notfound needs to return the specific error code and details
+		if errors.Is(err, model.ErrNotFound) {
+			customError := &model.CustomError{
+				Error: err.Error(),
+				Code:  http.StatusNotFound,
+			}
+			c.JSON(http.StatusNotFound, customError)
+			return
+		}
+		// rest of all errors falls under this category
+		logger.FromContext(c).Errorf("fetching copies of book %d failed. Error: %v", idInt, err)
+		customError := &model.CustomError{
+			Error: err.Error(),
+			Code:  http.StatusInternalServerError,
+		}
+		c.JSON(http.StatusInternalServerError, customError)
+		return
+	}
+	c.JSON(http.StatusOK, copies)
 }
 
-// GetAllBooks godoc
+// validCopyCondition reports whether the condition is empty or a known one
+func validCopyCondition(condition string) bool {
+	switch condition {
+	case "", constants.ConditionNew, constants.ConditionGood, constants.ConditionWorn, constants.ConditionDamaged:
+		return true
+	}
+	return false
+}
+
+// AddBookCopy godoc
 //
-//	@Summary 		GetAllBooks fetches the book details
-//	@Description 	GetAllBooks retrieves the detail and available copies of a book title
+//	@Summary 		AddBookCopy adds a copy of a book
+//	@Description 	AddBookCopy adds a physical copy to a book, the barcode is generated when missed
+//	@Param			id					path	int						true	"Book id"
+//	@Param			bookCopyRequest		body	model.BookCopyRequest	true	"Book Copy Request"
+//	@Consume 		json	model.BookCopyRequest
 //	@Produce 		json
-//	@Success 		200	{array}	model.BookDetails
+//	@Success 		201	{object}	model.BookCopy
+//	@Failure 		400	{object}	model.CustomError
+//	@Failure 		401	{object}	model.CustomError
+//	@Failure 		403	{object}	model.CustomError
 //	@Failure 		404	{object}	model.CustomError
+//	@Failure 		409	{object}	model.CustomError
 //	@Failure 		500	{object}	model.CustomError
-//	@Router 		/book	[get]
+//	@Security 		BearerAuth
+//	@Security 		APIKeyAuth
+//	@Router 		/book/id/{id}/copy	[post]
 //
-// GetAllBooks retrieves all books in store
-func (h *Handler) GetAllBooks(c *gin.Context) {
-	det, err := h.repo.GetAllBookDetails(c)
+// AddBookCopy adds a copy of a book
+func (h *Handler) AddBookCopy(c *gin.Context) {
+	id := c.Param("id")
+	idInt, err := strconv.Atoi(id)
+	if err != nil {
+		logger.FromContext(c).Errorf("invalid id %s to add copy", id)
+		customError := &model.CustomError{
+			Error: "id is mandatory",
+			Code:  http.StatusBadRequest,
+		}
+		c.JSON(http.StatusBadRequest, customError)
+		return
+	}
+	var copyReq model.BookCopyRequest
+	if err := c.ShouldBindJSON(&copyReq); err != nil {
+		logger.FromContext(c).Errorf("Failed to unamrshal the request body: %v", err)
+		customError := &model.CustomError{
+			Error: "invalid request body",
+			Code:  http.StatusBadRequest,
+		}
+		c.JSON(http.StatusBadRequest, customError)
+		return
+	}
+	if !validCopyCondition(copyReq.Condition) {
+		logger.FromContext(c).Errorf("invalid condition %s to add copy", copyReq.Condition)
+		customError := &model.CustomError{
+			Error: "Condition must be one of new, good, worn or damaged",
+			Code:  http.StatusBadRequest,
+		}
+		c.JSON(http.StatusBadRequest, customError)
+		return
+	}
+	bookCopy := &model.BookCopy{
+		BookID:          idInt,
+		Barcode:         copyReq.Barcode,
+		ShelfLocation:   copyReq.ShelfLocation,
+		Condition:       copyReq.Condition,
+		AcquisitionDate: copyReq.AcquisitionDate,
+	}
+	_, err = h.repo.AddBookCopy(c, bookCopy)
 	if err != nil {
 		// if notfound needs to return the specific error code and details
 		if errors.Is(err, model.ErrNotFound) {
 			customError := &model.CustomError{
-				Error: "zero books available in store",
+				Error: err.Error(),
 				Code:  http.StatusNotFound,
 			}
 			c.JSON(http.StatusNotFound, customError)
 			return
 		}
+		// if the barcode is taken by another copy
+		if errors.Is(err, model.ErrAlreadyExists) {
+			customError := &model.CustomError{
+				Error: err.Error(),
+				Code:  http.StatusConflict,
+			}
+			c.JSON(http.StatusConflict, customError)
+			return
+		}
 		// rest of all errors falls under this category
+		logger.FromContext(c).Errorf("adding copy to book %d failed. Error: %v", idInt, err)
 		customError := &model.CustomError{
 			Error: err.Error(),
 			Code:  http.StatusInternalServerError,
@@ -63,33 +892,39 @@ func (h *Handler) GetAllBooks(c *gin.Context) {
 		c.JSON(http.StatusInternalServerError, customError)
 		return
 	}
-	c.JSON(http.StatusOK, det)
+	c.JSON(http.StatusCreated, bookCopy)
 }
 
-// GetAllLoans godoc
+// GetBookCopy godoc
 //
-//	@Summary 		GetAllLoans fetches the all loan details
-//	@Description 	GetAllLoans retrieves the detail of all loans
+//	@Summary 		GetBookCopy fetches a copy by its barcode
+//	@Description 	GetBookCopy retrieves the shelf location, condition and status of a copy
+//	@Param			barcode	path	string	true	"Barcode of the copy"
 //	@Produce 		json
-//	@Success 		200	{array}		model.LoanDetails
+//	@Success 		200	{object}	model.BookCopy
+//	@Failure 		401	{object}	model.CustomError
 //	@Failure 		404	{object}	model.CustomError
 //	@Failure 		500	{object}	model.CustomError
-//	@Router 		/loan	[get]
+//	@Security 		BearerAuth
+//	@Security 		APIKeyAuth
+//	@Router 		/copy/{barcode}	[get]
 //
-// GetAllLoans retrieves all loans from store
-func (h *Handler) GetAllLoans(c *gin.Context) {
-	det, err := h.repo.GetAllLoans(c)
+// GetBookCopy retrieves a copy by its barcode
+func (h *Handler) GetBookCopy(c *gin.Context) {
+	barcode := c.Param("barcode")
+	bookCopy, err := h.repo.GetBookCopy(c, barcode)
 	if err != nil {
 		// if notfound needs to return the specific error code and details
 		if errors.Is(err, model.ErrNotFound) {
 			customError := &model.CustomError{
-				Error: "zero loans available in store",
+				Error: err.Error(),
 				Code:  http.StatusNotFound,
 			}
 			c.JSON(http.StatusNotFound, customError)
 			return
 		}
 		// rest of all errors falls under this category
+		logger.FromContext(c).Errorf("fetching copy %s failed. Error: %v", barcode, err)
 		customError := &model.CustomError{
 			Error: err.Error(),
 			Code:  http.StatusInternalServerError,
@@ -97,41 +932,68 @@ func (h *Handler) GetAllLoans(c *gin.Context) {
 		c.JSON(http.StatusInternalServerError, customError)
 		return
 	}
-	if len(det) == 0 {
-		customError := &model.CustomError{
-			Error: "zero loans available in store",
-			Code:  http.StatusNotFound,
-		}
-		c.JSON(http.StatusNotFound, customError)
-		return
-	}
-	c.JSON(http.StatusOK, det)
+	c.JSON(http.StatusOK, bookCopy)
 }
 
-// GetBook godoc
+// UpdateBookCopy godoc
 //
-//	@Summary 		GetBook fetches the book details
-//	@Description 	GetBook retrieves the detail and available copies of a book title
-//	@Param			title	path	string	true	"Title of the book"
+//	@Summary 		UpdateBookCopy updates a copy
+//	@Description 	UpdateBookCopy updates the shelf location, condition and status of a copy, empty fields are left unchanged
+//	@Param			barcode				path	string					true	"Barcode of the copy"
+//	@Param			bookCopyRequest		body	model.BookCopyRequest	true	"Book Copy Request"
+//	@Consume 		json	model.BookCopyRequest
 //	@Produce 		json
-//	@Success 		200	{object}	model.BookDetails
+//	@Success 		200	{object}	model.BookCopy
+//	@Failure 		400	{object}	model.CustomError
+//	@Failure 		401	{object}	model.CustomError
+//	@Failure 		403	{object}	model.CustomError
 //	@Failure 		404	{object}	model.CustomError
+//	@Failure 		409	{object}	model.CustomError
 //	@Failure 		500	{object}	model.CustomError
-//	@Router 		/book/{title}	[get]
+//	@Security 		BearerAuth
+//	@Security 		APIKeyAuth
+//	@Router 		/copy/{barcode}	[patch]
 //
-// GetBook retrieves the detail and available copies of a book title
-func (h *Handler) GetBook(c *gin.Context) {
-	title := c.Param("title")
-	if len(title) == 0 {
-		logger.Errorf("title is mandatory")
+// UpdateBookCopy updates a copy
+func (h *Handler) UpdateBookCopy(c *gin.Context) {
+	barcode := c.Param("barcode")
+	var copyReq model.BookCopyRequest
+	if err := c.ShouldBindJSON(&copyReq); err != nil {
+		logger.FromContext(c).Errorf("Failed to unamrshal the request body: %v", err)
 		customError := &model.CustomError{
-			Error: "title is mandatory",
+			Error: "invalid request body",
 			Code:  http.StatusBadRequest,
 		}
 		c.JSON(http.StatusBadRequest, customError)
 		return
 	}
-	det, err := h.repo.GetBookDetails(c, title)
+	if !validCopyCondition(copyReq.Condition) {
+		logger.FromContext(c).Errorf("invalid condition %s to update copy %s", copyReq.Condition, barcode)
+		customError := &model.CustomError{
+			Error: "Condition must be one of new, good, worn or damaged",
+			Code:  http.StatusBadRequest,
+		}
+		c.JSON(http.StatusBadRequest, customError)
+		return
+	}
+	// copies go on and off loan and hold only through loans and holds
+	switch copyReq.Status {
+	case "", constants.CopyAvailable, constants.CopyInRepair, constants.CopyLost, constants.CopyWithdrawn:
+	default:
+		logger.FromContext(c).Errorf("invalid status %s to update copy %s", copyReq.Status, barcode)
+		customError := &model.CustomError{
+			Error: "Status must be one of available, in_repair, lost or withdrawn",
+			Code:  http.StatusBadRequest,
+		}
+		c.JSON(http.StatusBadRequest, customError)
+		return
+	}
+	bookCopy, err := h.repo.UpdateBookCopy(c, barcode, &model.BookCopy{
+		ShelfLocation:   copyReq.ShelfLocation,
+		Condition:       copyReq.Condition,
+		AcquisitionDate: copyReq.AcquisitionDate,
+		Status:          copyReq.Status,
+	})
 	if err != nil {
 		// if notfound needs to return the specific error code and details
 		if errors.Is(err, model.ErrNotFound) {
@@ -142,8 +1004,17 @@ func (h *Handler) GetBook(c *gin.Context) {
 			c.JSON(http.StatusNotFound, customError)
 			return
 		}
-		logger.Errorf("fetching title %s failed. Error: %v", title, err)
+		// copy is on loan or on hold
+		if errors.Is(err, model.ErrConflict) {
+			customError := &model.CustomError{
+				Error: err.Error(),
+				Code:  http.StatusConflict,
+			}
+			c.JSON(http.StatusConflict, customError)
+			return
+		}
 		// rest of all errors falls under this category
+		logger.FromContext(c).Errorf("updating copy %s failed. Error: %v", barcode, err)
 		customError := &model.CustomError{
 			Error: err.Error(),
 			Code:  http.StatusInternalServerError,
@@ -151,28 +1022,33 @@ func (h *Handler) GetBook(c *gin.Context) {
 		c.JSON(http.StatusInternalServerError, customError)
 		return
 	}
-	c.JSON(http.StatusOK, det)
+	c.JSON(http.StatusOK, bookCopy)
 }
 
 // LoanBook godoc
 //
 //	@Summary 		LoanBook borrows a book from store
-//	@Description 	LoanBook borrows a book from store (loan period: 4 weeks) and returns the details of a loan
+//	@Description 	LoanBook lends a book to a member for the loan period of the member tier and book category, and returns the details of a loan. A loan refused by the borrowing policy fails with 403 and a reason code: member_inactive, loan_limit_reached, duplicate_title, overdue_items or unpaid_fines
 //	@Param			loanRequest	body	model.LoanRequest	true "Loan Request"
 //	@Consume 		json	model.LoanRequest
 //	@Produce 		json
 //	@Success 		201	{object}	model.LoanDetails
+//	@Failure 		401	{object}	model.CustomError
 //	@Failure 		404	{object}	model.CustomError
 //	@Failure 		400	{object}	model.CustomError
+//	@Failure 		403	{object}	model.CustomError
+//	@Failure 		409	{object}	model.CustomError
 //	@Failure 		500	{object}	model.CustomError
+//	@Security 		BearerAuth
+//	@Security 		APIKeyAuth
 //	@Router 		/loan	[post]
 //
-// LoanBook borrows a book from store (loan period: 4 weeks) and returns the details of a loan
+// LoanBook borrows a book from store and returns the details of a loan
 func (h *Handler) LoanBook(c *gin.Context) {
 	var borrowReq model.LoanRequest
 	err := c.BindJSON(&borrowReq)
 	if err != nil {
-		logger.Errorf("Failed to unamrshal the request body: %v", err)
+		logger.FromContext(c).Errorf("Failed to unamrshal the request body: %v", err)
 		customError := &model.CustomError{
 			Error: "internal server error",
 			Code:  http.StatusInternalServerError,
@@ -180,21 +1056,29 @@ func (h *Handler) LoanBook(c *gin.Context) {
 		c.JSON(http.StatusInternalServerError, customError)
 		return
 	}
-	if borrowReq.NameOfBorrower == "" || borrowReq.Title == "" {
-		logger.Errorf("NameOfBorrower & Title are mandatory to borrow a a book.")
+	// members borrow for themselves
+	if borrowReq.MemberID == 0 {
+		borrowReq.MemberID = memberScope(c)
+	}
+	if borrowReq.MemberID == 0 || (borrowReq.Title == "" && borrowReq.BookID == 0 && borrowReq.Barcode == "") {
+		logger.FromContext(c).Errorf("MemberID & Barcode, BookID or Title are mandatory to borrow a a book.")
 		customError := &model.CustomError{
-			Error: "NameOfBorrower or Title missed in the request",
+			Error: "MemberID or Barcode/BookID/Title missed in the request",
 			Code:  http.StatusBadRequest,
 		}
 		c.JSON(http.StatusBadRequest, customError)
 		return
 	}
+	if !allowMember(c, borrowReq.MemberID) {
+		return
+	}
 	loanDetails := &model.LoanDetails{
-		NameOfBorrower: borrowReq.NameOfBorrower,
-		Title:          borrowReq.Title,
-		LoanDate:       time.Now().Unix(),
-		ReturnDate:     time.Now().Add(4 * 7 * 24 * time.Hour).Unix(), // 4 weeks return period
-		Status:         constants.Active,
+		MemberID: borrowReq.MemberID,
+		BookID:   borrowReq.BookID,
+		Barcode:  borrowReq.Barcode,
+		Title:    borrowReq.Title,
+		LoanDate: time.Now().Unix(),
+		Status:   constants.Active,
 	}
 	_, err = h.repo.AddLoan(c, loanDetails)
 	if err != nil {
@@ -207,6 +1091,28 @@ func (h *Handler) LoanBook(c *gin.Context) {
 			c.JSON(http.StatusNotFound, customError)
 			return
 		}
+		// refused by the borrowing policy, the reason code tells why
+		if errors.Is(err, model.ErrNotAllowed) {
+			customError := &model.CustomError{
+				Error: err.Error(),
+				Code:  http.StatusForbidden,
+			}
+			var refusal *model.RefusalError
+			if errors.As(err, &refusal) {
+				customError.Reason = refusal.Reason
+			}
+			c.JSON(http.StatusForbidden, customError)
+			return
+		}
+		// title shared by several books or requested copy isn't on the shelf
+		if errors.Is(err, model.ErrConflict) {
+			customError := &model.CustomError{
+				Error: err.Error(),
+				Code:  http.StatusConflict,
+			}
+			c.JSON(http.StatusConflict, customError)
+			return
+		}
 		customError := &model.CustomError{
 			Error: "adding loan failed",
 			Code:  http.StatusConflict,
@@ -220,22 +1126,28 @@ func (h *Handler) LoanBook(c *gin.Context) {
 // ExtendLoan godoc
 //
 //	@Summary 		ExtendLoan extends the loan of a book
-//	@Description 	ExtendLoan extends the loan of a book
-//	@Param			id	path	int	true	"Loan id"
+//	@Description 	ExtendLoan extends the return date of a loan by the extension period of the member tier and book category. Refused with 403 and the reason overdue_items once overdue, or extension_limit_reached once extended the most times
+//	@Param			id			path	int		true	"Loan id"
+//	@Param			If-Match	header	string	false	"ETag of the loan the extension is based on"
 //	@Consume 		json	model.LoanRequest
 //	@Produce 		json
 //	@Success 		202	{object}	model.LoanDetails
+//	@Header 		202	{string}	ETag	"Version of the loan"
+//	@Failure 		401	{object}	model.CustomError
 //	@Failure 		404	{object}	model.CustomError
 //	@Failure 		400	{object}	model.CustomError
+//	@Failure 		403	{object}	model.CustomError
+//	@Failure 		412	{object}	model.CustomError
+//	@Security 		BearerAuth
+//	@Security 		APIKeyAuth
 //	@Router 		/loan/extend/{id}	[post]
 //
 // ExtendLoan extends the loan of a book
 func (h *Handler) ExtendLoan(c *gin.Context) {
-	// TODO: Request body be accepted with title and name_of_borrower check if any book already borrowed by same user reject if exists
 	id := c.Param("id")
 	idInt, err := strconv.Atoi(id)
 	if err != nil {
-		logger.Errorf("invalid id %s to update loan", id)
+		logger.FromContext(c).Errorf("invalid id %s to update loan", id)
 		customError := &model.CustomError{
 			Error: "id is mandatory",
 			Code:  http.StatusBadRequest,
@@ -243,8 +1155,21 @@ func (h *Handler) ExtendLoan(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, customError)
 		return
 	}
+	version, ok := ifMatchVersion(c)
+	if !ok {
+		logger.FromContext(c).Errorf("invalid If-Match %s to extend loan %d", c.GetHeader(constants.IfMatchHeader), idInt)
+		customError := &model.CustomError{
+			Error: "If-Match must be a strong ETag",
+			Code:  http.StatusPreconditionFailed,
+		}
+		c.JSON(http.StatusPreconditionFailed, customError)
+		return
+	}
+	if !h.allowLoan(c, idInt) {
+		return
+	}
 	// extenidng loan
-	loan, err := h.repo.ExtendLoan(c, idInt)
+	loan, err := h.repo.ExtendLoan(c, idInt, version)
 	if err != nil {
 		// if notfound needs to return the specific error code and details
 		if errors.Is(err, model.ErrNotFound) {
@@ -255,8 +1180,30 @@ func (h *Handler) ExtendLoan(c *gin.Context) {
 			c.JSON(http.StatusNotFound, customError)
 			return
 		}
+		// refused by the extension rules
+		if errors.Is(err, model.ErrNotAllowed) {
+			customError := &model.CustomError{
+				Error: err.Error(),
+				Code:  http.StatusForbidden,
+			}
+			var refusal *model.RefusalError
+			if errors.As(err, &refusal) {
+				customError.Reason = refusal.Reason
+			}
+			c.JSON(http.StatusForbidden, customError)
+			return
+		}
+		// changed since the version the request is conditioned on
+		if errors.Is(err, model.ErrPreconditionFailed) {
+			customError := &model.CustomError{
+				Error: err.Error(),
+				Code:  http.StatusPreconditionFailed,
+			}
+			c.JSON(http.StatusPreconditionFailed, customError)
+			return
+		}
 		// rest of all errors falls under this category
-		logger.Errorf("fetching loan %d failed. Error: %v", idInt, err)
+		logger.FromContext(c).Errorf("fetching loan %d failed. Error: %v", idInt, err)
 		customError := &model.CustomError{
 			Error: err.Error(),
 			Code:  http.StatusInternalServerError,
@@ -264,18 +1211,27 @@ func (h *Handler) ExtendLoan(c *gin.Context) {
 		c.JSON(http.StatusInternalServerError, customError)
 		return
 	}
-	c.JSON(http.StatusAccepted, gin.H{"loanDetails": loan, "message": "loan got extended to 3 weeks"})
+	c.Header(constants.ETagHeader, etag(loan.Version))
+	message := fmt.Sprintf("loan got extended till %s", time.Unix(loan.ReturnDate, 0).Format(time.DateOnly))
+	c.JSON(http.StatusAccepted, gin.H{"loanDetails": loan, "message": message})
 }
 
 // ReturnBook godoc
 //
 //	@Summary 		ReturnBook returns the book
-//	@Description 	ReturnBook returns the book
-//	@Param			id	path	int	true	"Loan id"
+//	@Description 	ReturnBook returns the book closing the loan, the fine of an overdue loan stops accruing and becomes unpaid
+//	@Param			id			path	int		true	"Loan id"
+//	@Param			If-Match	header	string	false	"ETag of the loan the return is based on"
 //	@Produce 		json
 //	@Success 		202	{object}	model.LoanDetails
+//	@Header 		202	{string}	ETag	"Version of the loan"
+//	@Failure 		401	{object}	model.CustomError
+//	@Failure 		403	{object}	model.CustomError
 //	@Failure 		404	{object}	model.CustomError
 //	@Failure 		400	{object}	model.CustomError
+//	@Failure 		412	{object}	model.CustomError
+//	@Security 		BearerAuth
+//	@Security 		APIKeyAuth
 //	@Router 		/loan/return/{id}	[post]
 //
 // ReturnBook returns the book
@@ -283,7 +1239,7 @@ func (h *Handler) ReturnBook(c *gin.Context) {
 	id := c.Param("id")
 	idInt, err := strconv.Atoi(id)
 	if err != nil {
-		logger.Errorf("invalid id %s to update loan", id)
+		logger.FromContext(c).Errorf("invalid id %s to update loan", id)
 		customError := &model.CustomError{
 			Error: "id is mandatory",
 			Code:  http.StatusBadRequest,
@@ -291,7 +1247,20 @@ func (h *Handler) ReturnBook(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, customError)
 		return
 	}
-	loan, err := h.repo.ReturnBook(c, idInt)
+	version, ok := ifMatchVersion(c)
+	if !ok {
+		logger.FromContext(c).Errorf("invalid If-Match %s to return loan %d", c.GetHeader(constants.IfMatchHeader), idInt)
+		customError := &model.CustomError{
+			Error: "If-Match must be a strong ETag",
+			Code:  http.StatusPreconditionFailed,
+		}
+		c.JSON(http.StatusPreconditionFailed, customError)
+		return
+	}
+	if !h.allowLoan(c, idInt) {
+		return
+	}
+	loan, err := h.repo.ReturnBook(c, idInt, version)
 	if err != nil {
 		// if notfound needs to return the specific error code and details
 		if errors.Is(err, model.ErrNotFound) {
@@ -302,8 +1271,17 @@ func (h *Handler) ReturnBook(c *gin.Context) {
 			c.JSON(http.StatusNotFound, customError)
 			return
 		}
+		// changed since the version the request is conditioned on
+		if errors.Is(err, model.ErrPreconditionFailed) {
+			customError := &model.CustomError{
+				Error: err.Error(),
+				Code:  http.StatusPreconditionFailed,
+			}
+			c.JSON(http.StatusPreconditionFailed, customError)
+			return
+		}
 		// rest of all errors falls under this category
-		logger.Errorf("fetching loan %d failed. Error: %v", idInt, err)
+		logger.FromContext(c).Errorf("fetching loan %d failed. Error: %v", idInt, err)
 		customError := &model.CustomError{
 			Error: err.Error(),
 			Code:  http.StatusInternalServerError,
@@ -311,5 +1289,173 @@ func (h *Handler) ReturnBook(c *gin.Context) {
 		c.JSON(http.StatusInternalServerError, customError)
 		return
 	}
+	c.Header(constants.ETagHeader, etag(loan.Version))
 	c.JSON(http.StatusAccepted, gin.H{"loanDetails": loan, "message": "book returned"})
 }
+
+// GetLoanHistory godoc
+//
+//	@Summary 		GetLoanHistory fetches the history of a loan
+//	@Description 	GetLoanHistory retrieves the transitions of a loan in the order made: created, extended, overdue and returned, each with the actor, the time and the values before and after. The actor is named by the X-Actor header of the request, system for background jobs
+//	@Param			id	path	int	true	"Loan id"
+//	@Produce 		json
+//	@Success 		200	{object}	[]model.LoanEvent
+//	@Failure 		400	{object}	model.CustomError
+//	@Failure 		401	{object}	model.CustomError
+//	@Failure 		403	{object}	model.CustomError
+//	@Failure 		404	{object}	model.CustomError
+//	@Failure 		500	{object}	model.CustomError
+//	@Security 		BearerAuth
+//	@Security 		APIKeyAuth
+//	@Router 		/loan/{id}/history	[get]
+//
+// GetLoanHistory retrieves the transitions of a loan
+func (h *Handler) GetLoanHistory(c *gin.Context) {
+	id := c.Param("id")
+	idInt, err := strconv.Atoi(id)
+	if err != nil {
+		logger.FromContext(c).Errorf("invalid id %s to fetch loan history", id)
+		customError := &model.CustomError{
+			Error: "id is mandatory",
+			Code:  http.StatusBadRequest,
+		}
+		c.JSON(http.StatusBadRequest, customError)
+		return
+	}
+	if !h.allowLoan(c, idInt) {
+		return
+	}
+	events, err := h.repo.GetLoanHistory(c, idInt)
+	if err != nil {
+		// if notfound needs to return the specific error code and details
+		if errors.Is(err, model.ErrNotFound) {
+			customError := &model.CustomError{
+				Error: err.Error(),
+				Code:  http.StatusNotFound,
+			}
+			c.JSON(http.StatusNotFound, customError)
+			return
+		}
+		logger.FromContext(c).Errorf("fetching history of loan %d failed. Error: %v", idInt, err)
+		// rest of all errors falls under this category
+		customError := &model.CustomError{
+			Error: err.Error(),
+			Code:  http.StatusInternalServerError,
+		}
+		c.JSON(http.StatusInternalServerError, customError)
+		return
+	}
+	c.JSON(http.StatusOK, events)
+}
+
+// GetLoan godoc
+//
+//	@Summary 		GetLoan fetches a loan
+//	@Description 	GetLoan retrieves a loan by its id whether active, overdue or closed
+//	@Param			id	path	int	true	"Loan id"
+//	@Produce 		json
+//	@Success 		200	{object}	model.LoanDetails
+//	@Header 		200	{string}	ETag	"Version of the loan"
+//	@Failure 		400	{object}	model.CustomError
+//	@Failure 		401	{object}	model.CustomError
+//	@Failure 		403	{object}	model.CustomError
+//	@Failure 		404	{object}	model.CustomError
+//	@Failure 		500	{object}	model.CustomError
+//	@Security 		BearerAuth
+//	@Security 		APIKeyAuth
+//	@Router 		/loan/{id}	[get]
+//
+// GetLoan retrieves a loan by its id
+func (h *Handler) GetLoan(c *gin.Context) {
+	id := c.Param("id")
+	idInt, err := strconv.Atoi(id)
+	if err != nil {
+		logger.FromContext(c).Errorf("invalid id %s to fetch loan", id)
+		customError := &model.CustomError{
+			Error: "id is mandatory",
+			Code:  http.StatusBadRequest,
+		}
+		c.JSON(http.StatusBadRequest, customError)
+		return
+	}
+	if !h.allowLoan(c, idInt) {
+		return
+	}
+	loan, err := h.repo.GetLoan(c, idInt)
+	if err != nil {
+		// if notfound needs to return the specific error code and details
+		if errors.Is(err, model.ErrNotFound) {
+			customError := &model.CustomError{
+				Error: err.Error(),
+				Code:  http.StatusNotFound,
+			}
+			c.JSON(http.StatusNotFound, customError)
+			return
+		}
+		logger.FromContext(c).Errorf("fetching loan %d failed. Error: %v", idInt, err)
+		// rest of all errors falls under this category
+		customError := &model.CustomError{
+			Error: err.Error(),
+			Code:  http.StatusInternalServerError,
+		}
+		c.JSON(http.StatusInternalServerError, customError)
+		return
+	}
+	c.Header(constants.ETagHeader, etag(loan.Version))
+	c.JSON(http.StatusOK, loan)
+}
+
+// GetMemberLoans godoc
+//
+//	@Summary 		GetMemberLoans fetches the loans of a member
+//	@Description 	GetMemberLoans retrieves the active and past loans of a member in the order loaned
+//	@Param			id	path	int	true	"Member id"
+//	@Produce 		json
+//	@Success 		200	{object}	[]model.LoanDetails
+//	@Failure 		400	{object}	model.CustomError
+//	@Failure 		401	{object}	model.CustomError
+//	@Failure 		403	{object}	model.CustomError
+//	@Failure 		404	{object}	model.CustomError
+//	@Failure 		500	{object}	model.CustomError
+//	@Security 		BearerAuth
+//	@Security 		APIKeyAuth
+//	@Router 		/member/{id}/loans	[get]
+//
+// GetMemberLoans retrieves the loans of a member
+func (h *Handler) GetMemberLoans(c *gin.Context) {
+	id := c.Param("id")
+	idInt, err := strconv.Atoi(id)
+	if err != nil {
+		logger.FromContext(c).Errorf("invalid id %s to fetch loans", id)
+		customError := &model.CustomError{
+			Error: "id is mandatory",
+			Code:  http.StatusBadRequest,
+		}
+		c.JSON(http.StatusBadRequest, customError)
+		return
+	}
+	if !allowMember(c, idInt) {
+		return
+	}
+	loans, err := h.repo.GetLoansByBorrower(c, idInt)
+	if err != nil {
+		// if notfound needs to return the specific error code and details
+		if errors.Is(err, model.ErrNotFound) {
+			customError := &model.CustomError{
+				Error: err.Error(),
+				Code:  http.StatusNotFound,
+			}
+			c.JSON(http.StatusNotFound, customError)
+			return
+		}
+		logger.FromContext(c).Errorf("fetching loans of member %d failed. Error: %v", idInt, err)
+		// rest of all errors falls under this category
+		customError := &model.CustomError{
+			Error: err.Error(),
+			Code:  http.StatusInternalServerError,
+		}
+		c.JSON(http.StatusInternalServerError, customError)
+		return
+	}
+	c.JSON(http.StatusOK, loans)
+}
@@ -2,21 +2,41 @@ package handlertest
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"io"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
 	"github.com/stretchr/testify/assert"
+	"github.com/test/library-app/internal/auth"
 	"github.com/test/library-app/internal/config"
+	"github.com/test/library-app/internal/constants"
 	"github.com/test/library-app/internal/handler"
+	"github.com/test/library-app/internal/health"
+	"github.com/test/library-app/internal/logger"
+	"github.com/test/library-app/internal/metrics"
 	"github.com/test/library-app/internal/model"
 	"github.com/test/library-app/internal/store"
+	"github.com/test/library-app/internal/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.uber.org/zap/zapcore"
 )
 
-var reqHandler *handler.Handler
+var (
+	reqHandler *handler.Handler
+	testStore  store.Store
+)
 
 func TestMain(m *testing.M) {
 	// loading configuration
@@ -24,10 +44,11 @@ func TestMain(m *testing.M) {
 	// updating store type to local for unit testing
 	config.CommonConfig.StoreType = "local"
 	// initializing the store
-	store, err := store.NewStore()
+	var err error
+	testStore, err = store.NewStore()
 	assert.Nil(&testing.T{}, err)
 	// initializing the handler
-	reqHandler = handler.NewHandler(store)
+	reqHandler = handler.NewHandler(testStore, nil, health.NewRegistry(time.Second))
 	m.Run()
 }
 
@@ -79,8 +100,8 @@ func TestBorrowBook(t *testing.T) {
 	w := httptest.NewRecorder()
 	c := GetTestGinContext(w)
 	req := model.LoanRequest{
-		NameOfBorrower: "test_user",
-		Title:          "alchemist",
+		MemberID: 1,
+		Title:    "alchemist",
 	}
 	reqBytes, _ := json.Marshal(&req)
 	c.Request.Body = io.NopCloser(bytes.NewBuffer(reqBytes))
@@ -93,8 +114,8 @@ func TestBorrowBook(t *testing.T) {
 	w = httptest.NewRecorder()
 	c = GetTestGinContext(w)
 	req = model.LoanRequest{
-		NameOfBorrower: "test_user",
-		Title:          "book_100",
+		MemberID: 1,
+		Title:    "book_100",
 	}
 	reqBytes, _ = json.Marshal(&req)
 	c.Request.Body = io.NopCloser(bytes.NewBuffer(reqBytes))
@@ -107,8 +128,8 @@ func TestExtendLoan(t *testing.T) {
 	w := httptest.NewRecorder()
 	c := GetTestGinContext(w)
 	req := model.LoanRequest{
-		NameOfBorrower: "test_user",
-		Title:          "alchemist",
+		MemberID: 1,
+		Title:    "sapiens",
 	}
 	reqBytes, _ := json.Marshal(&req)
 	c.Request.Body = io.NopCloser(bytes.NewBuffer(reqBytes))
@@ -151,8 +172,8 @@ func TestReturnBook(t *testing.T) {
 	w := httptest.NewRecorder()
 	c := GetTestGinContext(w)
 	req := model.LoanRequest{
-		NameOfBorrower: "test_user",
-		Title:          "alchemist",
+		MemberID: 1,
+		Title:    "animal farm",
 	}
 	reqBytes, _ := json.Marshal(&req)
 	c.Request.Body = io.NopCloser(bytes.NewBuffer(reqBytes))
@@ -190,3 +211,1173 @@ func TestReturnBook(t *testing.T) {
 	reqHandler.ReturnBook(c)
 	assert.EqualValues(t, http.StatusNotFound, w.Code)
 }
+
+// addTestBook adds a book through the handler and returns its details
+func addTestBook(t *testing.T, title string, copies int) model.BookDetails {
+	w := httptest.NewRecorder()
+	c := GetTestGinContext(w)
+	req := model.BookRequest{
+		Title:  title,
+		Copies: copies,
+	}
+	reqBytes, _ := json.Marshal(&req)
+	c.Request.Body = io.NopCloser(bytes.NewBuffer(reqBytes))
+	reqHandler.AddBook(c)
+	assert.EqualValues(t, http.StatusCreated, w.Code)
+	var book model.BookDetails
+	assert.Nil(t, json.Unmarshal(w.Body.Bytes(), &book))
+	return book
+}
+
+func TestAddBook(t *testing.T) {
+	book := addTestBook(t, "Dune", 2)
+	assert.NotZero(t, book.ID)
+	assert.Equal(t, 2, book.AvailableCopies)
+
+	w := httptest.NewRecorder()
+	c := GetTestGinContext(w)
+	reqBytes, _ := json.Marshal(&model.BookRequest{ISBN: "978-0-441-17271-9", Title: "Dune", Copies: 1})
+	c.Request.Body = io.NopCloser(bytes.NewBuffer(reqBytes))
+	reqHandler.AddBook(c)
+	assert.EqualValues(t, http.StatusCreated, w.Code)
+
+	// failure case: duplicate isbn
+	w = httptest.NewRecorder()
+	c = GetTestGinContext(w)
+	reqBytes, _ = json.Marshal(&model.BookRequest{ISBN: "9780441172719", Title: "Dune", Copies: 1})
+	c.Request.Body = io.NopCloser(bytes.NewBuffer(reqBytes))
+	reqHandler.AddBook(c)
+	assert.EqualValues(t, http.StatusConflict, w.Code)
+
+	// failure case: malformed isbn
+	w = httptest.NewRecorder()
+	c = GetTestGinContext(w)
+	reqBytes, _ = json.Marshal(&model.BookRequest{ISBN: "12345", Title: "Dune", Copies: 1})
+	c.Request.Body = io.NopCloser(bytes.NewBuffer(reqBytes))
+	reqHandler.AddBook(c)
+	assert.EqualValues(t, http.StatusBadRequest, w.Code)
+
+	// failure case: missing title
+	w = httptest.NewRecorder()
+	c = GetTestGinContext(w)
+	reqBytes, _ = json.Marshal(&model.BookRequest{Copies: 1})
+	c.Request.Body = io.NopCloser(bytes.NewBuffer(reqBytes))
+	reqHandler.AddBook(c)
+	assert.EqualValues(t, http.StatusBadRequest, w.Code)
+
+	// failure case: more copies than a request may create
+	w = httptest.NewRecorder()
+	c = GetTestGinContext(w)
+	reqBytes, _ = json.Marshal(&model.BookRequest{Title: "Dune", Copies: constants.MaxCopiesChange + 1})
+	c.Request.Body = io.NopCloser(bytes.NewBuffer(reqBytes))
+	reqHandler.AddBook(c)
+	assert.EqualValues(t, http.StatusBadRequest, w.Code)
+}
+
+func TestGetBookByID(t *testing.T) {
+	w := httptest.NewRecorder()
+	c := GetTestGinContext(w)
+	c.Params = []gin.Param{{Key: "id", Value: "1"}}
+	reqHandler.GetBookByID(c)
+	assert.EqualValues(t, http.StatusOK, w.Code)
+
+	// failure case
+	w = httptest.NewRecorder()
+	c = GetTestGinContext(w)
+	c.Params = []gin.Param{{Key: "id", Value: "1000"}}
+	reqHandler.GetBookByID(c)
+	assert.EqualValues(t, http.StatusNotFound, w.Code)
+}
+
+func TestUpdateBook(t *testing.T) {
+	book := addTestBook(t, "Emma", 1)
+
+	w := httptest.NewRecorder()
+	c := GetTestGinContext(w)
+	c.Params = []gin.Param{{Key: "id", Value: strconv.Itoa(book.ID)}}
+	reqBytes, _ := json.Marshal(&model.BookRequest{Title: "Emma (Annotated)", Copies: 4})
+	c.Request.Body = io.NopCloser(bytes.NewBuffer(reqBytes))
+	reqHandler.UpdateBook(c)
+	assert.EqualValues(t, http.StatusOK, w.Code)
+
+	// failure case
+	w = httptest.NewRecorder()
+	c = GetTestGinContext(w)
+	c.Params = []gin.Param{{Key: "id", Value: "1000"}}
+	reqBytes, _ = json.Marshal(&model.BookRequest{Title: "Nothing", Copies: 4})
+	c.Request.Body = io.NopCloser(bytes.NewBuffer(reqBytes))
+	reqHandler.UpdateBook(c)
+	assert.EqualValues(t, http.StatusNotFound, w.Code)
+}
+
+func TestUpdateBookIfMatch(t *testing.T) {
+	book := addTestBook(t, "Persuasion", 1)
+
+	// the version is served as the ETag
+	w := httptest.NewRecorder()
+	c := GetTestGinContext(w)
+	c.Params = []gin.Param{{Key: "id", Value: strconv.Itoa(book.ID)}}
+	reqHandler.GetBookByID(c)
+	assert.EqualValues(t, http.StatusOK, w.Code)
+	etag := w.Header().Get("ETag")
+	assert.Equal(t, `"1"`, etag)
+
+	// success case
+	w = httptest.NewRecorder()
+	c = GetTestGinContext(w)
+	c.Params = []gin.Param{{Key: "id", Value: strconv.Itoa(book.ID)}}
+	c.Request.Header.Set("If-Match", etag)
+	reqBytes, _ := json.Marshal(&model.BookRequest{Title: "Persuasion (Annotated)"})
+	c.Request.Body = io.NopCloser(bytes.NewBuffer(reqBytes))
+	reqHandler.UpdateBook(c)
+	assert.EqualValues(t, http.StatusOK, w.Code)
+	assert.Equal(t, `"2"`, w.Header().Get("ETag"))
+
+	// failure case: stale and malformed tags
+	for _, ifMatch := range []string{etag, `W/"2"`, "2"} {
+		w = httptest.NewRecorder()
+		c = GetTestGinContext(w)
+		c.Params = []gin.Param{{Key: "id", Value: strconv.Itoa(book.ID)}}
+		c.Request.Header.Set("If-Match", ifMatch)
+		reqBytes, _ = json.Marshal(&model.BookRequest{Title: "Persuasion"})
+		c.Request.Body = io.NopCloser(bytes.NewBuffer(reqBytes))
+		reqHandler.UpdateBook(c)
+		assert.EqualValues(t, http.StatusPreconditionFailed, w.Code)
+	}
+}
+
+func TestUpdateBookCopies(t *testing.T) {
+	book := addTestBook(t, "Ulysses", 1)
+
+	w := httptest.NewRecorder()
+	c := GetTestGinContext(w)
+	c.Params = []gin.Param{{Key: "id", Value: strconv.Itoa(book.ID)}}
+	c.Request.Header.Set("If-Match", `"1"`)
+	reqBytes, _ := json.Marshal(&model.BookCopiesRequest{Delta: 2})
+	c.Request.Body = io.NopCloser(bytes.NewBuffer(reqBytes))
+	reqHandler.UpdateBookCopies(c)
+	assert.EqualValues(t, http.StatusOK, w.Code)
+	assert.Equal(t, `"2"`, w.Header().Get("ETag"))
+
+	// failure case: stale and malformed tags
+	for _, ifMatch := range []string{`"1"`, `W/"2"`, "2"} {
+		w = httptest.NewRecorder()
+		c = GetTestGinContext(w)
+		c.Params = []gin.Param{{Key: "id", Value: strconv.Itoa(book.ID)}}
+		c.Request.Header.Set("If-Match", ifMatch)
+		reqBytes, _ = json.Marshal(&model.BookCopiesRequest{Delta: 1})
+		c.Request.Body = io.NopCloser(bytes.NewBuffer(reqBytes))
+		reqHandler.UpdateBookCopies(c)
+		assert.EqualValues(t, http.StatusPreconditionFailed, w.Code)
+	}
+
+	// failure case: withdrawing more copies than available
+	w = httptest.NewRecorder()
+	c = GetTestGinContext(w)
+	c.Params = []gin.Param{{Key: "id", Value: strconv.Itoa(book.ID)}}
+	reqBytes, _ = json.Marshal(&model.BookCopiesRequest{Delta: -10})
+	c.Request.Body = io.NopCloser(bytes.NewBuffer(reqBytes))
+	reqHandler.UpdateBookCopies(c)
+	assert.EqualValues(t, http.StatusConflict, w.Code)
+
+	// failure case: no copies or more copies than a request may change
+	for _, delta := range []int{0, constants.MaxCopiesChange + 1, -constants.MaxCopiesChange - 1} {
+		w = httptest.NewRecorder()
+		c = GetTestGinContext(w)
+		c.Params = []gin.Param{{Key: "id", Value: strconv.Itoa(book.ID)}}
+		reqBytes, _ = json.Marshal(&model.BookCopiesRequest{Delta: delta})
+		c.Request.Body = io.NopCloser(bytes.NewBuffer(reqBytes))
+		reqHandler.UpdateBookCopies(c)
+		assert.EqualValues(t, http.StatusBadRequest, w.Code)
+	}
+}
+
+func TestDeleteBook(t *testing.T) {
+	book := addTestBook(t, "Hamlet", 1)
+
+	// loaned book can't be deleted
+	w := httptest.NewRecorder()
+	c := GetTestGinContext(w)
+	reqBytes, _ := json.Marshal(&model.LoanRequest{MemberID: 1, Title: "hamlet"})
+	c.Request.Body = io.NopCloser(bytes.NewBuffer(reqBytes))
+	reqHandler.LoanBook(c)
+	assert.EqualValues(t, http.StatusCreated, w.Code)
+
+	w = httptest.NewRecorder()
+	c = GetTestGinContext(w)
+	c.Params = []gin.Param{{Key: "id", Value: strconv.Itoa(book.ID)}}
+	reqHandler.DeleteBook(c)
+	assert.EqualValues(t, http.StatusConflict, w.Code)
+
+	// success case
+	book = addTestBook(t, "Macbeth", 1)
+	w = httptest.NewRecorder()
+	c = GetTestGinContext(w)
+	c.Params = []gin.Param{{Key: "id", Value: strconv.Itoa(book.ID)}}
+	reqHandler.DeleteBook(c)
+	assert.EqualValues(t, http.StatusOK, w.Code)
+}
+
+func TestBookCopies(t *testing.T) {
+	book := addTestBook(t, "Walden", 1)
+
+	w := httptest.NewRecorder()
+	c := GetTestGinContext(w)
+	c.Params = []gin.Param{{Key: "id", Value: strconv.Itoa(book.ID)}}
+	reqBytes, _ := json.Marshal(&model.BookCopyRequest{Barcode: "WALDEN-RARE", ShelfLocation: "RARE-1", Condition: "new"})
+	c.Request.Body = io.NopCloser(bytes.NewBuffer(reqBytes))
+	reqHandler.AddBookCopy(c)
+	assert.EqualValues(t, http.StatusCreated, w.Code)
+
+	w = httptest.NewRecorder()
+	c = GetTestGinContext(w)
+	c.Params = []gin.Param{{Key: "id", Value: strconv.Itoa(book.ID)}}
+	reqHandler.GetBookCopies(c)
+	assert.EqualValues(t, http.StatusOK, w.Code)
+	var copies []model.BookCopy
+	assert.Nil(t, json.Unmarshal(w.Body.Bytes(), &copies))
+	assert.Len(t, copies, 2)
+
+	w = httptest.NewRecorder()
+	c = GetTestGinContext(w)
+	c.Params = []gin.Param{{Key: "barcode", Value: "WALDEN-RARE"}}
+	reqBytes, _ = json.Marshal(&model.BookCopyRequest{Status: "in_repair"})
+	c.Request.Body = io.NopCloser(bytes.NewBuffer(reqBytes))
+	reqHandler.UpdateBookCopy(c)
+	assert.EqualValues(t, http.StatusOK, w.Code)
+
+	w = httptest.NewRecorder()
+	c = GetTestGinContext(w)
+	c.Params = []gin.Param{{Key: "barcode", Value: "WALDEN-RARE"}}
+	reqHandler.GetBookCopy(c)
+	assert.EqualValues(t, http.StatusOK, w.Code)
+
+	// failure case: copies go on loan only through loans
+	w = httptest.NewRecorder()
+	c = GetTestGinContext(w)
+	c.Params = []gin.Param{{Key: "barcode", Value: "WALDEN-RARE"}}
+	reqBytes, _ = json.Marshal(&model.BookCopyRequest{Status: "on_loan"})
+	c.Request.Body = io.NopCloser(bytes.NewBuffer(reqBytes))
+	reqHandler.UpdateBookCopy(c)
+	assert.EqualValues(t, http.StatusBadRequest, w.Code)
+
+	// failure case
+	w = httptest.NewRecorder()
+	c = GetTestGinContext(w)
+	c.Params = []gin.Param{{Key: "barcode", Value: "NOPE"}}
+	reqHandler.GetBookCopy(c)
+	assert.EqualValues(t, http.StatusNotFound, w.Code)
+}
+
+func TestSearchBooks(t *testing.T) {
+	// success case
+	w := httptest.NewRecorder()
+	c := GetTestGinContext(w)
+	c.Request.URL = &url.URL{RawQuery: url.Values{"q": {"harari"}}.Encode()}
+	reqHandler.SearchBooks(c)
+	assert.EqualValues(t, http.StatusOK, w.Code)
+	var results []model.BookSearchResult
+	assert.Nil(t, json.Unmarshal(w.Body.Bytes(), &results))
+	assert.Len(t, results, 1)
+	assert.Equal(t, "Sapiens", results[0].Book.Title)
+
+	// failure case
+	w = httptest.NewRecorder()
+	c = GetTestGinContext(w)
+	c.Request.URL = &url.URL{}
+	reqHandler.SearchBooks(c)
+	assert.EqualValues(t, http.StatusBadRequest, w.Code)
+
+	// failure case
+	w = httptest.NewRecorder()
+	c = GetTestGinContext(w)
+	c.Request.URL = &url.URL{RawQuery: url.Values{"q": {"harari"}, "limit": {"1000"}}.Encode()}
+	reqHandler.SearchBooks(c)
+	assert.EqualValues(t, http.StatusBadRequest, w.Code)
+}
+
+func TestGetAllBooks(t *testing.T) {
+	// success case
+	w := httptest.NewRecorder()
+	c := GetTestGinContext(w)
+	c.Request.URL = &url.URL{RawQuery: url.Values{"limit": {"2"}, "sort": {"title"}}.Encode()}
+	reqHandler.GetAllBooks(c)
+	assert.EqualValues(t, http.StatusOK, w.Code)
+	var page model.BookPage
+	assert.Nil(t, json.Unmarshal(w.Body.Bytes(), &page))
+	assert.Len(t, page.Books, 2)
+	assert.NotEmpty(t, page.NextCursor)
+
+	// next page
+	w = httptest.NewRecorder()
+	c = GetTestGinContext(w)
+	c.Request.URL = &url.URL{RawQuery: url.Values{"limit": {"2"}, "sort": {"title"}, "cursor": {page.NextCursor}}.Encode()}
+	reqHandler.GetAllBooks(c)
+	assert.EqualValues(t, http.StatusOK, w.Code)
+	var next model.BookPage
+	assert.Nil(t, json.Unmarshal(w.Body.Bytes(), &next))
+	assert.Len(t, next.Books, 2)
+	assert.NotEqual(t, page.Books[1].ID, next.Books[0].ID)
+
+	// failure case: cursor of another sort order
+	w = httptest.NewRecorder()
+	c = GetTestGinContext(w)
+	c.Request.URL = &url.URL{RawQuery: url.Values{"sort": {"id"}, "cursor": {page.NextCursor}}.Encode()}
+	reqHandler.GetAllBooks(c)
+	assert.EqualValues(t, http.StatusBadRequest, w.Code)
+
+	// failure case
+	w = httptest.NewRecorder()
+	c = GetTestGinContext(w)
+	c.Request.URL = &url.URL{RawQuery: url.Values{"sort": {"isbn"}}.Encode()}
+	reqHandler.GetAllBooks(c)
+	assert.EqualValues(t, http.StatusBadRequest, w.Code)
+}
+
+func TestGetAllLoans(t *testing.T) {
+	// success case
+	w := httptest.NewRecorder()
+	c := GetTestGinContext(w)
+	c.Request.URL = &url.URL{RawQuery: url.Values{"status": {"active"}, "sort": {"loan_date"}, "order": {"desc"}}.Encode()}
+	reqHandler.GetAllLoans(c)
+	assert.EqualValues(t, http.StatusOK, w.Code)
+
+	// no matching loans isn't an error
+	w = httptest.NewRecorder()
+	c = GetTestGinContext(w)
+	c.Request.URL = &url.URL{RawQuery: url.Values{"borrower": {"nobody"}}.Encode()}
+	reqHandler.GetAllLoans(c)
+	assert.EqualValues(t, http.StatusOK, w.Code)
+	var page model.LoanPage
+	assert.Nil(t, json.Unmarshal(w.Body.Bytes(), &page))
+	assert.Empty(t, page.Loans)
+
+	// failure case
+	w = httptest.NewRecorder()
+	c = GetTestGinContext(w)
+	c.Request.URL = &url.URL{RawQuery: url.Values{"status": {"lost"}}.Encode()}
+	reqHandler.GetAllLoans(c)
+	assert.EqualValues(t, http.StatusBadRequest, w.Code)
+
+	// failure case
+	w = httptest.NewRecorder()
+	c = GetTestGinContext(w)
+	c.Request.URL = &url.URL{RawQuery: url.Values{"overdue": {"maybe"}}.Encode()}
+	reqHandler.GetAllLoans(c)
+	assert.EqualValues(t, http.StatusBadRequest, w.Code)
+}
+
+func TestMembers(t *testing.T) {
+	// success case
+	w := httptest.NewRecorder()
+	c := GetTestGinContext(w)
+	reqBytes, _ := json.Marshal(&model.MemberRequest{Name: "Mary", Email: "mary@example.com", Tier: "student"})
+	c.Request.Body = io.NopCloser(bytes.NewBuffer(reqBytes))
+	reqHandler.AddMember(c)
+	assert.EqualValues(t, http.StatusCreated, w.Code)
+	var member model.Member
+	assert.Nil(t, json.Unmarshal(w.Body.Bytes(), &member))
+	assert.NotEmpty(t, member.CardNumber)
+
+	// failure case: email taken
+	w = httptest.NewRecorder()
+	c = GetTestGinContext(w)
+	c.Request.Body = io.NopCloser(bytes.NewBuffer(reqBytes))
+	reqHandler.AddMember(c)
+	assert.EqualValues(t, http.StatusConflict, w.Code)
+
+	// failure case
+	w = httptest.NewRecorder()
+	c = GetTestGinContext(w)
+	reqBytes, _ = json.Marshal(&model.MemberRequest{Name: "Mary", Email: "not-an-email"})
+	c.Request.Body = io.NopCloser(bytes.NewBuffer(reqBytes))
+	reqHandler.AddMember(c)
+	assert.EqualValues(t, http.StatusBadRequest, w.Code)
+
+	// suspended members don't borrow
+	w = httptest.NewRecorder()
+	c = GetTestGinContext(w)
+	c.Params = []gin.Param{{Key: "id", Value: strconv.Itoa(member.ID)}}
+	reqBytes, _ = json.Marshal(&model.MemberRequest{Status: "suspended"})
+	c.Request.Body = io.NopCloser(bytes.NewBuffer(reqBytes))
+	reqHandler.UpdateMember(c)
+	assert.EqualValues(t, http.StatusOK, w.Code)
+
+	w = httptest.NewRecorder()
+	c = GetTestGinContext(w)
+	reqBytes, _ = json.Marshal(&model.LoanRequest{MemberID: member.ID, Title: "sapiens"})
+	c.Request.Body = io.NopCloser(bytes.NewBuffer(reqBytes))
+	reqHandler.LoanBook(c)
+	assert.EqualValues(t, http.StatusForbidden, w.Code)
+
+	// failure case
+	w = httptest.NewRecorder()
+	c = GetTestGinContext(w)
+	reqBytes, _ = json.Marshal(&model.LoanRequest{MemberID: 1000, Title: "sapiens"})
+	c.Request.Body = io.NopCloser(bytes.NewBuffer(reqBytes))
+	reqHandler.LoanBook(c)
+	assert.EqualValues(t, http.StatusNotFound, w.Code)
+
+	w = httptest.NewRecorder()
+	c = GetTestGinContext(w)
+	c.Request.URL = &url.URL{RawQuery: url.Values{"status": {"suspended"}}.Encode()}
+	reqHandler.GetAllMembers(c)
+	assert.EqualValues(t, http.StatusOK, w.Code)
+	var page model.MemberPage
+	assert.Nil(t, json.Unmarshal(w.Body.Bytes(), &page))
+	assert.Len(t, page.Members, 1)
+
+	w = httptest.NewRecorder()
+	c = GetTestGinContext(w)
+	c.Params = []gin.Param{{Key: "id", Value: strconv.Itoa(member.ID)}}
+	reqHandler.DeleteMember(c)
+	assert.EqualValues(t, http.StatusOK, w.Code)
+
+	w = httptest.NewRecorder()
+	c = GetTestGinContext(w)
+	c.Params = []gin.Param{{Key: "id", Value: strconv.Itoa(member.ID)}}
+	reqHandler.GetMember(c)
+	assert.EqualValues(t, http.StatusNotFound, w.Code)
+}
+
+func TestLoanBookRefused(t *testing.T) {
+	w := httptest.NewRecorder()
+	c := GetTestGinContext(w)
+	reqBytes, _ := json.Marshal(&model.MemberRequest{Name: "Nora", Tier: "student"})
+	c.Request.Body = io.NopCloser(bytes.NewBuffer(reqBytes))
+	reqHandler.AddMember(c)
+	assert.EqualValues(t, http.StatusCreated, w.Code)
+	var member model.Member
+	assert.Nil(t, json.Unmarshal(w.Body.Bytes(), &member))
+
+	// success case
+	w = httptest.NewRecorder()
+	c = GetTestGinContext(w)
+	reqBytes, _ = json.Marshal(&model.LoanRequest{MemberID: member.ID, Title: "mocking bird"})
+	c.Request.Body = io.NopCloser(bytes.NewBuffer(reqBytes))
+	reqHandler.LoanBook(c)
+	assert.EqualValues(t, http.StatusCreated, w.Code)
+
+	// failure case: the same title again
+	w = httptest.NewRecorder()
+	c = GetTestGinContext(w)
+	c.Request.Body = io.NopCloser(bytes.NewBuffer(reqBytes))
+	reqHandler.LoanBook(c)
+	assert.EqualValues(t, http.StatusForbidden, w.Code)
+	var customError model.CustomError
+	assert.Nil(t, json.Unmarshal(w.Body.Bytes(), &customError))
+	assert.Equal(t, "duplicate_title", customError.Reason)
+}
+
+func TestLoanIfMatch(t *testing.T) {
+	book := addTestBook(t, "Walden", 1)
+	w := httptest.NewRecorder()
+	c := GetTestGinContext(w)
+	reqBytes, _ := json.Marshal(&model.LoanRequest{MemberID: 1, BookID: book.ID})
+	c.Request.Body = io.NopCloser(bytes.NewBuffer(reqBytes))
+	reqHandler.LoanBook(c)
+	assert.EqualValues(t, http.StatusCreated, w.Code)
+	var loan model.LoanDetails
+	assert.Nil(t, json.Unmarshal(w.Body.Bytes(), &loan))
+
+	// the version is served as the ETag
+	w = httptest.NewRecorder()
+	c = GetTestGinContext(w)
+	c.Params = []gin.Param{{Key: "id", Value: strconv.Itoa(loan.ID)}}
+	reqHandler.GetLoan(c)
+	assert.EqualValues(t, http.StatusOK, w.Code)
+	etag := w.Header().Get("ETag")
+	assert.Equal(t, `"1"`, etag)
+
+	// success case
+	w = httptest.NewRecorder()
+	c = GetTestGinContext(w)
+	c.Params = []gin.Param{{Key: "id", Value: strconv.Itoa(loan.ID)}}
+	c.Request.Header.Set("If-Match", etag)
+	reqHandler.ExtendLoan(c)
+	assert.EqualValues(t, http.StatusAccepted, w.Code)
+	assert.Equal(t, `"2"`, w.Header().Get("ETag"))
+
+	// failure case: returning the version before the extension
+	w = httptest.NewRecorder()
+	c = GetTestGinContext(w)
+	c.Params = []gin.Param{{Key: "id", Value: strconv.Itoa(loan.ID)}}
+	c.Request.Header.Set("If-Match", etag)
+	reqHandler.ReturnBook(c)
+	assert.EqualValues(t, http.StatusPreconditionFailed, w.Code)
+
+	w = httptest.NewRecorder()
+	c = GetTestGinContext(w)
+	c.Params = []gin.Param{{Key: "id", Value: strconv.Itoa(loan.ID)}}
+	c.Request.Header.Set("If-Match", "*")
+	reqHandler.ReturnBook(c)
+	assert.EqualValues(t, http.StatusAccepted, w.Code)
+	assert.Equal(t, `"3"`, w.Header().Get("ETag"))
+}
+
+func TestIdempotency(t *testing.T) {
+	book := addTestBook(t, "Siddhartha", 2)
+	w := httptest.NewRecorder()
+	c := GetTestGinContext(w)
+	reqBytes, _ := json.Marshal(&model.MemberRequest{Name: "Olga"})
+	c.Request.Body = io.NopCloser(bytes.NewBuffer(reqBytes))
+	reqHandler.AddMember(c)
+	assert.EqualValues(t, http.StatusCreated, w.Code)
+	var member model.Member
+	assert.Nil(t, json.Unmarshal(w.Body.Bytes(), &member))
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.POST("/loan", reqHandler.Idempotency, reqHandler.LoanBook)
+	send := func(key string, req model.LoanRequest) *httptest.ResponseRecorder {
+		reqBytes, _ := json.Marshal(&req)
+		r := httptest.NewRequest(http.MethodPost, "/loan", bytes.NewBuffer(reqBytes))
+		r.Header.Set("Idempotency-Key", key)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, r)
+		return w
+	}
+
+	// success case: the retry replays the loan made
+	first := send("loan-siddhartha", model.LoanRequest{MemberID: member.ID, BookID: book.ID})
+	assert.EqualValues(t, http.StatusCreated, first.Code)
+	retry := send("loan-siddhartha", model.LoanRequest{MemberID: member.ID, BookID: book.ID})
+	assert.EqualValues(t, http.StatusCreated, retry.Code)
+	assert.Equal(t, first.Body.String(), retry.Body.String())
+	assert.Equal(t, "true", retry.Header().Get("Idempotent-Replayed"))
+	w = httptest.NewRecorder()
+	c = GetTestGinContext(w)
+	c.Params = []gin.Param{{Key: "id", Value: strconv.Itoa(book.ID)}}
+	reqHandler.GetBookByID(c)
+	var det model.BookDetails
+	assert.Nil(t, json.Unmarshal(w.Body.Bytes(), &det))
+	assert.Equal(t, 1, det.AvailableCopies)
+
+	// failure case: the key reused with another request
+	reused := send("loan-siddhartha", model.LoanRequest{MemberID: member.ID, Title: "sapiens"})
+	assert.EqualValues(t, http.StatusUnprocessableEntity, reused.Code)
+
+	// failure case: a body too large to fingerprint is refused, the key is left free for a retry
+	r := httptest.NewRequest(http.MethodPost, "/loan", strings.NewReader(`{"title":"`+strings.Repeat("x", 1<<20)+`"}`))
+	r.Header.Set("Idempotency-Key", "loan-large")
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, r)
+	assert.EqualValues(t, http.StatusRequestEntityTooLarge, w.Code)
+	other := addTestBook(t, "Gertrud", 1)
+	large := send("loan-large", model.LoanRequest{MemberID: member.ID, BookID: other.ID})
+	assert.EqualValues(t, http.StatusCreated, large.Code)
+
+	// success case: the same key of another caller names another request
+	for _, title := range []string{"Demian", "Narziss"} {
+		book := addTestBook(t, title, 1)
+		principal := &auth.Principal{Subject: "librarian-" + title, Role: constants.RoleLibrarian}
+		router := gin.New()
+		router.Use(func(c *gin.Context) {
+			c.Request = c.Request.WithContext(auth.WithPrincipal(c.Request.Context(), principal))
+		})
+		router.POST("/loan", reqHandler.Idempotency, reqHandler.LoanBook)
+		reqBytes, _ := json.Marshal(&model.LoanRequest{MemberID: member.ID, BookID: book.ID})
+		r := httptest.NewRequest(http.MethodPost, "/loan", bytes.NewBuffer(reqBytes))
+		r.Header.Set("Idempotency-Key", "loan-shared")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, r)
+		assert.EqualValues(t, http.StatusCreated, w.Code)
+		assert.Empty(t, w.Header().Get("Idempotent-Replayed"))
+	}
+}
+
+func TestExtendLoanLimit(t *testing.T) {
+	config.PolicyConfig.MaxExtensionsByCategory = map[string]int{"reference": 0}
+	defer func() {
+		config.PolicyConfig.MaxExtensionsByCategory = nil
+	}()
+	w := httptest.NewRecorder()
+	c := GetTestGinContext(w)
+	reqBytes, _ := json.Marshal(&model.BookRequest{Title: "Atlas", Category: "Reference", Copies: 1})
+	c.Request.Body = io.NopCloser(bytes.NewBuffer(reqBytes))
+	reqHandler.AddBook(c)
+	assert.EqualValues(t, http.StatusCreated, w.Code)
+
+	w = httptest.NewRecorder()
+	c = GetTestGinContext(w)
+	reqBytes, _ = json.Marshal(&model.LoanRequest{MemberID: 1, Title: "atlas"})
+	c.Request.Body = io.NopCloser(bytes.NewBuffer(reqBytes))
+	reqHandler.LoanBook(c)
+	assert.EqualValues(t, http.StatusCreated, w.Code)
+	var loan model.LoanDetails
+	assert.Nil(t, json.Unmarshal(w.Body.Bytes(), &loan))
+	assert.Greater(t, loan.ReturnDate, loan.LoanDate)
+
+	// failure case: reference books aren't extended
+	w = httptest.NewRecorder()
+	c = GetTestGinContext(w)
+	c.Params = []gin.Param{{Key: "id", Value: strconv.Itoa(loan.ID)}}
+	reqHandler.ExtendLoan(c)
+	assert.EqualValues(t, http.StatusForbidden, w.Code)
+	var customError model.CustomError
+	assert.Nil(t, json.Unmarshal(w.Body.Bytes(), &customError))
+	assert.Equal(t, "extension_limit_reached", customError.Reason)
+}
+
+func TestHolds(t *testing.T) {
+	w := httptest.NewRecorder()
+	c := GetTestGinContext(w)
+	reqBytes, _ := json.Marshal(&model.BookRequest{Title: "Beloved", Copies: 1})
+	c.Request.Body = io.NopCloser(bytes.NewBuffer(reqBytes))
+	reqHandler.AddBook(c)
+	assert.EqualValues(t, http.StatusCreated, w.Code)
+	var book model.BookDetails
+	assert.Nil(t, json.Unmarshal(w.Body.Bytes(), &book))
+
+	w = httptest.NewRecorder()
+	c = GetTestGinContext(w)
+	reqBytes, _ = json.Marshal(&model.MemberRequest{Name: "Otto"})
+	c.Request.Body = io.NopCloser(bytes.NewBuffer(reqBytes))
+	reqHandler.AddMember(c)
+	assert.EqualValues(t, http.StatusCreated, w.Code)
+	var member model.Member
+	assert.Nil(t, json.Unmarshal(w.Body.Bytes(), &member))
+
+	// failure case: a copy is on the shelf
+	w = httptest.NewRecorder()
+	c = GetTestGinContext(w)
+	holdBytes, _ := json.Marshal(&model.HoldRequest{MemberID: member.ID, BookID: book.ID})
+	c.Request.Body = io.NopCloser(bytes.NewBuffer(holdBytes))
+	reqHandler.PlaceHold(c)
+	assert.EqualValues(t, http.StatusConflict, w.Code)
+
+	w = httptest.NewRecorder()
+	c = GetTestGinContext(w)
+	reqBytes, _ = json.Marshal(&model.LoanRequest{MemberID: 1, BookID: book.ID})
+	c.Request.Body = io.NopCloser(bytes.NewBuffer(reqBytes))
+	reqHandler.LoanBook(c)
+	assert.EqualValues(t, http.StatusCreated, w.Code)
+
+	// success case
+	w = httptest.NewRecorder()
+	c = GetTestGinContext(w)
+	c.Request.Body = io.NopCloser(bytes.NewBuffer(holdBytes))
+	reqHandler.PlaceHold(c)
+	assert.EqualValues(t, http.StatusCreated, w.Code)
+	var hold model.Hold
+	assert.Nil(t, json.Unmarshal(w.Body.Bytes(), &hold))
+	assert.Equal(t, "waiting", hold.Status)
+
+	w = httptest.NewRecorder()
+	c = GetTestGinContext(w)
+	c.Params = []gin.Param{{Key: "id", Value: strconv.Itoa(member.ID)}}
+	reqHandler.GetMemberHolds(c)
+	assert.EqualValues(t, http.StatusOK, w.Code)
+	var holds []model.Hold
+	assert.Nil(t, json.Unmarshal(w.Body.Bytes(), &holds))
+	assert.Len(t, holds, 1)
+
+	// failure case: the borrower can't hold the title
+	w = httptest.NewRecorder()
+	c = GetTestGinContext(w)
+	reqBytes, _ = json.Marshal(&model.HoldRequest{MemberID: 1, Title: "beloved"})
+	c.Request.Body = io.NopCloser(bytes.NewBuffer(reqBytes))
+	reqHandler.PlaceHold(c)
+	assert.EqualValues(t, http.StatusForbidden, w.Code)
+	var customError model.CustomError
+	assert.Nil(t, json.Unmarshal(w.Body.Bytes(), &customError))
+	assert.Equal(t, "duplicate_title", customError.Reason)
+
+	// success case: cancelling
+	w = httptest.NewRecorder()
+	c = GetTestGinContext(w)
+	c.Params = []gin.Param{{Key: "id", Value: strconv.Itoa(hold.ID)}}
+	reqHandler.CancelHold(c)
+	assert.EqualValues(t, http.StatusOK, w.Code)
+
+	// failure case: cancelled twice
+	w = httptest.NewRecorder()
+	c = GetTestGinContext(w)
+	c.Params = []gin.Param{{Key: "id", Value: strconv.Itoa(hold.ID)}}
+	reqHandler.CancelHold(c)
+	assert.EqualValues(t, http.StatusConflict, w.Code)
+}
+
+func TestFines(t *testing.T) {
+	// success case
+	w := httptest.NewRecorder()
+	c := GetTestGinContext(w)
+	c.Params = []gin.Param{{Key: "id", Value: "1"}}
+	reqHandler.GetMemberFines(c)
+	assert.EqualValues(t, http.StatusOK, w.Code)
+	var fines model.MemberFines
+	assert.Nil(t, json.Unmarshal(w.Body.Bytes(), &fines))
+	assert.Equal(t, int64(0), fines.OutstandingInCents)
+
+	// failure case: unknown member
+	w = httptest.NewRecorder()
+	c = GetTestGinContext(w)
+	c.Params = []gin.Param{{Key: "id", Value: "1000"}}
+	reqHandler.GetMemberFines(c)
+	assert.EqualValues(t, http.StatusNotFound, w.Code)
+
+	// failure case: negative amount
+	w = httptest.NewRecorder()
+	c = GetTestGinContext(w)
+	c.Params = []gin.Param{{Key: "id", Value: "1"}}
+	reqBytes, _ := json.Marshal(&model.FinePaymentRequest{AmountInCents: -1})
+	c.Request.Body = io.NopCloser(bytes.NewBuffer(reqBytes))
+	reqHandler.PayFine(c)
+	assert.EqualValues(t, http.StatusBadRequest, w.Code)
+
+	// failure case: unknown fine
+	w = httptest.NewRecorder()
+	c = GetTestGinContext(w)
+	c.Params = []gin.Param{{Key: "id", Value: "1000"}}
+	c.Request.Body = io.NopCloser(bytes.NewBuffer(nil))
+	reqHandler.PayFine(c)
+	assert.EqualValues(t, http.StatusNotFound, w.Code)
+
+	w = httptest.NewRecorder()
+	c = GetTestGinContext(w)
+	c.Params = []gin.Param{{Key: "id", Value: "1000"}}
+	reqHandler.WaiveFine(c)
+	assert.EqualValues(t, http.StatusNotFound, w.Code)
+}
+
+func TestLoanHistory(t *testing.T) {
+	w := httptest.NewRecorder()
+	c := GetTestGinContext(w)
+	reqBytes, _ := json.Marshal(&model.MemberRequest{Name: "Gina"})
+	c.Request.Body = io.NopCloser(bytes.NewBuffer(reqBytes))
+	reqHandler.AddMember(c)
+	assert.EqualValues(t, http.StatusCreated, w.Code)
+	var member model.Member
+	assert.Nil(t, json.Unmarshal(w.Body.Bytes(), &member))
+
+	// borrowing through the actor middleware
+	w = httptest.NewRecorder()
+	c, engine := gin.CreateTestContext(w)
+	engine.ContextWithFallback = true
+	reqBytes, _ = json.Marshal(&model.LoanRequest{MemberID: member.ID, Title: "atomic habbits"})
+	c.Request = httptest.NewRequest(http.MethodPost, "/api/v1/loan", bytes.NewBuffer(reqBytes))
+	c.Request.Header.Set("X-Actor", "librarian-1")
+	reqHandler.Actor(c)
+	reqHandler.LoanBook(c)
+	assert.EqualValues(t, http.StatusCreated, w.Code)
+	var loan model.LoanDetails
+	assert.Nil(t, json.Unmarshal(w.Body.Bytes(), &loan))
+
+	// success case
+	w = httptest.NewRecorder()
+	c = GetTestGinContext(w)
+	c.Params = []gin.Param{{Key: "id", Value: strconv.Itoa(loan.ID)}}
+	reqHandler.GetLoanHistory(c)
+	assert.EqualValues(t, http.StatusOK, w.Code)
+	var events []model.LoanEvent
+	assert.Nil(t, json.Unmarshal(w.Body.Bytes(), &events))
+	assert.Len(t, events, 1)
+	assert.Equal(t, "created", events[0].Type)
+	assert.Equal(t, "librarian-1", events[0].Actor)
+
+	// failure case: unknown loan
+	w = httptest.NewRecorder()
+	c = GetTestGinContext(w)
+	c.Params = []gin.Param{{Key: "id", Value: "1000"}}
+	reqHandler.GetLoanHistory(c)
+	assert.EqualValues(t, http.StatusNotFound, w.Code)
+}
+
+func TestMemberLoans(t *testing.T) {
+	w := httptest.NewRecorder()
+	c := GetTestGinContext(w)
+	reqBytes, _ := json.Marshal(&model.MemberRequest{Name: "Hana"})
+	c.Request.Body = io.NopCloser(bytes.NewBuffer(reqBytes))
+	reqHandler.AddMember(c)
+	assert.EqualValues(t, http.StatusCreated, w.Code)
+	var member model.Member
+	assert.Nil(t, json.Unmarshal(w.Body.Bytes(), &member))
+	w = httptest.NewRecorder()
+	c = GetTestGinContext(w)
+	reqBytes, _ = json.Marshal(&model.LoanRequest{MemberID: member.ID, Title: "mocking bird"})
+	c.Request.Body = io.NopCloser(bytes.NewBuffer(reqBytes))
+	reqHandler.LoanBook(c)
+	assert.EqualValues(t, http.StatusCreated, w.Code)
+	var loan model.LoanDetails
+	assert.Nil(t, json.Unmarshal(w.Body.Bytes(), &loan))
+
+	// success case
+	w = httptest.NewRecorder()
+	c = GetTestGinContext(w)
+	c.Params = []gin.Param{{Key: "id", Value: strconv.Itoa(loan.ID)}}
+	reqHandler.GetLoan(c)
+	assert.EqualValues(t, http.StatusOK, w.Code)
+	var fetched model.LoanDetails
+	assert.Nil(t, json.Unmarshal(w.Body.Bytes(), &fetched))
+	assert.Equal(t, loan.ID, fetched.ID)
+	assert.Equal(t, "Mocking Bird", fetched.Title)
+	w = httptest.NewRecorder()
+	c = GetTestGinContext(w)
+	c.Params = []gin.Param{{Key: "id", Value: strconv.Itoa(member.ID)}}
+	reqHandler.GetMemberLoans(c)
+	assert.EqualValues(t, http.StatusOK, w.Code)
+	var loans []model.LoanDetails
+	assert.Nil(t, json.Unmarshal(w.Body.Bytes(), &loans))
+	assert.Len(t, loans, 1)
+	assert.Equal(t, loan.ID, loans[0].ID)
+
+	// failure case: invalid id
+	w = httptest.NewRecorder()
+	c = GetTestGinContext(w)
+	c.Params = []gin.Param{{Key: "id", Value: "abc"}}
+	reqHandler.GetLoan(c)
+	assert.EqualValues(t, http.StatusBadRequest, w.Code)
+
+	// failure case: unknown loan and member
+	w = httptest.NewRecorder()
+	c = GetTestGinContext(w)
+	c.Params = []gin.Param{{Key: "id", Value: "1000"}}
+	reqHandler.GetLoan(c)
+	assert.EqualValues(t, http.StatusNotFound, w.Code)
+	w = httptest.NewRecorder()
+	c = GetTestGinContext(w)
+	c.Params = []gin.Param{{Key: "id", Value: "1000"}}
+	reqHandler.GetMemberLoans(c)
+	assert.EqualValues(t, http.StatusNotFound, w.Code)
+}
+
+func TestAuthorization(t *testing.T) {
+	book := addTestBook(t, "Demian", 2)
+	w := httptest.NewRecorder()
+	c := GetTestGinContext(w)
+	reqBytes, _ := json.Marshal(&model.MemberRequest{Name: "Hermann"})
+	c.Request.Body = io.NopCloser(bytes.NewBuffer(reqBytes))
+	reqHandler.AddMember(c)
+	assert.EqualValues(t, http.StatusCreated, w.Code)
+	var member model.Member
+	assert.Nil(t, json.Unmarshal(w.Body.Bytes(), &member))
+
+	config.AuthConfig.JWTSecret = "library-secret"
+	defer func() { config.AuthConfig.JWTSecret = "" }()
+	authn, err := auth.NewAuthenticator()
+	assert.Nil(t, err)
+	authHandler := handler.NewHandler(testStore, authn, health.NewRegistry(time.Second))
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	api := router.Group("", authHandler.Authenticate, authHandler.Actor)
+	api.POST("/book", authHandler.Librarian, authHandler.AddBook)
+	api.GET("/loan", authHandler.GetAllLoans)
+	api.POST("/loan", authHandler.LoanBook)
+	api.GET("/loan/:id", authHandler.GetLoan)
+	api.GET("/member/:id/loans", authHandler.GetMemberLoans)
+	api.GET("/apikey", authHandler.Admin, authHandler.GetAllAPIKeys)
+	token := func(claims jwt.MapClaims) string {
+		claims["exp"] = time.Now().Add(time.Hour).Unix()
+		signed, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte("library-secret"))
+		assert.Nil(t, err)
+		return signed
+	}
+	librarian := token(jwt.MapClaims{"sub": "anna", "role": "librarian"})
+	own := token(jwt.MapClaims{"sub": "hermann", "role": "member", "member_id": member.ID})
+	other := token(jwt.MapClaims{"sub": "ben", "role": "member", "member_id": member.ID + 1000})
+	send := func(method, path, bearer string, body any) *httptest.ResponseRecorder {
+		reqBytes, _ := json.Marshal(body)
+		r := httptest.NewRequest(method, path, bytes.NewBuffer(reqBytes))
+		if bearer != "" {
+			r.Header.Set("Authorization", "Bearer "+bearer)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, r)
+		return w
+	}
+
+	// failure cases: no token or a forged one
+	w = send(http.MethodGet, "/loan", "", nil)
+	assert.EqualValues(t, http.StatusUnauthorized, w.Code)
+	assert.NotEmpty(t, w.Header().Get("WWW-Authenticate"))
+	w = send(http.MethodGet, "/loan", librarian+"x", nil)
+	assert.EqualValues(t, http.StatusUnauthorized, w.Code)
+
+	// failure case: members don't change the catalog
+	w = send(http.MethodPost, "/book", own, model.BookRequest{Title: "Narziss"})
+	assert.EqualValues(t, http.StatusForbidden, w.Code)
+
+	// success case: librarians manage the api keys, members don't
+	assert.EqualValues(t, http.StatusOK, send(http.MethodGet, "/apikey", librarian, nil).Code)
+	assert.EqualValues(t, http.StatusForbidden, send(http.MethodGet, "/apikey", own, nil).Code)
+
+	// success case: a member borrows for themselves with the member ID filled in
+	w = send(http.MethodPost, "/loan", own, model.LoanRequest{BookID: book.ID})
+	assert.EqualValues(t, http.StatusCreated, w.Code)
+	var loan model.LoanDetails
+	assert.Nil(t, json.Unmarshal(w.Body.Bytes(), &loan))
+	assert.Equal(t, member.ID, loan.MemberID)
+	assert.EqualValues(t, http.StatusOK, send(http.MethodGet, "/loan/"+strconv.Itoa(loan.ID), own, nil).Code)
+	assert.EqualValues(t, http.StatusOK, send(http.MethodGet, "/loan/"+strconv.Itoa(loan.ID), librarian, nil).Code)
+
+	// failure cases: another member borrows for them or looks at their loans
+	w = send(http.MethodPost, "/loan", other, model.LoanRequest{MemberID: member.ID, BookID: book.ID})
+	assert.EqualValues(t, http.StatusForbidden, w.Code)
+	assert.EqualValues(t, http.StatusForbidden, send(http.MethodGet, "/loan/"+strconv.Itoa(loan.ID), other, nil).Code)
+	assert.EqualValues(t, http.StatusForbidden, send(http.MethodGet, "/member/"+strconv.Itoa(member.ID)+"/loans", other, nil).Code)
+
+	// success case: the listing of a member holds their own loans only
+	w = send(http.MethodGet, "/loan", other, nil)
+	assert.EqualValues(t, http.StatusOK, w.Code)
+	var page model.LoanPage
+	assert.Nil(t, json.Unmarshal(w.Body.Bytes(), &page))
+	for _, item := range page.Loans {
+		assert.Equal(t, member.ID+1000, item.MemberID)
+	}
+}
+
+func TestAPIKeys(t *testing.T) {
+	book := addTestBook(t, "Steppenwolf", 1)
+	w := httptest.NewRecorder()
+	c := GetTestGinContext(w)
+	reqBytes, _ := json.Marshal(&model.APIKeyRequest{Name: "kiosk-east", Scopes: []string{"catalog:read"}, RequestsPerMinute: 2})
+	c.Request.Body = io.NopCloser(bytes.NewBuffer(reqBytes))
+	reqHandler.IssueAPIKey(c)
+	assert.EqualValues(t, http.StatusCreated, w.Code)
+	var issued model.IssuedAPIKey
+	assert.Nil(t, json.Unmarshal(w.Body.Bytes(), &issued))
+	assert.True(t, strings.HasPrefix(issued.Key, issued.Prefix))
+	assert.NotContains(t, w.Body.String(), "hash")
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	api := router.Group("", reqHandler.Authenticate, reqHandler.Quota, reqHandler.Actor)
+	api.GET("/book/id/:id", reqHandler.GetBookByID)
+	api.POST("/book", reqHandler.Librarian, reqHandler.AddBook)
+	api.GET("/apikey", reqHandler.Admin, reqHandler.GetAllAPIKeys)
+	api.POST("/apikey", reqHandler.Admin, reqHandler.IssueAPIKey)
+	send := func(method, path, key string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(method, path, bytes.NewBufferString(`{"title":"Narziss"}`))
+		if key != "" {
+			r.Header.Set("X-API-Key", key)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, r)
+		return w
+	}
+	path := "/book/id/" + strconv.Itoa(book.ID)
+
+	// success case: reading within the scopes of the key counts against its quota
+	w = send(http.MethodGet, path, issued.Key)
+	assert.EqualValues(t, http.StatusOK, w.Code)
+	assert.Equal(t, "2", w.Header().Get("X-RateLimit-Limit"))
+	assert.Equal(t, "1", w.Header().Get("X-RateLimit-Remaining"))
+
+	// failure cases: writing out of the scopes, managing the keys and unknown keys
+	assert.EqualValues(t, http.StatusForbidden, send(http.MethodPost, "/book", issued.Key).Code)
+	assert.EqualValues(t, http.StatusForbidden, send(http.MethodGet, "/apikey", issued.Key).Code)
+	assert.EqualValues(t, http.StatusUnauthorized, send(http.MethodGet, path, issued.Key+"x").Code)
+
+	// failure case: anonymous requests don't manage the keys even while authentication is disabled
+	w = send(http.MethodPost, "/apikey", "")
+	assert.EqualValues(t, http.StatusUnauthorized, w.Code)
+	assert.NotEmpty(t, w.Header().Get("WWW-Authenticate"))
+	assert.EqualValues(t, http.StatusUnauthorized, send(http.MethodGet, "/apikey", "").Code)
+
+	// failure case: the quota is used up
+	assert.EqualValues(t, http.StatusOK, send(http.MethodGet, path, issued.Key).Code)
+	w = send(http.MethodGet, path, issued.Key)
+	assert.EqualValues(t, http.StatusTooManyRequests, w.Code)
+	assert.NotEmpty(t, w.Header().Get("Retry-After"))
+
+	// failure case: revoked keys are refused
+	w = httptest.NewRecorder()
+	c = GetTestGinContext(w)
+	c.Params = []gin.Param{{Key: "id", Value: strconv.Itoa(issued.ID)}}
+	reqHandler.RevokeAPIKey(c)
+	assert.EqualValues(t, http.StatusOK, w.Code)
+	assert.EqualValues(t, http.StatusUnauthorized, send(http.MethodGet, path, issued.Key).Code)
+
+	// failure case: unknown scopes
+	w = httptest.NewRecorder()
+	c = GetTestGinContext(w)
+	reqBytes, _ = json.Marshal(&model.APIKeyRequest{Name: "portal", Scopes: []string{"catalog:delete"}})
+	c.Request.Body = io.NopCloser(bytes.NewBuffer(reqBytes))
+	reqHandler.IssueAPIKey(c)
+	assert.EqualValues(t, http.StatusBadRequest, w.Code)
+}
+
+func TestMetrics(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(reqHandler.Metrics)
+	router.GET("/book/id/:id", reqHandler.GetBookByID)
+	router.GET("/metrics", gin.WrapH(metrics.Handler()))
+	for _, path := range []string{"/book/id/1", "/book/id/1000", "/nope"} {
+		router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	assert.EqualValues(t, http.StatusOK, w.Code)
+	body := w.Body.String()
+
+	// success case: requests are counted by their route, the store operations they made are timed
+	assert.Contains(t, body, `library_http_requests_total{code="200",method="GET",route="/book/id/:id"}`)
+	assert.Contains(t, body, `library_http_requests_total{code="404",method="GET",route="/book/id/:id"}`)
+	assert.Contains(t, body, `library_http_requests_total{code="404",method="GET",route="unmatched"}`)
+	assert.Contains(t, body, `library_http_request_duration_seconds_count{method="GET",route="/book/id/:id"}`)
+	assert.Contains(t, body, `library_store_operation_errors_total{kind="not_found",operation="GetBookDetailsByID"}`)
+}
+
+func TestTracing(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	tracing.UseExporter(exporter)
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	// the stores see the span of the request through the gin context
+	router.ContextWithFallback = true
+	router.Use(reqHandler.Tracing)
+	router.GET("/book/id/:id", reqHandler.GetBookByID)
+
+	// success case: the trace of the caller is continued, the store operation is traced under the request
+	traceID := "4bf92f3577b34da6a3ce929d0e0e4736"
+	req := httptest.NewRequest(http.MethodGet, "/book/id/1000", nil)
+	req.Header.Set("traceparent", "00-"+traceID+"-00f067aa0ba902b7-01")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.EqualValues(t, http.StatusNotFound, w.Code)
+	spans := exporter.GetSpans()
+	assert.Len(t, spans, 2)
+	storeSpan, serverSpan := spans[0], spans[1]
+	assert.Equal(t, "GET /book/id/:id", serverSpan.Name)
+	assert.Equal(t, traceID, serverSpan.SpanContext.TraceID().String())
+	assert.Contains(t, serverSpan.Attributes, attribute.Int("http.response.status_code", http.StatusNotFound))
+	assert.Equal(t, "store.GetBookDetailsByID", storeSpan.Name)
+	assert.Equal(t, serverSpan.SpanContext.SpanID(), storeSpan.Parent.SpanID())
+	// a missing book is an answer rather than a failure
+	assert.Contains(t, storeSpan.Attributes, attribute.String("error.type", "not_found"))
+	assert.Equal(t, codes.Unset, storeSpan.Status.Code)
+
+	// success case: requests matching no route are traced as unmatched
+	exporter.Reset()
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/nope", nil))
+	spans = exporter.GetSpans()
+	assert.Len(t, spans, 1)
+	assert.Equal(t, "GET unmatched", spans[0].Name)
+	assert.False(t, spans[0].Parent.IsValid())
+}
+
+func TestHealth(t *testing.T) {
+	checks := health.NewRegistry(time.Second)
+	checks.Register("local", testStore.Ping)
+	healthHandler := handler.NewHandler(testStore, nil, checks)
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/health", healthHandler.Health)
+	router.GET("/live", healthHandler.Live)
+	getHealth := func() (int, *model.HealthReport) {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/health", nil))
+		report := &model.HealthReport{}
+		assert.Nil(t, json.Unmarshal(w.Body.Bytes(), report))
+		return w.Code, report
+	}
+
+	// success case: every check passed
+	code, report := getHealth()
+	assert.EqualValues(t, http.StatusOK, code)
+	assert.Equal(t, "ok", report.Status)
+	assert.Len(t, report.Checks, 1)
+	assert.Equal(t, "local", report.Checks[0].Name)
+	assert.Equal(t, "ok", report.Checks[0].Status)
+
+	// failure case: a failed check is reported along with its error
+	checks.Register("jobs", func(ctx context.Context) error { return errors.New("jobs stalled: accrue-fines last ran 2h0m0s ago") })
+	code, report = getHealth()
+	assert.EqualValues(t, http.StatusServiceUnavailable, code)
+	assert.Equal(t, "unavailable", report.Status)
+	assert.Equal(t, "failed", report.Checks[1].Status)
+	assert.Equal(t, "jobs stalled: accrue-fines last ran 2h0m0s ago", report.Checks[1].Error)
+
+	// failure case: not ready once shutting down, while still alive
+	checks.Shutdown()
+	code, report = getHealth()
+	assert.EqualValues(t, http.StatusServiceUnavailable, code)
+	assert.Equal(t, "shutting_down", report.Status)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/live", nil))
+	assert.EqualValues(t, http.StatusOK, w.Code)
+}
+
+func TestRequestID(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(reqHandler.RequestID)
+	router.GET("/book/id/:id", reqHandler.GetBookByID)
+	requestID := func(id string) string {
+		req := httptest.NewRequest(http.MethodGet, "/book/id/1", nil)
+		if id != "" {
+			req.Header.Set("X-Request-ID", id)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w.Header().Get("X-Request-ID")
+	}
+
+	// success case: the ID of the caller is returned
+	assert.Equal(t, "3f9c2a7e-req-42", requestID("3f9c2a7e-req-42"))
+
+	// success case: an ID is generated when missed
+	generated := requestID("")
+	assert.Len(t, generated, 32)
+	assert.NotEqual(t, generated, requestID(""))
+
+	// success case: malformed IDs are replaced rather than logged
+	assert.Len(t, requestID("forged\tentry"), 32)
+	assert.Len(t, requestID(strings.Repeat("a", 129)), 32)
+}
+
+func TestLogLevel(t *testing.T) {
+	defer logger.SetLevel(logger.Level())
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	// the level is changed by authenticated librarians only
+	router.Use(func(c *gin.Context) {
+		librarian := &auth.Principal{Subject: "anna", Role: constants.RoleLibrarian}
+		c.Request = c.Request.WithContext(auth.WithPrincipal(c.Request.Context(), librarian))
+	})
+	router.GET("/log/level", reqHandler.Admin, reqHandler.GetLogLevel)
+	router.PUT("/log/level", reqHandler.Admin, reqHandler.SetLogLevel)
+	setLevel := func(body string) (int, *model.LogLevel) {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodPut, "/log/level", strings.NewReader(body)))
+		level := &model.LogLevel{}
+		json.Unmarshal(w.Body.Bytes(), level)
+		return w.Code, level
+	}
+
+	// success case: the level is changed by its name
+	code, level := setLevel(`{"level": "debug"}`)
+	assert.EqualValues(t, http.StatusOK, code)
+	assert.Equal(t, "debug", level.Level)
+	assert.Equal(t, zapcore.DebugLevel, logger.Level())
+
+	// success case: the level is changed by its number
+	code, level = setLevel(`{"level": "1"}`)
+	assert.EqualValues(t, http.StatusOK, code)
+	assert.Equal(t, "warn", level.Level)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/log/level", nil))
+	assert.EqualValues(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"level": "warn"}`, w.Body.String())
+
+	// failure case: unknown level
+	code, _ = setLevel(`{"level": "loud"}`)
+	assert.EqualValues(t, http.StatusBadRequest, code)
+	assert.Equal(t, zapcore.WarnLevel, logger.Level())
+
+	// failure case: invalid request body
+	code, _ = setLevel(`level=debug`)
+	assert.EqualValues(t, http.StatusBadRequest, code)
+
+	// failure case: anonymous requests neither read nor change the level, even while authentication is disabled
+	anonymous := gin.New()
+	anonymous.GET("/log/level", reqHandler.Admin, reqHandler.GetLogLevel)
+	anonymous.PUT("/log/level", reqHandler.Admin, reqHandler.SetLogLevel)
+	w = httptest.NewRecorder()
+	anonymous.ServeHTTP(w, httptest.NewRequest(http.MethodPut, "/log/level", strings.NewReader(`{"level": "debug"}`)))
+	assert.EqualValues(t, http.StatusUnauthorized, w.Code)
+	assert.Equal(t, zapcore.WarnLevel, logger.Level())
+	w = httptest.NewRecorder()
+	anonymous.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/log/level", nil))
+	assert.EqualValues(t, http.StatusUnauthorized, w.Code)
+}
@@ -0,0 +1,229 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/test/library-app/internal/logger"
+	"github.com/test/library-app/internal/model"
+)
+
+// PlaceHold godoc
+//
+//	@Summary 		PlaceHold places a hold on a book
+//	@Description 	PlaceHold queues a member for a book with no copy available. A returned copy is set aside for the longest waiting hold, which gets ready and expires when not borrowed within the pickup window. A hold refused by the borrowing policy fails with 403 and a reason code: member_inactive, hold_limit_reached or duplicate_title
+//	@Param			holdRequest	body	model.HoldRequest	true	"Hold Request"
+//	@Consume 		json	model.HoldRequest
+//	@Produce 		json
+//	@Success 		201	{object}	model.Hold
+//	@Failure 		400	{object}	model.CustomError
+//	@Failure 		401	{object}	model.CustomError
+//	@Failure 		403	{object}	model.CustomError
+//	@Failure 		404	{object}	model.CustomError
+//	@Failure 		409	{object}	model.CustomError
+//	@Failure 		500	{object}	model.CustomError
+//	@Security 		BearerAuth
+//	@Security 		APIKeyAuth
+//	@Router 		/hold	[post]
+//
+// PlaceHold places a hold on a book
+func (h *Handler) PlaceHold(c *gin.Context) {
+	var holdReq model.HoldRequest
+	if err := c.ShouldBindJSON(&holdReq); err != nil {
+		logger.FromContext(c).Errorf("Failed to unamrshal the request body: %v", err)
+		customError := &model.CustomError{
+			Error: "invalid request body",
+			Code:  http.StatusBadRequest,
+		}
+		c.JSON(http.StatusBadRequest, customError)
+		return
+	}
+	holdReq.Title = strings.TrimSpace(holdReq.Title)
+	// members hold for themselves
+	if holdReq.MemberID == 0 {
+		holdReq.MemberID = memberScope(c)
+	}
+	if holdReq.MemberID == 0 || (holdReq.BookID == 0 && holdReq.Title == "") {
+		logger.FromContext(c).Errorf("MemberID & BookID or Title are mandatory to place a hold.")
+		customError := &model.CustomError{
+			Error: "MemberID or BookID/Title missed in the request",
+			Code:  http.StatusBadRequest,
+		}
+		c.JSON(http.StatusBadRequest, customError)
+		return
+	}
+	if !allowMember(c, holdReq.MemberID) {
+		return
+	}
+	hold := &model.Hold{
+		MemberID: holdReq.MemberID,
+		BookID:   holdReq.BookID,
+		Title:    holdReq.Title,
+	}
+	_, err := h.repo.AddHold(c, hold)
+	if err != nil {
+		// if notfound needs to return the specific error code and details
+		if errors.Is(err, model.ErrNotFound) {
+			customError := &model.CustomError{
+				Error: err.Error(),
+				Code:  http.StatusNotFound,
+			}
+			c.JSON(http.StatusNotFound, customError)
+			return
+		}
+		// refused by the borrowing policy, the reason code tells why
+		if errors.Is(err, model.ErrNotAllowed) {
+			customError := &model.CustomError{
+				Error: err.Error(),
+				Code:  http.StatusForbidden,
+			}
+			var refusal *model.RefusalError
+			if errors.As(err, &refusal) {
+				customError.Reason = refusal.Reason
+			}
+			c.JSON(http.StatusForbidden, customError)
+			return
+		}
+		// member already holds the book, a copy is available or the title is ambiguous
+		if errors.Is(err, model.ErrAlreadyExists) || errors.Is(err, model.ErrConflict) {
+			customError := &model.CustomError{
+				Error: err.Error(),
+				Code:  http.StatusConflict,
+			}
+			c.JSON(http.StatusConflict, customError)
+			return
+		}
+		// rest of all errors falls under this category
+		logger.FromContext(c).Errorf("placing hold for member %d failed. Error: %v", holdReq.MemberID, err)
+		customError := &model.CustomError{
+			Error: err.Error(),
+			Code:  http.StatusInternalServerError,
+		}
+		c.JSON(http.StatusInternalServerError, customError)
+		return
+	}
+	c.JSON(http.StatusCreated, hold)
+}
+
+// GetMemberHolds godoc
+//
+//	@Summary 		GetMemberHolds fetches the holds of a member
+//	@Description 	GetMemberHolds retrieves all the holds placed by a member in the order placed
+//	@Param			id	path	int	true	"Member id"
+//	@Produce 		json
+//	@Success 		200	{object}	[]model.Hold
+//	@Failure 		400	{object}	model.CustomError
+//	@Failure 		401	{object}	model.CustomError
+//	@Failure 		403	{object}	model.CustomError
+//	@Failure 		404	{object}	model.CustomError
+//	@Failure 		500	{object}	model.CustomError
+//	@Security 		BearerAuth
+//	@Security 		APIKeyAuth
+//	@Router 		/member/{id}/holds	[get]
+//
+// GetMemberHolds retrieves the holds of a member
+func (h *Handler) GetMemberHolds(c *gin.Context) {
+	id := c.Param("id")
+	idInt, err := strconv.Atoi(id)
+	if err != nil {
+		logger.FromContext(c).Errorf("invalid id %s to fetch holds", id)
+		customError := &model.CustomError{
+			Error: "id is mandatory",
+			Code:  http.StatusBadRequest,
+		}
+		c.JSON(http.StatusBadRequest, customError)
+		return
+	}
+	if !allowMember(c, idInt) {
+		return
+	}
+	holds, err := h.repo.GetMemberHolds(c, idInt)
+	if err != nil {
+		// if notfound needs to return the specific error code and details
+		if errors.Is(err, model.ErrNotFound) {
+			customError := &model.CustomError{
+				Error: err.Error(),
+				Code:  http.StatusNotFound,
+			}
+			c.JSON(http.StatusNotFound, customError)
+			return
+		}
+		logger.FromContext(c).Errorf("fetching holds of member %d failed. Error: %v", idInt, err)
+		// rest of all errors falls under this category
+		customError := &model.CustomError{
+			Error: err.Error(),
+			Code:  http.StatusInternalServerError,
+		}
+		c.JSON(http.StatusInternalServerError, customError)
+		return
+	}
+	c.JSON(http.StatusOK, holds)
+}
+
+// CancelHold godoc
+//
+//	@Summary 		CancelHold cancels a hold
+//	@Description 	CancelHold cancels a waiting or ready hold, the copy set aside for a ready hold passes on to the next waiting hold
+//	@Param			id	path	int	true	"Hold id"
+//	@Produce 		json
+//	@Success 		200	{object}	model.Hold
+//	@Failure 		400	{object}	model.CustomError
+//	@Failure 		401	{object}	model.CustomError
+//	@Failure 		403	{object}	model.CustomError
+//	@Failure 		404	{object}	model.CustomError
+//	@Failure 		409	{object}	model.CustomError
+//	@Failure 		500	{object}	model.CustomError
+//	@Security 		BearerAuth
+//	@Security 		APIKeyAuth
+//	@Router 		/hold/{id}	[delete]
+//
+// CancelHold cancels a hold
+func (h *Handler) CancelHold(c *gin.Context) {
+	id := c.Param("id")
+	idInt, err := strconv.Atoi(id)
+	if err != nil {
+		logger.FromContext(c).Errorf("invalid id %s to cancel hold", id)
+		customError := &model.CustomError{
+			Error: "id is mandatory",
+			Code:  http.StatusBadRequest,
+		}
+		c.JSON(http.StatusBadRequest, customError)
+		return
+	}
+	if !h.allowHold(c, idInt) {
+		return
+	}
+	hold, err := h.repo.CancelHold(c, idInt)
+	if err != nil {
+		// if notfound needs to return the specific error code and details
+		if errors.Is(err, model.ErrNotFound) {
+			customError := &model.CustomError{
+				Error: err.Error(),
+				Code:  http.StatusNotFound,
+			}
+			c.JSON(http.StatusNotFound, customError)
+			return
+		}
+		// hold already fulfilled, cancelled or expired
+		if errors.Is(err, model.ErrConflict) {
+			customError := &model.CustomError{
+				Error: err.Error(),
+				Code:  http.StatusConflict,
+			}
+			c.JSON(http.StatusConflict, customError)
+			return
+		}
+		// rest of all errors falls under this category
+		logger.FromContext(c).Errorf("cancelling hold %d failed. Error: %v", idInt, err)
+		customError := &model.CustomError{
+			Error: err.Error(),
+			Code:  http.StatusInternalServerError,
+		}
+		c.JSON(http.StatusInternalServerError, customError)
+		return
+	}
+	c.JSON(http.StatusOK, hold)
+}
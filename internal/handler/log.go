@@ -0,0 +1,67 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/test/library-app/internal/logger"
+	"github.com/test/library-app/internal/model"
+)
+
+// GetLogLevel godoc
+//
+//	@Summary 		GetLogLevel fetches the log level
+//	@Description 	GetLogLevel retrieves the level the app logs at
+//	@Produce 		json
+//	@Success 		200	{object}	model.LogLevel
+//	@Failure 		401	{object}	model.CustomError
+//	@Failure 		403	{object}	model.CustomError
+//	@Security 		BearerAuth
+//	@Router 		/log/level	[get]
+//
+// GetLogLevel retrieves the level the app logs at
+func (h *Handler) GetLogLevel(c *gin.Context) {
+	c.JSON(http.StatusOK, &model.LogLevel{Level: logger.Level().String()})
+}
+
+// SetLogLevel godoc
+//
+//	@Summary 		SetLogLevel changes the log level
+//	@Description 	SetLogLevel changes the level the app logs at till it's changed again or the app restarts, the level is named or numbered from -1 for debug
+//	@Param			logLevel	body	model.LogLevel	true	"Log Level"
+//	@Consume 		json	model.LogLevel
+//	@Produce 		json
+//	@Success 		200	{object}	model.LogLevel
+//	@Failure 		400	{object}	model.CustomError
+//	@Failure 		401	{object}	model.CustomError
+//	@Failure 		403	{object}	model.CustomError
+//	@Security 		BearerAuth
+//	@Router 		/log/level	[put]
+//
+// SetLogLevel changes the level the app logs at
+func (h *Handler) SetLogLevel(c *gin.Context) {
+	var req model.LogLevel
+	if err := c.ShouldBindJSON(&req); err != nil {
+		logger.FromContext(c).Errorf("Failed to unamrshal the request body: %v", err)
+		customError := &model.CustomError{
+			Error: "invalid request body",
+			Code:  http.StatusBadRequest,
+		}
+		c.JSON(http.StatusBadRequest, customError)
+		return
+	}
+	lvl, err := logger.ParseLevel(req.Level)
+	if err != nil {
+		logger.FromContext(c).Errorf("invalid request to change the log level: %v", err)
+		customError := &model.CustomError{
+			Error: err.Error(),
+			Code:  http.StatusBadRequest,
+		}
+		c.JSON(http.StatusBadRequest, customError)
+		return
+	}
+	previous := logger.Level()
+	logger.SetLevel(lvl)
+	logger.FromContext(c).Warnf("log level changed from %s to %s", previous, lvl)
+	c.JSON(http.StatusOK, &model.LogLevel{Level: lvl.String()})
+}
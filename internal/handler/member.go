@@ -0,0 +1,372 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"net/mail"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/test/library-app/internal/constants"
+	"github.com/test/library-app/internal/logger"
+	"github.com/test/library-app/internal/model"
+)
+
+// validateMemberRequest validates a request to register (adding) or update a member, returns the reason when invalid
+func validateMemberRequest(req *model.MemberRequest, adding bool) string {
+	req.Name = strings.TrimSpace(req.Name)
+	if adding && req.Name == "" {
+		return "Name missed in the request"
+	}
+	if req.Email != "" {
+		addr, err := mail.ParseAddress(req.Email)
+		if err != nil || addr.Address != req.Email {
+			return "Email is invalid"
+		}
+	}
+	switch req.Tier {
+	case "", constants.TierStandard, constants.TierStudent, constants.TierPremium:
+	default:
+		return "Tier must be one of standard, student or premium"
+	}
+	switch req.Status {
+	case "", constants.MemberActive, constants.MemberSuspended, constants.MemberExpired:
+	default:
+		return "Status must be one of active, suspended or expired"
+	}
+	return ""
+}
+
+// memberFromRequest builds the member of a request
+func memberFromRequest(req *model.MemberRequest) *model.Member {
+	return &model.Member{
+		Name:       req.Name,
+		Email:      req.Email,
+		CardNumber: req.CardNumber,
+		Tier:       req.Tier,
+		Status:     req.Status,
+	}
+}
+
+// AddMember godoc
+//
+//	@Summary 		AddMember registers a member
+//	@Description 	AddMember registers a member allowed to borrow books, the card number is generated when missed
+//	@Param			memberRequest	body	model.MemberRequest	true	"Member Request"
+//	@Consume 		json	model.MemberRequest
+//	@Produce 		json
+//	@Success 		201	{object}	model.Member
+//	@Failure 		400	{object}	model.CustomError
+//	@Failure 		401	{object}	model.CustomError
+//	@Failure 		403	{object}	model.CustomError
+//	@Failure 		409	{object}	model.CustomError
+//	@Failure 		500	{object}	model.CustomError
+//	@Security 		BearerAuth
+//	@Security 		APIKeyAuth
+//	@Router 		/member	[post]
+//
+// AddMember registers a member
+func (h *Handler) AddMember(c *gin.Context) {
+	var memberReq model.MemberRequest
+	if err := c.ShouldBindJSON(&memberReq); err != nil {
+		logger.FromContext(c).Errorf("Failed to unamrshal the request body: %v", err)
+		customError := &model.CustomError{
+			Error: "invalid request body",
+			Code:  http.StatusBadRequest,
+		}
+		c.JSON(http.StatusBadRequest, customError)
+		return
+	}
+	if msg := validateMemberRequest(&memberReq, true); msg != "" {
+		logger.FromContext(c).Errorf("invalid request to add a member: %s", msg)
+		customError := &model.CustomError{
+			Error: msg,
+			Code:  http.StatusBadRequest,
+		}
+		c.JSON(http.StatusBadRequest, customError)
+		return
+	}
+	member := memberFromRequest(&memberReq)
+	_, err := h.repo.AddMember(c, member)
+	if err != nil {
+		// if the email or card number is taken by another member
+		if errors.Is(err, model.ErrAlreadyExists) {
+			customError := &model.CustomError{
+				Error: err.Error(),
+				Code:  http.StatusConflict,
+			}
+			c.JSON(http.StatusConflict, customError)
+			return
+		}
+		// rest of all errors falls under this category
+		logger.FromContext(c).Errorf("adding member %s failed. Error: %v", memberReq.Name, err)
+		customError := &model.CustomError{
+			Error: err.Error(),
+			Code:  http.StatusInternalServerError,
+		}
+		c.JSON(http.StatusInternalServerError, customError)
+		return
+	}
+	c.JSON(http.StatusCreated, member)
+}
+
+// GetMember godoc
+//
+//	@Summary 		GetMember fetches a member
+//	@Description 	GetMember retrieves a member by its id
+//	@Param			id	path	int	true	"Member id"
+//	@Produce 		json
+//	@Success 		200	{object}	model.Member
+//	@Failure 		400	{object}	model.CustomError
+//	@Failure 		401	{object}	model.CustomError
+//	@Failure 		403	{object}	model.CustomError
+//	@Failure 		404	{object}	model.CustomError
+//	@Failure 		500	{object}	model.CustomError
+//	@Security 		BearerAuth
+//	@Security 		APIKeyAuth
+//	@Router 		/member/{id}	[get]
+//
+// GetMember retrieves a member by its id
+func (h *Handler) GetMember(c *gin.Context) {
+	id := c.Param("id")
+	idInt, err := strconv.Atoi(id)
+	if err != nil {
+		logger.FromContext(c).Errorf("invalid id %s to fetch member", id)
+		customError := &model.CustomError{
+			Error: "id is mandatory",
+			Code:  http.StatusBadRequest,
+		}
+		c.JSON(http.StatusBadRequest, customError)
+		return
+	}
+	if !allowMember(c, idInt) {
+		return
+	}
+	member, err := h.repo.GetMember(c, idInt)
+	if err != nil {
+		// if notfound needs to return the specific error code and details
+		if errors.Is(err, model.ErrNotFound) {
+			customError := &model.CustomError{
+				Error: err.Error(),
+				Code:  http.StatusNotFound,
+			}
+			c.JSON(http.StatusNotFound, customError)
+			return
+		}
+		logger.FromContext(c).Errorf("fetching member %d failed. Error: %v", idInt, err)
+		// rest of all errors falls under this category
+		customError := &model.CustomError{
+			Error: err.Error(),
+			Code:  http.StatusInternalServerError,
+		}
+		c.JSON(http.StatusInternalServerError, customError)
+		return
+	}
+	c.JSON(http.StatusOK, member)
+}
+
+// GetAllMembers godoc
+//
+//	@Summary 		GetAllMembers fetches the members
+//	@Description 	GetAllMembers retrieves a page of the members passing the filters, next_cursor continues the listing and is missed on the last page
+//	@Param			limit	query	int		false	"No of members in the page (default 50, max 100)"
+//	@Param			cursor	query	string	false	"next_cursor of the previous page"
+//	@Param			sort	query	string	false	"Sort key"	Enums(id, name)
+//	@Param			order	query	string	false	"Sort order"	Enums(asc, desc)
+//	@Param			name	query	string	false	"Part of the name"
+//	@Param			tier	query	string	false	"Membership tier"	Enums(standard, student, premium)
+//	@Param			status	query	string	false	"Member status"	Enums(active, suspended, expired)
+//	@Produce 		json
+//	@Success 		200	{object}	model.MemberPage
+//	@Failure 		400	{object}	model.CustomError
+//	@Failure 		401	{object}	model.CustomError
+//	@Failure 		403	{object}	model.CustomError
+//	@Failure 		500	{object}	model.CustomError
+//	@Security 		BearerAuth
+//	@Security 		APIKeyAuth
+//	@Router 		/member	[get]
+//
+// GetAllMembers retrieves a page of the members
+func (h *Handler) GetAllMembers(c *gin.Context) {
+	var query model.MemberQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		logger.FromContext(c).Errorf("invalid query to list members. Error: %v", err)
+		customError := &model.CustomError{
+			Error: err.Error(),
+			Code:  http.StatusBadRequest,
+		}
+		c.JSON(http.StatusBadRequest, customError)
+		return
+	}
+	msg := validatePageQuery(&query.PageQuery, constants.SortByID, constants.SortByName)
+	if msg == "" {
+		// the filters are validated like a request
+		msg = validateMemberRequest(&model.MemberRequest{Tier: query.Tier, Status: query.Status}, false)
+	}
+	if msg != "" {
+		logger.FromContext(c).Errorf("invalid query to list members: %s", msg)
+		customError := &model.CustomError{
+			Error: msg,
+			Code:  http.StatusBadRequest,
+		}
+		c.JSON(http.StatusBadRequest, customError)
+		return
+	}
+	page, err := h.repo.GetAllMembers(c, &query)
+	if err != nil {
+		// rest of all errors falls under this category
+		customError := &model.CustomError{
+			Error: err.Error(),
+			Code:  http.StatusInternalServerError,
+		}
+		c.JSON(http.StatusInternalServerError, customError)
+		return
+	}
+	c.JSON(http.StatusOK, page)
+}
+
+// UpdateMember godoc
+//
+//	@Summary 		UpdateMember updates a member
+//	@Description 	UpdateMember updates the details of a member, empty fields are left unchanged. Members other than active can't borrow
+//	@Param			id				path	int					true	"Member id"
+//	@Param			memberRequest	body	model.MemberRequest	true	"Member Request"
+//	@Consume 		json	model.MemberRequest
+//	@Produce 		json
+//	@Success 		200	{object}	model.Member
+//	@Failure 		400	{object}	model.CustomError
+//	@Failure 		401	{object}	model.CustomError
+//	@Failure 		403	{object}	model.CustomError
+//	@Failure 		404	{object}	model.CustomError
+//	@Failure 		409	{object}	model.CustomError
+//	@Failure 		500	{object}	model.CustomError
+//	@Security 		BearerAuth
+//	@Security 		APIKeyAuth
+//	@Router 		/member/{id}	[put]
+//
+// UpdateMember updates a member
+func (h *Handler) UpdateMember(c *gin.Context) {
+	id := c.Param("id")
+	idInt, err := strconv.Atoi(id)
+	if err != nil {
+		logger.FromContext(c).Errorf("invalid id %s to update member", id)
+		customError := &model.CustomError{
+			Error: "id is mandatory",
+			Code:  http.StatusBadRequest,
+		}
+		c.JSON(http.StatusBadRequest, customError)
+		return
+	}
+	var memberReq model.MemberRequest
+	if err := c.ShouldBindJSON(&memberReq); err != nil {
+		logger.FromContext(c).Errorf("Failed to unamrshal the request body: %v", err)
+		customError := &model.CustomError{
+			Error: "invalid request body",
+			Code:  http.StatusBadRequest,
+		}
+		c.JSON(http.StatusBadRequest, customError)
+		return
+	}
+	if msg := validateMemberRequest(&memberReq, false); msg != "" {
+		logger.FromContext(c).Errorf("invalid request to update member %d: %s", idInt, msg)
+		customError := &model.CustomError{
+			Error: msg,
+			Code:  http.StatusBadRequest,
+		}
+		c.JSON(http.StatusBadRequest, customError)
+		return
+	}
+	member, err := h.repo.UpdateMember(c, idInt, memberFromRequest(&memberReq))
+	if err != nil {
+		// if notfound needs to return the specific error code and details
+		if errors.Is(err, model.ErrNotFound) {
+			customError := &model.CustomError{
+				Error: err.Error(),
+				Code:  http.StatusNotFound,
+			}
+			c.JSON(http.StatusNotFound, customError)
+			return
+		}
+		// if the email or card number is taken by another member
+		if errors.Is(err, model.ErrAlreadyExists) {
+			customError := &model.CustomError{
+				Error: err.Error(),
+				Code:  http.StatusConflict,
+			}
+			c.JSON(http.StatusConflict, customError)
+			return
+		}
+		// rest of all errors falls under this category
+		logger.FromContext(c).Errorf("updating member %d failed. Error: %v", idInt, err)
+		customError := &model.CustomError{
+			Error: err.Error(),
+			Code:  http.StatusInternalServerError,
+		}
+		c.JSON(http.StatusInternalServerError, customError)
+		return
+	}
+	c.JSON(http.StatusOK, member)
+}
+
+// DeleteMember godoc
+//
+//	@Summary 		DeleteMember removes a member
+//	@Description 	DeleteMember removes a member, refused while the member has active loans. Past loans keep the name of the member
+//	@Param			id	path	int	true	"Member id"
+//	@Produce 		json
+//	@Success 		200
+//	@Failure 		400	{object}	model.CustomError
+//	@Failure 		401	{object}	model.CustomError
+//	@Failure 		403	{object}	model.CustomError
+//	@Failure 		404	{object}	model.CustomError
+//	@Failure 		409	{object}	model.CustomError
+//	@Failure 		500	{object}	model.CustomError
+//	@Security 		BearerAuth
+//	@Security 		APIKeyAuth
+//	@Router 		/member/{id}	[delete]
+//
+// DeleteMember removes a member
+func (h *Handler) DeleteMember(c *gin.Context) {
+	id := c.Param("id")
+	idInt, err := strconv.Atoi(id)
+	if err != nil {
+		logger.FromContext(c).Errorf("invalid id %s to delete member", id)
+		customError := &model.CustomError{
+			Error: "id is mandatory",
+			Code:  http.StatusBadRequest,
+		}
+		c.JSON(http.StatusBadRequest, customError)
+		return
+	}
+	err = h.repo.DeleteMember(c, idInt)
+	if err != nil {
+		// if notfound needs to return the specific error code and details
+		if errors.Is(err, model.ErrNotFound) {
+			customError := &model.CustomError{
+				Error: err.Error(),
+				Code:  http.StatusNotFound,
+			}
+			c.JSON(http.StatusNotFound, customError)
+			return
+		}
+		// member still has active loans
+		if errors.Is(err, model.ErrConflict) {
+			customError := &model.CustomError{
+				Error: err.Error(),
+				Code:  http.StatusConflict,
+			}
+			c.JSON(http.StatusConflict, customError)
+			return
+		}
+		// rest of all errors falls under this category
+		logger.FromContext(c).Errorf("deleting member %d failed. Error: %v", idInt, err)
+		customError := &model.CustomError{
+			Error: err.Error(),
+			Code:  http.StatusInternalServerError,
+		}
+		c.JSON(http.StatusInternalServerError, customError)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "member deleted"})
+}
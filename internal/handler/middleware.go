@@ -0,0 +1,284 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/test/library-app/internal/audit"
+	"github.com/test/library-app/internal/auth"
+	"github.com/test/library-app/internal/config"
+	"github.com/test/library-app/internal/constants"
+	"github.com/test/library-app/internal/logger"
+	"github.com/test/library-app/internal/metrics"
+	"github.com/test/library-app/internal/model"
+	"github.com/test/library-app/internal/tracing"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+)
+
+// RequestID tags the request with the ID of its X-Request-ID header, generated when missed or malformed, and returns it in the response.
+// The entries logged through the request context carry the request ID and the route, the loan and the member once known
+func (h *Handler) RequestID(c *gin.Context) {
+	id := c.GetHeader(constants.RequestIDHeader)
+	if !validRequestID(id) {
+		id = newRequestID()
+	}
+	c.Header(constants.RequestIDHeader, id)
+	route := c.FullPath()
+	if route == "" {
+		route = "unmatched"
+	}
+	logWith(c, zap.String("request_id", id), zap.String("route", route))
+	c.Next()
+}
+
+// validRequestID reports whether the ID of the caller is fit for the logs, printable with no spaces and not too long
+func validRequestID(id string) bool {
+	if id == "" || len(id) > constants.MaxRequestIDLength {
+		return false
+	}
+	for _, r := range id {
+		if r <= ' ' || r > '~' {
+			return false
+		}
+	}
+	return true
+}
+
+// newRequestID generates a random request ID
+func newRequestID() string {
+	id := make([]byte, 16)
+	rand.Read(id)
+	return hex.EncodeToString(id)
+}
+
+// logWith carries the fields into the request context for the entries logged through it
+func logWith(c *gin.Context, fields ...zap.Field) {
+	c.Request = c.Request.WithContext(logger.With(c.Request.Context(), fields...))
+}
+
+// Actor carries the actor named by the request into its context for the loan history,
+// the router needs ContextWithFallback for the stores to see it. The subject of the token names the actor of authenticated requests
+func (h *Handler) Actor(c *gin.Context) {
+	actor := strings.TrimSpace(c.GetHeader(constants.ActorHeader))
+	if principal := auth.PrincipalFrom(c.Request.Context()); principal != nil {
+		actor = principal.Subject
+	}
+	if actor == "" {
+		actor = constants.ActorAnonymous
+	}
+	c.Request = c.Request.WithContext(audit.WithActor(c.Request.Context(), actor))
+	c.Next()
+}
+
+// Metrics counts the requests by their route, method and status and observes their latency,
+// requests matching no route are counted under the route unmatched
+func (h *Handler) Metrics(c *gin.Context) {
+	start := time.Now()
+	c.Next()
+	route := c.FullPath()
+	if route == "" {
+		route = "unmatched"
+	}
+	metrics.ObserveRequest(route, c.Request.Method, c.Writer.Status(), time.Since(start))
+}
+
+// Tracing runs the request in a server span named by its route, continuing the trace of the caller propagated by its headers.
+// The store operations and the queries of the request are traced under it, the failed requests are logged with the trace ID
+func (h *Handler) Tracing(c *gin.Context) {
+	route := c.FullPath()
+	if route == "" {
+		route = "unmatched"
+	}
+	ctx := otel.GetTextMapPropagator().Extract(c.Request.Context(), propagation.HeaderCarrier(c.Request.Header))
+	ctx, span := tracing.Tracer().Start(ctx, c.Request.Method+" "+route,
+		trace.WithSpanKind(trace.SpanKindServer),
+		trace.WithAttributes(
+			semconv.HTTPRequestMethodKey.String(c.Request.Method),
+			semconv.HTTPRoute(route),
+			semconv.URLPath(c.Request.URL.Path),
+		))
+	defer span.End()
+	c.Request = c.Request.WithContext(ctx)
+	c.Next()
+	status := c.Writer.Status()
+	span.SetAttributes(semconv.HTTPResponseStatusCode(status))
+	if status >= http.StatusInternalServerError {
+		span.SetStatus(codes.Error, http.StatusText(status))
+		logger.FromContext(ctx).Errorf("%s %s failed with %d", c.Request.Method, c.Request.URL.Path, status)
+	}
+}
+
+// replayedHeaders are the headers of a response stored along with its body for the retries
+var replayedHeaders = []string{"Content-Type", constants.ETagHeader}
+
+// responseRecorder keeps a copy of the response body written through it
+type responseRecorder struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *responseRecorder) Write(data []byte) (int, error) {
+	w.body.Write(data)
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *responseRecorder) WriteString(s string) (int, error) {
+	w.body.WriteString(s)
+	return w.ResponseWriter.WriteString(s)
+}
+
+// scopedIdempotencyKey scopes an Idempotency-Key to the caller, as each caller picks its keys on its own the same key
+// of two callers names two requests. The subject is prefixed with its length so no other subject and key read the same
+func scopedIdempotencyKey(principal *auth.Principal, key string) string {
+	subject := ""
+	if principal != nil {
+		subject = principal.Subject
+	}
+	return strconv.Itoa(len(subject)) + ":" + subject + ":" + key
+}
+
+// Idempotency replays the response of a mutating request made with an Idempotency-Key to its retries.
+// The key reused with another request is refused with 422, and with 409 while the request is in progress
+func (h *Handler) Idempotency(c *gin.Context) {
+	key := c.GetHeader(constants.IdempotencyKeyHeader)
+	method := c.Request.Method
+	if key == "" || method == http.MethodGet || method == http.MethodHead || method == http.MethodOptions {
+		c.Next()
+		return
+	}
+	if len(key) > constants.MaxIdempotencyKeyLength {
+		logger.FromContext(c).Errorf("idempotency key of %d characters is too long", len(key))
+		customError := &model.CustomError{
+			Error: "Idempotency-Key must be at most 255 characters",
+			Code:  http.StatusBadRequest,
+		}
+		c.AbortWithStatusJSON(http.StatusBadRequest, customError)
+		return
+	}
+	// the body is read whole before the handler runs, a large one is refused rather than held in memory
+	body, err := io.ReadAll(http.MaxBytesReader(c.Writer, c.Request.Body, constants.MaxIdempotentBodyBytes))
+	if maxBytesErr := (*http.MaxBytesError)(nil); errors.As(err, &maxBytesErr) {
+		logger.FromContext(c).Errorf("request body of idempotency key %s exceeds %d bytes", key, maxBytesErr.Limit)
+		customError := &model.CustomError{
+			Error: fmt.Sprintf("request body must be at most %d bytes", constants.MaxIdempotentBodyBytes),
+			Code:  http.StatusRequestEntityTooLarge,
+		}
+		c.AbortWithStatusJSON(http.StatusRequestEntityTooLarge, customError)
+		return
+	}
+	if err != nil {
+		logger.FromContext(c).Errorf("Failed to read the request body: %v", err)
+		customError := &model.CustomError{
+			Error: "invalid request body",
+			Code:  http.StatusBadRequest,
+		}
+		c.AbortWithStatusJSON(http.StatusBadRequest, customError)
+		return
+	}
+	// the handlers read the body again
+	c.Request.Body = io.NopCloser(bytes.NewReader(body))
+	fingerprint := sha256.New()
+	fingerprint.Write([]byte(method + " " + c.Request.URL.Path + "\n"))
+	fingerprint.Write(body)
+	// a key reused by another caller is stored apart, it neither replays nor refuses the requests of the first one
+	scopedKey := scopedIdempotencyKey(auth.PrincipalFrom(c.Request.Context()), key)
+
+	now := time.Now()
+	ttl := time.Duration(config.CommonConfig.IdempotencyKeyTTLInSec) * time.Second
+	// the reservation lapses once the request timed out, a request dying with its instance doesn't hold up the retries
+	lease := time.Duration(config.CommonConfig.WriteTimeoutInSec) * time.Second
+	if lease <= 0 || lease > ttl {
+		lease = ttl
+	}
+	rec := &model.IdempotencyRecord{
+		Key:         scopedKey,
+		Fingerprint: hex.EncodeToString(fingerprint.Sum(nil)),
+		ExpiresAt:   now.Add(lease).Unix(),
+	}
+	stored, err := h.repo.ReserveIdempotencyKey(c, rec, now)
+	if err != nil {
+		// released by a failed request in between, the client retries
+		if errors.Is(err, model.ErrConflict) {
+			customError := &model.CustomError{
+				Error: err.Error(),
+				Code:  http.StatusConflict,
+			}
+			c.AbortWithStatusJSON(http.StatusConflict, customError)
+			return
+		}
+		logger.FromContext(c).Errorf("reserving idempotency key %s failed. Error: %v", key, err)
+		customError := &model.CustomError{
+			Error: err.Error(),
+			Code:  http.StatusInternalServerError,
+		}
+		c.AbortWithStatusJSON(http.StatusInternalServerError, customError)
+		return
+	}
+	if stored != nil {
+		if stored.Fingerprint != rec.Fingerprint {
+			logger.FromContext(c).Errorf("idempotency key %s reused with another request", key)
+			customError := &model.CustomError{
+				Error: "Idempotency-Key is used by another request",
+				Code:  http.StatusUnprocessableEntity,
+			}
+			c.AbortWithStatusJSON(http.StatusUnprocessableEntity, customError)
+			return
+		}
+		if stored.StatusCode == 0 {
+			customError := &model.CustomError{
+				Error: "request with the Idempotency-Key is in progress",
+				Code:  http.StatusConflict,
+			}
+			c.AbortWithStatusJSON(http.StatusConflict, customError)
+			return
+		}
+		for name, value := range stored.Header {
+			c.Header(name, value)
+		}
+		c.Header(constants.IdempotentReplayedHeader, "true")
+		c.Data(stored.StatusCode, stored.Header["Content-Type"], stored.Body)
+		c.Abort()
+		return
+	}
+
+	recorder := &responseRecorder{ResponseWriter: c.Writer}
+	c.Writer = recorder
+	c.Next()
+	// storing the response even when the client went away, that's when it retries
+	ctx := context.WithoutCancel(c.Request.Context())
+	if c.Writer.Status() >= http.StatusInternalServerError {
+		// retries of a failed request are let through
+		if err := h.repo.ReleaseIdempotencyKey(ctx, scopedKey); err != nil {
+			logger.FromContext(c).Errorf("releasing idempotency key %s failed. Error: %v", key, err)
+		}
+		return
+	}
+	rec.StatusCode = c.Writer.Status()
+	rec.Header = make(map[string]string)
+	for _, name := range replayedHeaders {
+		if value := c.Writer.Header().Get(name); value != "" {
+			rec.Header[name] = value
+		}
+	}
+	rec.Body = recorder.body.Bytes()
+	rec.ExpiresAt = time.Now().Add(ttl).Unix()
+	if err := h.repo.CompleteIdempotencyKey(ctx, rec); err != nil {
+		logger.FromContext(c).Errorf("storing the response of idempotency key %s failed. Error: %v", key, err)
+	}
+}
@@ -0,0 +1,78 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/test/library-app/internal/auth"
+	"github.com/test/library-app/internal/config"
+	"github.com/test/library-app/internal/constants"
+	"github.com/test/library-app/internal/logger"
+	"github.com/test/library-app/internal/model"
+)
+
+// quotaWindow counts the requests of a key in the minute started at start
+type quotaWindow struct {
+	start time.Time
+	count int
+}
+
+// quotas counts the requests of the api keys in fixed windows of a minute, each instance of the app counts its own
+type quotas struct {
+	mu      sync.Mutex
+	windows map[int]*quotaWindow // stores the current window key as api key ID
+}
+
+func newQuotas() *quotas {
+	return &quotas{windows: make(map[int]*quotaWindow)}
+}
+
+// take counts a request of the key against its limit a minute, returns the No of requests left in the window
+// and the time till the next one. ok is false once the limit is reached
+func (q *quotas) take(keyID, limit int, now time.Time) (left int, reset time.Duration, ok bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	window, found := q.windows[keyID]
+	if !found || now.Sub(window.start) >= time.Minute {
+		window = &quotaWindow{start: now.Truncate(time.Minute)}
+		q.windows[keyID] = window
+	}
+	reset = window.start.Add(time.Minute).Sub(now)
+	if window.count >= limit {
+		return 0, reset, false
+	}
+	window.count++
+	return limit - window.count, reset, true
+}
+
+// Quota holds the requests made with an api key to the quota of the key a minute, refused with 429 once used up.
+// Requests made with tokens aren't counted
+func (h *Handler) Quota(c *gin.Context) {
+	principal := auth.PrincipalFrom(c.Request.Context())
+	if principal == nil || principal.APIKey == nil {
+		c.Next()
+		return
+	}
+	limit := principal.APIKey.RequestsPerMinute
+	if limit <= 0 {
+		limit = config.CommonConfig.APIKeyQuotaPerMinute
+	}
+	left, reset, ok := h.quotas.take(principal.APIKey.ID, limit, time.Now())
+	c.Header(constants.RateLimitHeader, strconv.Itoa(limit))
+	c.Header(constants.RateLimitLeft, strconv.Itoa(left))
+	if !ok {
+		logger.FromContext(c).Errorf("api key %d used up its quota of %d requests a minute", principal.APIKey.ID, limit)
+		// rounding up so the retry lands in the next window
+		c.Header(constants.RetryAfterHeader, strconv.Itoa(int((reset+time.Second-1)/time.Second)))
+		customError := &model.CustomError{
+			Error: "quota of the api key is used up",
+			Code:  http.StatusTooManyRequests,
+		}
+		c.AbortWithStatusJSON(http.StatusTooManyRequests, customError)
+		return
+	}
+	c.Next()
+}
@@ -0,0 +1,103 @@
+package health
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/test/library-app/internal/constants"
+	"github.com/test/library-app/internal/model"
+)
+
+// Check verifies a dependency of the app, failing with the reason it's unhealthy
+type Check func(ctx context.Context) error
+
+// namedCheck is a check registered under its name
+type namedCheck struct {
+	name  string
+	check Check
+}
+
+// Registry runs the checks registered by the app to report whether it's ready to serve
+type Registry struct {
+	timeout      time.Duration
+	mu           sync.RWMutex
+	checks       []namedCheck
+	shuttingDown atomic.Bool
+}
+
+// NewRegistry creates a registry with no checks, each check is failed once it takes longer than the timeout
+func NewRegistry(timeout time.Duration) *Registry {
+	return &Registry{timeout: timeout}
+}
+
+// Register registers the check under the name, the checks are reported in the order registered
+func (r *Registry) Register(name string, check Check) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.checks = append(r.checks, namedCheck{name: name, check: check})
+}
+
+// Shutdown reports the app not ready from now on, the load balancers stop routing to it while the requests in flight finish
+func (r *Registry) Shutdown() {
+	r.shuttingDown.Store(true)
+}
+
+// Run runs the checks concurrently and reports each of them, the report is ok once every check passed
+func (r *Registry) Run(ctx context.Context) *model.HealthReport {
+	r.mu.RLock()
+	checks := append([]namedCheck(nil), r.checks...)
+	r.mu.RUnlock()
+
+	report := &model.HealthReport{
+		Status: constants.HealthOK,
+		Checks: make([]*model.HealthCheck, len(checks)),
+	}
+	var wg sync.WaitGroup
+	for i, c := range checks {
+		wg.Add(1)
+		go func(i int, c namedCheck) {
+			defer wg.Done()
+			report.Checks[i] = r.run(ctx, c)
+		}(i, c)
+	}
+	wg.Wait()
+	for _, check := range report.Checks {
+		if check.Status != constants.HealthOK {
+			report.Status = constants.HealthUnavailable
+		}
+	}
+	// the checks still run while shutting down, the report tells what's left of the dependencies
+	if r.shuttingDown.Load() {
+		report.Status = constants.HealthShuttingDown
+	}
+	return report
+}
+
+// run runs a check within the timeout, a check not returning in time is failed and left to finish by itself
+func (r *Registry) run(ctx context.Context, c namedCheck) *model.HealthCheck {
+	ctx, cancel := context.WithTimeout(ctx, r.timeout)
+	defer cancel()
+	start := time.Now()
+	done := make(chan error, 1)
+	go func() {
+		done <- c.check(ctx)
+	}()
+	var err error
+	select {
+	case err = <-done:
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+	result := &model.HealthCheck{
+		Name:       c.name,
+		Status:     constants.HealthOK,
+		DurationMs: time.Since(start).Milliseconds(),
+	}
+	if err != nil {
+		result.Status = constants.HealthFailed
+		result.Error = err.Error()
+	}
+	return result
+}
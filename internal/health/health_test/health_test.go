@@ -0,0 +1,69 @@
+package healthtest
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/test/library-app/internal/constants"
+	"github.com/test/library-app/internal/health"
+)
+
+func TestRun(t *testing.T) {
+	checks := health.NewRegistry(50 * time.Millisecond)
+
+	// success case: no checks registered
+	report := checks.Run(context.Background())
+	assert.Equal(t, constants.HealthOK, report.Status)
+	assert.Empty(t, report.Checks)
+
+	// success case: every check passed
+	checks.Register("store", func(ctx context.Context) error { return nil })
+	report = checks.Run(context.Background())
+	assert.Equal(t, constants.HealthOK, report.Status)
+	assert.Len(t, report.Checks, 1)
+	assert.Equal(t, "store", report.Checks[0].Name)
+	assert.Equal(t, constants.HealthOK, report.Checks[0].Status)
+
+	// failure case: a check failed, the others are still reported in the order registered
+	checks.Register("jobs", func(ctx context.Context) error { return errors.New("jobs stalled: accrue-fines") })
+	report = checks.Run(context.Background())
+	assert.Equal(t, constants.HealthUnavailable, report.Status)
+	assert.Len(t, report.Checks, 2)
+	assert.Equal(t, constants.HealthOK, report.Checks[0].Status)
+	assert.Equal(t, "jobs", report.Checks[1].Name)
+	assert.Equal(t, constants.HealthFailed, report.Checks[1].Status)
+	assert.Equal(t, "jobs stalled: accrue-fines", report.Checks[1].Error)
+}
+
+func TestRunTimeout(t *testing.T) {
+	checks := health.NewRegistry(20 * time.Millisecond)
+	release := make(chan struct{})
+	defer close(release)
+	// a check ignoring its context
+	checks.Register("postgres", func(ctx context.Context) error {
+		<-release
+		return nil
+	})
+
+	// failure case: the check is failed once it took longer than the timeout
+	start := time.Now()
+	report := checks.Run(context.Background())
+	assert.Less(t, time.Since(start), time.Second)
+	assert.Equal(t, constants.HealthUnavailable, report.Status)
+	assert.Equal(t, constants.HealthFailed, report.Checks[0].Status)
+	assert.Equal(t, context.DeadlineExceeded.Error(), report.Checks[0].Error)
+}
+
+func TestShutdown(t *testing.T) {
+	checks := health.NewRegistry(time.Second)
+	checks.Register("store", func(ctx context.Context) error { return nil })
+	checks.Shutdown()
+
+	// failure case: not ready once shutting down, whatever the checks
+	report := checks.Run(context.Background())
+	assert.Equal(t, constants.HealthShuttingDown, report.Status)
+	assert.Equal(t, constants.HealthOK, report.Checks[0].Status)
+}
@@ -0,0 +1,101 @@
+package jobs
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/test/library-app/internal/logger"
+	"github.com/test/library-app/internal/tracing"
+	"go.opentelemetry.io/otel/codes"
+	"go.uber.org/zap"
+)
+
+// Runner runs the background jobs of the app till stopped
+type Runner struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+	mu     sync.Mutex
+	beats  map[string]*heartbeat // last run of every job by its name
+}
+
+// heartbeat tells when a job running every interval last finished a run, failed or not
+type heartbeat struct {
+	interval time.Duration
+	last     time.Time
+}
+
+// NewRunner creates a runner with no jobs
+func NewRunner() *Runner {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Runner{ctx: ctx, cancel: cancel, beats: make(map[string]*heartbeat)}
+}
+
+// Every runs the job of the name once per interval in a seperate go routine, failures are logged and retried on the next tick
+func (r *Runner) Every(name string, interval time.Duration, job func(ctx context.Context, now time.Time) error) {
+	if interval <= 0 {
+		logger.Warnf("Job %s disabled with interval %v", name, interval)
+		return
+	}
+	// the job is due its first run an interval from now
+	r.mu.Lock()
+	r.beats[name] = &heartbeat{interval: interval, last: time.Now()}
+	r.mu.Unlock()
+	r.wg.Add(1)
+	go func() {
+		defer r.wg.Done()
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		logger.Infof("Job %s runs every %v", name, interval)
+		for {
+			select {
+			case <-r.ctx.Done():
+				return
+			case now := <-ticker.C:
+				r.run(name, now, job)
+			}
+		}
+	}()
+}
+
+// run runs the job once in a span of its own, the store operations it makes are traced under it
+func (r *Runner) run(name string, now time.Time, job func(ctx context.Context, now time.Time) error) {
+	ctx, span := tracing.Tracer().Start(logger.With(r.ctx, zap.String("job", name)), "job "+name)
+	defer span.End()
+	if err := job(ctx, now); err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		logger.FromContext(ctx).Errorf("Job %s failed. Error: %v", name, err)
+	}
+	r.mu.Lock()
+	r.beats[name].last = time.Now()
+	r.mu.Unlock()
+}
+
+// Check fails with the jobs that missed their runs for two intervals, stuck in a run or with their go routine gone
+func (r *Runner) Check(ctx context.Context) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	now := time.Now()
+	stalled := make([]string, 0)
+	for name, beat := range r.beats {
+		if since := now.Sub(beat.last); since > 2*beat.interval {
+			stalled = append(stalled, fmt.Sprintf("%s last ran %v ago", name, since.Round(time.Second)))
+		}
+	}
+	if len(stalled) > 0 {
+		sort.Strings(stalled)
+		return fmt.Errorf("jobs stalled: %s", strings.Join(stalled, ", "))
+	}
+	return nil
+}
+
+// Stop stops the jobs and waits for the running ones to finish
+func (r *Runner) Stop() {
+	r.cancel()
+	r.wg.Wait()
+}
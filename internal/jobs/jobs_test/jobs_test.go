@@ -0,0 +1,34 @@
+package jobstest
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/test/library-app/internal/jobs"
+)
+
+func TestCheck(t *testing.T) {
+	runner := jobs.NewRunner()
+	defer runner.Stop()
+	release := make(chan struct{})
+	runner.Every("ticking", 25*time.Millisecond, func(ctx context.Context, now time.Time) error {
+		return nil
+	})
+	runner.Every("stuck", 25*time.Millisecond, func(ctx context.Context, now time.Time) error {
+		<-release
+		return nil
+	})
+
+	// success case: no job missed a run yet
+	assert.Nil(t, runner.Check(context.Background()))
+
+	// failure case: the job stuck in its run misses its heartbeat, the ticking one doesn't
+	time.Sleep(120 * time.Millisecond)
+	err := runner.Check(context.Background())
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "stuck last ran")
+	assert.NotContains(t, err.Error(), "ticking")
+	close(release)
+}
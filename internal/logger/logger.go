@@ -1,10 +1,15 @@
 package logger
 
 import (
+	"context"
 	"fmt"
+	"slices"
+	"strconv"
 	"time"
 
 	"github.com/test/library-app/internal/config"
+	"github.com/test/library-app/internal/constants"
+	"go.opentelemetry.io/otel/trace"
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
 )
@@ -12,6 +17,9 @@ import (
 // log is a global variable which holds the logger instance created once during the app starts
 var log *zap.Logger
 
+// level is the level of the logger, changed at runtime through SetLevel
+var level = zap.NewAtomicLevel()
+
 func Log() *zap.Logger {
 	if log != nil {
 		return log
@@ -20,19 +28,70 @@ func Log() *zap.Logger {
 	return tmpLog
 }
 
-// InitLogger initializes the logger
+// fieldsKey is the key of the fields a context carries for its logger
+type fieldsKey struct{}
+
+// With carries the fields into the context for the logger of FromContext, a field replaces the one of its key carried already
+func With(ctx context.Context, fields ...zap.Field) context.Context {
+	prev, _ := ctx.Value(fieldsKey{}).([]zap.Field)
+	all := make([]zap.Field, 0, len(prev)+len(fields))
+	for _, field := range prev {
+		if !slices.ContainsFunc(fields, func(f zap.Field) bool { return f.Key == field.Key }) {
+			all = append(all, field)
+		}
+	}
+	return context.WithValue(ctx, fieldsKey{}, append(all, fields...))
+}
+
+// FromContext gives the logger of the request or the job running with the context, its entries carry the fields
+// of the context, the request ID, the route, the loan and the member, and the IDs of the trace and the span
+func FromContext(ctx context.Context) *zap.SugaredLogger {
+	fields, _ := ctx.Value(fieldsKey{}).([]zap.Field)
+	if spanCtx := trace.SpanContextFromContext(ctx); spanCtx.IsValid() {
+		fields = append(fields[:len(fields):len(fields)],
+			zap.String("trace_id", spanCtx.TraceID().String()),
+			zap.String("span_id", spanCtx.SpanID().String()))
+	}
+	return Log().With(fields...).Sugar()
+}
+
+// InitLogger initializes the logger with the level, the mode, the encoding and the sampling of the config
 func InitLogger() error {
-	cfg := zap.NewDevelopmentConfig()
+	lvl, err := ParseLevel(config.LogConfig.Level)
+	if err != nil {
+		Log().With(zap.Error(err)).Warn("Settings not applied to the logger")
+		return err
+	}
+	var cfg zap.Config
+	switch config.LogConfig.Mode {
+	case constants.LogModeDevelopment:
+		cfg = zap.NewDevelopmentConfig()
+	case constants.LogModeProduction:
+		cfg = zap.NewProductionConfig()
+	default:
+		err := fmt.Errorf("unknown log mode: %v", config.LogConfig.Mode)
+		Log().With(zap.Error(err)).Warn("Settings not applied to the logger")
+		return err
+	}
 	cfg.EncoderConfig.StacktraceKey = "stack"
 	cfg.EncoderConfig.EncodeTime = func(t time.Time, pae zapcore.PrimitiveArrayEncoder) {
 		pae.AppendString(t.Format(config.LogConfig.Format))
 	}
-	cfg.Level = zap.NewAtomicLevel()
+	level.SetLevel(lvl)
+	cfg.Level = level
 	cfg.Encoding = config.LogConfig.Encoding
+	// the entries of a message past the first ones of a second are sampled, every one is logged when off
+	cfg.Sampling = nil
+	if config.LogConfig.SamplingInitial > 0 {
+		cfg.Sampling = &zap.SamplingConfig{
+			Initial:    config.LogConfig.SamplingInitial,
+			Thereafter: config.LogConfig.SamplingThereafter,
+		}
+	}
 
 	logger, err := cfg.Build()
 	if err != nil {
-		log.With(zap.Error(err)).Warn("Settings not applied to the logger")
+		Log().With(zap.Error(err)).Warn("Settings not applied to the logger")
 		return err
 	}
 	if log != nil {
@@ -42,6 +101,28 @@ func InitLogger() error {
 	return nil
 }
 
+// ParseLevel parses a level by its name, debug, info, warn, error and the like, or by its number, -1 for debug up to 5 for fatal
+func ParseLevel(text string) (zapcore.Level, error) {
+	if n, err := strconv.Atoi(text); err == nil {
+		lvl := zapcore.Level(n)
+		if lvl < zapcore.DebugLevel || lvl > zapcore.FatalLevel {
+			return 0, fmt.Errorf("unknown log level: %v", text)
+		}
+		return lvl, nil
+	}
+	return zapcore.ParseLevel(text)
+}
+
+// Level gives the level the logger logs at
+func Level() zapcore.Level {
+	return level.Level()
+}
+
+// SetLevel changes the level the logger logs at from now on
+func SetLevel(lvl zapcore.Level) {
+	level.SetLevel(lvl)
+}
+
 func Debugf(format string, vals ...interface{}) {
 	Log().Debug(fmt.Sprintf(format, vals...))
 }
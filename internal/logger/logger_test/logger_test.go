@@ -6,13 +6,71 @@ import (
 	"github.com/stretchr/testify/assert"
 	"github.com/test/library-app/internal/config"
 	"github.com/test/library-app/internal/logger"
+	"go.uber.org/zap/zapcore"
 )
 
 func TestMain(m *testing.M) {
 	config.LoadConfig()
+	m.Run()
 }
 
 func TestInitLogger(t *testing.T) {
 	err := logger.InitLogger()
 	assert.Nil(t, err)
 }
+
+func TestInitLoggerConfig(t *testing.T) {
+	defer func(cfg config.LogConfiguration) {
+		config.LogConfig = cfg
+		logger.InitLogger()
+	}(config.LogConfig)
+
+	// success case: the level, the mode, the encoding and the sampling of the config are applied
+	config.LogConfig.Level = "warn"
+	config.LogConfig.Mode = "production"
+	config.LogConfig.Encoding = "json"
+	config.LogConfig.SamplingInitial = 100
+	config.LogConfig.SamplingThereafter = 100
+	assert.Nil(t, logger.InitLogger())
+	assert.Equal(t, zapcore.WarnLevel, logger.Level())
+	assert.False(t, logger.Log().Core().Enabled(zapcore.InfoLevel))
+
+	// failure case: unknown level
+	config.LogConfig.Level = "loud"
+	assert.NotNil(t, logger.InitLogger())
+
+	// failure case: unknown mode
+	config.LogConfig.Level = "info"
+	config.LogConfig.Mode = "staging"
+	assert.NotNil(t, logger.InitLogger())
+}
+
+func TestParseLevel(t *testing.T) {
+	// success case: levels by their name and their number
+	lvl, err := logger.ParseLevel("debug")
+	assert.Nil(t, err)
+	assert.Equal(t, zapcore.DebugLevel, lvl)
+	lvl, err = logger.ParseLevel("-1")
+	assert.Nil(t, err)
+	assert.Equal(t, zapcore.DebugLevel, lvl)
+	lvl, err = logger.ParseLevel("2")
+	assert.Nil(t, err)
+	assert.Equal(t, zapcore.ErrorLevel, lvl)
+
+	// failure case: unknown levels
+	_, err = logger.ParseLevel("loud")
+	assert.NotNil(t, err)
+	_, err = logger.ParseLevel("6")
+	assert.NotNil(t, err)
+}
+
+func TestSetLevel(t *testing.T) {
+	assert.Nil(t, logger.InitLogger())
+	defer logger.SetLevel(logger.Level())
+
+	// success case: the logger built once follows the level changed at runtime
+	logger.SetLevel(zapcore.ErrorLevel)
+	assert.False(t, logger.Log().Core().Enabled(zapcore.WarnLevel))
+	logger.SetLevel(zapcore.DebugLevel)
+	assert.True(t, logger.Log().Core().Enabled(zapcore.DebugLevel))
+}
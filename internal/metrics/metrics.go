@@ -0,0 +1,156 @@
+package metrics
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/test/library-app/internal/logger"
+	"github.com/test/library-app/internal/model"
+)
+
+// namespace prefixes the metrics of the app
+const namespace = "library"
+
+// Registry holds the metrics served on /metrics, along with the go runtime and process metrics
+var Registry = prometheus.NewRegistry()
+
+var (
+	httpRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "http_requests_total",
+		Help:      "No of requests served by route, method and status code.",
+	}, []string{"route", "method", "code"})
+	httpDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "Latency of the requests by route and method.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"route", "method"})
+	storeDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "store_operation_duration_seconds",
+		Help:      "Duration of the store operations by operation.",
+		Buckets:   []float64{.0005, .001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5},
+	}, []string{"operation"})
+	storeErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "store_operation_errors_total",
+		Help:      "No of failed store operations by operation and kind of error: not_found, already_exists, conflict, not_allowed, precondition_failed or internal.",
+	}, []string{"operation", "kind"})
+	openLoans = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "open_loans",
+		Help:      "No of loans not returned.",
+	})
+	overdueLoans = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "overdue_loans",
+		Help:      "No of loans not returned past their return date.",
+	})
+	unavailableTitles = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "unavailable_titles",
+		Help:      "No of books with no copy available.",
+	})
+)
+
+func init() {
+	Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		httpRequests, httpDuration, storeDuration, storeErrors, openLoans, overdueLoans, unavailableTitles,
+	)
+}
+
+// Handler serves the metrics in the Prometheus exposition format
+func Handler() http.Handler {
+	return promhttp.HandlerFor(Registry, promhttp.HandlerOpts{Registry: Registry})
+}
+
+// ObserveRequest counts a request served by the route and observes its latency
+func ObserveRequest(route, method string, code int, elapsed time.Duration) {
+	httpRequests.WithLabelValues(route, method, strconv.Itoa(code)).Inc()
+	httpDuration.WithLabelValues(route, method).Observe(elapsed.Seconds())
+}
+
+// ObserveStore observes the duration of a store operation and counts it by the kind of its error when failed
+func ObserveStore(operation string, elapsed time.Duration, err error) {
+	storeDuration.WithLabelValues(operation).Observe(elapsed.Seconds())
+	if err != nil {
+		storeErrors.WithLabelValues(operation, ErrorKind(err)).Inc()
+	}
+}
+
+// ErrorKind names the kind of a store error by the sentinel it wraps
+func ErrorKind(err error) string {
+	switch {
+	case errors.Is(err, model.ErrNotFound):
+		return "not_found"
+	case errors.Is(err, model.ErrAlreadyExists):
+		return "already_exists"
+	case errors.Is(err, model.ErrConflict):
+		return "conflict"
+	case errors.Is(err, model.ErrNotAllowed):
+		return "not_allowed"
+	case errors.Is(err, model.ErrPreconditionFailed):
+		return "precondition_failed"
+	default:
+		return "internal"
+	}
+}
+
+// SetCirculation sets the gauges of the circulation to the stats
+func SetCirculation(stats *model.CirculationStats) {
+	openLoans.Set(float64(stats.OpenLoans))
+	overdueLoans.Set(float64(stats.OverdueLoans))
+	unavailableTitles.Set(float64(stats.UnavailableTitles))
+}
+
+// poolCollector collects the stats of a postgres connection pool on each scrape
+type poolCollector struct {
+	pool     *pgxpool.Pool
+	acquired *prometheus.Desc
+	idle     *prometheus.Desc
+	total    *prometheus.Desc
+	max      *prometheus.Desc
+}
+
+func (p *poolCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- p.acquired
+	ch <- p.idle
+	ch <- p.total
+	ch <- p.max
+}
+
+func (p *poolCollector) Collect(ch chan<- prometheus.Metric) {
+	stat := p.pool.Stat()
+	ch <- prometheus.MustNewConstMetric(p.acquired, prometheus.GaugeValue, float64(stat.AcquiredConns()))
+	ch <- prometheus.MustNewConstMetric(p.idle, prometheus.GaugeValue, float64(stat.IdleConns()))
+	ch <- prometheus.MustNewConstMetric(p.total, prometheus.GaugeValue, float64(stat.TotalConns()))
+	ch <- prometheus.MustNewConstMetric(p.max, prometheus.GaugeValue, float64(stat.MaxConns()))
+}
+
+// RegisterPool exposes the acquired, idle, total and max connections of the postgres pool,
+// a pool registered already is replaced
+func RegisterPool(pool *pgxpool.Pool) {
+	collector := &poolCollector{
+		pool:     pool,
+		acquired: prometheus.NewDesc(namespace+"_pgxpool_acquired_conns", "No of connections of the pool in use.", nil, nil),
+		idle:     prometheus.NewDesc(namespace+"_pgxpool_idle_conns", "No of idle connections of the pool.", nil, nil),
+		total:    prometheus.NewDesc(namespace+"_pgxpool_total_conns", "No of connections of the pool, in use, idle or being opened.", nil, nil),
+		max:      prometheus.NewDesc(namespace+"_pgxpool_max_conns", "Max No of connections of the pool.", nil, nil),
+	}
+	var registered prometheus.AlreadyRegisteredError
+	if err := Registry.Register(collector); errors.As(err, &registered) {
+		Registry.Unregister(registered.ExistingCollector)
+		Registry.MustRegister(collector)
+	} else if err != nil {
+		logger.Errorf("failed to register the pool stats. Error: %v", err)
+	}
+}
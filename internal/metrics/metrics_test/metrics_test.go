@@ -0,0 +1,46 @@
+package metricstest
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/test/library-app/internal/metrics"
+	"github.com/test/library-app/internal/model"
+)
+
+// scrape gives the metrics served as Prometheus would scrape them
+func scrape(t *testing.T) string {
+	w := httptest.NewRecorder()
+	metrics.Handler().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	assert.EqualValues(t, http.StatusOK, w.Code)
+	body, err := io.ReadAll(w.Body)
+	assert.Nil(t, err)
+	return string(body)
+}
+
+func TestObserveStore(t *testing.T) {
+	metrics.ObserveStore("GetLoan", time.Millisecond, nil)
+	metrics.ObserveStore("GetLoan", time.Millisecond, fmt.Errorf("loan 7 isn't presents. %w", model.ErrNotFound))
+	metrics.ObserveStore("AddLoan", time.Millisecond, &model.RefusalError{Reason: "loan_limit_reached"})
+	metrics.ObserveStore("AddLoan", time.Millisecond, fmt.Errorf("connection refused"))
+	body := scrape(t)
+
+	// success case: every operation is timed, the failed ones are counted by the kind of error
+	assert.Contains(t, body, `library_store_operation_duration_seconds_count{operation="GetLoan"} 2`)
+	assert.Contains(t, body, `library_store_operation_errors_total{kind="not_found",operation="GetLoan"} 1`)
+	assert.Contains(t, body, `library_store_operation_errors_total{kind="not_allowed",operation="AddLoan"} 1`)
+	assert.Contains(t, body, `library_store_operation_errors_total{kind="internal",operation="AddLoan"} 1`)
+}
+
+func TestSetCirculation(t *testing.T) {
+	metrics.SetCirculation(&model.CirculationStats{OpenLoans: 12, OverdueLoans: 3, UnavailableTitles: 4})
+	body := scrape(t)
+	assert.Contains(t, body, "library_open_loans 12")
+	assert.Contains(t, body, "library_overdue_loans 3")
+	assert.Contains(t, body, "library_unavailable_titles 4")
+}
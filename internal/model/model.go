@@ -1,42 +1,381 @@
 package model
 
 import (
+	"encoding/base64"
+	"encoding/json"
 	"errors"
+	"fmt"
+
+	"github.com/test/library-app/internal/constants"
 )
 
 // BookDetail represents book details
 type BookDetails struct {
-	Title           string `json:"title" example:"alchemist"`     // Unique Identifier for the book
-	AvailableCopies int    `json:"available_copies" example:"10"` // No of available copies of the book that can be loaned
+	ID              int      `json:"id" example:"1"`                                       // auto generated at the backend, unique identifier for the book
+	ISBN            string   `json:"isbn,omitempty" example:"9780062315007"`               // ISBN-10 or ISBN-13, unique when given
+	Title           string   `json:"title" example:"alchemist"`                            // title of the book, different books may share it
+	Authors         []string `json:"authors,omitempty" example:"Paulo Coelho"`             // authors of the book
+	Publisher       string   `json:"publisher,omitempty" example:"HarperOne"`              // publisher of the edition
+	PublicationYear int      `json:"publication_year,omitempty" example:"1988"`            // year the edition was published
+	Language        string   `json:"language,omitempty" example:"en"`                      // language of the edition
+	Subjects        []string `json:"subjects,omitempty" example:"fiction"`                 // genres or subjects of the book
+	Edition         string   `json:"edition,omitempty" example:"25th anniversary"`         // edition statement
+	Description     string   `json:"description,omitempty" example:"A shepherd's journey"` // summary of the book
+	Category        string   `json:"category,omitempty" example:"reference"`               // lending category, picks the loan period and extension rules
+	AvailableCopies int      `json:"available_copies" example:"10"`                        // No of copies on the shelf that can be loaned, derived from copies
+	TotalCopies     int      `json:"total_copies" example:"12"`                            // No of copies not withdrawn, derived from copies
+	Version         int      `json:"version" example:"1"`                                  // bumped by every update of the book, served as the ETag
+}
+
+// BookSearchResult represents a book matching a catalog search
+type BookSearchResult struct {
+	Book       *BookDetails      `json:"book"`                                                  // matched book
+	Score      float64           `json:"score" example:"0.75"`                                  // relevance of the match, higher is better
+	Highlights map[string]string `json:"highlights" example:"title:The <mark>Alchemist</mark>"` // matched fields with the matches wrapped in <mark> tags
+}
+
+// BookCopy represents a physical copy of a book
+type BookCopy struct {
+	ID              int    `json:"id" example:"1"`                        // auto generated at the backend
+	BookID          int    `json:"book_id" example:"1"`                   // ID of the book the copy belongs to
+	Barcode         string `json:"barcode" example:"1-0001"`              // Unique identifier printed on the copy
+	ShelfLocation   string `json:"shelf_location" example:"FIC-COE-A2"`   // where the copy is shelved
+	Condition       string `json:"condition" example:"good"`              // new | good | worn | damaged
+	AcquisitionDate int64  `json:"acquisition_date" example:"1700000000"` // Date when the copy was acquired, unix epoch format
+	Status          string `json:"status" example:"available"`            // available | on_loan | in_repair | lost | withdrawn
+}
+
+// BookRequest to add or update a book in the catalog
+type BookRequest struct {
+	ISBN            string   `json:"isbn" example:"9780062315007"`               // ISBN-10 or ISBN-13
+	Title           string   `json:"title" example:"alchemist"`                  // title of the book
+	Authors         []string `json:"authors" example:"Paulo Coelho"`             // authors of the book
+	Publisher       string   `json:"publisher" example:"HarperOne"`              // publisher of the edition
+	PublicationYear int      `json:"publication_year" example:"1988"`            // year the edition was published
+	Language        string   `json:"language" example:"en"`                      // language of the edition
+	Subjects        []string `json:"subjects" example:"fiction"`                 // genres or subjects of the book
+	Edition         string   `json:"edition" example:"25th anniversary"`         // edition statement
+	Description     string   `json:"description" example:"A shepherd's journey"` // summary of the book
+	Category        string   `json:"category" example:"reference"`               // lending category, picks the loan period and extension rules
+	Copies          int      `json:"copies" example:"10"`                        // No of copies to create with generated barcodes, only honoured on add
+}
+
+// BookCopyRequest to add or update a copy of a book, empty fields are left unchanged on update
+type BookCopyRequest struct {
+	Barcode         string `json:"barcode" example:"1-0001"`              // generated when missed on add
+	ShelfLocation   string `json:"shelf_location" example:"FIC-COE-A2"`   // where the copy is shelved
+	Condition       string `json:"condition" example:"good"`              // new | good | worn | damaged
+	AcquisitionDate int64  `json:"acquisition_date" example:"1700000000"` // unix epoch format, defaults to now on add
+	Status          string `json:"status" example:"in_repair"`            // available | in_repair | lost | withdrawn, only honoured on update
+}
+
+// BookCopiesRequest adjusts the available copies of a book
+type BookCopiesRequest struct {
+	Delta int `json:"delta" example:"2"` // No of copies to add with generated barcodes (positive) or available copies to withdraw (negative)
 }
 
 // LoanDetails represents loan of the book
 type LoanDetails struct {
 	ID             int    `json:"id"`               // auto generated at the backend
-	NameOfBorrower string `json:"name_of_borrower"` // Name of borrower
+	MemberID       int    `json:"member_id"`        // ID of the borrowing member
+	NameOfBorrower string `json:"name_of_borrower"` // Name of the member when borrowed
+	BookID         int    `json:"book_id"`          // ID of the loaned book
+	CopyID         int    `json:"copy_id"`          // ID of the loaned copy
+	Barcode        string `json:"barcode"`          // barcode of the loaned copy
 	Title          string `json:"title"`            // title of the book
 	LoanDate       int64  `json:"loan_date"`        // Date when the book was borrowed, unix epoch format. relavant for api calls
 	ReturnDate     int64  `json:"return_date"`      // Date when the book should be returned, unix epoch format. relavant for api calls
-	Status         string `json:"status"`           // active | closed
-	// TODO: adding extra fields for additional functionality
-	// RentPerDay     int    `json:"cost_per_day"`
+	Status         string `json:"status"`           // active | overdue | closed
+	Extensions     int    `json:"extensions"`       // No of times the loan got extended
+	Version        int    `json:"version"`          // bumped by every transition of the loan, served as the ETag
+}
+
+// LoanState represents the values of a loan changed by its transitions
+type LoanState struct {
+	Status     string `json:"status,omitempty" example:"active"`
+	ReturnDate int64  `json:"return_date,omitempty" example:"1700000000"`
+	Extensions int    `json:"extensions,omitempty" example:"1"`
+}
+
+// LoanEvent represents a transition of a loan, loan events are never changed once recorded
+type LoanEvent struct {
+	ID     int        `json:"id" example:"1"`              // auto generated at the backend
+	LoanID int        `json:"loan_id" example:"1"`         // ID of the loan
+	Type   string     `json:"type" example:"extended"`     // created | extended | overdue | returned
+	Actor  string     `json:"actor" example:"librarian-1"` // who made the transition, system for background jobs
+	At     int64      `json:"at" example:"1700000000"`     // Date when the transition happened, unix epoch format
+	Old    *LoanState `json:"old,omitempty"`               // values before the transition, missed on creation
+	New    *LoanState `json:"new"`                         // values after the transition
+}
+
+// IdempotencyRecord represents a request made with an Idempotency-Key, its response is replayed to the retries
+type IdempotencyRecord struct {
+	Key         string            `json:"key"`              // Idempotency-Key of the request scoped to the caller
+	Fingerprint string            `json:"fingerprint"`      // hash of the method, path and body of the request
+	StatusCode  int               `json:"status_code"`      // status of the response, 0 while the request is in progress
+	Header      map[string]string `json:"header,omitempty"` // headers of the response replayed along with the body
+	Body        []byte            `json:"body,omitempty"`   // body of the response
+	ExpiresAt   int64             `json:"expires_at"`       // Date till the response is replayed, unix epoch format
+}
+
+// CirculationStats represents the state of the circulation at a point in time
+type CirculationStats struct {
+	OpenLoans         int `json:"open_loans"`         // loans not returned
+	OverdueLoans      int `json:"overdue_loans"`      // loans not returned past their return date
+	UnavailableTitles int `json:"unavailable_titles"` // books with no copy available
+}
+
+// LogLevel represents the level the app logs at
+type LogLevel struct {
+	Level string `json:"level" example:"info"` // debug, info, warn, error, dpanic, panic or fatal
+}
+
+// HealthReport represents the result of the health checks of the app
+type HealthReport struct {
+	Status string         `json:"status" example:"ok"` // ok, unavailable when any check failed or shutting_down
+	Checks []*HealthCheck `json:"checks"`
+}
+
+// HealthCheck represents the result of a single health check
+type HealthCheck struct {
+	Name       string `json:"name" example:"postgres"`
+	Status     string `json:"status" example:"ok"` // ok or failed
+	Error      string `json:"error,omitempty"`
+	DurationMs int64  `json:"duration_ms"` // time taken by the check in milliseconds
+}
+
+// APIKey represents a key an integrating system calls the api with, only the hash of the key is stored
+type APIKey struct {
+	ID                int      `json:"id" example:"1"`                                  // auto generated at the backend
+	Name              string   `json:"name" example:"kiosk-east"`                       // unique name of the integrating system
+	Prefix            string   `json:"prefix" example:"lib_Xq3v"`                       // first characters of the key telling the keys apart
+	Hash              string   `json:"-"`                                               // SHA-256 of the key
+	Scopes            []string `json:"scopes" example:"catalog:read,circulation:write"` // resources the key may read or write
+	RequestsPerMinute int      `json:"requests_per_minute,omitempty" example:"120"`     // quota of the key, 0 for the default quota
+	CreatedAt         int64    `json:"created_at" example:"1712850000"`                 // Date of issue, unix epoch format
+	RevokedAt         int64    `json:"revoked_at,omitempty" example:"1712936400"`       // Date of revocation, 0 while usable
+}
+
+// APIKeyRequest request
+type APIKeyRequest struct {
+	// binding: required
+	Name              string   `json:"name" example:"kiosk-east"`
+	Scopes            []string `json:"scopes" example:"catalog:read,circulation:write"` // catalog, members or circulation with :read or :write
+	RequestsPerMinute int      `json:"requests_per_minute,omitempty" example:"120"`     // 0 for the default quota
+}
+
+// IssuedAPIKey represents a key just issued, the key itself is returned once and not kept
+type IssuedAPIKey struct {
+	APIKey
+	Key string `json:"key" example:"lib_Xq3vZb8b6yqkS0Ub1m3nJ2z0u1dF4oP0Ia1iW3cR7sE"`
 }
 
 // LoanDetails request
 type LoanRequest struct {
 	// binding: required
-	NameOfBorrower string `json:"name_of_borrower" example:"john"` // Name of borrower
-	Title          string `json:"title" example:"alchemist"`       // title of the book
+	MemberID int    `json:"member_id" example:"1"`     // ID of the borrowing member
+	BookID   int    `json:"book_id" example:"1"`       // ID of the book, takes precedence over title
+	Title    string `json:"title" example:"alchemist"` // title of the book, must be unambiguous when book_id is absent
+	Barcode  string `json:"barcode" example:"1-0001"`  // barcode of the copy to loan, any available copy when missed
+}
+
+// Hold represents a place of a member in the queue of a book with no copy available
+type Hold struct {
+	ID        int    `json:"id" example:"1"`                            // auto generated at the backend
+	MemberID  int    `json:"member_id" example:"1"`                     // ID of the waiting member
+	BookID    int    `json:"book_id" example:"1"`                       // ID of the held book
+	Title     string `json:"title" example:"alchemist"`                 // title of the book
+	CopyID    int    `json:"copy_id,omitempty" example:"1"`             // ID of the copy set aside once ready
+	Barcode   string `json:"barcode,omitempty" example:"1-0001"`        // barcode of the copy set aside once ready
+	Status    string `json:"status" example:"waiting"`                  // waiting | ready | fulfilled | cancelled | expired
+	PlacedAt  int64  `json:"placed_at" example:"1700000000"`            // Date when the hold was placed, unix epoch format
+	ReadyAt   int64  `json:"ready_at,omitempty" example:"1700000000"`   // Date when a copy got set aside, unix epoch format
+	ExpiresAt int64  `json:"expires_at,omitempty" example:"1700259200"` // Date till the copy is set aside, unix epoch format
+}
+
+// HoldRequest to place a hold
+type HoldRequest struct {
+	MemberID int    `json:"member_id" example:"1"`     // ID of the waiting member
+	BookID   int    `json:"book_id" example:"1"`       // ID of the book, takes precedence over title
+	Title    string `json:"title" example:"alchemist"` // title of the book, must be unambiguous when book_id is absent
+}
+
+// Fine represents the fine of an overdue loan
+type Fine struct {
+	ID            int    `json:"id" example:"1"`                            // auto generated at the backend
+	LoanID        int    `json:"loan_id" example:"1"`                       // ID of the overdue loan
+	MemberID      int    `json:"member_id" example:"1"`                     // ID of the fined member
+	Title         string `json:"title" example:"alchemist"`                 // title of the loaned book
+	AmountInCents int64  `json:"amount_in_cents" example:"250"`             // fine accrued so far, final once returned
+	PaidInCents   int64  `json:"paid_in_cents" example:"100"`               // part of the amount paid
+	Status        string `json:"status" example:"unpaid"`                   // accruing | unpaid | paid | waived
+	AccruedAt     int64  `json:"accrued_at" example:"1700000000"`           // Date when the amount got last updated, unix epoch format
+	SettledAt     int64  `json:"settled_at,omitempty" example:"1700000000"` // Date when the fine got paid or waived, unix epoch format
+}
+
+// Outstanding gives the part of the fine still owed, nothing once waived
+func (f *Fine) Outstanding() int64 {
+	if f.Status == constants.FineWaived || f.PaidInCents >= f.AmountInCents {
+		return 0
+	}
+	return f.AmountInCents - f.PaidInCents
+}
+
+// MemberFines represents the fines of a member
+type MemberFines struct {
+	MemberID           int     `json:"member_id" example:"1"`
+	Fines              []*Fine `json:"fines"`                              // fines of the member in the order fined
+	OutstandingInCents int64   `json:"outstanding_in_cents" example:"150"` // total owed by the member
+}
+
+// FinePaymentRequest to pay a fine
+type FinePaymentRequest struct {
+	AmountInCents int64 `json:"amount_in_cents" example:"100"` // amount paid, the outstanding amount when missed
+}
+
+// Member represents a registered library member
+type Member struct {
+	ID         int    `json:"id" example:"1"`                             // auto generated at the backend
+	Name       string `json:"name" example:"John Doe"`                    // full name of the member
+	Email      string `json:"email,omitempty" example:"john@example.com"` // unique when given
+	CardNumber string `json:"card_number" example:"LIB-000001"`           // unique library card number
+	Tier       string `json:"tier" example:"standard"`                    // standard | student | premium
+	Status     string `json:"status" example:"active"`                    // active | suspended | expired, only active members borrow
+	JoinedAt   int64  `json:"joined_at" example:"1700000000"`             // Date when the member registered, unix epoch format
+}
+
+// MemberRequest to register or update a member, empty fields are left unchanged on update
+type MemberRequest struct {
+	Name       string `json:"name" example:"John Doe"`          // mandatory on register
+	Email      string `json:"email" example:"john@example.com"` // unique when given
+	CardNumber string `json:"card_number" example:"LIB-000001"` // generated when missed on register
+	Tier       string `json:"tier" example:"standard"`          // standard | student | premium, standard by default
+	Status     string `json:"status" example:"active"`          // active | suspended | expired, active by default
+}
+
+// PageQuery pages and sorts a listing, the cursor is the next_cursor of the previous page
+type PageQuery struct {
+	Limit  int     `form:"limit"`  // No of items in the page
+	Cursor string  `form:"cursor"` // opaque position to continue from
+	SortBy string  `form:"sort"`   // sort key of the listing, id by default
+	Order  string  `form:"order"`  // asc | desc
+	After  *Cursor `form:"-"`      // decoded cursor
+}
+
+// BookQuery filters, sorts and pages the book listing
+type BookQuery struct {
+	PageQuery
+	Title     string `form:"title"`     // case insensitive part of the title
+	Author    string `form:"author"`    // case insensitive part of any author
+	Available *bool  `form:"available"` // books with (true) or without (false) available copies
+}
+
+// LoanQuery filters, sorts and pages the loan listing, dates are unix epoch format
+type LoanQuery struct {
+	PageQuery
+	Status     string `form:"status"`      // active | overdue | closed
+	MemberID   int    `form:"member_id"`   // ID of the borrowing member
+	Borrower   string `form:"borrower"`    // case insensitive name of borrower
+	Title      string `form:"title"`       // case insensitive part of the title
+	Overdue    bool   `form:"overdue"`     // active loans past their return date
+	LoanedFrom int64  `form:"loaned_from"` // loaned at or after
+	LoanedTo   int64  `form:"loaned_to"`   // loaned at or before
+	DueFrom    int64  `form:"due_from"`    // to be returned at or after
+	DueTo      int64  `form:"due_to"`      // to be returned at or before
+}
+
+// MemberQuery filters, sorts and pages the member listing
+type MemberQuery struct {
+	PageQuery
+	Name   string `form:"name"`   // case insensitive part of the name
+	Tier   string `form:"tier"`   // standard | student | premium
+	Status string `form:"status"` // active | suspended | expired
+}
+
+// Cursor is the sort position of the last item of a page
+type Cursor struct {
+	SortBy string `json:"s"`
+	Desc   bool   `json:"d,omitempty"`
+	Text   string `json:"t,omitempty"` // sort value of text keys
+	Num    int64  `json:"n,omitempty"` // sort value of numeric keys
+	ID     int    `json:"i"`           // tie breaker
+}
+
+// BookPage represents a page of the book listing
+type BookPage struct {
+	Books      []*BookDetails `json:"books"`
+	NextCursor string         `json:"next_cursor,omitempty" example:"eyJzIjoiaWQiLCJpIjo1MH0"` // missed on the last page
+}
+
+// LoanPage represents a page of the loan listing
+type LoanPage struct {
+	Loans      []*LoanDetails `json:"loans"`
+	NextCursor string         `json:"next_cursor,omitempty" example:"eyJzIjoiaWQiLCJpIjo1MH0"` // missed on the last page
+}
+
+// MemberPage represents a page of the member listing
+type MemberPage struct {
+	Members    []*Member `json:"members"`
+	NextCursor string    `json:"next_cursor,omitempty" example:"eyJzIjoiaWQiLCJpIjo1MH0"` // missed on the last page
+}
+
+// CardNumber generates the card number of a member
+func CardNumber(memberID int) string {
+	return fmt.Sprintf("LIB-%06d", memberID)
+}
+
+// CopyBarcode generates the barcode of the n-th copy of a book
+func CopyBarcode(bookID, n int) string {
+	return fmt.Sprintf("%d-%04d", bookID, n)
+}
+
+// Encode encodes the cursor to be handed out as next_cursor
+func (c *Cursor) Encode() string {
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// DecodeCursor decodes a cursor handed out as next_cursor
+func DecodeCursor(cursor string) (*Cursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, err
+	}
+	var c Cursor
+	if err := json.Unmarshal(data, &c); err != nil {
+		return nil, err
+	}
+	return &c, nil
 }
 
 // Custom Errors
 var (
-	ErrNotFound = errors.New("not found")
+	ErrNotFound      = errors.New("not found")
+	ErrAlreadyExists = errors.New("already exists")
+	ErrConflict      = errors.New("conflict")
+	ErrNotAllowed    = errors.New("not allowed")
+	// ErrPreconditionFailed refuses a change based on a version other than the current one
+	ErrPreconditionFailed = errors.New("precondition failed")
 )
 
+// RefusalError refuses a loan breaking the borrowing policy, it wraps ErrNotAllowed
+type RefusalError struct {
+	Reason  string // reason code, one of the constants.Reason values
+	Message string
+}
+
+func (e *RefusalError) Error() string {
+	return e.Message
+}
+
+func (e *RefusalError) Unwrap() error {
+	return ErrNotAllowed
+}
+
 // CustomError
 type CustomError struct {
 	Error   string `json:"error"`
 	Details string `json:"details"`
+	Reason  string `json:"reason,omitempty" example:"loan_limit_reached"` // reason code of a refused loan
 	Code    int    `json:"code"`
 }
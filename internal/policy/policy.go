@@ -0,0 +1,142 @@
+package policy
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/test/library-app/internal/config"
+	"github.com/test/library-app/internal/constants"
+	"github.com/test/library-app/internal/model"
+)
+
+// Standing summarizes what a member holds at the time of borrowing
+type Standing struct {
+	Member      *model.Member
+	ActiveLoans []*model.LoanDetails // loans of the member not yet returned
+	ActiveHolds []*model.Hold        // holds of the member waiting or ready
+	UnpaidFines int64                // fines owed by the member in cents
+}
+
+// MaxLoans gives the No of books a member of the tier may hold at once
+func MaxLoans(tier string) int {
+	switch tier {
+	case constants.TierStudent:
+		return config.PolicyConfig.MaxLoansStudent
+	case constants.TierPremium:
+		return config.PolicyConfig.MaxLoansPremium
+	default:
+		return config.PolicyConfig.MaxLoansStandard
+	}
+}
+
+// LoanTerms are the loan period and extension rules of a book lent to a member
+type LoanTerms struct {
+	Period        time.Duration
+	Extension     time.Duration // added to the return date by each extension
+	MaxExtensions int
+}
+
+// override looks up the value of the category, then of the tier, falling back to the global value
+func override(byCategory, byTier map[string]int, category, tier string, global int) int {
+	if v, ok := byCategory[category]; ok && category != "" {
+		return v
+	}
+	if v, ok := byTier[tier]; ok {
+		return v
+	}
+	return global
+}
+
+// Terms gives the loan terms of a book of the category lent to a member of the tier
+func Terms(tier, category string) LoanTerms {
+	cfg := &config.PolicyConfig
+	day := 24 * time.Hour
+	return LoanTerms{
+		Period:        time.Duration(override(cfg.LoanPeriodInDaysByCategory, cfg.LoanPeriodInDaysByTier, category, tier, cfg.LoanPeriodInDays)) * day,
+		Extension:     time.Duration(override(cfg.ExtensionInDaysByCategory, cfg.ExtensionInDaysByTier, category, tier, cfg.ExtensionInDays)) * day,
+		MaxExtensions: override(cfg.MaxExtensionsByCategory, cfg.MaxExtensionsByTier, category, tier, cfg.MaxExtensions),
+	}
+}
+
+// refuse builds the error refusing a loan for the reason
+func refuse(reason string, format string, args ...any) error {
+	return &model.RefusalError{Reason: reason, Message: fmt.Sprintf(format, args...)}
+}
+
+// CheckLoan decides whether the member in the standing may borrow the resolved book of the loan,
+// returns a *model.RefusalError naming the reason when refused
+func CheckLoan(standing *Standing, det *model.LoanDetails, now time.Time) error {
+	member := standing.Member
+	if member.Status != constants.MemberActive {
+		return refuse(constants.ReasonMemberInactive, "member %d is %s", member.ID, member.Status)
+	}
+	if max := MaxLoans(member.Tier); len(standing.ActiveLoans) >= max {
+		return refuse(constants.ReasonLoanLimitReached, "member %d already holds %d books, the limit of %s members is %d", member.ID, len(standing.ActiveLoans), member.Tier, max)
+	}
+	overdue := 0
+	for _, loan := range standing.ActiveLoans {
+		if loan.BookID == det.BookID || strings.EqualFold(loan.Title, det.Title) {
+			return refuse(constants.ReasonDuplicateTitle, "member %d already borrowed '%s' with loan %d", member.ID, det.Title, loan.ID)
+		}
+		if loan.ReturnDate < now.Unix() {
+			overdue++
+		}
+	}
+	if config.PolicyConfig.BlockOnOverdue && overdue > 0 {
+		return refuse(constants.ReasonOverdueItems, "member %d has %d overdue books", member.ID, overdue)
+	}
+	if standing.UnpaidFines > config.PolicyConfig.MaxUnpaidFinesInCents {
+		return refuse(constants.ReasonUnpaidFines, "member %d owes %d cents of fines", member.ID, standing.UnpaidFines)
+	}
+	return nil
+}
+
+// CheckExtension decides whether the loan may be extended once more under the terms, overdue loans are not,
+// returns a *model.RefusalError naming the reason when refused
+func CheckExtension(loan *model.LoanDetails, terms LoanTerms) error {
+	if loan.Status == constants.Overdue {
+		return refuse(constants.ReasonOverdueItems, "loan %d is overdue", loan.ID)
+	}
+	if loan.Extensions >= terms.MaxExtensions {
+		return refuse(constants.ReasonExtensionLimitReached, "loan %d got extended %d times, the limit is %d", loan.ID, loan.Extensions, terms.MaxExtensions)
+	}
+	return nil
+}
+
+// FineAmount gives the fine of a loan due by the return date as of now, counting the full overdue days past the grace period
+func FineAmount(returnDate int64, now time.Time) int64 {
+	days := int64(now.Sub(time.Unix(returnDate, 0)) / (24 * time.Hour))
+	days -= int64(config.PolicyConfig.FineGraceInDays)
+	if days <= 0 {
+		return 0
+	}
+	amount := days * config.PolicyConfig.FinePerDayInCents
+	if max := config.PolicyConfig.MaxFineInCents; max > 0 && amount > max {
+		return max
+	}
+	return amount
+}
+
+// CheckHold decides whether the member in the standing may place the hold on the resolved book,
+// returns a *model.RefusalError naming the reason when refused, or ErrAlreadyExists when the member holds the book
+func CheckHold(standing *Standing, det *model.Hold) error {
+	member := standing.Member
+	if member.Status != constants.MemberActive {
+		return refuse(constants.ReasonMemberInactive, "member %d is %s", member.ID, member.Status)
+	}
+	for _, hold := range standing.ActiveHolds {
+		if hold.BookID == det.BookID {
+			return fmt.Errorf("member %d already holds book %d with hold %d. %w", member.ID, det.BookID, hold.ID, model.ErrAlreadyExists)
+		}
+	}
+	if len(standing.ActiveHolds) >= config.PolicyConfig.MaxHolds {
+		return refuse(constants.ReasonHoldLimitReached, "member %d already has %d holds, the limit is %d", member.ID, len(standing.ActiveHolds), config.PolicyConfig.MaxHolds)
+	}
+	for _, loan := range standing.ActiveLoans {
+		if loan.BookID == det.BookID || strings.EqualFold(loan.Title, det.Title) {
+			return refuse(constants.ReasonDuplicateTitle, "member %d already borrowed '%s' with loan %d", member.ID, det.Title, loan.ID)
+		}
+	}
+	return nil
+}
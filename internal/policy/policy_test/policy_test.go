@@ -0,0 +1,143 @@
+package policytest
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/test/library-app/internal/config"
+	"github.com/test/library-app/internal/constants"
+	"github.com/test/library-app/internal/model"
+	"github.com/test/library-app/internal/policy"
+)
+
+func TestMain(m *testing.M) {
+	// loading configuration
+	config.LoadConfig()
+	m.Run()
+}
+
+// reason gives the reason code of a refusal, empty when allowed
+func reason(err error) string {
+	var refusal *model.RefusalError
+	if errors.As(err, &refusal) {
+		return refusal.Reason
+	}
+	return ""
+}
+
+func TestCheckLoan(t *testing.T) {
+	now := time.Now()
+	member := &model.Member{ID: 1, Tier: constants.TierStudent, Status: constants.MemberActive}
+	loan := &model.LoanDetails{BookID: 10, Title: "Sapiens"}
+	borrowed := func(bookID int, title string, due time.Time) *model.LoanDetails {
+		return &model.LoanDetails{BookID: bookID, Title: title, ReturnDate: due.Unix(), Status: constants.Active}
+	}
+
+	// success case
+	standing := &policy.Standing{Member: member, ActiveLoans: []*model.LoanDetails{borrowed(1, "Dune", now.Add(time.Hour))}}
+	assert.Nil(t, policy.CheckLoan(standing, loan, now))
+
+	// failure case: inactive member
+	suspended := *member
+	suspended.Status = constants.MemberSuspended
+	err := policy.CheckLoan(&policy.Standing{Member: &suspended}, loan, now)
+	assert.ErrorIs(t, err, model.ErrNotAllowed)
+	assert.Equal(t, constants.ReasonMemberInactive, reason(err))
+
+	// failure case: the tier limit reached
+	standing = &policy.Standing{Member: member}
+	for i := 0; i < policy.MaxLoans(constants.TierStudent); i++ {
+		standing.ActiveLoans = append(standing.ActiveLoans, borrowed(i+1, "Dune", now.Add(time.Hour)))
+	}
+	assert.Equal(t, constants.ReasonLoanLimitReached, reason(policy.CheckLoan(standing, loan, now)))
+
+	// failure case: the title is already borrowed, ignoring the case
+	standing = &policy.Standing{Member: member, ActiveLoans: []*model.LoanDetails{borrowed(11, "SAPIENS", now.Add(time.Hour))}}
+	assert.Equal(t, constants.ReasonDuplicateTitle, reason(policy.CheckLoan(standing, loan, now)))
+
+	// failure case: overdue books
+	standing = &policy.Standing{Member: member, ActiveLoans: []*model.LoanDetails{borrowed(1, "Dune", now.Add(-time.Hour))}}
+	assert.Equal(t, constants.ReasonOverdueItems, reason(policy.CheckLoan(standing, loan, now)))
+
+	// failure case: unpaid fines
+	standing = &policy.Standing{Member: member, UnpaidFines: config.PolicyConfig.MaxUnpaidFinesInCents + 1}
+	assert.Equal(t, constants.ReasonUnpaidFines, reason(policy.CheckLoan(standing, loan, now)))
+}
+
+func TestTerms(t *testing.T) {
+	config.PolicyConfig.LoanPeriodInDaysByTier = map[string]int{constants.TierPremium: 42}
+	config.PolicyConfig.LoanPeriodInDaysByCategory = map[string]int{"reference": 7}
+	config.PolicyConfig.MaxExtensionsByCategory = map[string]int{"reference": 0}
+	defer func() {
+		config.PolicyConfig.LoanPeriodInDaysByTier = nil
+		config.PolicyConfig.LoanPeriodInDaysByCategory = nil
+		config.PolicyConfig.MaxExtensionsByCategory = nil
+	}()
+	day := 24 * time.Hour
+
+	// global terms
+	terms := policy.Terms(constants.TierStandard, "")
+	assert.Equal(t, time.Duration(config.PolicyConfig.LoanPeriodInDays)*day, terms.Period)
+	assert.Equal(t, time.Duration(config.PolicyConfig.ExtensionInDays)*day, terms.Extension)
+	assert.Equal(t, config.PolicyConfig.MaxExtensions, terms.MaxExtensions)
+
+	// the tier overrides the global terms, the category overrides the tier
+	assert.Equal(t, 42*day, policy.Terms(constants.TierPremium, "").Period)
+	terms = policy.Terms(constants.TierPremium, "reference")
+	assert.Equal(t, 7*day, terms.Period)
+	assert.Equal(t, 0, terms.MaxExtensions)
+
+	// failure case: extended the most times
+	err := policy.CheckExtension(&model.LoanDetails{ID: 1}, terms)
+	assert.Equal(t, constants.ReasonExtensionLimitReached, reason(err))
+	assert.Nil(t, policy.CheckExtension(&model.LoanDetails{ID: 1, Extensions: 1}, policy.Terms(constants.TierStandard, "")))
+}
+
+func TestCheckHold(t *testing.T) {
+	member := &model.Member{ID: 1, Tier: constants.TierStandard, Status: constants.MemberActive}
+	hold := &model.Hold{BookID: 10, Title: "Sapiens"}
+
+	// success case
+	standing := &policy.Standing{Member: member, ActiveHolds: []*model.Hold{{ID: 1, BookID: 1}}}
+	assert.Nil(t, policy.CheckHold(standing, hold))
+
+	// failure case: the book is already held
+	standing = &policy.Standing{Member: member, ActiveHolds: []*model.Hold{{ID: 1, BookID: 10}}}
+	assert.ErrorIs(t, policy.CheckHold(standing, hold), model.ErrAlreadyExists)
+
+	// failure case: the hold limit reached
+	standing = &policy.Standing{Member: member}
+	for i := 0; i < config.PolicyConfig.MaxHolds; i++ {
+		standing.ActiveHolds = append(standing.ActiveHolds, &model.Hold{ID: i + 1, BookID: i + 1})
+	}
+	assert.Equal(t, constants.ReasonHoldLimitReached, reason(policy.CheckHold(standing, hold)))
+
+	// failure case: the title is already borrowed
+	standing = &policy.Standing{Member: member, ActiveLoans: []*model.LoanDetails{{ID: 1, BookID: 11, Title: "sapiens"}}}
+	assert.Equal(t, constants.ReasonDuplicateTitle, reason(policy.CheckHold(standing, hold)))
+}
+
+func TestFineAmount(t *testing.T) {
+	config.PolicyConfig.FineGraceInDays = 1
+	defer func() {
+		config.PolicyConfig.FineGraceInDays = 0
+	}()
+	now := time.Now()
+	day := 24 * time.Hour
+
+	// not fined before the return date or within the grace period
+	assert.Equal(t, int64(0), policy.FineAmount(now.Add(day).Unix(), now))
+	assert.Equal(t, int64(0), policy.FineAmount(now.Add(-day-time.Hour).Unix(), now))
+
+	// fined per full day past the grace period
+	assert.Equal(t, 2*config.PolicyConfig.FinePerDayInCents, policy.FineAmount(now.Add(-3*day-time.Hour).Unix(), now))
+
+	// capped
+	assert.Equal(t, config.PolicyConfig.MaxFineInCents, policy.FineAmount(now.Add(-1000*day).Unix(), now))
+
+	// failure case: overdue loans aren't extended
+	err := policy.CheckExtension(&model.LoanDetails{ID: 1, Status: constants.Overdue}, policy.Terms(constants.TierStandard, ""))
+	assert.Equal(t, constants.ReasonOverdueItems, reason(err))
+}
@@ -0,0 +1,84 @@
+package local
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/test/library-app/internal/logger"
+	"github.com/test/library-app/internal/model"
+)
+
+// AddAPIKey stores an issued api key, refused with ErrAlreadyExists when its name or hash is taken
+func (l *LocalStore) AddAPIKey(ctx context.Context, key *model.APIKey) (int, error) {
+	l.rmu.Lock()
+	defer l.rmu.Unlock()
+	if err := l.writable(); err != nil {
+		return 0, err
+	}
+	if _, ok := l.keyHashes[key.Hash]; ok {
+		return 0, fmt.Errorf("api key %s already presents. %w", key.Prefix, model.ErrAlreadyExists)
+	}
+	for _, stored := range l.apiKeys {
+		if stored.Name == key.Name {
+			return 0, fmt.Errorf("api key named '%s' already presents. %w", key.Name, model.ErrAlreadyExists)
+		}
+	}
+	l.lastKeyID++
+	key.ID = l.lastKeyID
+	l.apiKeys[key.ID] = key
+	l.keyHashes[key.Hash] = key.ID
+	if err := l.persistAPIKeys([]int{key.ID}); err != nil {
+		return 0, err
+	}
+	logger.FromContext(ctx).Infof("API key %d issued to %s", key.ID, key.Name)
+	return key.ID, nil
+}
+
+// GetAPIKeyByHash retreves the api key with the hash whether revoked or not
+func (l *LocalStore) GetAPIKeyByHash(ctx context.Context, hash string) (*model.APIKey, error) {
+	l.rmu.RLock()
+	defer l.rmu.RUnlock()
+	id, ok := l.keyHashes[hash]
+	if !ok {
+		return nil, fmt.Errorf("api key isn't presents. %w", model.ErrNotFound)
+	}
+	return l.apiKeys[id], nil
+}
+
+// GetAllAPIKeys retreves the api keys in the order issued
+func (l *LocalStore) GetAllAPIKeys(ctx context.Context) ([]*model.APIKey, error) {
+	l.rmu.RLock()
+	defer l.rmu.RUnlock()
+	keys := make([]*model.APIKey, 0, len(l.apiKeys))
+	for _, key := range l.apiKeys {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return keys[i].ID < keys[j].ID
+	})
+	return keys, nil
+}
+
+// RevokeAPIKey revokes an api key as of now, refused with ErrConflict once revoked
+func (l *LocalStore) RevokeAPIKey(ctx context.Context, keyID int, now time.Time) (*model.APIKey, error) {
+	l.rmu.Lock()
+	defer l.rmu.Unlock()
+	if err := l.writable(); err != nil {
+		return nil, err
+	}
+	key, ok := l.apiKeys[keyID]
+	if !ok {
+		return nil, fmt.Errorf("api key %d isn't presents. %w", keyID, model.ErrNotFound)
+	}
+	if key.RevokedAt != 0 {
+		return nil, fmt.Errorf("api key %d is revoked already. %w", keyID, model.ErrConflict)
+	}
+	key.RevokedAt = now.Unix()
+	if err := l.persistAPIKeys([]int{key.ID}); err != nil {
+		return nil, err
+	}
+	logger.FromContext(ctx).Infof("API key %d of %s revoked", key.ID, key.Name)
+	return key, nil
+}
@@ -0,0 +1,168 @@
+package local
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/test/library-app/internal/constants"
+	"github.com/test/library-app/internal/logger"
+	"github.com/test/library-app/internal/model"
+)
+
+// addCopy stores a copy of an existing book, generating the barcode when missed, callers must hold the lock
+func (l *LocalStore) addCopy(det *model.BookCopy) error {
+	if det.Barcode == "" {
+		// skipping barcodes taken by copies added with explicit barcodes
+		for n := len(l.bookCopies[det.BookID]) + 1; ; n++ {
+			if _, ok := l.barcodes[model.CopyBarcode(det.BookID, n)]; !ok {
+				det.Barcode = model.CopyBarcode(det.BookID, n)
+				break
+			}
+		}
+	}
+	if _, ok := l.barcodes[det.Barcode]; ok {
+		return fmt.Errorf("copy with barcode '%s' already presents. %w", det.Barcode, model.ErrAlreadyExists)
+	}
+	if det.Condition == "" {
+		det.Condition = constants.DefaultCondition
+	}
+	if det.AcquisitionDate == 0 {
+		det.AcquisitionDate = time.Now().Unix()
+	}
+	if det.Status == "" {
+		det.Status = constants.CopyAvailable
+	}
+	l.lastCopyID++
+	det.ID = l.lastCopyID
+	l.copies[det.ID] = det
+	l.barcodes[det.Barcode] = det.ID
+	l.bookCopies[det.BookID] = append(l.bookCopies[det.BookID], det.ID)
+	return nil
+}
+
+// copyOfBookCopy copies a copy of a book for the callers to read once the lock is released,
+// the copies passed on by the jobs change status in place
+func copyOfBookCopy(bookCopy *model.BookCopy) *model.BookCopy {
+	cp := *bookCopy
+	return &cp
+}
+
+// bookDetailsCopy copies a book for the callers to read once the lock is released, its copy counts change in place
+func bookDetailsCopy(book *model.BookDetails) *model.BookDetails {
+	cp := *book
+	return &cp
+}
+
+// refreshCopyCounts derives the available and total copies of a book from its copies, callers must hold the lock
+func (l *LocalStore) refreshCopyCounts(bookID int) {
+	book, ok := l.books[bookID]
+	if !ok {
+		return
+	}
+	book.AvailableCopies = 0
+	book.TotalCopies = 0
+	for _, id := range l.bookCopies[bookID] {
+		switch l.copies[id].Status {
+		case constants.CopyAvailable:
+			book.AvailableCopies++
+			book.TotalCopies++
+		case constants.CopyWithdrawn:
+		default:
+			book.TotalCopies++
+		}
+	}
+}
+
+// availableCopy finds the oldest copy of a book on the shelf, callers must hold the lock
+func (l *LocalStore) availableCopy(bookID int) (*model.BookCopy, bool) {
+	for _, id := range l.bookCopies[bookID] {
+		if l.copies[id].Status == constants.CopyAvailable {
+			return l.copies[id], true
+		}
+	}
+	return nil, false
+}
+
+// AddBookCopy adds a physical copy to a book, generating the barcode when missed
+func (l *LocalStore) AddBookCopy(ctx context.Context, det *model.BookCopy) (int, error) {
+	l.rmu.Lock()
+	defer l.rmu.Unlock()
+	if err := l.writable(); err != nil {
+		return 0, err
+	}
+	if _, ok := l.books[det.BookID]; !ok {
+		return 0, fmt.Errorf("book %d isn't presents. %w", det.BookID, model.ErrNotFound)
+	}
+	det.Status = constants.CopyAvailable
+	if err := l.addCopy(det); err != nil {
+		return 0, err
+	}
+	l.refreshCopyCounts(det.BookID)
+	if err := l.persist([]int{det.BookID}, nil); err != nil {
+		return 0, err
+	}
+	logger.FromContext(ctx).Infof("Copy %s added to book %d", det.Barcode, det.BookID)
+	return det.ID, nil
+}
+
+// GetBookCopies retreves all copies of a book
+func (l *LocalStore) GetBookCopies(ctx context.Context, bookID int) ([]*model.BookCopy, error) {
+	l.rmu.RLock()
+	defer l.rmu.RUnlock()
+	if _, ok := l.books[bookID]; !ok {
+		return nil, fmt.Errorf("book %d isn't presents. %w", bookID, model.ErrNotFound)
+	}
+	copies := make([]*model.BookCopy, 0, len(l.bookCopies[bookID]))
+	for _, id := range l.bookCopies[bookID] {
+		copies = append(copies, copyOfBookCopy(l.copies[id]))
+	}
+	return copies, nil
+}
+
+// GetBookCopy retreves a copy by its barcode
+func (l *LocalStore) GetBookCopy(ctx context.Context, barcode string) (*model.BookCopy, error) {
+	l.rmu.RLock()
+	defer l.rmu.RUnlock()
+	id, ok := l.barcodes[barcode]
+	if !ok {
+		return nil, fmt.Errorf("copy with barcode '%s' isn't presents. %w", barcode, model.ErrNotFound)
+	}
+	return copyOfBookCopy(l.copies[id]), nil
+}
+
+// UpdateBookCopy updates the non empty shelf location, condition and status of a copy
+func (l *LocalStore) UpdateBookCopy(ctx context.Context, barcode string, det *model.BookCopy) (*model.BookCopy, error) {
+	l.rmu.Lock()
+	defer l.rmu.Unlock()
+	if err := l.writable(); err != nil {
+		return nil, err
+	}
+	id, ok := l.barcodes[barcode]
+	if !ok {
+		return nil, fmt.Errorf("copy with barcode '%s' isn't presents. %w", barcode, model.ErrNotFound)
+	}
+	bookCopy := l.copies[id]
+	if det.Status != "" && det.Status != bookCopy.Status && (bookCopy.Status == constants.CopyOnLoan || bookCopy.Status == constants.CopyOnHold) {
+		// loaned copies change status only by returning them, held copies by loaning or releasing the hold
+		return nil, fmt.Errorf("copy with barcode '%s' is %s. %w", barcode, bookCopy.Status, model.ErrConflict)
+	}
+	if det.ShelfLocation != "" {
+		bookCopy.ShelfLocation = det.ShelfLocation
+	}
+	if det.Condition != "" {
+		bookCopy.Condition = det.Condition
+	}
+	if det.AcquisitionDate != 0 {
+		bookCopy.AcquisitionDate = det.AcquisitionDate
+	}
+	if det.Status != "" {
+		bookCopy.Status = det.Status
+	}
+	l.refreshCopyCounts(bookCopy.BookID)
+	if err := l.persist([]int{bookCopy.BookID}, nil); err != nil {
+		return nil, err
+	}
+	logger.FromContext(ctx).Infof("Copy %s updated", barcode)
+	return copyOfBookCopy(bookCopy), nil
+}
@@ -0,0 +1,201 @@
+package local
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/test/library-app/internal/constants"
+	"github.com/test/library-app/internal/logger"
+	"github.com/test/library-app/internal/model"
+	"github.com/test/library-app/internal/policy"
+)
+
+// loanFine gives the fine of the loan, nil while not fined, callers must hold the lock
+func (l *LocalStore) loanFine(loanID int) *model.Fine {
+	for _, fine := range l.fines {
+		if fine.LoanID == loanID {
+			return fine
+		}
+	}
+	return nil
+}
+
+// fineCopy copies a fine for the callers to read once the lock is released, accruing updates the fines in place
+func fineCopy(fine *model.Fine) *model.Fine {
+	cp := *fine
+	return &cp
+}
+
+// memberOwes sums the outstanding fines of the member, callers must hold the lock
+func (l *LocalStore) memberOwes(memberID int) int64 {
+	var owed int64
+	for _, fine := range l.fines {
+		if fine.MemberID == memberID {
+			owed += fine.Outstanding()
+		}
+	}
+	return owed
+}
+
+// fineLoan updates the fine of the loan as of now, fining the loan once past the grace period, reports whether the fine changed.
+// Returning finalises the fine so it stops accruing, callers must hold the lock
+func (l *LocalStore) fineLoan(loan *model.LoanDetails, now time.Time, returning bool) bool {
+	fine := l.loanFine(loan.ID)
+	if fine != nil && fine.Status != constants.FineAccruing {
+		// waived while accruing
+		return false
+	}
+	amount := policy.FineAmount(loan.ReturnDate, now)
+	if fine == nil {
+		if amount == 0 {
+			return false
+		}
+		l.lastFineID++
+		fine = &model.Fine{
+			ID:       l.lastFineID,
+			LoanID:   loan.ID,
+			MemberID: loan.MemberID,
+			Title:    loan.Title,
+			Status:   constants.FineAccruing,
+		}
+		l.fines[fine.ID] = fine
+		logger.Infof("Loan %d fined", loan.ID)
+	}
+	// the accrual date moves along with the amount only, the fines capped or within a day stay as they are
+	if amount == fine.AmountInCents && !returning {
+		return false
+	}
+	fine.AmountInCents = amount
+	fine.AccruedAt = now.Unix()
+	if returning {
+		fine.Status = constants.FineUnpaid
+		if fine.Outstanding() == 0 {
+			fine.Status = constants.FinePaid
+			fine.SettledAt = now.Unix()
+		}
+	}
+	return true
+}
+
+// AccrueFines marks the loans not returned by now overdue and accrues their fines, returns the No of overdue loans
+func (l *LocalStore) AccrueFines(ctx context.Context, now time.Time) (int, error) {
+	l.rmu.Lock()
+	defer l.rmu.Unlock()
+	if err := l.writable(); err != nil {
+		return 0, err
+	}
+	overdue := 0
+	// only the loans turning overdue and the fines growing are logged, not every loan of their members
+	loanIDs := make([]int, 0)
+	for _, loan := range l.loans {
+		if loan.Status == constants.Closed || loan.ReturnDate >= now.Unix() {
+			continue
+		}
+		changed := false
+		if loan.Status == constants.Active {
+			old := loanState(loan)
+			loan.Status = constants.Overdue
+			loan.Version++
+			l.recordEvent(ctx, loan, constants.LoanOverdue, old, now)
+			changed = true
+		}
+		if l.fineLoan(loan, now, false) {
+			changed = true
+		}
+		if changed {
+			loanIDs = append(loanIDs, loan.ID)
+		}
+		overdue++
+	}
+	if len(loanIDs) > 0 {
+		if err := l.persistLoans(loanIDs); err != nil {
+			return 0, err
+		}
+	}
+	return overdue, nil
+}
+
+// GetMemberFines retreves the fines of a member in the order fined along with the total owed
+func (l *LocalStore) GetMemberFines(ctx context.Context, memberID int) (*model.MemberFines, error) {
+	l.rmu.RLock()
+	defer l.rmu.RUnlock()
+	if _, ok := l.members[memberID]; !ok {
+		return nil, fmt.Errorf("member %d isn't presents. %w", memberID, model.ErrNotFound)
+	}
+	fines := &model.MemberFines{MemberID: memberID, Fines: make([]*model.Fine, 0)}
+	for _, fine := range l.fines {
+		if fine.MemberID == memberID {
+			fines.Fines = append(fines.Fines, fineCopy(fine))
+			fines.OutstandingInCents += fine.Outstanding()
+		}
+	}
+	sort.Slice(fines.Fines, func(i, j int) bool {
+		return fines.Fines[i].ID < fines.Fines[j].ID
+	})
+	return fines, nil
+}
+
+// openFine finds a fine still open for paying or waiving, callers must hold the lock
+func (l *LocalStore) openFine(fineID int) (*model.Fine, error) {
+	fine, ok := l.fines[fineID]
+	if !ok {
+		return nil, fmt.Errorf("fine %d isn't presents. %w", fineID, model.ErrNotFound)
+	}
+	if fine.Status == constants.FinePaid || fine.Status == constants.FineWaived {
+		return nil, fmt.Errorf("fine %d is %s. %w", fineID, fine.Status, model.ErrConflict)
+	}
+	return fine, nil
+}
+
+// PayFine pays the amount of a fine, the outstanding amount when zero. Refused when paying more than owed
+func (l *LocalStore) PayFine(ctx context.Context, fineID int, amount int64) (*model.Fine, error) {
+	l.rmu.Lock()
+	defer l.rmu.Unlock()
+	if err := l.writable(); err != nil {
+		return nil, err
+	}
+	fine, err := l.openFine(fineID)
+	if err != nil {
+		return nil, err
+	}
+	outstanding := fine.Outstanding()
+	if amount == 0 {
+		amount = outstanding
+	}
+	if amount == 0 || amount > outstanding {
+		return nil, fmt.Errorf("fine %d has %d cents outstanding, can't pay %d. %w", fineID, outstanding, amount, model.ErrConflict)
+	}
+	fine.PaidInCents += amount
+	// accruing fines may grow till returned
+	if fine.Status == constants.FineUnpaid && fine.Outstanding() == 0 {
+		fine.Status = constants.FinePaid
+		fine.SettledAt = time.Now().Unix()
+	}
+	if err := l.persist(nil, []int{fine.MemberID}); err != nil {
+		return nil, err
+	}
+	logger.FromContext(ctx).Infof("%d cents paid for fine %d", amount, fineID)
+	return fineCopy(fine), nil
+}
+
+// WaiveFine waives an accruing or unpaid fine, nothing more is owed for it
+func (l *LocalStore) WaiveFine(ctx context.Context, fineID int) (*model.Fine, error) {
+	l.rmu.Lock()
+	defer l.rmu.Unlock()
+	if err := l.writable(); err != nil {
+		return nil, err
+	}
+	fine, err := l.openFine(fineID)
+	if err != nil {
+		return nil, err
+	}
+	fine.Status = constants.FineWaived
+	fine.SettledAt = time.Now().Unix()
+	if err := l.persist(nil, []int{fine.MemberID}); err != nil {
+		return nil, err
+	}
+	logger.FromContext(ctx).Infof("Fine %d waived", fineID)
+	return fineCopy(fine), nil
+}
@@ -0,0 +1,46 @@
+package local
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/test/library-app/internal/audit"
+	"github.com/test/library-app/internal/model"
+)
+
+// loanState snapshots the values of the loan changed by its transitions
+func loanState(loan *model.LoanDetails) *model.LoanState {
+	return &model.LoanState{
+		Status:     loan.Status,
+		ReturnDate: loan.ReturnDate,
+		Extensions: loan.Extensions,
+	}
+}
+
+// recordEvent appends the transition of the loan from the old state made by the actor of the context,
+// callers must hold the lock
+func (l *LocalStore) recordEvent(ctx context.Context, loan *model.LoanDetails, eventType string, old *model.LoanState, now time.Time) {
+	l.lastEventID++
+	l.events[loan.ID] = append(l.events[loan.ID], &model.LoanEvent{
+		ID:     l.lastEventID,
+		LoanID: loan.ID,
+		Type:   eventType,
+		Actor:  audit.Actor(ctx),
+		At:     now.Unix(),
+		Old:    old,
+		New:    loanState(loan),
+	})
+}
+
+// GetLoanHistory retreves the transitions of a loan in the order made
+func (l *LocalStore) GetLoanHistory(ctx context.Context, loanID int) ([]*model.LoanEvent, error) {
+	l.rmu.RLock()
+	defer l.rmu.RUnlock()
+	if _, ok := l.loans[loanID]; !ok {
+		return nil, fmt.Errorf("loan %d isn't presents. %w", loanID, model.ErrNotFound)
+	}
+	events := make([]*model.LoanEvent, len(l.events[loanID]))
+	copy(events, l.events[loanID])
+	return events, nil
+}
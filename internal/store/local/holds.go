@@ -0,0 +1,177 @@
+package local
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/test/library-app/internal/config"
+	"github.com/test/library-app/internal/constants"
+	"github.com/test/library-app/internal/logger"
+	"github.com/test/library-app/internal/model"
+	"github.com/test/library-app/internal/policy"
+)
+
+// isActiveHold reports whether the hold still waits for a copy or has one set aside
+func isActiveHold(hold *model.Hold) bool {
+	return hold.Status == constants.HoldWaiting || hold.Status == constants.HoldReady
+}
+
+// holdCopy copies a hold for the callers to read once the lock is released, the jobs keep updating the holds in place
+func holdCopy(hold *model.Hold) *model.Hold {
+	cp := *hold
+	return &cp
+}
+
+// nextHold gives the longest waiting hold of the book, callers must hold the lock
+func (l *LocalStore) nextHold(bookID int) *model.Hold {
+	var next *model.Hold
+	for _, hold := range l.holds {
+		// hold IDs are handed out in the order placed
+		if hold.BookID == bookID && hold.Status == constants.HoldWaiting && (next == nil || hold.ID < next.ID) {
+			next = hold
+		}
+	}
+	return next
+}
+
+// readyHold gives the hold of the member with a copy of the book set aside, callers must hold the lock
+func (l *LocalStore) readyHold(memberID, bookID int) *model.Hold {
+	for _, hold := range l.holds {
+		if hold.MemberID == memberID && hold.BookID == bookID && hold.Status == constants.HoldReady {
+			return hold
+		}
+	}
+	return nil
+}
+
+// shelveCopy sets a copy coming back aside for the next waiting hold of its book,
+// or puts it on the shelf when none waits, callers must hold the lock
+func (l *LocalStore) shelveCopy(bookCopy *model.BookCopy, now time.Time) {
+	if hold := l.nextHold(bookCopy.BookID); hold != nil {
+		hold.Status = constants.HoldReady
+		hold.CopyID = bookCopy.ID
+		hold.Barcode = bookCopy.Barcode
+		hold.ReadyAt = now.Unix()
+		hold.ExpiresAt = now.Add(time.Duration(config.PolicyConfig.HoldPickupInDays) * 24 * time.Hour).Unix()
+		bookCopy.Status = constants.CopyOnHold
+		logger.Infof("Copy %s set aside for hold %d", bookCopy.Barcode, hold.ID)
+	} else {
+		bookCopy.Status = constants.CopyAvailable
+	}
+	l.refreshCopyCounts(bookCopy.BookID)
+}
+
+// closeHold closes an active hold with the status, passing its copy on when set aside, callers must hold the lock
+func (l *LocalStore) closeHold(hold *model.Hold, status string, now time.Time) {
+	ready := hold.Status == constants.HoldReady
+	hold.Status = status
+	if bookCopy, ok := l.copies[hold.CopyID]; ok && ready {
+		l.shelveCopy(bookCopy, now)
+	}
+}
+
+// AddHold places a hold at the end of the queue of a book with no copy available
+func (l *LocalStore) AddHold(ctx context.Context, det *model.Hold) (int, error) {
+	l.rmu.Lock()
+	defer l.rmu.Unlock()
+	if err := l.writable(); err != nil {
+		return 0, err
+	}
+	member, ok := l.members[det.MemberID]
+	if !ok {
+		return 0, fmt.Errorf("member %d isn't presents. %w", det.MemberID, model.ErrNotFound)
+	}
+	book, err := l.bookForLoan(&model.LoanDetails{BookID: det.BookID, Title: det.Title})
+	if err != nil {
+		return 0, err
+	}
+	det.BookID = book.ID
+	det.Title = book.Title
+	if err := policy.CheckHold(l.memberStanding(member), det); err != nil {
+		return 0, err
+	}
+	if book.AvailableCopies > 0 {
+		return 0, fmt.Errorf("book %d has %d copies available to borrow. %w", book.ID, book.AvailableCopies, model.ErrConflict)
+	}
+	l.lastHoldID++
+	det.ID = l.lastHoldID
+	det.Status = constants.HoldWaiting
+	det.PlacedAt = time.Now().Unix()
+	l.holds[det.ID] = det
+	if err := l.persist([]int{det.BookID}, nil); err != nil {
+		return 0, err
+	}
+	logger.FromContext(ctx).Infof("Hold %d placed on book %d by member %d", det.ID, det.BookID, det.MemberID)
+	return det.ID, nil
+}
+
+// GetMemberHolds retreves the holds of a member in the order placed
+func (l *LocalStore) GetMemberHolds(ctx context.Context, memberID int) ([]*model.Hold, error) {
+	l.rmu.RLock()
+	defer l.rmu.RUnlock()
+	if _, ok := l.members[memberID]; !ok {
+		return nil, fmt.Errorf("member %d isn't presents. %w", memberID, model.ErrNotFound)
+	}
+	holds := make([]*model.Hold, 0)
+	for _, hold := range l.holds {
+		if hold.MemberID == memberID {
+			holds = append(holds, holdCopy(hold))
+		}
+	}
+	sort.Slice(holds, func(i, j int) bool {
+		return holds[i].ID < holds[j].ID
+	})
+	return holds, nil
+}
+
+// CancelHold cancels a waiting or ready hold, the copy set aside passes on to the next hold
+func (l *LocalStore) CancelHold(ctx context.Context, holdID int) (*model.Hold, error) {
+	l.rmu.Lock()
+	defer l.rmu.Unlock()
+	if err := l.writable(); err != nil {
+		return nil, err
+	}
+	hold, ok := l.holds[holdID]
+	if !ok {
+		return nil, fmt.Errorf("hold %d isn't presents. %w", holdID, model.ErrNotFound)
+	}
+	if !isActiveHold(hold) {
+		return nil, fmt.Errorf("hold %d is %s. %w", holdID, hold.Status, model.ErrConflict)
+	}
+	l.closeHold(hold, constants.HoldCancelled, time.Now())
+	if err := l.persist([]int{hold.BookID}, nil); err != nil {
+		return nil, err
+	}
+	logger.FromContext(ctx).Infof("Hold %d cancelled", holdID)
+	return holdCopy(hold), nil
+}
+
+// ExpireHolds expires the ready holds not picked up by now, their copies pass on to the next holds
+func (l *LocalStore) ExpireHolds(ctx context.Context, now time.Time) (int, error) {
+	l.rmu.Lock()
+	defer l.rmu.Unlock()
+	if err := l.writable(); err != nil {
+		return 0, err
+	}
+	// collecting first as the copies passed on make other holds ready
+	expired := make([]*model.Hold, 0)
+	for _, hold := range l.holds {
+		if hold.Status == constants.HoldReady && hold.ExpiresAt <= now.Unix() {
+			expired = append(expired, hold)
+		}
+	}
+	bookIDs := make([]int, 0, len(expired))
+	for _, hold := range expired {
+		l.closeHold(hold, constants.HoldExpired, now)
+		bookIDs = append(bookIDs, hold.BookID)
+		logger.FromContext(ctx).Infof("Hold %d expired", hold.ID)
+	}
+	if len(expired) > 0 {
+		if err := l.persist(bookIDs, nil); err != nil {
+			return 0, err
+		}
+	}
+	return len(expired), nil
+}
@@ -0,0 +1,73 @@
+package local
+
+import (
+	"context"
+	"time"
+
+	"github.com/test/library-app/internal/model"
+)
+
+// ReserveIdempotencyKey reserves the key of the record for its request till the record expires, nil is returned once reserved.
+// The record of the key is returned instead while the key is reserved or completed and not expired by now
+func (l *LocalStore) ReserveIdempotencyKey(ctx context.Context, rec *model.IdempotencyRecord, now time.Time) (*model.IdempotencyRecord, error) {
+	l.rmu.Lock()
+	defer l.rmu.Unlock()
+	if err := l.writable(); err != nil {
+		return nil, err
+	}
+	if stored, ok := l.idempotency[rec.Key]; ok && stored.ExpiresAt > now.Unix() {
+		// copying as the stored record is completed under the lock
+		found := *stored
+		return &found, nil
+	}
+	reserved := *rec
+	reserved.StatusCode = 0
+	// reservations aren't logged, the request in progress dies with a restart and its retries go through
+	l.idempotency[rec.Key] = &reserved
+	return nil, nil
+}
+
+// CompleteIdempotencyKey stores the response of the request the key of the record is reserved for
+func (l *LocalStore) CompleteIdempotencyKey(ctx context.Context, rec *model.IdempotencyRecord) error {
+	l.rmu.Lock()
+	defer l.rmu.Unlock()
+	if err := l.writable(); err != nil {
+		return err
+	}
+	completed := *rec
+	l.idempotency[rec.Key] = &completed
+	return l.persistKeys([]string{rec.Key})
+}
+
+// ReleaseIdempotencyKey drops the reservation of a key so the request can be retried
+func (l *LocalStore) ReleaseIdempotencyKey(ctx context.Context, key string) error {
+	l.rmu.Lock()
+	defer l.rmu.Unlock()
+	// completed keys are kept till they expire
+	if stored, ok := l.idempotency[key]; ok && stored.StatusCode == 0 {
+		delete(l.idempotency, key)
+	}
+	return nil
+}
+
+// ExpireIdempotencyKeys drops the keys expired by now, returns the No of keys dropped
+func (l *LocalStore) ExpireIdempotencyKeys(ctx context.Context, now time.Time) (int, error) {
+	l.rmu.Lock()
+	defer l.rmu.Unlock()
+	if err := l.writable(); err != nil {
+		return 0, err
+	}
+	expired := make([]string, 0)
+	for key, stored := range l.idempotency {
+		if stored.ExpiresAt <= now.Unix() {
+			delete(l.idempotency, key)
+			expired = append(expired, key)
+		}
+	}
+	if len(expired) > 0 {
+		if err := l.persistKeys(expired); err != nil {
+			return 0, err
+		}
+	}
+	return len(expired), nil
+}
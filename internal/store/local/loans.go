@@ -0,0 +1,39 @@
+package local
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/test/library-app/internal/model"
+)
+
+// loanCopy copies a loan for the callers to read once the lock is released, the jobs keep updating the loans in place
+func loanCopy(loan *model.LoanDetails) *model.LoanDetails {
+	cp := *loan
+	return &cp
+}
+
+// GetLoan retreves a loan by its ID
+func (l *LocalStore) GetLoan(ctx context.Context, loanID int) (*model.LoanDetails, error) {
+	l.rmu.RLock()
+	defer l.rmu.RUnlock()
+	loan, ok := l.loans[loanID]
+	if !ok {
+		return nil, fmt.Errorf("loan %d isn't presents. %w", loanID, model.ErrNotFound)
+	}
+	return loanCopy(loan), nil
+}
+
+// GetLoansByBorrower retreves the active and past loans of a member in the order loaned
+func (l *LocalStore) GetLoansByBorrower(ctx context.Context, memberID int) ([]*model.LoanDetails, error) {
+	l.rmu.RLock()
+	defer l.rmu.RUnlock()
+	if _, ok := l.members[memberID]; !ok {
+		return nil, fmt.Errorf("member %d isn't presents. %w", memberID, model.ErrNotFound)
+	}
+	loans := make([]*model.LoanDetails, 0, len(l.memberLoans[memberID]))
+	for _, loanID := range l.memberLoans[memberID] {
+		loans = append(loans, loanCopy(l.loans[loanID]))
+	}
+	return loans, nil
+}
@@ -2,10 +2,18 @@ package localtest
 
 import (
 	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/test/library-app/internal/audit"
+	"github.com/test/library-app/internal/config"
+	"github.com/test/library-app/internal/constants"
 	"github.com/test/library-app/internal/model"
 	"github.com/test/library-app/internal/store/local"
 )
@@ -16,6 +24,8 @@ var ctx context.Context
 
 func TestMain(m *testing.M) {
 	ctx = context.Background()
+	// loading configuration for the borrowing policy
+	config.LoadConfig()
 	localStore, err = local.InitLocalStore()
 	m.Run()
 }
@@ -30,10 +40,50 @@ func TestGetAllBookDetails(t *testing.T) {
 	assert.Nil(t, err)
 	assert.NotNil(t, store)
 
-	books, err := store.GetAllBookDetails(ctx)
+	page, err := store.GetAllBookDetails(ctx, &model.BookQuery{})
 	assert.Nil(t, err)
-	assert.NotNil(t, store)
-	assert.GreaterOrEqual(t, len(books), 0)
+	assert.Len(t, page.Books, 5)
+	assert.Empty(t, page.NextCursor)
+
+	// paging through titles
+	titles := []string{}
+	query := &model.BookQuery{PageQuery: model.PageQuery{Limit: 2, SortBy: constants.SortByTitle}}
+	for {
+		page, err = store.GetAllBookDetails(ctx, query)
+		assert.Nil(t, err)
+		for _, book := range page.Books {
+			titles = append(titles, book.Title)
+		}
+		if page.NextCursor == "" {
+			break
+		}
+		query.After, err = model.DecodeCursor(page.NextCursor)
+		assert.Nil(t, err)
+	}
+	assert.Equal(t, []string{"Alchemist", "Animal Farm", "Atomic Habbits", "Mocking Bird", "Sapiens"}, titles)
+
+	// newest first
+	query = &model.BookQuery{PageQuery: model.PageQuery{Limit: 3, SortBy: constants.SortByPublicationYear, Order: constants.OrderDesc}}
+	page, err = store.GetAllBookDetails(ctx, query)
+	assert.Nil(t, err)
+	assert.Equal(t, 2018, page.Books[0].PublicationYear)
+	assert.NotEmpty(t, page.NextCursor)
+	query.After, err = model.DecodeCursor(page.NextCursor)
+	assert.Nil(t, err)
+	page, err = store.GetAllBookDetails(ctx, query)
+	assert.Nil(t, err)
+	assert.Len(t, page.Books, 2)
+	assert.Equal(t, 1945, page.Books[1].PublicationYear)
+
+	// filters
+	page, err = store.GetAllBookDetails(ctx, &model.BookQuery{Author: "ORWELL"})
+	assert.Nil(t, err)
+	assert.Len(t, page.Books, 1)
+	assert.Equal(t, "Animal Farm", page.Books[0].Title)
+	available := false
+	page, err = store.GetAllBookDetails(ctx, &model.BookQuery{Available: &available})
+	assert.Nil(t, err)
+	assert.Empty(t, page.Books)
 }
 
 func TestGetBookDetails(t *testing.T) {
@@ -51,22 +101,22 @@ func TestGetBookDetails(t *testing.T) {
 func TestAddLoan(t *testing.T) {
 	// success case
 	loanID, err := localStore.AddLoan(ctx, &model.LoanDetails{
-		ID:             1,
-		NameOfBorrower: "test_user",
-		Title:          "alchemist",
-		LoanDate:       time.Now().Unix(),
-		ReturnDate:     time.Now().Add(24 * time.Hour).Unix(),
+		ID:         1,
+		MemberID:   1,
+		Title:      "alchemist",
+		LoanDate:   time.Now().Unix(),
+		ReturnDate: time.Now().Add(24 * time.Hour).Unix(),
 	})
 	assert.Nil(t, err)
 	assert.GreaterOrEqual(t, loanID, 0)
 
 	// failure case
 	loanID, err = localStore.AddLoan(ctx, &model.LoanDetails{
-		ID:             2,
-		NameOfBorrower: "test_user",
-		Title:          "Book_100",
-		LoanDate:       time.Now().Unix(),
-		ReturnDate:     time.Now().Add(24 * time.Hour).Unix(),
+		ID:         2,
+		MemberID:   1,
+		Title:      "Book_100",
+		LoanDate:   time.Now().Unix(),
+		ReturnDate: time.Now().Add(24 * time.Hour).Unix(),
 	})
 
 	assert.NotNil(t, err)
@@ -75,28 +125,935 @@ func TestAddLoan(t *testing.T) {
 
 func TestExtendLoan(t *testing.T) {
 	// success case
-	det, err := localStore.ExtendLoan(ctx, 1)
+	det, err := localStore.ExtendLoan(ctx, 1, 0)
 	assert.Nil(t, err)
 	assert.NotNil(t, det)
 
 	// failure case
-	det, err = localStore.ExtendLoan(ctx, 10)
+	det, err = localStore.ExtendLoan(ctx, 10, 0)
 	assert.NotNil(t, err)
 	assert.Nil(t, det)
 }
 
 func TestReturnBook(t *testing.T) {
 	// success case
-	loan, err := localStore.ReturnBook(ctx, 1)
+	loan, err := localStore.ReturnBook(ctx, 1, 0)
 	assert.Nil(t, err)
 	assert.NotNil(t, loan)
 
 	// failure case
-	loan, err = localStore.ReturnBook(ctx, 10)
+	loan, err = localStore.ReturnBook(ctx, 10, 0)
 	assert.NotNil(t, err)
 	assert.Nil(t, loan)
 }
 
+func TestAddBook(t *testing.T) {
+	// success case
+	bookID, err := localStore.AddBook(ctx, &model.BookDetails{
+		ISBN:        "9780441172719",
+		Title:       "Dune",
+		Authors:     []string{"Frank Herbert"},
+		TotalCopies: 2,
+	})
+	assert.Nil(t, err)
+	assert.Greater(t, bookID, 0)
+
+	// different book sharing the title
+	otherID, err := localStore.AddBook(ctx, &model.BookDetails{
+		Title:       "dune",
+		TotalCopies: 1,
+	})
+	assert.Nil(t, err)
+	assert.NotEqual(t, bookID, otherID)
+
+	// oldest book is returned by title
+	book, err := localStore.GetBookDetails(ctx, "DUNE")
+	assert.Nil(t, err)
+	assert.Equal(t, bookID, book.ID)
+
+	// loaning by an ambiguous title is refused
+	_, err = localStore.AddLoan(ctx, &model.LoanDetails{MemberID: 1, Title: "dune"})
+	assert.ErrorIs(t, err, model.ErrConflict)
+	_, err = localStore.AddLoan(ctx, &model.LoanDetails{MemberID: 1, BookID: otherID})
+	assert.Nil(t, err)
+
+	// failure case
+	bookID, err = localStore.AddBook(ctx, &model.BookDetails{
+		ISBN:        "9780441172719",
+		Title:       "Dune (Reprint)",
+		TotalCopies: 1,
+	})
+	assert.ErrorIs(t, err, model.ErrAlreadyExists)
+	assert.Equal(t, 0, bookID)
+}
+
+func TestGetBookDetailsByID(t *testing.T) {
+	// success case
+	book, err := localStore.GetBookDetailsByID(ctx, 1)
+	assert.Nil(t, err)
+	assert.Equal(t, "Alchemist", book.Title)
+	assert.Equal(t, []string{"Paulo Coelho"}, book.Authors)
+
+	// failure case
+	book, err = localStore.GetBookDetailsByID(ctx, 1000)
+	assert.ErrorIs(t, err, model.ErrNotFound)
+	assert.Nil(t, book)
+}
+
+func TestUpdateBook(t *testing.T) {
+	bookID, err := localStore.AddBook(ctx, &model.BookDetails{Title: "Emma", TotalCopies: 1})
+	assert.Nil(t, err)
+
+	// success case
+	book, err := localStore.UpdateBook(ctx, bookID, 0, &model.BookDetails{Title: "Emma (Annotated)", TotalCopies: 3})
+	assert.Nil(t, err)
+	assert.Equal(t, "Emma (Annotated)", book.Title)
+	book, err = localStore.GetBookDetails(ctx, "emma (annotated)")
+	assert.Nil(t, err)
+	// copy counts are derived from the copies
+	assert.Equal(t, 1, book.AvailableCopies)
+	assert.Equal(t, 1, book.TotalCopies)
+
+	// failure case
+	book, err = localStore.UpdateBook(ctx, 1000, 0, &model.BookDetails{Title: "Nothing"})
+	assert.ErrorIs(t, err, model.ErrNotFound)
+	assert.Nil(t, book)
+}
+
+func TestVersions(t *testing.T) {
+	bookID, err := localStore.AddBook(ctx, &model.BookDetails{Title: "Middlemarch", TotalCopies: 1})
+	assert.Nil(t, err)
+
+	// success case: each update moves the book on to the next version
+	book, err := localStore.UpdateBook(ctx, bookID, 1, &model.BookDetails{Title: "Middlemarch"})
+	assert.Nil(t, err)
+	assert.Equal(t, 2, book.Version)
+	book, err = localStore.UpdateBookCopies(ctx, bookID, 2, 1)
+	assert.Nil(t, err)
+	assert.Equal(t, 3, book.Version)
+
+	// failure case: updating a stale version
+	_, err = localStore.UpdateBook(ctx, bookID, 2, &model.BookDetails{Title: "Middlemarch (Annotated)"})
+	assert.ErrorIs(t, err, model.ErrPreconditionFailed)
+	_, err = localStore.UpdateBookCopies(ctx, bookID, 2, 1)
+	assert.ErrorIs(t, err, model.ErrPreconditionFailed)
+	book, err = localStore.GetBookDetailsByID(ctx, bookID)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, book.TotalCopies)
+	book, err = localStore.GetBookDetailsByID(ctx, bookID)
+	assert.Nil(t, err)
+	assert.Equal(t, "Middlemarch", book.Title)
+
+	loanID, err := localStore.AddLoan(ctx, &model.LoanDetails{MemberID: 1, BookID: bookID, LoanDate: time.Now().Unix(), Status: constants.Active})
+	assert.Nil(t, err)
+	loan, err := localStore.ExtendLoan(ctx, loanID, 1)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, loan.Version)
+	_, err = localStore.ReturnBook(ctx, loanID, 1)
+	assert.ErrorIs(t, err, model.ErrPreconditionFailed)
+	loan, err = localStore.ReturnBook(ctx, loanID, 2)
+	assert.Nil(t, err)
+	assert.Equal(t, 3, loan.Version)
+}
+
+func TestUpdateBookCopies(t *testing.T) {
+	bookID, err := localStore.AddBook(ctx, &model.BookDetails{Title: "Ulysses", TotalCopies: 1})
+	assert.Nil(t, err)
+
+	// success case
+	book, err := localStore.UpdateBookCopies(ctx, bookID, 0, 2)
+	assert.Nil(t, err)
+	assert.Equal(t, 3, book.AvailableCopies)
+	book, err = localStore.UpdateBookCopies(ctx, bookID, 0, -1)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, book.AvailableCopies)
+	assert.Equal(t, 2, book.TotalCopies)
+
+	// failure case
+	book, err = localStore.UpdateBookCopies(ctx, bookID, 0, -4)
+	assert.ErrorIs(t, err, model.ErrConflict)
+	assert.Nil(t, book)
+}
+
+func TestDeleteBook(t *testing.T) {
+	bookID, err := localStore.AddBook(ctx, &model.BookDetails{Title: "Hamlet", TotalCopies: 1})
+	assert.Nil(t, err)
+	_, err = localStore.AddLoan(ctx, &model.LoanDetails{
+		MemberID:   1,
+		Title:      "hamlet",
+		ReturnDate: time.Now().Add(24 * time.Hour).Unix(),
+		Status:     constants.Active,
+	})
+	assert.Nil(t, err)
+
+	// failure case: active loan
+	err = localStore.DeleteBook(ctx, bookID)
+	assert.ErrorIs(t, err, model.ErrConflict)
+
+	// success case
+	bookID, err = localStore.AddBook(ctx, &model.BookDetails{Title: "Macbeth", TotalCopies: 1})
+	assert.Nil(t, err)
+	err = localStore.DeleteBook(ctx, bookID)
+	assert.Nil(t, err)
+	_, err = localStore.GetBookDetails(ctx, "macbeth")
+	assert.ErrorIs(t, err, model.ErrNotFound)
+}
+
+func TestBookCopies(t *testing.T) {
+	bookID, err := localStore.AddBook(ctx, &model.BookDetails{Title: "Walden", TotalCopies: 1})
+	assert.Nil(t, err)
+
+	// success case
+	copyID, err := localStore.AddBookCopy(ctx, &model.BookCopy{BookID: bookID, Barcode: "WALDEN-RARE", ShelfLocation: "RARE-1"})
+	assert.Nil(t, err)
+	assert.Greater(t, copyID, 0)
+	copies, err := localStore.GetBookCopies(ctx, bookID)
+	assert.Nil(t, err)
+	assert.Len(t, copies, 2)
+	assert.Equal(t, model.CopyBarcode(bookID, 1), copies[0].Barcode)
+	assert.Equal(t, constants.DefaultCondition, copies[1].Condition)
+
+	// failure case: barcode taken
+	_, err = localStore.AddBookCopy(ctx, &model.BookCopy{BookID: bookID, Barcode: "WALDEN-RARE"})
+	assert.ErrorIs(t, err, model.ErrAlreadyExists)
+
+	// loaning a specific copy
+	_, err = localStore.AddLoan(ctx, &model.LoanDetails{MemberID: 1, Barcode: "WALDEN-RARE", ReturnDate: time.Now().Add(24 * time.Hour).Unix(), Status: constants.Active})
+	assert.Nil(t, err)
+	bookCopy, err := localStore.GetBookCopy(ctx, "WALDEN-RARE")
+	assert.Nil(t, err)
+	assert.Equal(t, constants.CopyOnLoan, bookCopy.Status)
+	book, err := localStore.GetBookDetailsByID(ctx, bookID)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, book.AvailableCopies)
+	assert.Equal(t, 2, book.TotalCopies)
+
+	// failure case: loaned copy can't be loaned or repaired
+	memberID, err := localStore.AddMember(ctx, &model.Member{Name: "Henry"})
+	assert.Nil(t, err)
+	_, err = localStore.AddLoan(ctx, &model.LoanDetails{MemberID: memberID, Barcode: "WALDEN-RARE"})
+	assert.ErrorIs(t, err, model.ErrConflict)
+	_, err = localStore.UpdateBookCopy(ctx, "WALDEN-RARE", &model.BookCopy{Status: constants.CopyInRepair})
+	assert.ErrorIs(t, err, model.ErrConflict)
+
+	// success case: shelved copy sent to repair
+	bookCopy, err = localStore.UpdateBookCopy(ctx, model.CopyBarcode(bookID, 1), &model.BookCopy{Status: constants.CopyInRepair, Condition: constants.ConditionDamaged})
+	assert.Nil(t, err)
+	assert.Equal(t, constants.ConditionDamaged, bookCopy.Condition)
+	book, err = localStore.GetBookDetailsByID(ctx, bookID)
+	assert.Nil(t, err)
+	assert.Equal(t, 0, book.AvailableCopies)
+
+	// failure case
+	_, err = localStore.GetBookCopy(ctx, "NOPE")
+	assert.ErrorIs(t, err, model.ErrNotFound)
+}
+
+func TestSearchBooks(t *testing.T) {
+	// success case
+	results, err := localStore.SearchBooks(ctx, "coelho", 10)
+	assert.Nil(t, err)
+	assert.Len(t, results, 1)
+	assert.Equal(t, "Alchemist", results[0].Book.Title)
+	assert.Equal(t, "Paulo <mark>Coelho</mark>", results[0].Highlights[constants.SearchFieldAuthors])
+
+	// title matches rank above subject matches
+	bookID, err := localStore.AddBook(ctx, &model.BookDetails{Title: "Satire", Subjects: []string{"essays"}, TotalCopies: 1})
+	assert.Nil(t, err)
+	results, err = localStore.SearchBooks(ctx, "satire", 10)
+	assert.Nil(t, err)
+	assert.Len(t, results, 2)
+	assert.Equal(t, bookID, results[0].Book.ID)
+	assert.Equal(t, "Animal Farm", results[1].Book.Title)
+
+	// updates are re-indexed
+	_, err = localStore.UpdateBook(ctx, bookID, 0, &model.BookDetails{Title: "Essays"})
+	assert.Nil(t, err)
+	results, err = localStore.SearchBooks(ctx, "satire", 10)
+	assert.Nil(t, err)
+	assert.Len(t, results, 1)
+
+	// failure case: every word must match
+	results, err = localStore.SearchBooks(ctx, "coelho orwell", 10)
+	assert.Nil(t, err)
+	assert.Empty(t, results)
+}
+
+func TestMembers(t *testing.T) {
+	// success case
+	memberID, err := localStore.AddMember(ctx, &model.Member{Name: "Ann", Email: "ann@example.com"})
+	assert.Nil(t, err)
+	member, err := localStore.GetMember(ctx, memberID)
+	assert.Nil(t, err)
+	assert.Equal(t, model.CardNumber(memberID), member.CardNumber)
+	assert.Equal(t, constants.DefaultTier, member.Tier)
+	assert.Equal(t, constants.MemberActive, member.Status)
+	page, err := localStore.GetAllMembers(ctx, &model.MemberQuery{Name: "ANN"})
+	assert.Nil(t, err)
+	assert.Len(t, page.Members, 1)
+
+	// failure case: emails are unique ignoring the case
+	_, err = localStore.AddMember(ctx, &model.Member{Name: "Ann", Email: "ANN@example.com"})
+	assert.ErrorIs(t, err, model.ErrAlreadyExists)
+
+	// suspended members don't borrow
+	_, err = localStore.UpdateMember(ctx, memberID, &model.Member{Status: constants.MemberSuspended})
+	assert.Nil(t, err)
+	_, err = localStore.AddLoan(ctx, &model.LoanDetails{MemberID: memberID, Title: "sapiens", Status: constants.Active})
+	assert.ErrorIs(t, err, model.ErrNotAllowed)
+	_, err = localStore.AddLoan(ctx, &model.LoanDetails{MemberID: 1000, Title: "sapiens", Status: constants.Active})
+	assert.ErrorIs(t, err, model.ErrNotFound)
+
+	// members with active loans aren't deleted
+	member, err = localStore.UpdateMember(ctx, memberID, &model.Member{Status: constants.MemberActive})
+	assert.Nil(t, err)
+	assert.Equal(t, "ann@example.com", member.Email)
+	loanID, err := localStore.AddLoan(ctx, &model.LoanDetails{MemberID: memberID, Title: "sapiens", Status: constants.Active})
+	assert.Nil(t, err)
+	loan, err := localStore.ReturnBook(ctx, loanID, 0)
+	assert.Nil(t, err)
+	assert.Equal(t, "Ann", loan.NameOfBorrower)
+	loanID, err = localStore.AddLoan(ctx, &model.LoanDetails{MemberID: memberID, Title: "sapiens", Status: constants.Active})
+	assert.Nil(t, err)
+	err = localStore.DeleteMember(ctx, memberID)
+	assert.ErrorIs(t, err, model.ErrConflict)
+	_, err = localStore.ReturnBook(ctx, loanID, 0)
+	assert.Nil(t, err)
+	err = localStore.DeleteMember(ctx, memberID)
+	assert.Nil(t, err)
+	_, err = localStore.GetMember(ctx, memberID)
+	assert.ErrorIs(t, err, model.ErrNotFound)
+}
+
+func TestGetAllLoans(t *testing.T) {
+	store, err := local.InitLocalStore()
+	assert.Nil(t, err)
+	memberIDs := map[string]int{}
+	for _, name := range []string{"john", "jane", "adam"} {
+		memberIDs[name], err = store.AddMember(ctx, &model.Member{Name: name})
+		assert.Nil(t, err)
+	}
+	_, err = store.AddLoan(ctx, &model.LoanDetails{MemberID: memberIDs["john"], Title: "alchemist", ReturnDate: time.Now().Add(-time.Hour).Unix(), Status: constants.Active})
+	assert.Nil(t, err)
+	loanID, err := store.AddLoan(ctx, &model.LoanDetails{MemberID: memberIDs["jane"], Title: "sapiens", ReturnDate: time.Now().Add(time.Hour).Unix(), Status: constants.Active})
+	assert.Nil(t, err)
+	_, err = store.AddLoan(ctx, &model.LoanDetails{MemberID: memberIDs["adam"], Title: "sapiens", ReturnDate: time.Now().Add(time.Hour).Unix(), Status: constants.Active})
+	assert.Nil(t, err)
+	_, err = store.ReturnBook(ctx, loanID, 0)
+	assert.Nil(t, err)
+
+	page, err := store.GetAllLoans(ctx, &model.LoanQuery{PageQuery: model.PageQuery{SortBy: constants.SortByBorrower}})
+	assert.Nil(t, err)
+	assert.Len(t, page.Loans, 3)
+	assert.Equal(t, "adam", page.Loans[0].NameOfBorrower)
+
+	page, err = store.GetAllLoans(ctx, &model.LoanQuery{Status: constants.Active, Title: "SAP"})
+	assert.Nil(t, err)
+	assert.Len(t, page.Loans, 1)
+	assert.Equal(t, "adam", page.Loans[0].NameOfBorrower)
+
+	page, err = store.GetAllLoans(ctx, &model.LoanQuery{Borrower: "JOHN"})
+	assert.Nil(t, err)
+	assert.Len(t, page.Loans, 1)
+
+	page, err = store.GetAllLoans(ctx, &model.LoanQuery{MemberID: memberIDs["jane"]})
+	assert.Nil(t, err)
+	assert.Len(t, page.Loans, 1)
+
+	page, err = store.GetAllLoans(ctx, &model.LoanQuery{Overdue: true})
+	assert.Nil(t, err)
+	assert.Len(t, page.Loans, 1)
+	assert.Equal(t, "john", page.Loans[0].NameOfBorrower)
+
+	page, err = store.GetAllLoans(ctx, &model.LoanQuery{DueFrom: time.Now().Unix()})
+	assert.Nil(t, err)
+	assert.Len(t, page.Loans, 2)
+}
+
+func TestLoanTerms(t *testing.T) {
+	config.PolicyConfig.LoanPeriodInDaysByCategory = map[string]int{"reference": 7}
+	config.PolicyConfig.MaxExtensionsByCategory = map[string]int{"reference": 1}
+	defer func() {
+		config.PolicyConfig.LoanPeriodInDaysByCategory = nil
+		config.PolicyConfig.MaxExtensionsByCategory = nil
+	}()
+	_, err := localStore.AddBook(ctx, &model.BookDetails{Title: "Atlas", Category: "reference", TotalCopies: 1})
+	assert.Nil(t, err)
+	loanDate := time.Now().Unix()
+	loanID, err := localStore.AddLoan(ctx, &model.LoanDetails{MemberID: 1, Title: "atlas", LoanDate: loanDate, Status: constants.Active})
+	assert.Nil(t, err)
+
+	// success case
+	loan, err := localStore.ExtendLoan(ctx, loanID, 0)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, loan.Extensions)
+	assert.Equal(t, time.Unix(loanDate, 0).Add(time.Duration(7+config.PolicyConfig.ExtensionInDays)*24*time.Hour).Unix(), loan.ReturnDate)
+
+	// failure case: extended the most times
+	_, err = localStore.ExtendLoan(ctx, loanID, 0)
+	assert.ErrorIs(t, err, model.ErrNotAllowed)
+	_, err = localStore.ReturnBook(ctx, loanID, 0)
+	assert.Nil(t, err)
+}
+
+func TestHolds(t *testing.T) {
+	store, err := local.InitLocalStore()
+	assert.Nil(t, err)
+	bookID, err := store.AddBook(ctx, &model.BookDetails{Title: "Beloved", TotalCopies: 1})
+	assert.Nil(t, err)
+	memberIDs := map[string]int{}
+	for _, name := range []string{"john", "jane", "adam"} {
+		memberIDs[name], err = store.AddMember(ctx, &model.Member{Name: name})
+		assert.Nil(t, err)
+	}
+
+	// failure case: a copy is on the shelf
+	_, err = store.AddHold(ctx, &model.Hold{MemberID: memberIDs["jane"], BookID: bookID})
+	assert.ErrorIs(t, err, model.ErrConflict)
+
+	loanID, err := store.AddLoan(ctx, &model.LoanDetails{MemberID: memberIDs["john"], BookID: bookID, Status: constants.Active})
+	assert.Nil(t, err)
+
+	// success case: holds queue up in the order placed
+	janeHoldID, err := store.AddHold(ctx, &model.Hold{MemberID: memberIDs["jane"], Title: "beloved"})
+	assert.Nil(t, err)
+	adamHoldID, err := store.AddHold(ctx, &model.Hold{MemberID: memberIDs["adam"], BookID: bookID})
+	assert.Nil(t, err)
+
+	// failure case: placed twice or by the borrower
+	_, err = store.AddHold(ctx, &model.Hold{MemberID: memberIDs["jane"], BookID: bookID})
+	assert.ErrorIs(t, err, model.ErrAlreadyExists)
+	_, err = store.AddHold(ctx, &model.Hold{MemberID: memberIDs["john"], BookID: bookID})
+	assert.ErrorIs(t, err, model.ErrNotAllowed)
+
+	// the returned copy is set aside for the longest waiting hold
+	_, err = store.ReturnBook(ctx, loanID, 0)
+	assert.Nil(t, err)
+	holds, err := store.GetMemberHolds(ctx, memberIDs["jane"])
+	assert.Nil(t, err)
+	assert.Len(t, holds, 1)
+	assert.Equal(t, constants.HoldReady, holds[0].Status)
+	bookCopy, err := store.GetBookCopy(ctx, holds[0].Barcode)
+	assert.Nil(t, err)
+	assert.Equal(t, constants.CopyOnHold, bookCopy.Status)
+	_, err = store.AddLoan(ctx, &model.LoanDetails{MemberID: memberIDs["john"], BookID: bookID, Status: constants.Active})
+	assert.ErrorIs(t, err, model.ErrNotFound)
+
+	// expiring passes the copy on to the next hold
+	expired, err := store.ExpireHolds(ctx, time.Now().Add(time.Duration(config.PolicyConfig.HoldPickupInDays+1)*24*time.Hour))
+	assert.Nil(t, err)
+	assert.Equal(t, 1, expired)
+	holds, err = store.GetMemberHolds(ctx, memberIDs["jane"])
+	assert.Nil(t, err)
+	assert.Equal(t, constants.HoldExpired, holds[0].Status)
+
+	// borrowing fulfills the ready hold with the copy set aside
+	loanID, err = store.AddLoan(ctx, &model.LoanDetails{MemberID: memberIDs["adam"], Title: "beloved", Status: constants.Active})
+	assert.Nil(t, err)
+	holds, err = store.GetMemberHolds(ctx, memberIDs["adam"])
+	assert.Nil(t, err)
+	assert.Equal(t, adamHoldID, holds[0].ID)
+	assert.Equal(t, constants.HoldFulfilled, holds[0].Status)
+
+	// cancelling a ready hold puts the copy back on the shelf when none waits
+	janeHoldID, err = store.AddHold(ctx, &model.Hold{MemberID: memberIDs["jane"], BookID: bookID})
+	assert.Nil(t, err)
+	_, err = store.ReturnBook(ctx, loanID, 0)
+	assert.Nil(t, err)
+	hold, err := store.CancelHold(ctx, janeHoldID)
+	assert.Nil(t, err)
+	assert.Equal(t, constants.HoldCancelled, hold.Status)
+	book, err := store.GetBookDetailsByID(ctx, bookID)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, book.AvailableCopies)
+
+	// failure case: cancelled twice or unknown
+	_, err = store.CancelHold(ctx, janeHoldID)
+	assert.ErrorIs(t, err, model.ErrConflict)
+	_, err = store.CancelHold(ctx, 1000)
+	assert.ErrorIs(t, err, model.ErrNotFound)
+}
+
+func TestExpireHoldsWhileReading(t *testing.T) {
+	store, err := local.InitLocalStore()
+	assert.Nil(t, err)
+	bookID, err := store.AddBook(ctx, &model.BookDetails{Title: "Jazz", TotalCopies: 1})
+	assert.Nil(t, err)
+	borrowerID, err := store.AddMember(ctx, &model.Member{Name: "Hugo"})
+	assert.Nil(t, err)
+	loanID, err := store.AddLoan(ctx, &model.LoanDetails{MemberID: borrowerID, BookID: bookID, Status: constants.Active})
+	assert.Nil(t, err)
+	memberIDs := make([]int, 0)
+	for i := 0; i < 20; i++ {
+		memberID, err := store.AddMember(ctx, &model.Member{Name: fmt.Sprintf("waiting %d", i)})
+		assert.Nil(t, err)
+		_, err = store.AddHold(ctx, &model.Hold{MemberID: memberID, BookID: bookID})
+		assert.Nil(t, err)
+		memberIDs = append(memberIDs, memberID)
+	}
+	_, err = store.ReturnBook(ctx, loanID, 0)
+	assert.Nil(t, err)
+
+	// each expiry passes the copy on to the next hold while the holds and copies read are encoded, run with -race
+	pickup := time.Duration(config.PolicyConfig.HoldPickupInDays+1) * 24 * time.Hour
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := range memberIDs {
+			expired, err := store.ExpireHolds(ctx, time.Now().Add(time.Duration(i+1)*pickup))
+			assert.Nil(t, err)
+			assert.Equal(t, 1, expired)
+		}
+	}()
+	for _, memberID := range memberIDs {
+		holds, err := store.GetMemberHolds(ctx, memberID)
+		assert.Nil(t, err)
+		copies, err := store.GetBookCopies(ctx, bookID)
+		assert.Nil(t, err)
+		book, err := store.GetBookDetailsByID(ctx, bookID)
+		assert.Nil(t, err)
+		_, err = json.Marshal([]any{holds, copies, book})
+		assert.Nil(t, err)
+	}
+	<-done
+	book, err := store.GetBookDetailsByID(ctx, bookID)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, book.AvailableCopies)
+}
+
+func TestFines(t *testing.T) {
+	store, err := local.InitLocalStore()
+	assert.Nil(t, err)
+	memberID, err := store.AddMember(ctx, &model.Member{Name: "Fred"})
+	assert.Nil(t, err)
+	now := time.Now()
+	day := 24 * time.Hour
+	loanID, err := store.AddLoan(ctx, &model.LoanDetails{MemberID: memberID, Title: "sapiens", ReturnDate: now.Add(-3 * day).Unix(), Status: constants.Active})
+	assert.Nil(t, err)
+
+	// success case: overdue loans accrue fines
+	overdue, err := store.AccrueFines(ctx, now)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, overdue)
+	fines, err := store.GetMemberFines(ctx, memberID)
+	assert.Nil(t, err)
+	assert.Len(t, fines.Fines, 1)
+	fine := fines.Fines[0]
+	assert.Equal(t, constants.FineAccruing, fine.Status)
+	assert.Equal(t, 3*config.PolicyConfig.FinePerDayInCents, fine.AmountInCents)
+	_, err = store.ExtendLoan(ctx, loanID, 0)
+	assert.ErrorIs(t, err, model.ErrNotAllowed)
+
+	// paying part of an accruing fine
+	fine, err = store.PayFine(ctx, fine.ID, 25)
+	assert.Nil(t, err)
+	assert.Equal(t, constants.FineAccruing, fine.Status)
+	_, err = store.PayFine(ctx, fine.ID, fine.AmountInCents)
+	assert.ErrorIs(t, err, model.ErrConflict)
+
+	// returning finalises the fine, members owing fines don't borrow
+	_, err = store.ReturnBook(ctx, loanID, 0)
+	assert.Nil(t, err)
+	fines, err = store.GetMemberFines(ctx, memberID)
+	assert.Nil(t, err)
+	assert.Equal(t, constants.FineUnpaid, fines.Fines[0].Status)
+	_, err = store.AddLoan(ctx, &model.LoanDetails{MemberID: memberID, Title: "alchemist", Status: constants.Active})
+	assert.ErrorIs(t, err, model.ErrNotAllowed)
+	err = store.DeleteMember(ctx, memberID)
+	assert.ErrorIs(t, err, model.ErrConflict)
+	fine, err = store.PayFine(ctx, fine.ID, 0)
+	assert.Nil(t, err)
+	assert.Equal(t, constants.FinePaid, fine.Status)
+
+	// waived fines stay waived on return
+	loanID, err = store.AddLoan(ctx, &model.LoanDetails{MemberID: memberID, Title: "alchemist", ReturnDate: now.Add(-2 * day).Unix(), Status: constants.Active})
+	assert.Nil(t, err)
+	_, err = store.AccrueFines(ctx, now)
+	assert.Nil(t, err)
+	fines, err = store.GetMemberFines(ctx, memberID)
+	assert.Nil(t, err)
+	assert.Len(t, fines.Fines, 2)
+	fine, err = store.WaiveFine(ctx, fines.Fines[1].ID)
+	assert.Nil(t, err)
+	_, err = store.ReturnBook(ctx, loanID, 0)
+	assert.Nil(t, err)
+	fines, err = store.GetMemberFines(ctx, memberID)
+	assert.Nil(t, err)
+	assert.Equal(t, constants.FineWaived, fines.Fines[1].Status)
+	assert.Equal(t, int64(0), fines.OutstandingInCents)
+
+	// failure case: settled fines
+	_, err = store.WaiveFine(ctx, fine.ID)
+	assert.ErrorIs(t, err, model.ErrConflict)
+	_, err = store.PayFine(ctx, 1000, 0)
+	assert.ErrorIs(t, err, model.ErrNotFound)
+}
+
+func TestAccrueFinesWhileReading(t *testing.T) {
+	store, err := local.InitLocalStore()
+	assert.Nil(t, err)
+	memberID, err := store.AddMember(ctx, &model.Member{Name: "Flora"})
+	assert.Nil(t, err)
+	now := time.Now()
+	loanID, err := store.AddLoan(ctx, &model.LoanDetails{MemberID: memberID, Title: "sapiens", ReturnDate: now.Add(-time.Hour).Unix(), Status: constants.Active})
+	assert.Nil(t, err)
+
+	// the loans and fines read are encoded outside the lock while the job accrues, run with -race
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 100; i++ {
+			_, err := store.AccrueFines(ctx, now.Add(time.Duration(i)*time.Hour))
+			assert.Nil(t, err)
+		}
+	}()
+	for i := 0; i < 100; i++ {
+		loan, err := store.GetLoan(ctx, loanID)
+		assert.Nil(t, err)
+		loans, err := store.GetLoansByBorrower(ctx, memberID)
+		assert.Nil(t, err)
+		page, err := store.GetAllLoans(ctx, &model.LoanQuery{MemberID: memberID})
+		assert.Nil(t, err)
+		fines, err := store.GetMemberFines(ctx, memberID)
+		assert.Nil(t, err)
+		_, err = json.Marshal([]any{loan, loans, page, fines})
+		assert.Nil(t, err)
+	}
+	<-done
+}
+
+func TestLoanHistory(t *testing.T) {
+	store, err := local.InitLocalStore()
+	assert.Nil(t, err)
+	memberID, err := store.AddMember(ctx, &model.Member{Name: "Gina"})
+	assert.Nil(t, err)
+	librarianCtx := audit.WithActor(ctx, "librarian-1")
+	loanID, err := store.AddLoan(librarianCtx, &model.LoanDetails{MemberID: memberID, Title: "sapiens", Status: constants.Active})
+	assert.Nil(t, err)
+	loan, err := store.ExtendLoan(librarianCtx, loanID, 0)
+	assert.Nil(t, err)
+	// marked overdue by the background job
+	_, err = store.AccrueFines(ctx, time.Unix(loan.ReturnDate, 0).Add(time.Hour))
+	assert.Nil(t, err)
+	_, err = store.ReturnBook(librarianCtx, loanID, 0)
+	assert.Nil(t, err)
+
+	// success case
+	events, err := store.GetLoanHistory(ctx, loanID)
+	assert.Nil(t, err)
+	assert.Len(t, events, 4)
+	types := []string{}
+	for _, event := range events {
+		types = append(types, event.Type)
+	}
+	assert.Equal(t, []string{constants.LoanCreated, constants.LoanExtended, constants.LoanOverdue, constants.LoanReturned}, types)
+	assert.Nil(t, events[0].Old)
+	assert.Equal(t, "librarian-1", events[1].Actor)
+	assert.Equal(t, 0, events[1].Old.Extensions)
+	assert.Equal(t, 1, events[1].New.Extensions)
+	assert.Equal(t, loan.ReturnDate, events[1].New.ReturnDate)
+	assert.Equal(t, constants.ActorSystem, events[2].Actor)
+	assert.Equal(t, constants.Overdue, events[3].Old.Status)
+	assert.Equal(t, constants.Closed, events[3].New.Status)
+
+	// failure case: unknown loan
+	_, err = store.GetLoanHistory(ctx, 1000)
+	assert.ErrorIs(t, err, model.ErrNotFound)
+}
+
+func TestLoansByBorrower(t *testing.T) {
+	store, err := local.InitLocalStore()
+	assert.Nil(t, err)
+	memberID, err := store.AddMember(ctx, &model.Member{Name: "Hana"})
+	assert.Nil(t, err)
+	firstID, err := store.AddLoan(ctx, &model.LoanDetails{MemberID: memberID, Title: "sapiens", Status: constants.Active})
+	assert.Nil(t, err)
+	secondID, err := store.AddLoan(ctx, &model.LoanDetails{MemberID: memberID, Title: "animal farm", Status: constants.Active})
+	assert.Nil(t, err)
+	_, err = store.ReturnBook(ctx, firstID, 0)
+	assert.Nil(t, err)
+
+	// success case
+	loan, err := store.GetLoan(ctx, secondID)
+	assert.Nil(t, err)
+	assert.Equal(t, "Animal Farm", loan.Title)
+	assert.Equal(t, memberID, loan.MemberID)
+	loans, err := store.GetLoansByBorrower(ctx, memberID)
+	assert.Nil(t, err)
+	assert.Len(t, loans, 2)
+	assert.Equal(t, firstID, loans[0].ID)
+	assert.Equal(t, constants.Closed, loans[0].Status)
+	assert.Equal(t, secondID, loans[1].ID)
+	assert.Equal(t, constants.Active, loans[1].Status)
+	// a member without loans
+	loans, err = store.GetLoansByBorrower(ctx, 1)
+	assert.Nil(t, err)
+	assert.Empty(t, loans)
+
+	// failure case: unknown loan and member
+	_, err = store.GetLoan(ctx, 1000)
+	assert.ErrorIs(t, err, model.ErrNotFound)
+	_, err = store.GetLoansByBorrower(ctx, 1000)
+	assert.ErrorIs(t, err, model.ErrNotFound)
+}
+
+func TestDurableStore(t *testing.T) {
+	config.LocalConfig.DataDir = t.TempDir()
+	defer func() { config.LocalConfig.DataDir = "" }()
+	store, err := local.InitLocalStore()
+	assert.Nil(t, err)
+	memberID, err := store.AddMember(ctx, &model.Member{Name: "Ivan"})
+	assert.Nil(t, err)
+	loanID, err := store.AddLoan(ctx, &model.LoanDetails{MemberID: memberID, Title: "sapiens", Status: constants.Active})
+	assert.Nil(t, err)
+	_, err = store.ExtendLoan(ctx, loanID, 0)
+	assert.Nil(t, err)
+	bookID, err := store.AddBook(ctx, &model.BookDetails{Title: "Dune", TotalCopies: 1})
+	assert.Nil(t, err)
+	assert.Nil(t, store.DeleteBook(ctx, bookID))
+
+	// success case: recovering from the log after a crash, the crashed store isn't closed
+	recovered, err := local.InitLocalStore()
+	assert.Nil(t, err)
+	loan, err := recovered.GetLoan(ctx, loanID)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, loan.Extensions)
+	assert.Equal(t, "Ivan", loan.NameOfBorrower)
+	book, err := recovered.GetBookDetails(ctx, "sapiens")
+	assert.Nil(t, err)
+	assert.Equal(t, 9, book.AvailableCopies)
+	_, err = recovered.GetBookDetailsByID(ctx, bookID)
+	assert.ErrorIs(t, err, model.ErrNotFound)
+	events, err := recovered.GetLoanHistory(ctx, loanID)
+	assert.Nil(t, err)
+	assert.Len(t, events, 2)
+	// the counters are restored, no ID is handed out twice
+	nextLoanID, err := recovered.AddLoan(ctx, &model.LoanDetails{MemberID: memberID, Title: "animal farm", Status: constants.Active})
+	assert.Nil(t, err)
+	assert.Equal(t, loanID+1, nextLoanID)
+	nextBookID, err := recovered.AddBook(ctx, &model.BookDetails{Title: "Emma"})
+	assert.Nil(t, err)
+	assert.Equal(t, bookID+1, nextBookID)
+
+	// success case: recovering from the snapshot written on close
+	assert.Nil(t, recovered.Close())
+	reopened, err := local.InitLocalStore()
+	assert.Nil(t, err)
+	loans, err := reopened.GetLoansByBorrower(ctx, memberID)
+	assert.Nil(t, err)
+	assert.Len(t, loans, 2)
+	_, err = reopened.GetBookDetailsByID(ctx, nextBookID)
+	assert.Nil(t, err)
+
+	// success case: a record torn by a crash is dropped
+	_, err = reopened.ReturnBook(ctx, nextLoanID, 0)
+	assert.Nil(t, err)
+	wal, err := os.OpenFile(filepath.Join(config.LocalConfig.DataDir, "wal.log"), os.O_WRONLY|os.O_APPEND, 0o644)
+	assert.Nil(t, err)
+	_, err = wal.WriteString(`{"seq":1000,"book_ids":[1],"cou`)
+	assert.Nil(t, err)
+	assert.Nil(t, wal.Close())
+	reopened, err = local.InitLocalStore()
+	assert.Nil(t, err)
+	loan, err = reopened.GetLoan(ctx, nextLoanID)
+	assert.Nil(t, err)
+	assert.Equal(t, constants.Closed, loan.Status)
+	book, err = reopened.GetBookDetailsByID(ctx, 1)
+	assert.Nil(t, err)
+	assert.Equal(t, "Alchemist", book.Title)
+	assert.Nil(t, reopened.Close())
+}
+
+func TestDurableFines(t *testing.T) {
+	config.LocalConfig.DataDir = t.TempDir()
+	defer func() { config.LocalConfig.DataDir = "" }()
+	store, err := local.InitLocalStore()
+	assert.Nil(t, err)
+	memberID, err := store.AddMember(ctx, &model.Member{Name: "Jack"})
+	assert.Nil(t, err)
+	now := time.Now()
+	day := 24 * time.Hour
+	_, err = store.AddLoan(ctx, &model.LoanDetails{MemberID: memberID, Title: "alchemist", Status: constants.Active})
+	assert.Nil(t, err)
+	overdueID, err := store.AddLoan(ctx, &model.LoanDetails{MemberID: memberID, Title: "sapiens", ReturnDate: now.Add(-3 * day).Unix(), Status: constants.Active})
+	assert.Nil(t, err)
+	records := func() []string {
+		data, err := os.ReadFile(filepath.Join(config.LocalConfig.DataDir, "wal.log"))
+		assert.Nil(t, err)
+		return strings.Split(strings.TrimSpace(string(data)), "\n")
+	}
+
+	// success case: accruing logs the overdue loan alone, not every loan of its member
+	_, err = store.AccrueFines(ctx, now)
+	assert.Nil(t, err)
+	logged := records()
+	var record struct {
+		LoanIDs   []int                `json:"loan_ids"`
+		MemberIDs []int                `json:"member_ids"`
+		Loans     []*model.LoanDetails `json:"loans"`
+		Fines     []*model.Fine        `json:"fines"`
+	}
+	assert.Nil(t, json.Unmarshal([]byte(logged[len(logged)-1]), &record))
+	assert.Equal(t, []int{overdueID}, record.LoanIDs)
+	assert.Empty(t, record.MemberIDs)
+	assert.Len(t, record.Loans, 1)
+	assert.Len(t, record.Fines, 1)
+
+	// nothing is logged till the fine grows
+	_, err = store.AccrueFines(ctx, now.Add(time.Minute))
+	assert.Nil(t, err)
+	assert.Len(t, records(), len(logged))
+	_, err = store.AccrueFines(ctx, now.Add(day))
+	assert.Nil(t, err)
+	assert.Len(t, records(), len(logged)+1)
+
+	// success case: recovering the loans and fines after a crash
+	recovered, err := local.InitLocalStore()
+	assert.Nil(t, err)
+	loan, err := recovered.GetLoan(ctx, overdueID)
+	assert.Nil(t, err)
+	assert.Equal(t, constants.Overdue, loan.Status)
+	loans, err := recovered.GetLoansByBorrower(ctx, memberID)
+	assert.Nil(t, err)
+	assert.Len(t, loans, 2)
+	assert.Equal(t, overdueID, loans[1].ID)
+	events, err := recovered.GetLoanHistory(ctx, overdueID)
+	assert.Nil(t, err)
+	assert.Len(t, events, 2)
+	fines, err := recovered.GetMemberFines(ctx, memberID)
+	assert.Nil(t, err)
+	assert.Len(t, fines.Fines, 1)
+	assert.Equal(t, 4*config.PolicyConfig.FinePerDayInCents, fines.OutstandingInCents)
+	assert.Nil(t, recovered.Close())
+}
+
+func TestIdempotencyKeys(t *testing.T) {
+	config.LocalConfig.DataDir = t.TempDir()
+	defer func() { config.LocalConfig.DataDir = "" }()
+	store, err := local.InitLocalStore()
+	assert.Nil(t, err)
+	now := time.Now()
+	rec := &model.IdempotencyRecord{Key: "key-1", Fingerprint: "abc", ExpiresAt: now.Add(time.Minute).Unix()}
+
+	// success case: reserved by the first request, in progress for its retries
+	stored, err := store.ReserveIdempotencyKey(ctx, rec, now)
+	assert.Nil(t, err)
+	assert.Nil(t, stored)
+	stored, err = store.ReserveIdempotencyKey(ctx, rec, now)
+	assert.Nil(t, err)
+	assert.Equal(t, 0, stored.StatusCode)
+
+	// released keys are reserved again
+	assert.Nil(t, store.ReleaseIdempotencyKey(ctx, rec.Key))
+	stored, err = store.ReserveIdempotencyKey(ctx, rec, now)
+	assert.Nil(t, err)
+	assert.Nil(t, stored)
+
+	// completed keys survive a restart and aren't released
+	completed := *rec
+	completed.StatusCode = 201
+	completed.Body = []byte(`{"id":1}`)
+	completed.Header = map[string]string{"Content-Type": "application/json"}
+	assert.Nil(t, store.CompleteIdempotencyKey(ctx, &completed))
+	assert.Nil(t, store.ReleaseIdempotencyKey(ctx, rec.Key))
+	recovered, err := local.InitLocalStore()
+	assert.Nil(t, err)
+	stored, err = recovered.ReserveIdempotencyKey(ctx, rec, now)
+	assert.Nil(t, err)
+	assert.Equal(t, 201, stored.StatusCode)
+	assert.Equal(t, `{"id":1}`, string(stored.Body))
+
+	// failure case: expired keys are dropped and taken over
+	expired, err := recovered.ExpireIdempotencyKeys(ctx, now.Add(2*time.Minute))
+	assert.Nil(t, err)
+	assert.Equal(t, 1, expired)
+	stored, err = recovered.ReserveIdempotencyKey(ctx, rec, now.Add(2*time.Minute))
+	assert.Nil(t, err)
+	assert.Nil(t, stored)
+	assert.Nil(t, recovered.Close())
+	assert.Nil(t, store.Close())
+}
+
+func TestCirculationStats(t *testing.T) {
+	store, err := local.InitLocalStore()
+	assert.Nil(t, err)
+	bookID, err := store.AddBook(ctx, &model.BookDetails{Title: "Dune", TotalCopies: 1})
+	assert.Nil(t, err)
+	_, err = store.AddLoan(ctx, &model.LoanDetails{MemberID: 1, BookID: bookID, Status: constants.Active})
+	assert.Nil(t, err)
+
+	// success case: the loan is open, its book has no copy left
+	stats, err := store.GetCirculationStats(ctx, time.Now())
+	assert.Nil(t, err)
+	assert.Equal(t, model.CirculationStats{OpenLoans: 1, OverdueLoans: 0, UnavailableTitles: 1}, *stats)
+
+	// success case: overdue once past its return date
+	stats, err = store.GetCirculationStats(ctx, time.Now().AddDate(0, 0, 60))
+	assert.Nil(t, err)
+	assert.Equal(t, 1, stats.OverdueLoans)
+	assert.Nil(t, store.Close())
+}
+
+func TestAPIKeys(t *testing.T) {
+	config.LocalConfig.DataDir = t.TempDir()
+	defer func() { config.LocalConfig.DataDir = "" }()
+	store, err := local.InitLocalStore()
+	assert.Nil(t, err)
+	key := &model.APIKey{Name: "kiosk-east", Prefix: "lib_abcdef", Hash: "hash-1", Scopes: []string{"circulation:write"}}
+
+	// success case: issued keys are found by their hash
+	id, err := store.AddAPIKey(ctx, key)
+	assert.Nil(t, err)
+	found, err := store.GetAPIKeyByHash(ctx, "hash-1")
+	assert.Nil(t, err)
+	assert.Equal(t, id, found.ID)
+
+	// failure case: the name is taken
+	_, err = store.AddAPIKey(ctx, &model.APIKey{Name: "kiosk-east", Hash: "hash-2"})
+	assert.ErrorIs(t, err, model.ErrAlreadyExists)
+
+	// revoked keys survive a restart along with their hash
+	revoked, err := store.RevokeAPIKey(ctx, id, time.Now())
+	assert.Nil(t, err)
+	assert.NotZero(t, revoked.RevokedAt)
+	recovered, err := local.InitLocalStore()
+	assert.Nil(t, err)
+	found, err = recovered.GetAPIKeyByHash(ctx, "hash-1")
+	assert.Nil(t, err)
+	assert.Equal(t, "kiosk-east", found.Name)
+	assert.NotZero(t, found.RevokedAt)
+	keys, err := recovered.GetAllAPIKeys(ctx)
+	assert.Nil(t, err)
+	assert.Len(t, keys, 1)
+
+	// failure cases: revoked once only, unknown keys
+	_, err = recovered.RevokeAPIKey(ctx, id, time.Now())
+	assert.ErrorIs(t, err, model.ErrConflict)
+	_, err = recovered.RevokeAPIKey(ctx, id+1, time.Now())
+	assert.ErrorIs(t, err, model.ErrNotFound)
+	_, err = recovered.GetAPIKeyByHash(ctx, "hash-2")
+	assert.ErrorIs(t, err, model.ErrNotFound)
+
+	// the IDs carry on after a restart
+	nextID, err := recovered.AddAPIKey(ctx, &model.APIKey{Name: "portal", Hash: "hash-3"})
+	assert.Nil(t, err)
+	assert.Equal(t, id+1, nextID)
+	assert.Nil(t, recovered.Close())
+	assert.Nil(t, store.Close())
+}
+
+func TestPing(t *testing.T) {
+	store, err := local.InitLocalStore()
+	assert.Nil(t, err)
+
+	// success case: the store serves the requests
+	assert.Nil(t, store.Ping(context.Background()))
+
+	// failure case: the store is closed
+	assert.Nil(t, store.Close())
+	assert.NotNil(t, store.Ping(context.Background()))
+}
+
 func TestClose(t *testing.T) {
 	err := localStore.Close()
 	assert.Nil(t, err)
@@ -0,0 +1,222 @@
+package local
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/test/library-app/internal/constants"
+	"github.com/test/library-app/internal/logger"
+	"github.com/test/library-app/internal/model"
+	"github.com/test/library-app/internal/policy"
+)
+
+// checkMemberUnique fails when the email or card number belongs to another member, callers must hold the lock
+func (l *LocalStore) checkMemberUnique(memberID int, email, cardNumber string) error {
+	if id, ok := l.emails[strings.ToLower(email)]; ok && email != "" && id != memberID {
+		return fmt.Errorf("member with email '%s' already presents. %w", email, model.ErrAlreadyExists)
+	}
+	if id, ok := l.cards[cardNumber]; ok && cardNumber != "" && id != memberID {
+		return fmt.Errorf("member with card number '%s' already presents. %w", cardNumber, model.ErrAlreadyExists)
+	}
+	return nil
+}
+
+// indexMember adds the member to the email and card number indexes, callers must hold the lock
+func (l *LocalStore) indexMember(member *model.Member) {
+	if member.Email != "" {
+		l.emails[strings.ToLower(member.Email)] = member.ID
+	}
+	l.cards[member.CardNumber] = member.ID
+}
+
+// unindexMember removes the member from the email and card number indexes, callers must hold the lock
+func (l *LocalStore) unindexMember(member *model.Member) {
+	delete(l.emails, strings.ToLower(member.Email))
+	delete(l.cards, member.CardNumber)
+}
+
+// addMember stores a member, generating the card number when missed, callers must hold the lock
+func (l *LocalStore) addMember(det *model.Member) error {
+	id := l.lastMemberID + 1
+	if det.CardNumber == "" {
+		det.CardNumber = model.CardNumber(id)
+	}
+	if err := l.checkMemberUnique(0, det.Email, det.CardNumber); err != nil {
+		return err
+	}
+	if det.Tier == "" {
+		det.Tier = constants.DefaultTier
+	}
+	if det.Status == "" {
+		det.Status = constants.MemberActive
+	}
+	if det.JoinedAt == 0 {
+		det.JoinedAt = time.Now().Unix()
+	}
+	l.lastMemberID = id
+	det.ID = id
+	l.members[det.ID] = det
+	l.indexMember(det)
+	return nil
+}
+
+// hasActiveMemberLoans reports whether the member has borrowed books not yet returned, callers must hold the lock
+func (l *LocalStore) hasActiveMemberLoans(memberID int) bool {
+	for _, loanID := range l.memberLoans[memberID] {
+		if l.loans[loanID].Status != constants.Closed {
+			return true
+		}
+	}
+	return false
+}
+
+// memberStanding gathers the active loans, holds and unpaid fines of the member, callers must hold the lock
+func (l *LocalStore) memberStanding(member *model.Member) *policy.Standing {
+	standing := &policy.Standing{Member: member}
+	for _, loanID := range l.memberLoans[member.ID] {
+		if loan := l.loans[loanID]; loan.Status != constants.Closed {
+			standing.ActiveLoans = append(standing.ActiveLoans, loan)
+		}
+	}
+	for _, hold := range l.holds {
+		if isActiveHold(hold) && hold.MemberID == member.ID {
+			standing.ActiveHolds = append(standing.ActiveHolds, hold)
+		}
+	}
+	standing.UnpaidFines = l.memberOwes(member.ID)
+	return standing
+}
+
+// memberKey gives the sort position of a member
+func memberKey(sortBy string) func(*model.Member) pageKey {
+	return func(member *model.Member) pageKey {
+		if sortBy == constants.SortByName {
+			return pageKey{text: member.Name, id: member.ID}
+		}
+		return pageKey{id: member.ID}
+	}
+}
+
+// AddMember registers a member, generating the card number when missed
+func (l *LocalStore) AddMember(ctx context.Context, det *model.Member) (int, error) {
+	l.rmu.Lock()
+	defer l.rmu.Unlock()
+	if err := l.writable(); err != nil {
+		return 0, err
+	}
+	if err := l.addMember(det); err != nil {
+		return 0, err
+	}
+	if err := l.persist(nil, []int{det.ID}); err != nil {
+		return 0, err
+	}
+	logger.FromContext(ctx).Infof("Member %d registered with card number: %s", det.ID, det.CardNumber)
+	return det.ID, nil
+}
+
+// GetMember retreves a member by its ID
+func (l *LocalStore) GetMember(ctx context.Context, memberID int) (*model.Member, error) {
+	l.rmu.RLock()
+	defer l.rmu.RUnlock()
+	member, ok := l.members[memberID]
+	if !ok {
+		return nil, fmt.Errorf("member %d isn't presents. %w", memberID, model.ErrNotFound)
+	}
+	return member, nil
+}
+
+// GetAllMembers retreves a page of the members passing the filters of the query
+func (l *LocalStore) GetAllMembers(ctx context.Context, query *model.MemberQuery) (*model.MemberPage, error) {
+	l.rmu.RLock()
+	defer l.rmu.RUnlock()
+	members := make([]*model.Member, 0)
+	for _, member := range l.members {
+		switch {
+		case query.Name != "" && !containsFold(member.Name, query.Name):
+		case query.Tier != "" && member.Tier != query.Tier:
+		case query.Status != "" && member.Status != query.Status:
+		default:
+			members = append(members, member)
+		}
+	}
+	members, next := paginate(members, memberKey(query.SortBy), &query.PageQuery)
+	page := &model.MemberPage{Members: members}
+	if next != nil {
+		page.NextCursor = next.Encode()
+	}
+	return page, nil
+}
+
+// UpdateMember updates the non empty name, email, card number, tier and status of a member
+func (l *LocalStore) UpdateMember(ctx context.Context, memberID int, det *model.Member) (*model.Member, error) {
+	l.rmu.Lock()
+	defer l.rmu.Unlock()
+	if err := l.writable(); err != nil {
+		return nil, err
+	}
+	member, ok := l.members[memberID]
+	if !ok {
+		return nil, fmt.Errorf("member %d isn't presents. %w", memberID, model.ErrNotFound)
+	}
+	if err := l.checkMemberUnique(memberID, det.Email, det.CardNumber); err != nil {
+		return nil, err
+	}
+	l.unindexMember(member)
+	if det.Name != "" {
+		member.Name = det.Name
+	}
+	if det.Email != "" {
+		member.Email = det.Email
+	}
+	if det.CardNumber != "" {
+		member.CardNumber = det.CardNumber
+	}
+	if det.Tier != "" {
+		member.Tier = det.Tier
+	}
+	if det.Status != "" {
+		member.Status = det.Status
+	}
+	l.indexMember(member)
+	if err := l.persist(nil, []int{memberID}); err != nil {
+		return nil, err
+	}
+	logger.FromContext(ctx).Infof("Member %d updated", memberID)
+	return member, nil
+}
+
+// DeleteMember removes a member cancelling its holds, refused while the member has active loans or owes fines
+func (l *LocalStore) DeleteMember(ctx context.Context, memberID int) error {
+	l.rmu.Lock()
+	defer l.rmu.Unlock()
+	if err := l.writable(); err != nil {
+		return err
+	}
+	member, ok := l.members[memberID]
+	if !ok {
+		return fmt.Errorf("member %d isn't presents. %w", memberID, model.ErrNotFound)
+	}
+	if l.hasActiveMemberLoans(memberID) {
+		return fmt.Errorf("member %d has active loans. %w", memberID, model.ErrConflict)
+	}
+	if owed := l.memberOwes(memberID); owed > 0 {
+		return fmt.Errorf("member %d owes %d cents of fines. %w", memberID, owed, model.ErrConflict)
+	}
+	// cancelling the holds passes the copies set aside on
+	bookIDs := make([]int, 0)
+	for _, hold := range l.holds {
+		if isActiveHold(hold) && hold.MemberID == memberID {
+			l.closeHold(hold, constants.HoldCancelled, time.Now())
+			bookIDs = append(bookIDs, hold.BookID)
+		}
+	}
+	l.unindexMember(member)
+	delete(l.members, memberID)
+	if err := l.persist(bookIDs, []int{memberID}); err != nil {
+		return err
+	}
+	logger.FromContext(ctx).Infof("Member %d deleted", memberID)
+	return nil
+}
@@ -0,0 +1,187 @@
+package local
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/test/library-app/internal/constants"
+	"github.com/test/library-app/internal/model"
+)
+
+// pageKey is the sort position of a listed item, text keys compare case insensitively
+type pageKey struct {
+	text string
+	num  int64
+	id   int
+}
+
+func (k pageKey) less(o pageKey) bool {
+	if t, ot := strings.ToLower(k.text), strings.ToLower(o.text); t != ot {
+		return t < ot
+	}
+	if k.num != o.num {
+		return k.num < o.num
+	}
+	return k.id < o.id
+}
+
+// paginate sorts the items by key and returns the page following the cursor,
+// the cursor of its last item is given when more items follow
+func paginate[T any](items []T, key func(T) pageKey, query *model.PageQuery) ([]T, *model.Cursor) {
+	desc := query.Order == constants.OrderDesc
+	sort.Slice(items, func(i, j int) bool {
+		if desc {
+			return key(items[j]).less(key(items[i]))
+		}
+		return key(items[i]).less(key(items[j]))
+	})
+	start := 0
+	if after := query.After; after != nil {
+		last := pageKey{text: after.Text, num: after.Num, id: after.ID}
+		start = sort.Search(len(items), func(i int) bool {
+			if desc {
+				return key(items[i]).less(last)
+			}
+			return last.less(key(items[i]))
+		})
+	}
+	limit := query.Limit
+	if limit <= 0 {
+		limit = constants.DefaultPageLimit
+	}
+	end := start + limit
+	if end >= len(items) {
+		return items[start:], nil
+	}
+	items = items[start:end]
+	last := key(items[len(items)-1])
+	return items, &model.Cursor{SortBy: query.SortBy, Desc: desc, Text: last.text, Num: last.num, ID: last.id}
+}
+
+// bookKey gives the sort position of a book
+func bookKey(sortBy string) func(*model.BookDetails) pageKey {
+	return func(book *model.BookDetails) pageKey {
+		switch sortBy {
+		case constants.SortByTitle:
+			return pageKey{text: book.Title, id: book.ID}
+		case constants.SortByPublicationYear:
+			return pageKey{num: int64(book.PublicationYear), id: book.ID}
+		default:
+			return pageKey{id: book.ID}
+		}
+	}
+}
+
+// loanKey gives the sort position of a loan
+func loanKey(sortBy string) func(*model.LoanDetails) pageKey {
+	return func(loan *model.LoanDetails) pageKey {
+		switch sortBy {
+		case constants.SortByBorrower:
+			return pageKey{text: loan.NameOfBorrower, id: loan.ID}
+		case constants.SortByLoanDate:
+			return pageKey{num: loan.LoanDate, id: loan.ID}
+		case constants.SortByReturnDate:
+			return pageKey{num: loan.ReturnDate, id: loan.ID}
+		default:
+			return pageKey{id: loan.ID}
+		}
+	}
+}
+
+// containsFold reports whether substr is within s ignoring the case
+func containsFold(s, substr string) bool {
+	return strings.Contains(strings.ToLower(s), strings.ToLower(substr))
+}
+
+// matchesBook reports whether the book passes the filters of the query
+func matchesBook(book *model.BookDetails, query *model.BookQuery) bool {
+	if query.Title != "" && !containsFold(book.Title, query.Title) {
+		return false
+	}
+	if query.Author != "" {
+		found := false
+		for _, author := range book.Authors {
+			if containsFold(author, query.Author) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	if query.Available != nil && *query.Available != (book.AvailableCopies > 0) {
+		return false
+	}
+	return true
+}
+
+// matchesLoan reports whether the loan passes the filters of the query
+func matchesLoan(loan *model.LoanDetails, query *model.LoanQuery, now int64) bool {
+	switch {
+	case query.Status != "" && loan.Status != query.Status:
+		return false
+	case query.MemberID != 0 && loan.MemberID != query.MemberID:
+		return false
+	case query.Borrower != "" && !strings.EqualFold(loan.NameOfBorrower, query.Borrower):
+		return false
+	case query.Title != "" && !containsFold(loan.Title, query.Title):
+		return false
+	case query.Overdue && (loan.Status == constants.Closed || loan.ReturnDate >= now):
+		return false
+	case query.LoanedFrom != 0 && loan.LoanDate < query.LoanedFrom:
+		return false
+	case query.LoanedTo != 0 && loan.LoanDate > query.LoanedTo:
+		return false
+	case query.DueFrom != 0 && loan.ReturnDate < query.DueFrom:
+		return false
+	case query.DueTo != 0 && loan.ReturnDate > query.DueTo:
+		return false
+	}
+	return true
+}
+
+// GetAllBookDetails retreves a page of the books passing the filters of the query
+func (l *LocalStore) GetAllBookDetails(ctx context.Context, query *model.BookQuery) (*model.BookPage, error) {
+	l.rmu.RLock()
+	defer l.rmu.RUnlock()
+	books := make([]*model.BookDetails, 0)
+	for _, book := range l.books {
+		if matchesBook(book, query) {
+			books = append(books, book)
+		}
+	}
+	books, next := paginate(books, bookKey(query.SortBy), &query.PageQuery)
+	for i, book := range books {
+		books[i] = bookDetailsCopy(book)
+	}
+	page := &model.BookPage{Books: books}
+	if next != nil {
+		page.NextCursor = next.Encode()
+	}
+	return page, nil
+}
+
+// GetAllLoans retreves a page of the loans passing the filters of the query
+func (l *LocalStore) GetAllLoans(ctx context.Context, query *model.LoanQuery) (*model.LoanPage, error) {
+	l.rmu.RLock()
+	defer l.rmu.RUnlock()
+	now := time.Now().Unix()
+	loans := make([]*model.LoanDetails, 0)
+	for _, loan := range l.loans {
+		if matchesLoan(loan, query, now) {
+			loans = append(loans, loan)
+		}
+	}
+	loans, next := paginate(loans, loanKey(query.SortBy), &query.PageQuery)
+	for i, loan := range loans {
+		loans[i] = loanCopy(loan)
+	}
+	page := &model.LoanPage{Loans: loans}
+	if next != nil {
+		page.NextCursor = next.Encode()
+	}
+	return page, nil
+}
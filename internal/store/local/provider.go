@@ -1,43 +1,121 @@
 package local
 
 import (
-	"strings"
-
+	"github.com/test/library-app/internal/config"
 	"github.com/test/library-app/internal/model"
 )
 
-// InitLocalStore initializes with some book details
+// InitLocalStore initializes with some book details, their copies and a member. A durable store
+// recovers its state from the data directory instead once it has any
 func InitLocalStore() (*LocalStore, error) {
 	books := []*model.BookDetails{
 		{
+			ISBN:            "9780062315007",
 			Title:           "Alchemist",
-			AvailableCopies: 3,
+			Authors:         []string{"Paulo Coelho"},
+			Publisher:       "HarperOne",
+			PublicationYear: 1988,
+			Language:        "en",
+			Subjects:        []string{"fiction", "fable"},
+			TotalCopies:     3,
 		},
 		{
+			ISBN:            "9780735211292",
 			Title:           "Atomic Habbits",
-			AvailableCopies: 10,
+			Authors:         []string{"James Clear"},
+			Publisher:       "Avery",
+			PublicationYear: 2018,
+			Language:        "en",
+			Subjects:        []string{"self-help"},
+			TotalCopies:     10,
 		},
 		{
+			ISBN:            "9780062316097",
 			Title:           "Sapiens",
-			AvailableCopies: 10,
+			Authors:         []string{"Yuval Noah Harari"},
+			Publisher:       "Harper",
+			PublicationYear: 2015,
+			Language:        "en",
+			Subjects:        []string{"history", "anthropology"},
+			TotalCopies:     10,
 		},
 		{
+			ISBN:            "9780061120084",
 			Title:           "Mocking Bird",
-			AvailableCopies: 10,
+			Authors:         []string{"Harper Lee"},
+			Publisher:       "Harper Perennial",
+			PublicationYear: 1960,
+			Language:        "en",
+			Subjects:        []string{"fiction", "classics"},
+			TotalCopies:     10,
 		},
 		{
+			ISBN:            "9780451526342",
 			Title:           "Animal Farm",
-			AvailableCopies: 10,
+			Authors:         []string{"George Orwell"},
+			Publisher:       "Signet Classics",
+			PublicationYear: 1945,
+			Language:        "en",
+			Subjects:        []string{"fiction", "satire"},
+			TotalCopies:     10,
 		},
 	}
-	var localStore = make(map[string]*model.BookDetails)
+	localStore := &LocalStore{
+		books:       make(map[int]*model.BookDetails),
+		titles:      make(map[string][]int),
+		isbns:       make(map[string]int),
+		search:      newSearchIndex(),
+		copies:      make(map[int]*model.BookCopy),
+		barcodes:    make(map[string]int),
+		bookCopies:  make(map[int][]int),
+		members:     make(map[int]*model.Member),
+		emails:      make(map[string]int),
+		cards:       make(map[string]int),
+		loans:       make(map[int]*model.LoanDetails), // initializing the map
+		memberLoans: make(map[int][]int),
+		holds:       make(map[int]*model.Hold),
+		fines:       make(map[int]*model.Fine),
+		events:      make(map[int][]*model.LoanEvent),
+		idempotency: make(map[string]*model.IdempotencyRecord),
+		apiKeys:     make(map[int]*model.APIKey),
+		keyHashes:   make(map[string]int),
+		dataDir:     config.LocalConfig.DataDir,
+	}
+	recovered := false
+	if localStore.dataDir != "" {
+		var err error
+		if recovered, err = localStore.recover(localStore.dataDir); err != nil {
+			return nil, err
+		}
+	}
+	if !recovered {
+		if err := localStore.seed(books); err != nil {
+			return nil, err
+		}
+	}
+	if localStore.dataDir != "" {
+		// starting the log off a snapshot of the state
+		if err := localStore.writeSnapshot(); err != nil {
+			return nil, err
+		}
+	}
+	return localStore, nil
+}
+
+// seed adds the books with their copies and a member to an empty store, callers must hold the lock
+func (l *LocalStore) seed(books []*model.BookDetails) error {
 	for _, book := range books {
-		// lowering the title to keep it as key
-		title := strings.ToLower(book.Title)
-		localStore[title] = book
+		l.lastBookID++
+		book.ID = l.lastBookID
+		book.Version = 1
+		l.books[book.ID] = book
+		l.indexBook(book)
+		for i := 0; i < book.TotalCopies; i++ {
+			if err := l.addCopy(&model.BookCopy{BookID: book.ID, ShelfLocation: "STACKS"}); err != nil {
+				return err
+			}
+		}
+		l.refreshCopyCounts(book.ID)
 	}
-	return &LocalStore{
-		books: localStore,
-		loans: make(map[int]*model.LoanDetails), // initializing the map
-	}, nil
+	return l.addMember(&model.Member{Name: "John Doe", Email: "john@example.com"})
 }
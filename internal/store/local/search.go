@@ -0,0 +1,208 @@
+package local
+
+import (
+	"context"
+	"math"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/test/library-app/internal/constants"
+	"github.com/test/library-app/internal/model"
+)
+
+// fieldWeights ranks a match in the title over the one in the description
+var fieldWeights = map[string]float64{
+	constants.SearchFieldTitle:       1.0,
+	constants.SearchFieldAuthors:     0.4,
+	constants.SearchFieldSubjects:    0.2,
+	constants.SearchFieldDescription: 0.1,
+}
+
+// stopWords are too common to be indexed
+var stopWords = map[string]bool{
+	"a": true, "an": true, "and": true, "are": true, "as": true, "at": true, "be": true, "by": true,
+	"for": true, "from": true, "in": true, "is": true, "it": true, "of": true, "on": true, "or": true,
+	"the": true, "to": true, "with": true,
+}
+
+// token is a word of a text along with its byte offsets
+type token struct {
+	term       string
+	start, end int
+}
+
+// tokenize splits the text into lowered words, skipping the stop words
+func tokenize(text string) []token {
+	tokens := make([]token, 0)
+	start := -1
+	flush := func(end int) {
+		if start < 0 {
+			return
+		}
+		term := strings.ToLower(text[start:end])
+		if !stopWords[term] {
+			tokens = append(tokens, token{term: term, start: start, end: end})
+		}
+		start = -1
+	}
+	for i, r := range text {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			if start < 0 {
+				start = i
+			}
+			continue
+		}
+		flush(i)
+	}
+	flush(len(text))
+	return tokens
+}
+
+// searchableFields maps the searchable fields of a book to their text
+func searchableFields(book *model.BookDetails) map[string]string {
+	return map[string]string{
+		constants.SearchFieldTitle:       book.Title,
+		constants.SearchFieldAuthors:     strings.Join(book.Authors, ", "),
+		constants.SearchFieldSubjects:    strings.Join(book.Subjects, ", "),
+		constants.SearchFieldDescription: book.Description,
+	}
+}
+
+// searchIndex is an inverted index of the searchable fields of books, guarded by the store lock
+type searchIndex struct {
+	postings map[string]map[int]map[string]int // term frequency key as term, book ID and field
+}
+
+func newSearchIndex() *searchIndex {
+	return &searchIndex{
+		postings: make(map[string]map[int]map[string]int),
+	}
+}
+
+// add indexes the searchable fields of a book
+func (s *searchIndex) add(book *model.BookDetails) {
+	for field, text := range searchableFields(book) {
+		for _, tok := range tokenize(text) {
+			books, ok := s.postings[tok.term]
+			if !ok {
+				books = make(map[int]map[string]int)
+				s.postings[tok.term] = books
+			}
+			fields, ok := books[book.ID]
+			if !ok {
+				fields = make(map[string]int)
+				books[book.ID] = fields
+			}
+			fields[field]++
+		}
+	}
+}
+
+// remove drops a book from the index
+func (s *searchIndex) remove(book *model.BookDetails) {
+	for _, text := range searchableFields(book) {
+		for _, tok := range tokenize(text) {
+			books, ok := s.postings[tok.term]
+			if !ok {
+				continue
+			}
+			delete(books, book.ID)
+			if len(books) == 0 {
+				delete(s.postings, tok.term)
+			}
+		}
+	}
+}
+
+// search scores the books matching every term of the query by weighted term frequency and
+// inverse document frequency, totalBooks is the no of books indexed
+func (s *searchIndex) search(query string, totalBooks int) map[int]float64 {
+	terms := make(map[string]bool)
+	for _, tok := range tokenize(query) {
+		terms[tok.term] = true
+	}
+	if len(terms) == 0 {
+		return nil
+	}
+	var scores map[int]float64
+	for term := range terms {
+		books := s.postings[term]
+		idf := math.Log(1 + float64(totalBooks)/float64(len(books)+1))
+		termScores := make(map[int]float64, len(books))
+		for bookID, fields := range books {
+			// a book has to match all the terms
+			if _, ok := scores[bookID]; scores != nil && !ok {
+				continue
+			}
+			for field, freq := range fields {
+				termScores[bookID] += fieldWeights[field] * float64(freq) * idf
+			}
+		}
+		for bookID, score := range scores {
+			if _, ok := termScores[bookID]; ok {
+				termScores[bookID] += score
+			}
+		}
+		scores = termScores
+		if len(scores) == 0 {
+			return nil
+		}
+	}
+	return scores
+}
+
+// highlight wraps the words of the searchable fields matching the query, fields without a match are left out
+func highlight(book *model.BookDetails, query string) map[string]string {
+	terms := make(map[string]bool)
+	for _, tok := range tokenize(query) {
+		terms[tok.term] = true
+	}
+	highlights := make(map[string]string)
+	for field, text := range searchableFields(book) {
+		var sb strings.Builder
+		last := 0
+		for _, tok := range tokenize(text) {
+			if !terms[tok.term] {
+				continue
+			}
+			sb.WriteString(text[last:tok.start])
+			sb.WriteString(constants.HighlightStart)
+			sb.WriteString(text[tok.start:tok.end])
+			sb.WriteString(constants.HighlightStop)
+			last = tok.end
+		}
+		if last == 0 {
+			continue
+		}
+		sb.WriteString(text[last:])
+		highlights[field] = sb.String()
+	}
+	return highlights
+}
+
+// SearchBooks retreves the books best matching the query across title, authors, subjects and description
+func (l *LocalStore) SearchBooks(ctx context.Context, query string, limit int) ([]*model.BookSearchResult, error) {
+	l.rmu.RLock()
+	defer l.rmu.RUnlock()
+	scores := l.search.search(query, len(l.books))
+	results := make([]*model.BookSearchResult, 0, len(scores))
+	for bookID, score := range scores {
+		book := l.books[bookID]
+		results = append(results, &model.BookSearchResult{
+			Book:       bookDetailsCopy(book),
+			Score:      score,
+			Highlights: highlight(book, query),
+		})
+	}
+	sort.Slice(results, func(i, j int) bool {
+		if results[i].Score != results[j].Score {
+			return results[i].Score > results[j].Score
+		}
+		return results[i].Book.ID < results[j].Book.ID
+	})
+	if limit > 0 && len(results) > limit {
+		results = results[:limit]
+	}
+	return results, nil
+}
@@ -3,109 +3,374 @@ package local
 import (
 	"context"
 	"fmt"
+	"os"
+	"sort"
 	"strings"
 	"sync"
-	"sync/atomic"
 	"time"
 
 	"github.com/test/library-app/internal/constants"
 	"github.com/test/library-app/internal/logger"
 	"github.com/test/library-app/internal/model"
+	"github.com/test/library-app/internal/policy"
 )
 
 // making the members of store as private to avoid updating from elsewhere other than the allowed functions
 type LocalStore struct {
-	rmu   sync.RWMutex
-	books map[string]*model.BookDetails // stores the Books key as book tiltle
-	loans map[int]*model.LoanDetails    // stores the loans key as loan ID
+	rmu          sync.RWMutex
+	books        map[int]*model.BookDetails // stores the Books key as book ID
+	titles       map[string][]int           // stores the book IDs in ascending order key as lowered book title
+	isbns        map[string]int             // stores the book ID key as ISBN
+	search       *searchIndex               // full text index of the books
+	copies       map[int]*model.BookCopy    // stores the copies key as copy ID
+	barcodes     map[string]int             // stores the copy ID key as barcode
+	bookCopies   map[int][]int              // stores the copy IDs in ascending order key as book ID
+	members      map[int]*model.Member      // stores the members key as member ID
+	emails       map[string]int             // stores the member ID key as lowered email
+	cards        map[string]int             // stores the member ID key as card number
+	loans        map[int]*model.LoanDetails // stores the loans key as loan ID
+	memberLoans  map[int][]int              // stores the loan IDs in ascending order key as member ID
+	holds        map[int]*model.Hold        // stores the holds key as hold ID
+	fines        map[int]*model.Fine        // stores the fines key as fine ID
+	events       map[int][]*model.LoanEvent // stores the transitions in the order made key as loan ID
+	lastBookID   int                        // last book ID handed out, guarded by rmu
+	lastCopyID   int                        // last copy ID handed out, guarded by rmu
+	lastMemberID int                        // last member ID handed out, guarded by rmu
+	lastLoanID   int                        // last loan ID handed out, guarded by rmu
+	lastHoldID   int                        // last hold ID handed out, guarded by rmu
+	lastFineID   int                        // last fine ID handed out, guarded by rmu
+	lastEventID  int                        // last loan event ID handed out, guarded by rmu
+	dataDir      string                     // directory of the snapshot and log of a durable store, empty in memory only
+	wal          *os.File                   // log the writes are appended to, nil in memory only
+	walRecords   int                        // No of records logged since the snapshot
+	walErr       error                      // failure of logging a write, refuses writes from then on
+	seq          uint64                     // last log record written or recovered
+
+	idempotency map[string]*model.IdempotencyRecord // stores the requests made with an Idempotency-Key key as the key
+	apiKeys     map[int]*model.APIKey               // stores the api keys key as api key ID
+	keyHashes   map[string]int                      // stores the api key ID key as hash of the key
+	lastKeyID   int                                 // last api key ID handed out, guarded by rmu
 }
 
-func (l *LocalStore) GetAllBookDetails(ctx context.Context) ([]*model.BookDetails, error) {
-	l.rmu.RLock()
-	defer l.rmu.RUnlock()
-	books := make([]*model.BookDetails, 0)
-	for _, book := range l.books {
-		books = append(books, book)
+// indexBook adds the book to the title, ISBN and search indexes, callers must hold the lock
+func (l *LocalStore) indexBook(book *model.BookDetails) {
+	title := strings.ToLower(book.Title)
+	ids := append(l.titles[title], book.ID)
+	sort.Ints(ids)
+	l.titles[title] = ids
+	if book.ISBN != "" {
+		l.isbns[book.ISBN] = book.ID
+	}
+	l.search.add(book)
+}
+
+// unindexBook removes the book from the title, ISBN and search indexes, callers must hold the lock
+func (l *LocalStore) unindexBook(book *model.BookDetails) {
+	title := strings.ToLower(book.Title)
+	ids := l.titles[title]
+	for i, id := range ids {
+		if id == book.ID {
+			ids = append(ids[:i], ids[i+1:]...)
+			break
+		}
+	}
+	if len(ids) == 0 {
+		delete(l.titles, title)
+	} else {
+		l.titles[title] = ids
+	}
+	delete(l.isbns, book.ISBN)
+	l.search.remove(book)
+}
+
+// bookByTitle looks up the oldest book with the case insensitive title, callers must hold the lock
+func (l *LocalStore) bookByTitle(title string) (*model.BookDetails, bool) {
+	ids, ok := l.titles[strings.ToLower(title)]
+	if !ok {
+		return nil, false
+	}
+	book, ok := l.books[ids[0]]
+	return book, ok
+}
+
+// bookForLoan resolves the loaned book by the copy barcode, its ID, or by its title when both are absent,
+// callers must hold the lock
+func (l *LocalStore) bookForLoan(det *model.LoanDetails) (*model.BookDetails, error) {
+	if det.Barcode != "" {
+		id, ok := l.barcodes[det.Barcode]
+		if !ok {
+			return nil, fmt.Errorf("copy with barcode '%s' isn't presents. %w", det.Barcode, model.ErrNotFound)
+		}
+		det.BookID = l.copies[id].BookID
+	}
+	if det.BookID != 0 {
+		book, ok := l.books[det.BookID]
+		if !ok {
+			return nil, fmt.Errorf("book %d isn't presents. %w", det.BookID, model.ErrNotFound)
+		}
+		return book, nil
+	}
+	ids := l.titles[strings.ToLower(det.Title)]
+	switch len(ids) {
+	case 0:
+		return nil, fmt.Errorf("book with title '%s' isn't presents. %w", det.Title, model.ErrNotFound)
+	case 1:
+		return l.books[ids[0]], nil
+	default:
+		return nil, fmt.Errorf("%d books share the title '%s', book_id is required. %w", len(ids), det.Title, model.ErrConflict)
+	}
+}
+
+// hasActiveLoans reports whether any active loan holds the book, callers must hold the lock
+func (l *LocalStore) hasActiveLoans(bookID int) bool {
+	for _, loan := range l.loans {
+		if loan.Status != constants.Closed && loan.BookID == bookID {
+			return true
+		}
 	}
-	return books, nil
+	return false
 }
 
-func (l *LocalStore) GetAllLoans(ctx context.Context) ([]*model.LoanDetails, error) {
+// GetBookDetailsByID retreves book details by its ID from store
+func (l *LocalStore) GetBookDetailsByID(ctx context.Context, bookID int) (*model.BookDetails, error) {
 	l.rmu.RLock()
 	defer l.rmu.RUnlock()
-	loans := make([]*model.LoanDetails, 0)
-	for _, loan := range l.loans {
-		loans = append(loans, loan)
+	book, ok := l.books[bookID]
+	if !ok {
+		// wrapping with NotFound error to identify the error type by caller or middleware
+		return nil, fmt.Errorf("book %d isn't presents. %w", bookID, model.ErrNotFound)
+	}
+	return bookDetailsCopy(book), nil
+}
+
+// AddBook adds a new book to the catalog
+func (l *LocalStore) AddBook(ctx context.Context, det *model.BookDetails) (int, error) {
+	l.rmu.Lock()
+	defer l.rmu.Unlock()
+	if err := l.writable(); err != nil {
+		return 0, err
+	}
+	if _, ok := l.isbns[det.ISBN]; ok && det.ISBN != "" {
+		// wrapping with AlreadyExists error to identify the error type by caller or middleware
+		return 0, fmt.Errorf("book with isbn '%s' already presents. %w", det.ISBN, model.ErrAlreadyExists)
+	}
+	l.lastBookID++
+	det.ID = l.lastBookID
+	det.Version = 1
+	l.books[det.ID] = det
+	l.indexBook(det)
+	for i := 0; i < det.TotalCopies; i++ {
+		if err := l.addCopy(&model.BookCopy{BookID: det.ID}); err != nil {
+			return 0, err
+		}
+	}
+	l.refreshCopyCounts(det.ID)
+	if err := l.persist([]int{det.ID}, nil); err != nil {
+		return 0, err
+	}
+	logger.FromContext(ctx).Infof("Book %d added with title: %s", det.ID, det.Title)
+	return det.ID, nil
+}
+
+// UpdateBook replaces the details of a book based on its version, any version when 0
+func (l *LocalStore) UpdateBook(ctx context.Context, bookID int, version int, det *model.BookDetails) (*model.BookDetails, error) {
+	l.rmu.Lock()
+	defer l.rmu.Unlock()
+	if err := l.writable(); err != nil {
+		return nil, err
+	}
+	book, ok := l.books[bookID]
+	if !ok {
+		return nil, fmt.Errorf("book %d isn't presents. %w", bookID, model.ErrNotFound)
+	}
+	if version != 0 && version != book.Version {
+		return nil, fmt.Errorf("book %d is at version %d, not %d. %w", bookID, book.Version, version, model.ErrPreconditionFailed)
+	}
+	if id, ok := l.isbns[det.ISBN]; ok && det.ISBN != "" && id != bookID {
+		return nil, fmt.Errorf("book with isbn '%s' already presents. %w", det.ISBN, model.ErrAlreadyExists)
+	}
+	l.unindexBook(book)
+	// copy counts are derived from the copies, not taken from the request
+	det.ID = bookID
+	det.AvailableCopies = book.AvailableCopies
+	det.TotalCopies = book.TotalCopies
+	det.Version = book.Version + 1
+	*book = *det
+	l.indexBook(book)
+	if err := l.persist([]int{bookID}, nil); err != nil {
+		return nil, err
+	}
+	logger.FromContext(ctx).Infof("Book %d updated", bookID)
+	return bookDetailsCopy(book), nil
+}
+
+// UpdateBookCopies adds copies with generated barcodes or withdraws available copies of a book based on its version, any version when 0
+func (l *LocalStore) UpdateBookCopies(ctx context.Context, bookID int, version int, delta int) (*model.BookDetails, error) {
+	l.rmu.Lock()
+	defer l.rmu.Unlock()
+	if err := l.writable(); err != nil {
+		return nil, err
+	}
+	book, ok := l.books[bookID]
+	if !ok {
+		return nil, fmt.Errorf("book %d isn't presents. %w", bookID, model.ErrNotFound)
+	}
+	if version != 0 && version != book.Version {
+		return nil, fmt.Errorf("book %d is at version %d, not %d. %w", bookID, book.Version, version, model.ErrPreconditionFailed)
+	}
+	if book.AvailableCopies+delta < 0 {
+		return nil, fmt.Errorf("book %d has only %d copies available. %w", bookID, book.AvailableCopies, model.ErrConflict)
+	}
+	for i := 0; i < delta; i++ {
+		if err := l.addCopy(&model.BookCopy{BookID: bookID}); err != nil {
+			return nil, err
+		}
+	}
+	for i := 0; i > delta; i-- {
+		bookCopy, _ := l.availableCopy(bookID)
+		bookCopy.Status = constants.CopyWithdrawn
+	}
+	l.refreshCopyCounts(bookID)
+	book.Version++
+	if err := l.persist([]int{bookID}, nil); err != nil {
+		return nil, err
+	}
+	logger.FromContext(ctx).Infof("Available copies of book %d updated by %d", bookID, delta)
+	return bookDetailsCopy(book), nil
+}
+
+// DeleteBook removes a book from the catalog, refused while it has active loans
+func (l *LocalStore) DeleteBook(ctx context.Context, bookID int) error {
+	l.rmu.Lock()
+	defer l.rmu.Unlock()
+	if err := l.writable(); err != nil {
+		return err
+	}
+	if _, ok := l.books[bookID]; !ok {
+		return fmt.Errorf("book %d isn't presents. %w", bookID, model.ErrNotFound)
+	}
+	if l.hasActiveLoans(bookID) {
+		return fmt.Errorf("book %d has active loans. %w", bookID, model.ErrConflict)
+	}
+	l.removeBookScope(bookID)
+	if err := l.persist([]int{bookID}, nil); err != nil {
+		return err
+	}
+	logger.FromContext(ctx).Infof("Book %d deleted", bookID)
+	return nil
+}
+
+// removeBookScope removes the book with its copies and holds, callers must hold the lock
+func (l *LocalStore) removeBookScope(bookID int) {
+	if book, ok := l.books[bookID]; ok {
+		l.unindexBook(book)
+		delete(l.books, bookID)
+	}
+	for _, id := range l.bookCopies[bookID] {
+		delete(l.barcodes, l.copies[id].Barcode)
+		delete(l.copies, id)
+	}
+	delete(l.bookCopies, bookID)
+	for id, hold := range l.holds {
+		if hold.BookID == bookID {
+			delete(l.holds, id)
+		}
 	}
-	return loans, nil
 }
 
-// GetBookDetails retreves book details from store
+// GetBookDetails retreves the oldest book with the title from store
 func (l *LocalStore) GetBookDetails(ctx context.Context, title string) (*model.BookDetails, error) {
 	l.rmu.RLock()
 	defer l.rmu.RUnlock()
 	// retireving it from store
-	book, ok := l.books[strings.ToLower(title)]
+	book, ok := l.bookByTitle(title)
 	if !ok {
 		// If requested title isn't presents returning error with info,
 		// err := fmt.Errorf("book with title '%s' isn't presents", title)
 		// wrapping with NotFound error to identify the error type by caller or middleware
 		return nil, fmt.Errorf("book with title '%s' isn't presents. %w", title, model.ErrNotFound)
 	}
-	return book, nil
+	return bookDetailsCopy(book), nil
 }
 
 // AddLoan adds the loan details to store
 func (l *LocalStore) AddLoan(ctx context.Context, det *model.LoanDetails) (int, error) {
 	l.rmu.Lock()
 	defer l.rmu.Unlock()
-	book, ok := l.books[strings.ToLower(det.Title)]
+	if err := l.writable(); err != nil {
+		return 0, err
+	}
+	member, ok := l.members[det.MemberID]
 	if !ok {
-		// If requested title isn't presents returning error with info,
-		err := fmt.Errorf("book with title '%s' isn't presents", det.Title)
-		// wrapping with NotFound error to identify the error type by caller or middleware
-		return 0, fmt.Errorf("%v %w", err, model.ErrNotFound)
+		return 0, fmt.Errorf("member %d isn't presents. %w", det.MemberID, model.ErrNotFound)
 	}
-	// if available copies are zero returning the error
-	if book.AvailableCopies == 0 {
-		// If requested title isn't presents returning error with info,
-		err := fmt.Errorf("book with title '%s' are out of stock", det.Title)
-		// wrapping with NotFound error to identify the error type by caller or middleware
-		return 0, fmt.Errorf("%v %w", err, model.ErrNotFound)
+	det.NameOfBorrower = member.Name
+	book, err := l.bookForLoan(det)
+	if err != nil {
+		return 0, err
 	}
-	// getting unique id
-	id := GetUniqueIncrementedID()
+	det.BookID = book.ID
+	det.Title = book.Title
+	if err := policy.CheckLoan(l.memberStanding(member), det, time.Now()); err != nil {
+		return 0, err
+	}
+	if det.LoanDate == 0 {
+		det.LoanDate = time.Now().Unix()
+	}
+	if det.ReturnDate == 0 {
+		det.ReturnDate = time.Unix(det.LoanDate, 0).Add(policy.Terms(member.Tier, book.Category).Period).Unix()
+	}
+	var bookCopy *model.BookCopy
+	hold := l.readyHold(det.MemberID, book.ID)
+	if hold != nil && (det.Barcode == "" || det.Barcode == hold.Barcode) {
+		// loaning the copy set aside for the member
+		bookCopy = l.copies[hold.CopyID]
+		hold.Status = constants.HoldFulfilled
+	} else if det.Barcode != "" {
+		bookCopy = l.copies[l.barcodes[det.Barcode]]
+		if bookCopy.Status != constants.CopyAvailable {
+			return 0, fmt.Errorf("copy with barcode '%s' is %s. %w", det.Barcode, bookCopy.Status, model.ErrConflict)
+		}
+	} else {
+		// if available copies are zero returning the error
+		var ok bool
+		bookCopy, ok = l.availableCopy(book.ID)
+		if !ok {
+			// If requested title isn't presents returning error with info,
+			err := fmt.Errorf("book with title '%s' are out of stock", det.Title)
+			// wrapping with NotFound error to identify the error type by caller or middleware
+			return 0, fmt.Errorf("%v %w", err, model.ErrNotFound)
+		}
+	}
+	det.CopyID = bookCopy.ID
+	det.Barcode = bookCopy.Barcode
+	l.lastLoanID++
+	id := l.lastLoanID
 	det.ID = id
+	det.Version = 1
 	// setting in to detailsshort
 	l.loans[id] = det
+	l.memberLoans[det.MemberID] = append(l.memberLoans[det.MemberID], id)
 
-	// reducing one from the avalilablecopies of the title
-	bookDet, ok := l.books[strings.ToLower(det.Title)]
-	if !ok {
-		// If requested title isn't presents returning error with info,
-		err := fmt.Errorf("book with title '%s' isn't presents", det.Title)
-		// wrapping with NotFound error to identify the error type by caller or middleware
-		return 0, fmt.Errorf("%v %w", err, model.ErrNotFound)
-	}
-	// reducing one from available copies
-	bookDet.AvailableCopies -= 1
+	// taking the copy off the shelf
+	bookCopy.Status = constants.CopyOnLoan
+	l.refreshCopyCounts(book.ID)
+	l.recordEvent(ctx, det, constants.LoanCreated, nil, time.Now())
 
-	logger.Infof("Loan entry added for book title: %s", det.Title)
+	if err := l.persist([]int{book.ID}, []int{det.MemberID}); err != nil {
+		return 0, err
+	}
+	logger.FromContext(ctx).Infof("Loan entry added for book title: %s", det.Title)
 	return id, nil
 }
 
-var uniqueID int32
-
-func GetUniqueIncrementedID() int {
-	// incrementing uniqueID
-	atomic.AddInt32(&uniqueID, 1)
-	return int(uniqueID)
-}
-
-// ExtendLoan by given value
-func (l *LocalStore) ExtendLoan(ctx context.Context, loanID int) (*model.LoanDetails, error) {
+// ExtendLoan extends the return date by the extension of the loan terms, refused once overdue or extended the most times
+func (l *LocalStore) ExtendLoan(ctx context.Context, loanID int, version int) (*model.LoanDetails, error) {
 	l.rmu.Lock()
 	defer l.rmu.Unlock()
+	if err := l.writable(); err != nil {
+		return nil, err
+	}
 	loan, ok := l.loans[loanID]
 	if !ok {
 		// If requested loan isn't presents returning error with info
@@ -114,20 +379,44 @@ func (l *LocalStore) ExtendLoan(ctx context.Context, loanID int) (*model.LoanDet
 		return nil, fmt.Errorf("%v %w", err, model.ErrNotFound)
 	}
 	if loan.Status == constants.Closed {
-		logger.Errorf("requested loan: %d already closed", loanID)
+		logger.FromContext(ctx).Errorf("requested loan: %d already closed", loanID)
 		return nil, fmt.Errorf("requested loan: %d already closed", loanID)
 	}
-	returnTime := time.Unix(loan.ReturnDate, 0)
-	// extending 3 weeks
-	loan.ReturnDate = returnTime.Add(24 * 7 * 3 * time.Hour).Unix()
-	logger.Infof("Loan extended for book title: %s", loan.Title)
-	return loan, nil
+	if version != 0 && version != loan.Version {
+		return nil, fmt.Errorf("loan %d is at version %d, not %d. %w", loanID, loan.Version, version, model.ErrPreconditionFailed)
+	}
+	// terms of deleted members and books fall back to the defaults
+	tier := constants.DefaultTier
+	if member, ok := l.members[loan.MemberID]; ok {
+		tier = member.Tier
+	}
+	category := ""
+	if book, ok := l.books[loan.BookID]; ok {
+		category = book.Category
+	}
+	terms := policy.Terms(tier, category)
+	if err := policy.CheckExtension(loan, terms); err != nil {
+		return nil, err
+	}
+	old := loanState(loan)
+	loan.ReturnDate = time.Unix(loan.ReturnDate, 0).Add(terms.Extension).Unix()
+	loan.Extensions++
+	loan.Version++
+	l.recordEvent(ctx, loan, constants.LoanExtended, old, time.Now())
+	if err := l.persist(nil, []int{loan.MemberID}); err != nil {
+		return nil, err
+	}
+	logger.FromContext(ctx).Infof("Loan extended for book title: %s", loan.Title)
+	return loanCopy(loan), nil
 }
 
 // ExtendLoan by given value
-func (l *LocalStore) ReturnBook(ctx context.Context, loanID int) (*model.LoanDetails, error) {
+func (l *LocalStore) ReturnBook(ctx context.Context, loanID int, version int) (*model.LoanDetails, error) {
 	l.rmu.Lock()
 	defer l.rmu.Unlock()
+	if err := l.writable(); err != nil {
+		return nil, err
+	}
 	loan, ok := l.loans[loanID]
 	if !ok {
 		// If requested loan isn't presents returning error with info
@@ -136,32 +425,77 @@ func (l *LocalStore) ReturnBook(ctx context.Context, loanID int) (*model.LoanDet
 		return nil, fmt.Errorf("%v %w", err, model.ErrNotFound)
 	}
 	if loan.Status == constants.Closed {
-		logger.Errorf("requested loan: %d already closed", loanID)
+		logger.FromContext(ctx).Errorf("requested loan: %d already closed", loanID)
 		return nil, fmt.Errorf("requested loan: %d already closed", loanID)
 	}
-	// reducing one from the avalilablecopies of the title
-	bookDet, ok := l.books[strings.ToLower(loan.Title)]
+	if version != 0 && version != loan.Version {
+		return nil, fmt.Errorf("loan %d is at version %d, not %d. %w", loanID, loan.Version, version, model.ErrPreconditionFailed)
+	}
+	// putting the copy back on the shelf or aside for the next hold
+	bookCopy, ok := l.copies[loan.CopyID]
 	if !ok {
-		// If requested title isn't presents returning error with info,
-		err := fmt.Errorf("book with title '%s' isn't presents", loan.Title)
+		// If requested copy isn't presents returning error with info,
+		err := fmt.Errorf("copy with barcode '%s' isn't presents", loan.Barcode)
 		// wrapping with NotFound error to identify the error type by caller or middleware
 		return nil, fmt.Errorf("%v %w", err, model.ErrNotFound)
 	}
-	// reducing one from available copies
-	bookDet.AvailableCopies += 1
-	logger.Infof("Title: %s is returned", loan.Title)
+	now := time.Now()
+	l.shelveCopy(bookCopy, now)
+	logger.FromContext(ctx).Infof("Title: %s is returned", loan.Title)
+	// the fine stops accruing once returned
+	l.fineLoan(loan, now, true)
 
 	// removing the loan from cache since book is returned
+	old := loanState(loan)
 	loan.Status = constants.Closed
-	logger.Infof("title: %s returned", loan.Title)
-	return loan, nil
+	loan.Version++
+	l.recordEvent(ctx, loan, constants.LoanReturned, old, now)
+	if err := l.persist([]int{bookCopy.BookID}, []int{loan.MemberID}); err != nil {
+		return nil, err
+	}
+	logger.FromContext(ctx).Infof("title: %s returned", loan.Title)
+	return loanCopy(loan), nil
 }
 
-// Close clears the memory
+// Ping fails once the store is closed or refuses writes after failing to log one
+func (l *LocalStore) Ping(ctx context.Context) error {
+	l.rmu.RLock()
+	defer l.rmu.RUnlock()
+	if l.books == nil {
+		return fmt.Errorf("local store is closed")
+	}
+	return l.writable()
+}
+
+// Close clears the memory, a durable store compacts its log into a snapshot first
 func (l *LocalStore) Close() error {
+	l.rmu.Lock()
+	defer l.rmu.Unlock()
+	var err error
+	if l.wal != nil {
+		// the state ran ahead of the log once logging failed
+		if l.walErr == nil {
+			err = l.writeSnapshot()
+		}
+		l.wal.Close()
+		l.wal = nil
+	}
 	logger.Infof("clearing up local store")
 	// clearing it up local store
 	l.books = nil
+	l.titles = nil
+	l.isbns = nil
+	l.search = nil
+	l.copies = nil
+	l.barcodes = nil
+	l.bookCopies = nil
+	l.members = nil
+	l.emails = nil
+	l.cards = nil
 	l.loans = nil
-	return nil
+	l.memberLoans = nil
+	l.holds = nil
+	l.fines = nil
+	l.events = nil
+	return err
 }
@@ -0,0 +1,31 @@
+package local
+
+import (
+	"context"
+	"time"
+
+	"github.com/test/library-app/internal/constants"
+	"github.com/test/library-app/internal/model"
+)
+
+// GetCirculationStats counts the open and overdue loans and the books with no copy available as of now
+func (l *LocalStore) GetCirculationStats(ctx context.Context, now time.Time) (*model.CirculationStats, error) {
+	l.rmu.RLock()
+	defer l.rmu.RUnlock()
+	stats := &model.CirculationStats{}
+	for _, loan := range l.loans {
+		if loan.Status == constants.Closed {
+			continue
+		}
+		stats.OpenLoans++
+		if loan.ReturnDate < now.Unix() {
+			stats.OverdueLoans++
+		}
+	}
+	for _, book := range l.books {
+		if book.AvailableCopies == 0 {
+			stats.UnavailableTitles++
+		}
+	}
+	return stats, nil
+}
@@ -0,0 +1,513 @@
+package local
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+
+	"github.com/test/library-app/internal/config"
+	"github.com/test/library-app/internal/logger"
+	"github.com/test/library-app/internal/model"
+)
+
+// files of a durable store under its data directory
+const (
+	snapshotFileName = "snapshot.json" // compacted state of the store
+	walFileName      = "wal.log"       // records of the writes since the snapshot, one JSON record per line
+)
+
+// counters are the last IDs handed out, restored so no ID is handed out twice
+type counters struct {
+	Book   int `json:"book"`
+	Copy   int `json:"copy"`
+	Member int `json:"member"`
+	Loan   int `json:"loan"`
+	Hold   int `json:"hold"`
+	Fine   int `json:"fine"`
+	Event  int `json:"event"`
+	APIKey int `json:"api_key"`
+}
+
+// storeState is the persisted state of the store, all of it in a snapshot and the scopes written in a log record
+type storeState struct {
+	Counters counters             `json:"counters"`
+	Books    []*model.BookDetails `json:"books,omitempty"`
+	Copies   []*model.BookCopy    `json:"copies,omitempty"`
+	Members  []*model.Member      `json:"members,omitempty"`
+	Loans    []*model.LoanDetails `json:"loans,omitempty"`
+	Holds    []*model.Hold        `json:"holds,omitempty"`
+	Fines    []*model.Fine        `json:"fines,omitempty"`
+	Events   []*model.LoanEvent   `json:"events,omitempty"`
+	// completed requests made with an Idempotency-Key, the ones in progress aren't persisted
+	IdempotencyKeys []*model.IdempotencyRecord `json:"idempotency_keys,omitempty"`
+	APIKeys         []*storedAPIKey            `json:"api_keys,omitempty"`
+}
+
+// storedAPIKey persists an api key along with its hash, which the api doesn't serve
+type storedAPIKey struct {
+	*model.APIKey
+	Hash string `json:"hash"`
+}
+
+// snapshot is the state of the store as of the log record Seq
+type snapshot struct {
+	Seq uint64 `json:"seq"`
+	storeState
+}
+
+// walRecord replaces the scopes of the books, members, loans, idempotency keys and api keys changed by a write with their state after it.
+// The scope of a book is the book with its copies and holds, the scope of a member is the member with
+// its loans, their events and its fines, the scope of a loan is the loan with its events and its fine
+type walRecord struct {
+	Seq       uint64   `json:"seq"`
+	BookIDs   []int    `json:"book_ids,omitempty"`
+	MemberIDs []int    `json:"member_ids,omitempty"`
+	LoanIDs   []int    `json:"loan_ids,omitempty"`
+	Keys      []string `json:"keys,omitempty"`
+	APIKeyIDs []int    `json:"api_key_ids,omitempty"`
+	storeState
+}
+
+// counters gives the last IDs handed out, callers must hold the lock
+func (l *LocalStore) counters() counters {
+	return counters{
+		Book:   l.lastBookID,
+		Copy:   l.lastCopyID,
+		Member: l.lastMemberID,
+		Loan:   l.lastLoanID,
+		Hold:   l.lastHoldID,
+		Fine:   l.lastFineID,
+		Event:  l.lastEventID,
+		APIKey: l.lastKeyID,
+	}
+}
+
+// fullState gives all of the state of the store, callers must hold the lock
+func (l *LocalStore) fullState() *storeState {
+	state := &storeState{Counters: l.counters()}
+	for _, book := range l.books {
+		state.Books = append(state.Books, book)
+	}
+	for _, bookCopy := range l.copies {
+		state.Copies = append(state.Copies, bookCopy)
+	}
+	for _, member := range l.members {
+		state.Members = append(state.Members, member)
+	}
+	for _, loan := range l.loans {
+		state.Loans = append(state.Loans, loan)
+	}
+	for _, hold := range l.holds {
+		state.Holds = append(state.Holds, hold)
+	}
+	for _, fine := range l.fines {
+		state.Fines = append(state.Fines, fine)
+	}
+	for _, events := range l.events {
+		state.Events = append(state.Events, events...)
+	}
+	for _, rec := range l.idempotency {
+		if rec.StatusCode != 0 {
+			state.IdempotencyKeys = append(state.IdempotencyKeys, rec)
+		}
+	}
+	for _, key := range l.apiKeys {
+		state.APIKeys = append(state.APIKeys, &storedAPIKey{APIKey: key, Hash: key.Hash})
+	}
+	return state
+}
+
+// scopeState gives the state of the scopes of the books and members, callers must hold the lock
+func (l *LocalStore) scopeState(bookIDs, memberIDs []int) *storeState {
+	state := &storeState{Counters: l.counters()}
+	for _, bookID := range bookIDs {
+		if book, ok := l.books[bookID]; ok {
+			state.Books = append(state.Books, book)
+		}
+		for _, id := range l.bookCopies[bookID] {
+			state.Copies = append(state.Copies, l.copies[id])
+		}
+		for _, hold := range l.holds {
+			if hold.BookID == bookID {
+				state.Holds = append(state.Holds, hold)
+			}
+		}
+	}
+	for _, memberID := range memberIDs {
+		if member, ok := l.members[memberID]; ok {
+			state.Members = append(state.Members, member)
+		}
+		for _, id := range l.memberLoans[memberID] {
+			state.Loans = append(state.Loans, l.loans[id])
+			state.Events = append(state.Events, l.events[id]...)
+		}
+		for _, fine := range l.fines {
+			if fine.MemberID == memberID {
+				state.Fines = append(state.Fines, fine)
+			}
+		}
+	}
+	return state
+}
+
+// restore stores the entities of the state rebuilding the indexes and takes over its counters,
+// callers must hold the lock
+func (l *LocalStore) restore(state *storeState) {
+	l.lastBookID = state.Counters.Book
+	l.lastCopyID = state.Counters.Copy
+	l.lastMemberID = state.Counters.Member
+	l.lastLoanID = state.Counters.Loan
+	l.lastHoldID = state.Counters.Hold
+	l.lastFineID = state.Counters.Fine
+	l.lastEventID = state.Counters.Event
+	l.lastKeyID = state.Counters.APIKey
+	for _, book := range state.Books {
+		// written before the books were versioned
+		if book.Version == 0 {
+			book.Version = 1
+		}
+		l.books[book.ID] = book
+		l.indexBook(book)
+	}
+	for _, bookCopy := range state.Copies {
+		l.copies[bookCopy.ID] = bookCopy
+		l.barcodes[bookCopy.Barcode] = bookCopy.ID
+		ids := append(l.bookCopies[bookCopy.BookID], bookCopy.ID)
+		sort.Ints(ids)
+		l.bookCopies[bookCopy.BookID] = ids
+	}
+	for _, member := range state.Members {
+		l.members[member.ID] = member
+		l.indexMember(member)
+	}
+	for _, loan := range state.Loans {
+		if loan.Version == 0 {
+			loan.Version = 1
+		}
+		l.loans[loan.ID] = loan
+		ids := append(l.memberLoans[loan.MemberID], loan.ID)
+		sort.Ints(ids)
+		l.memberLoans[loan.MemberID] = ids
+	}
+	for _, hold := range state.Holds {
+		l.holds[hold.ID] = hold
+	}
+	for _, fine := range state.Fines {
+		l.fines[fine.ID] = fine
+	}
+	for _, event := range state.Events {
+		events := append(l.events[event.LoanID], event)
+		sort.Slice(events, func(i, j int) bool {
+			return events[i].ID < events[j].ID
+		})
+		l.events[event.LoanID] = events
+	}
+	for _, rec := range state.IdempotencyKeys {
+		l.idempotency[rec.Key] = rec
+	}
+	for _, stored := range state.APIKeys {
+		key := stored.APIKey
+		key.Hash = stored.Hash
+		l.apiKeys[key.ID] = key
+		l.keyHashes[key.Hash] = key.ID
+	}
+}
+
+// removeMemberScope removes the member with its loans, their events and its fines, callers must hold the lock
+func (l *LocalStore) removeMemberScope(memberID int) {
+	if member, ok := l.members[memberID]; ok {
+		l.unindexMember(member)
+		delete(l.members, memberID)
+	}
+	for _, id := range l.memberLoans[memberID] {
+		delete(l.loans, id)
+		delete(l.events, id)
+	}
+	delete(l.memberLoans, memberID)
+	for id, fine := range l.fines {
+		if fine.MemberID == memberID {
+			delete(l.fines, id)
+		}
+	}
+}
+
+// removeLoanScopes removes the loans of the sorted IDs with their events and their fines, callers must hold the lock
+func (l *LocalStore) removeLoanScopes(loanIDs []int) {
+	for _, loanID := range loanIDs {
+		if loan, ok := l.loans[loanID]; ok {
+			l.memberLoans[loan.MemberID] = slices.DeleteFunc(l.memberLoans[loan.MemberID], func(id int) bool {
+				return id == loanID
+			})
+			delete(l.loans, loanID)
+		}
+		delete(l.events, loanID)
+	}
+	for id, fine := range l.fines {
+		if _, found := slices.BinarySearch(loanIDs, fine.LoanID); found {
+			delete(l.fines, id)
+		}
+	}
+}
+
+// replay applies a log record, callers must hold the lock
+func (l *LocalStore) replay(record *walRecord) {
+	for _, id := range record.BookIDs {
+		l.removeBookScope(id)
+	}
+	for _, id := range record.MemberIDs {
+		l.removeMemberScope(id)
+	}
+	l.removeLoanScopes(record.LoanIDs)
+	for _, key := range record.Keys {
+		delete(l.idempotency, key)
+	}
+	for _, id := range record.APIKeyIDs {
+		if key, ok := l.apiKeys[id]; ok {
+			delete(l.keyHashes, key.Hash)
+			delete(l.apiKeys, id)
+		}
+	}
+	l.restore(&record.storeState)
+}
+
+// recover loads the snapshot under dir and replays the log written since, reports whether any state was found.
+// A record torn by a crash while being appended is dropped, callers must hold the lock
+func (l *LocalStore) recover(dir string) (bool, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		logger.Errorf("failed to create the data directory %s. Error: %v", dir, err)
+		return false, err
+	}
+	found := false
+	data, err := os.ReadFile(filepath.Join(dir, snapshotFileName))
+	switch {
+	case err == nil:
+		var snap snapshot
+		if err := json.Unmarshal(data, &snap); err != nil {
+			return false, fmt.Errorf("failed to decode the snapshot under %s: %w", dir, err)
+		}
+		l.restore(&snap.storeState)
+		l.seq = snap.Seq
+		found = true
+	case !errors.Is(err, os.ErrNotExist):
+		logger.Errorf("failed to read the snapshot under %s. Error: %v", dir, err)
+		return false, err
+	}
+	file, err := os.Open(filepath.Join(dir, walFileName))
+	if errors.Is(err, os.ErrNotExist) {
+		return found, nil
+	}
+	if err != nil {
+		logger.Errorf("failed to open the log under %s. Error: %v", dir, err)
+		return false, err
+	}
+	defer file.Close()
+	reader := bufio.NewReader(file)
+	replayed := 0
+	for {
+		line, err := reader.ReadBytes('\n')
+		if errors.Is(err, io.EOF) {
+			if len(line) > 0 {
+				logger.Warnf("dropping the torn last record of the log under %s", dir)
+			}
+			break
+		}
+		if err != nil {
+			logger.Errorf("failed to read the log under %s. Error: %v", dir, err)
+			return false, err
+		}
+		var record walRecord
+		if err := json.Unmarshal(line, &record); err != nil {
+			return false, fmt.Errorf("failed to decode record %d of the log under %s: %w", l.seq+1, dir, err)
+		}
+		// compacted into the snapshot already when the log wasn't truncated after it
+		if record.Seq <= l.seq {
+			continue
+		}
+		l.replay(&record)
+		l.seq = record.Seq
+		replayed++
+		found = true
+	}
+	logger.Infof("local store recovered from %s as of record %d, %d records replayed", dir, l.seq, replayed)
+	return found, nil
+}
+
+// writeSnapshot compacts the state into a new snapshot under the data directory and starts a new log,
+// callers must hold the lock
+func (l *LocalStore) writeSnapshot() error {
+	data, err := json.Marshal(&snapshot{Seq: l.seq, storeState: *l.fullState()})
+	if err != nil {
+		logger.Errorf("failed to encode the snapshot. Error: %v", err)
+		return err
+	}
+	path := filepath.Join(l.dataDir, snapshotFileName)
+	// replacing the snapshot at once so a crash leaves either the old or the new one
+	if err := writeFileSync(path+".tmp", data); err != nil {
+		return err
+	}
+	if err := os.Rename(path+".tmp", path); err != nil {
+		logger.Errorf("failed to replace the snapshot %s. Error: %v", path, err)
+		return err
+	}
+	if err := syncDir(l.dataDir); err != nil {
+		return err
+	}
+	// the records are in the snapshot now, records left by a crash before the truncation are skipped by their Seq
+	wal, err := os.OpenFile(filepath.Join(l.dataDir, walFileName), os.O_CREATE|os.O_WRONLY|os.O_TRUNC|os.O_APPEND, 0o644)
+	if err != nil {
+		logger.Errorf("failed to open the log under %s. Error: %v", l.dataDir, err)
+		return err
+	}
+	if l.wal != nil {
+		l.wal.Close()
+	}
+	l.wal = wal
+	l.walRecords = 0
+	return nil
+}
+
+// writable fails once logging a write failed, the store refuses writes from then on as its state
+// runs ahead of the log. A restart recovers the logged state, callers must hold the lock
+func (l *LocalStore) writable() error {
+	if l.walErr != nil {
+		return fmt.Errorf("local store refuses writes after failing to log a write: %w", l.walErr)
+	}
+	return nil
+}
+
+// persist appends the state of the scopes of the books and members changed by a write to the log,
+// syncing it before the write is acknowledged, and compacts the log every SnapshotEveryWrites records.
+// Nothing is persisted by an in-memory store, callers must hold the lock
+func (l *LocalStore) persist(bookIDs, memberIDs []int) error {
+	if l.wal == nil {
+		return nil
+	}
+	// a scope is written once however often the write changed it
+	bookIDs, memberIDs = uniqueIDs(bookIDs), uniqueIDs(memberIDs)
+	return l.appendRecord(&walRecord{Seq: l.seq + 1, BookIDs: bookIDs, MemberIDs: memberIDs, storeState: *l.scopeState(bookIDs, memberIDs)})
+}
+
+// persistLoans appends the state of the scopes of the loans changed by a write to the log like persist,
+// sparing the writes changing a few loans of many members from logging every loan of them. Callers must hold the lock
+func (l *LocalStore) persistLoans(loanIDs []int) error {
+	if l.wal == nil {
+		return nil
+	}
+	loanIDs = uniqueIDs(loanIDs)
+	state := &storeState{Counters: l.counters()}
+	for _, id := range loanIDs {
+		if loan, ok := l.loans[id]; ok {
+			state.Loans = append(state.Loans, loan)
+			state.Events = append(state.Events, l.events[id]...)
+		}
+	}
+	for _, fine := range l.fines {
+		if _, found := slices.BinarySearch(loanIDs, fine.LoanID); found {
+			state.Fines = append(state.Fines, fine)
+		}
+	}
+	return l.appendRecord(&walRecord{Seq: l.seq + 1, LoanIDs: loanIDs, storeState: *state})
+}
+
+// persistKeys appends the state of the idempotency keys changed by a write to the log like persist,
+// the keys dropped are logged with no state. Callers must hold the lock
+func (l *LocalStore) persistKeys(keys []string) error {
+	if l.wal == nil {
+		return nil
+	}
+	state := &storeState{Counters: l.counters()}
+	for _, key := range keys {
+		if rec, ok := l.idempotency[key]; ok && rec.StatusCode != 0 {
+			state.IdempotencyKeys = append(state.IdempotencyKeys, rec)
+		}
+	}
+	return l.appendRecord(&walRecord{Seq: l.seq + 1, Keys: keys, storeState: *state})
+}
+
+// persistAPIKeys appends the state of the api keys changed by a write to the log like persist,
+// callers must hold the lock
+func (l *LocalStore) persistAPIKeys(ids []int) error {
+	if l.wal == nil {
+		return nil
+	}
+	state := &storeState{Counters: l.counters()}
+	for _, id := range ids {
+		if key, ok := l.apiKeys[id]; ok {
+			state.APIKeys = append(state.APIKeys, &storedAPIKey{APIKey: key, Hash: key.Hash})
+		}
+	}
+	return l.appendRecord(&walRecord{Seq: l.seq + 1, APIKeyIDs: ids, storeState: *state})
+}
+
+// appendRecord appends a record to the log syncing it, callers must hold the lock
+func (l *LocalStore) appendRecord(record *walRecord) error {
+	data, err := json.Marshal(record)
+	if err != nil {
+		logger.Errorf("failed to encode log record %d. Error: %v", record.Seq, err)
+		l.walErr = err
+		return err
+	}
+	if _, err = l.wal.Write(append(data, '\n')); err == nil {
+		err = l.wal.Sync()
+	}
+	if err != nil {
+		logger.Errorf("failed to append log record %d. Error: %v", record.Seq, err)
+		l.walErr = err
+		return err
+	}
+	l.seq = record.Seq
+	l.walRecords++
+	if l.walRecords >= config.LocalConfig.SnapshotEveryWrites {
+		// the write is logged, a failed compaction is retried by the next write
+		if err := l.writeSnapshot(); err != nil {
+			logger.Errorf("failed to compact the log into a snapshot. Error: %v", err)
+		}
+	}
+	return nil
+}
+
+// uniqueIDs sorts the IDs dropping the repeated ones
+func uniqueIDs(ids []int) []int {
+	slices.Sort(ids)
+	return slices.Compact(ids)
+}
+
+// writeFileSync writes the file and syncs it to the disk
+func writeFileSync(path string, data []byte) error {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o644)
+	if err != nil {
+		logger.Errorf("failed to create %s. Error: %v", path, err)
+		return err
+	}
+	if _, err = file.Write(data); err == nil {
+		err = file.Sync()
+	}
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		logger.Errorf("failed to write %s. Error: %v", path, err)
+	}
+	return err
+}
+
+// syncDir syncs the directory so the files renamed into it survive a crash
+func syncDir(dir string) error {
+	file, err := os.Open(dir)
+	if err != nil {
+		logger.Errorf("failed to open the data directory %s. Error: %v", dir, err)
+		return err
+	}
+	defer file.Close()
+	if err := file.Sync(); err != nil {
+		logger.Errorf("failed to sync the data directory %s. Error: %v", dir, err)
+		return err
+	}
+	return nil
+}
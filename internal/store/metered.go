@@ -0,0 +1,243 @@
+package store
+
+import (
+	"context"
+	"time"
+
+	"github.com/test/library-app/internal/metrics"
+	"github.com/test/library-app/internal/model"
+)
+
+// meteredStore observes the duration and the errors of every operation of the store it wraps
+type meteredStore struct {
+	next Store
+}
+
+// newMeteredStore wraps the store with the metrics of its operations
+func newMeteredStore(s Store) Store {
+	return &meteredStore{next: s}
+}
+
+// observe observes an operation started at start once it returns with the error
+func observe(operation string, start time.Time, err *error) {
+	metrics.ObserveStore(operation, time.Since(start), *err)
+}
+
+func (m *meteredStore) GetBookDetails(ctx context.Context, title string) (res *model.BookDetails, err error) {
+	defer observe("GetBookDetails", time.Now(), &err)
+	return m.next.GetBookDetails(ctx, title)
+}
+
+func (m *meteredStore) GetBookDetailsByID(ctx context.Context, bookID int) (res *model.BookDetails, err error) {
+	defer observe("GetBookDetailsByID", time.Now(), &err)
+	return m.next.GetBookDetailsByID(ctx, bookID)
+}
+
+func (m *meteredStore) GetAllBookDetails(ctx context.Context, query *model.BookQuery) (res *model.BookPage, err error) {
+	defer observe("GetAllBookDetails", time.Now(), &err)
+	return m.next.GetAllBookDetails(ctx, query)
+}
+
+func (m *meteredStore) SearchBooks(ctx context.Context, query string, limit int) (res []*model.BookSearchResult, err error) {
+	defer observe("SearchBooks", time.Now(), &err)
+	return m.next.SearchBooks(ctx, query, limit)
+}
+
+func (m *meteredStore) AddBook(ctx context.Context, det *model.BookDetails) (res int, err error) {
+	defer observe("AddBook", time.Now(), &err)
+	return m.next.AddBook(ctx, det)
+}
+
+func (m *meteredStore) UpdateBook(ctx context.Context, bookID int, version int, det *model.BookDetails) (res *model.BookDetails, err error) {
+	defer observe("UpdateBook", time.Now(), &err)
+	return m.next.UpdateBook(ctx, bookID, version, det)
+}
+
+func (m *meteredStore) UpdateBookCopies(ctx context.Context, bookID int, version int, delta int) (res *model.BookDetails, err error) {
+	defer observe("UpdateBookCopies", time.Now(), &err)
+	return m.next.UpdateBookCopies(ctx, bookID, version, delta)
+}
+
+func (m *meteredStore) AddBookCopy(ctx context.Context, det *model.BookCopy) (res int, err error) {
+	defer observe("AddBookCopy", time.Now(), &err)
+	return m.next.AddBookCopy(ctx, det)
+}
+
+func (m *meteredStore) GetBookCopies(ctx context.Context, bookID int) (res []*model.BookCopy, err error) {
+	defer observe("GetBookCopies", time.Now(), &err)
+	return m.next.GetBookCopies(ctx, bookID)
+}
+
+func (m *meteredStore) GetBookCopy(ctx context.Context, barcode string) (res *model.BookCopy, err error) {
+	defer observe("GetBookCopy", time.Now(), &err)
+	return m.next.GetBookCopy(ctx, barcode)
+}
+
+func (m *meteredStore) UpdateBookCopy(ctx context.Context, barcode string, det *model.BookCopy) (res *model.BookCopy, err error) {
+	defer observe("UpdateBookCopy", time.Now(), &err)
+	return m.next.UpdateBookCopy(ctx, barcode, det)
+}
+
+func (m *meteredStore) DeleteBook(ctx context.Context, bookID int) (err error) {
+	defer observe("DeleteBook", time.Now(), &err)
+	err = m.next.DeleteBook(ctx, bookID)
+	return err
+}
+
+func (m *meteredStore) AddMember(ctx context.Context, det *model.Member) (res int, err error) {
+	defer observe("AddMember", time.Now(), &err)
+	return m.next.AddMember(ctx, det)
+}
+
+func (m *meteredStore) GetMember(ctx context.Context, memberID int) (res *model.Member, err error) {
+	defer observe("GetMember", time.Now(), &err)
+	return m.next.GetMember(ctx, memberID)
+}
+
+func (m *meteredStore) GetAllMembers(ctx context.Context, query *model.MemberQuery) (res *model.MemberPage, err error) {
+	defer observe("GetAllMembers", time.Now(), &err)
+	return m.next.GetAllMembers(ctx, query)
+}
+
+func (m *meteredStore) UpdateMember(ctx context.Context, memberID int, det *model.Member) (res *model.Member, err error) {
+	defer observe("UpdateMember", time.Now(), &err)
+	return m.next.UpdateMember(ctx, memberID, det)
+}
+
+func (m *meteredStore) DeleteMember(ctx context.Context, memberID int) (err error) {
+	defer observe("DeleteMember", time.Now(), &err)
+	err = m.next.DeleteMember(ctx, memberID)
+	return err
+}
+
+func (m *meteredStore) AddHold(ctx context.Context, det *model.Hold) (res int, err error) {
+	defer observe("AddHold", time.Now(), &err)
+	return m.next.AddHold(ctx, det)
+}
+
+func (m *meteredStore) GetMemberHolds(ctx context.Context, memberID int) (res []*model.Hold, err error) {
+	defer observe("GetMemberHolds", time.Now(), &err)
+	return m.next.GetMemberHolds(ctx, memberID)
+}
+
+func (m *meteredStore) CancelHold(ctx context.Context, holdID int) (res *model.Hold, err error) {
+	defer observe("CancelHold", time.Now(), &err)
+	return m.next.CancelHold(ctx, holdID)
+}
+
+func (m *meteredStore) ExpireHolds(ctx context.Context, now time.Time) (res int, err error) {
+	defer observe("ExpireHolds", time.Now(), &err)
+	return m.next.ExpireHolds(ctx, now)
+}
+
+func (m *meteredStore) AccrueFines(ctx context.Context, now time.Time) (res int, err error) {
+	defer observe("AccrueFines", time.Now(), &err)
+	return m.next.AccrueFines(ctx, now)
+}
+
+func (m *meteredStore) GetMemberFines(ctx context.Context, memberID int) (res *model.MemberFines, err error) {
+	defer observe("GetMemberFines", time.Now(), &err)
+	return m.next.GetMemberFines(ctx, memberID)
+}
+
+func (m *meteredStore) PayFine(ctx context.Context, fineID int, amount int64) (res *model.Fine, err error) {
+	defer observe("PayFine", time.Now(), &err)
+	return m.next.PayFine(ctx, fineID, amount)
+}
+
+func (m *meteredStore) WaiveFine(ctx context.Context, fineID int) (res *model.Fine, err error) {
+	defer observe("WaiveFine", time.Now(), &err)
+	return m.next.WaiveFine(ctx, fineID)
+}
+
+func (m *meteredStore) GetLoan(ctx context.Context, loanID int) (res *model.LoanDetails, err error) {
+	defer observe("GetLoan", time.Now(), &err)
+	return m.next.GetLoan(ctx, loanID)
+}
+
+func (m *meteredStore) GetLoansByBorrower(ctx context.Context, memberID int) (res []*model.LoanDetails, err error) {
+	defer observe("GetLoansByBorrower", time.Now(), &err)
+	return m.next.GetLoansByBorrower(ctx, memberID)
+}
+
+func (m *meteredStore) GetAllLoans(ctx context.Context, query *model.LoanQuery) (res *model.LoanPage, err error) {
+	defer observe("GetAllLoans", time.Now(), &err)
+	return m.next.GetAllLoans(ctx, query)
+}
+
+func (m *meteredStore) AddLoan(ctx context.Context, det *model.LoanDetails) (res int, err error) {
+	defer observe("AddLoan", time.Now(), &err)
+	return m.next.AddLoan(ctx, det)
+}
+
+func (m *meteredStore) ExtendLoan(ctx context.Context, loanID int, version int) (res *model.LoanDetails, err error) {
+	defer observe("ExtendLoan", time.Now(), &err)
+	return m.next.ExtendLoan(ctx, loanID, version)
+}
+
+func (m *meteredStore) ReturnBook(ctx context.Context, loanID int, version int) (res *model.LoanDetails, err error) {
+	defer observe("ReturnBook", time.Now(), &err)
+	return m.next.ReturnBook(ctx, loanID, version)
+}
+
+func (m *meteredStore) GetLoanHistory(ctx context.Context, loanID int) (res []*model.LoanEvent, err error) {
+	defer observe("GetLoanHistory", time.Now(), &err)
+	return m.next.GetLoanHistory(ctx, loanID)
+}
+
+func (m *meteredStore) ReserveIdempotencyKey(ctx context.Context, rec *model.IdempotencyRecord, now time.Time) (res *model.IdempotencyRecord, err error) {
+	defer observe("ReserveIdempotencyKey", time.Now(), &err)
+	return m.next.ReserveIdempotencyKey(ctx, rec, now)
+}
+
+func (m *meteredStore) CompleteIdempotencyKey(ctx context.Context, rec *model.IdempotencyRecord) (err error) {
+	defer observe("CompleteIdempotencyKey", time.Now(), &err)
+	err = m.next.CompleteIdempotencyKey(ctx, rec)
+	return err
+}
+
+func (m *meteredStore) ReleaseIdempotencyKey(ctx context.Context, key string) (err error) {
+	defer observe("ReleaseIdempotencyKey", time.Now(), &err)
+	err = m.next.ReleaseIdempotencyKey(ctx, key)
+	return err
+}
+
+func (m *meteredStore) ExpireIdempotencyKeys(ctx context.Context, now time.Time) (res int, err error) {
+	defer observe("ExpireIdempotencyKeys", time.Now(), &err)
+	return m.next.ExpireIdempotencyKeys(ctx, now)
+}
+
+func (m *meteredStore) GetCirculationStats(ctx context.Context, now time.Time) (res *model.CirculationStats, err error) {
+	defer observe("GetCirculationStats", time.Now(), &err)
+	return m.next.GetCirculationStats(ctx, now)
+}
+
+func (m *meteredStore) AddAPIKey(ctx context.Context, key *model.APIKey) (res int, err error) {
+	defer observe("AddAPIKey", time.Now(), &err)
+	return m.next.AddAPIKey(ctx, key)
+}
+
+func (m *meteredStore) GetAPIKeyByHash(ctx context.Context, hash string) (res *model.APIKey, err error) {
+	defer observe("GetAPIKeyByHash", time.Now(), &err)
+	return m.next.GetAPIKeyByHash(ctx, hash)
+}
+
+func (m *meteredStore) GetAllAPIKeys(ctx context.Context) (res []*model.APIKey, err error) {
+	defer observe("GetAllAPIKeys", time.Now(), &err)
+	return m.next.GetAllAPIKeys(ctx)
+}
+
+func (m *meteredStore) RevokeAPIKey(ctx context.Context, keyID int, now time.Time) (res *model.APIKey, err error) {
+	defer observe("RevokeAPIKey", time.Now(), &err)
+	return m.next.RevokeAPIKey(ctx, keyID, now)
+}
+
+func (m *meteredStore) Ping(ctx context.Context) (err error) {
+	defer observe("Ping", time.Now(), &err)
+	err = m.next.Ping(ctx)
+	return err
+}
+
+func (m *meteredStore) Close() error {
+	return m.next.Close()
+}
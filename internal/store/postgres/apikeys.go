@@ -0,0 +1,120 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/test/library-app/internal/config"
+	"github.com/test/library-app/internal/logger"
+	"github.com/test/library-app/internal/model"
+)
+
+// apiKeyColumns lists the columns of the api keys table in the order scanAPIKey reads them
+const apiKeyColumns = `id, name, prefix, hash, scopes, requests_per_minute, created_at, revoked_at`
+
+// scanAPIKey scans a row selected with apiKeyColumns
+func scanAPIKey(row pgx.Row) (*model.APIKey, error) {
+	var key model.APIKey
+	var createdAt time.Time
+	var revokedAt *time.Time
+	if err := row.Scan(&key.ID, &key.Name, &key.Prefix, &key.Hash, &key.Scopes, &key.RequestsPerMinute, &createdAt, &revokedAt); err != nil {
+		return nil, err
+	}
+	key.CreatedAt = createdAt.Unix()
+	if revokedAt != nil {
+		key.RevokedAt = revokedAt.Unix()
+	}
+	return &key, nil
+}
+
+// AddAPIKey stores an issued api key, refused with ErrAlreadyExists when its name or hash is taken
+func (p *PostgresDB) AddAPIKey(ctx context.Context, key *model.APIKey) (int, error) {
+	if key.CreatedAt == 0 {
+		key.CreatedAt = time.Now().Unix()
+	}
+	query := fmt.Sprintf(`INSERT
+		INTO %s
+		(name, prefix, hash, scopes, requests_per_minute, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id
+	`, config.PostgresConfig.APIKeysTableName)
+	scopes := key.Scopes
+	if scopes == nil {
+		scopes = []string{}
+	}
+	err := p.DB.QueryRow(ctx, query, key.Name, key.Prefix, key.Hash, scopes, key.RequestsPerMinute, time.Unix(key.CreatedAt, 0)).Scan(&key.ID)
+	if err != nil {
+		logger.FromContext(ctx).Errorf("failed to insert api key %s. Error: %v", key.Name, err)
+		if isUniqueViolation(err) {
+			return 0, fmt.Errorf("api key named '%s' already presents. %w", key.Name, model.ErrAlreadyExists)
+		}
+		return 0, err
+	}
+	return key.ID, nil
+}
+
+// GetAPIKeyByHash retreves the api key with the hash whether revoked or not
+func (p *PostgresDB) GetAPIKeyByHash(ctx context.Context, hash string) (*model.APIKey, error) {
+	query := fmt.Sprintf(`SELECT %s FROM %s WHERE hash=$1`, apiKeyColumns, config.PostgresConfig.APIKeysTableName)
+	key, err := scanAPIKey(p.DB.QueryRow(ctx, query, hash))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("api key isn't presents. %w", model.ErrNotFound)
+		}
+		logger.FromContext(ctx).Errorf("failed to fetch api key. Error: %v", err)
+		return nil, err
+	}
+	return key, nil
+}
+
+// GetAllAPIKeys retreves the api keys in the order issued
+func (p *PostgresDB) GetAllAPIKeys(ctx context.Context) ([]*model.APIKey, error) {
+	query := fmt.Sprintf(`SELECT %s FROM %s ORDER BY id`, apiKeyColumns, config.PostgresConfig.APIKeysTableName)
+	rows, err := p.DB.Query(ctx, query)
+	if err != nil {
+		logger.FromContext(ctx).Errorf("failed to fetch api keys. Error: %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+	keys := make([]*model.APIKey, 0)
+	for rows.Next() {
+		key, err := scanAPIKey(rows)
+		if err != nil {
+			logger.FromContext(ctx).Errorf("failed to scan api key. Error: %v", err)
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+	return keys, rows.Err()
+}
+
+// RevokeAPIKey revokes an api key as of now, refused with ErrConflict once revoked
+func (p *PostgresDB) RevokeAPIKey(ctx context.Context, keyID int, now time.Time) (*model.APIKey, error) {
+	query := fmt.Sprintf(`UPDATE
+		%s SET revoked_at=$2
+		WHERE id=$1 AND revoked_at IS NULL
+		RETURNING %s
+	`, config.PostgresConfig.APIKeysTableName, apiKeyColumns)
+	key, err := scanAPIKey(p.DB.QueryRow(ctx, query, keyID, now))
+	if err == nil {
+		return key, nil
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		logger.FromContext(ctx).Errorf("failed to revoke api key %d. Error: %v", keyID, err)
+		return nil, err
+	}
+	// telling a missed key from a revoked one
+	query = fmt.Sprintf(`SELECT %s FROM %s WHERE id=$1`, apiKeyColumns, config.PostgresConfig.APIKeysTableName)
+	if _, err := scanAPIKey(p.DB.QueryRow(ctx, query, keyID)); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("api key %d isn't presents. %w", keyID, model.ErrNotFound)
+		}
+		logger.FromContext(ctx).Errorf("failed to fetch api key %d. Error: %v", keyID, err)
+		return nil, err
+	}
+	return nil, fmt.Errorf("api key %d is revoked already. %w", keyID, model.ErrConflict)
+}
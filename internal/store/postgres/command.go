@@ -0,0 +1,69 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+)
+
+// MigrateUsage describes the migrate subcommand
+const MigrateUsage = "usage: app migrate up | down [steps] | status"
+
+// RunMigrateCommand runs the migrate subcommand with its args, writing the outcome to out
+func RunMigrateCommand(ctx context.Context, args []string, out io.Writer) error {
+	if len(args) == 0 {
+		return errors.New(MigrateUsage)
+	}
+	steps := 1
+	switch args[0] {
+	case "up", "status":
+	case "down":
+		if len(args) > 1 {
+			var err error
+			steps, err = strconv.Atoi(args[1])
+			if err != nil || steps <= 0 {
+				return fmt.Errorf("steps must be a positive number. %s", MigrateUsage)
+			}
+		}
+	default:
+		return fmt.Errorf("unknown migrate command %q. %s", args[0], MigrateUsage)
+	}
+	db, err := Connect()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	switch args[0] {
+	case "up":
+		count, err := db.MigrateUp(ctx)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(out, "applied %d migrations\n", count)
+	case "down":
+		count, err := db.MigrateDown(ctx, steps)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(out, "reverted %d migrations\n", count)
+	case "status":
+		statuses, err := db.MigrationStatus(ctx)
+		if err != nil {
+			return err
+		}
+		for _, status := range statuses {
+			state := "pending"
+			switch {
+			case status.Unknown:
+				state = "applied " + status.AppliedAt.Format(time.RFC3339) + " (unknown to this app)"
+			case !status.AppliedAt.IsZero():
+				state = "applied " + status.AppliedAt.Format(time.RFC3339)
+			}
+			fmt.Fprintf(out, "%04d_%s\t%s\n", status.Version, status.Name, state)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,236 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/test/library-app/internal/config"
+	"github.com/test/library-app/internal/constants"
+	"github.com/test/library-app/internal/logger"
+	"github.com/test/library-app/internal/model"
+)
+
+// copyColumns lists the copies table columns in the order scanCopy reads them
+const copyColumns = `id,
+		book_id,
+		barcode,
+		shelf_location,
+		condition,
+		acquisition_date,
+		status`
+
+// scanCopy scans a row selected with copyColumns
+func scanCopy(row pgx.Row) (*model.BookCopy, error) {
+	var bookCopy model.BookCopy
+	var acquisitionDate time.Time
+	err := row.Scan(
+		&bookCopy.ID,
+		&bookCopy.BookID,
+		&bookCopy.Barcode,
+		&bookCopy.ShelfLocation,
+		&bookCopy.Condition,
+		&acquisitionDate,
+		&bookCopy.Status,
+	)
+	if err != nil {
+		return nil, err
+	}
+	bookCopy.AcquisitionDate = acquisitionDate.Unix()
+	return &bookCopy, nil
+}
+
+// lockBook locks the book row till the end of the transaction
+func lockBook(ctx context.Context, tx pgx.Tx, bookID int) error {
+	var id int
+	query := fmt.Sprintf(`SELECT id FROM %s WHERE id=$1 FOR UPDATE`, config.PostgresConfig.BooksTableName)
+	err := tx.QueryRow(ctx, query, bookID).Scan(&id)
+	if err != nil {
+		logger.FromContext(ctx).Errorf("failed to lock book: %d. Error: %v", bookID, err)
+		if errors.Is(err, sql.ErrNoRows) {
+			return fmt.Errorf("failed to find book: %d. %w", bookID, model.ErrNotFound)
+		}
+		return err
+	}
+	return nil
+}
+
+// addCopies inserts copies of a book locked by the transaction, generating the missed barcodes
+func addCopies(ctx context.Context, tx pgx.Tx, copies []*model.BookCopy) error {
+	if len(copies) == 0 {
+		return nil
+	}
+	bookID := copies[0].BookID
+	var n int
+	query := fmt.Sprintf(`SELECT COUNT(*) FROM %s WHERE book_id=$1`, config.PostgresConfig.CopiesTableName)
+	if err := tx.QueryRow(ctx, query, bookID).Scan(&n); err != nil {
+		logger.FromContext(ctx).Errorf("failed to count copies of book: %d. Error: %v", bookID, err)
+		return err
+	}
+	existsQuery := fmt.Sprintf(`SELECT EXISTS (SELECT 1 FROM %s WHERE barcode=$1)`, config.PostgresConfig.CopiesTableName)
+	insertQuery := fmt.Sprintf(`INSERT
+		INTO %s
+		(book_id, barcode, shelf_location, condition, acquisition_date, status)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id
+	`, config.PostgresConfig.CopiesTableName)
+	for _, det := range copies {
+		// skipping barcodes taken by copies added with explicit barcodes
+		for det.Barcode == "" {
+			n++
+			var taken bool
+			if err := tx.QueryRow(ctx, existsQuery, model.CopyBarcode(bookID, n)).Scan(&taken); err != nil {
+				logger.FromContext(ctx).Errorf("failed to check barcode of book: %d. Error: %v", bookID, err)
+				return err
+			}
+			if !taken {
+				det.Barcode = model.CopyBarcode(bookID, n)
+			}
+		}
+		if det.Condition == "" {
+			det.Condition = constants.DefaultCondition
+		}
+		if det.AcquisitionDate == 0 {
+			det.AcquisitionDate = time.Now().Unix()
+		}
+		if det.Status == "" {
+			det.Status = constants.CopyAvailable
+		}
+		err := tx.QueryRow(ctx, insertQuery,
+			det.BookID, det.Barcode, det.ShelfLocation, det.Condition, time.Unix(det.AcquisitionDate, 0), det.Status,
+		).Scan(&det.ID)
+		if err != nil {
+			logger.FromContext(ctx).Errorf("failed to insert into copies. Error: %v", err)
+			if isUniqueViolation(err) {
+				return fmt.Errorf("copy with barcode '%s' already presents. %w", det.Barcode, model.ErrAlreadyExists)
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+// AddBookCopy adds a physical copy to a book, generating the barcode when missed
+func (p *PostgresDB) AddBookCopy(ctx context.Context, det *model.BookCopy) (int, error) {
+	tx, err := p.DB.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		logger.FromContext(ctx).Errorf("failed to begin transaction. Error: %v", err)
+		return 0, err
+	}
+	defer tx.Rollback(ctx)
+	// locking the book row so barcodes are generated one after the other
+	if err = lockBook(ctx, tx, det.BookID); err != nil {
+		return 0, err
+	}
+	det.Status = constants.CopyAvailable
+	if err = addCopies(ctx, tx, []*model.BookCopy{det}); err != nil {
+		return 0, err
+	}
+	if err = tx.Commit(ctx); err != nil {
+		logger.FromContext(ctx).Errorf("failed to commit transaction of adding copy. Error: %v", err)
+		return 0, err
+	}
+	return det.ID, nil
+}
+
+// GetBookCopies retreves all copies of a book
+func (p *PostgresDB) GetBookCopies(ctx context.Context, bookID int) ([]*model.BookCopy, error) {
+	if _, err := p.GetBookDetailsByID(ctx, bookID); err != nil {
+		return nil, err
+	}
+	query := fmt.Sprintf(`SELECT 
+		%s
+		FROM %s
+		WHERE book_id=$1
+		ORDER BY id
+	`, copyColumns, config.PostgresConfig.CopiesTableName)
+	rows, err := p.DB.Query(ctx, query, bookID)
+	if err != nil {
+		logger.FromContext(ctx).Errorf("Failed to fetch copies of book: %d. Error: %v", bookID, err)
+		return nil, err
+	}
+	defer rows.Close()
+	copies := make([]*model.BookCopy, 0)
+	for rows.Next() {
+		bookCopy, err := scanCopy(rows)
+		if err != nil {
+			logger.FromContext(ctx).Errorf("Failed to scan copy fetched from DB. Error: %v", err)
+			continue
+		}
+		copies = append(copies, bookCopy)
+	}
+	return copies, nil
+}
+
+// GetBookCopy retreves a copy by its barcode
+func (p *PostgresDB) GetBookCopy(ctx context.Context, barcode string) (*model.BookCopy, error) {
+	query := fmt.Sprintf(`SELECT 
+		%s
+		FROM %s
+		WHERE barcode=$1
+	`, copyColumns, config.PostgresConfig.CopiesTableName)
+	bookCopy, err := scanCopy(p.DB.QueryRow(ctx, query, barcode))
+	if err != nil {
+		logger.FromContext(ctx).Errorf("Failed to scan the requested copy: %s. Error: %v", barcode, err)
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("failed to find copy: %s. %w", barcode, model.ErrNotFound)
+		}
+		return nil, err
+	}
+	return bookCopy, nil
+}
+
+// UpdateBookCopy updates the non empty shelf location, condition and status of a copy
+func (p *PostgresDB) UpdateBookCopy(ctx context.Context, barcode string, det *model.BookCopy) (*model.BookCopy, error) {
+	tx, err := p.DB.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		logger.FromContext(ctx).Errorf("failed to begin transaction. Error: %v", err)
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+	query := fmt.Sprintf(`SELECT %s FROM %s WHERE barcode=$1 FOR UPDATE`, copyColumns, config.PostgresConfig.CopiesTableName)
+	bookCopy, err := scanCopy(tx.QueryRow(ctx, query, barcode))
+	if err != nil {
+		logger.FromContext(ctx).Errorf("failed to find a requested copy: %s to update. Error: %v", barcode, err)
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("failed to find copy: %s. %w", barcode, model.ErrNotFound)
+		}
+		return nil, err
+	}
+	if det.Status != "" && det.Status != bookCopy.Status && (bookCopy.Status == constants.CopyOnLoan || bookCopy.Status == constants.CopyOnHold) {
+		// loaned copies change status only by returning them, held copies by loaning or releasing the hold
+		logger.FromContext(ctx).Errorf("requested copy: %s is %s", barcode, bookCopy.Status)
+		return nil, fmt.Errorf("copy with barcode '%s' is %s. %w", barcode, bookCopy.Status, model.ErrConflict)
+	}
+	if det.ShelfLocation != "" {
+		bookCopy.ShelfLocation = det.ShelfLocation
+	}
+	if det.Condition != "" {
+		bookCopy.Condition = det.Condition
+	}
+	if det.AcquisitionDate != 0 {
+		bookCopy.AcquisitionDate = det.AcquisitionDate
+	}
+	if det.Status != "" {
+		bookCopy.Status = det.Status
+	}
+	query = fmt.Sprintf(`UPDATE
+		%s SET shelf_location=$1, condition=$2, acquisition_date=$3, status=$4
+		WHERE id=$5
+	`, config.PostgresConfig.CopiesTableName)
+	_, err = tx.Exec(ctx, query,
+		bookCopy.ShelfLocation, bookCopy.Condition, time.Unix(bookCopy.AcquisitionDate, 0), bookCopy.Status, bookCopy.ID,
+	)
+	if err != nil {
+		logger.FromContext(ctx).Errorf("failed to update copy: %s. Error: %v", barcode, err)
+		return nil, err
+	}
+	if err = tx.Commit(ctx); err != nil {
+		logger.FromContext(ctx).Errorf("failed to commit transaction of updating copy. Error: %v", err)
+		return nil, err
+	}
+	return bookCopy, nil
+}
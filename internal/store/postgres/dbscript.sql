@@ -1,24 +1,32 @@
-create table books (
-	id SERIAL PRIMARY KEY,
-	title VARCHAR(255) NOT NULL UNIQUE,
-	available_copies INT NOT NULL CHECK (available_copies >= 0)
-)
+-- sample catalog and member, the schema comes from the migrations applied on start or by `app migrate up`
 
-INSERT INTO books (title, available_copies) VALUES ('Alchemist', 3);
-INSERT INTO books (title, available_copies) VALUES ('Atomic Habbits', 4);
-INSERT INTO books (title, available_copies) VALUES ('Sapiens', 7);
-INSERT INTO books (title, available_copies) VALUES ('Mocking Bird', 5);
-INSERT INTO books (title, available_copies) VALUES ('Animal Farm', 10);
+INSERT INTO books (isbn, title, authors, publication_year) VALUES ('9780062315007', 'Alchemist', '{"Paulo Coelho"}', 1988);
+INSERT INTO books (isbn, title, authors, publication_year) VALUES ('9780735211292', 'Atomic Habbits', '{"James Clear"}', 2018);
+INSERT INTO books (isbn, title, authors, publication_year) VALUES ('9780062316097', 'Sapiens', '{"Yuval Noah Harari"}', 2015);
+INSERT INTO books (isbn, title, authors, publication_year) VALUES ('9780061120084', 'Mocking Bird', '{"Harper Lee"}', 1960);
+INSERT INTO books (isbn, title, authors, publication_year) VALUES ('9780451526342', 'Animal Farm', '{"George Orwell"}', 1945);
 
 select * from  books;
 
-create table loans (
-	id SERIAL PRIMARY KEY,
-	title VARCHAR(256) NOT NULL,
-	name_of_borrower VARCHAR(256) NOT NULL,
-	loan_date TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
-	return_date TIMESTAMP NOT NULL,
-	status VARCHAR(100) NOT NULL
-)
+-- 3 copies of Alchemist and so on
+INSERT INTO book_copies (book_id, barcode, shelf_location)
+	SELECT b.id, b.id || '-' || lpad(n::text, 4, '0'), 'STACKS'
+	FROM books b, generate_series(1, 3) n WHERE b.title = 'Alchemist';
+INSERT INTO book_copies (book_id, barcode, shelf_location)
+	SELECT b.id, b.id || '-' || lpad(n::text, 4, '0'), 'STACKS'
+	FROM books b, generate_series(1, 4) n WHERE b.title = 'Atomic Habbits';
+INSERT INTO book_copies (book_id, barcode, shelf_location)
+	SELECT b.id, b.id || '-' || lpad(n::text, 4, '0'), 'STACKS'
+	FROM books b, generate_series(1, 7) n WHERE b.title = 'Sapiens';
+INSERT INTO book_copies (book_id, barcode, shelf_location)
+	SELECT b.id, b.id || '-' || lpad(n::text, 4, '0'), 'STACKS'
+	FROM books b, generate_series(1, 5) n WHERE b.title = 'Mocking Bird';
+INSERT INTO book_copies (book_id, barcode, shelf_location)
+	SELECT b.id, b.id || '-' || lpad(n::text, 4, '0'), 'STACKS'
+	FROM books b, generate_series(1, 10) n WHERE b.title = 'Animal Farm';
 
-select * from loans;
+select * from book_copies;
+
+INSERT INTO members (name, email, card_number) VALUES ('John Doe', 'john@example.com', 'LIB-000001');
+
+select * from members;
@@ -0,0 +1,18 @@
+package postgres
+
+import (
+	"errors"
+
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// postgres error codes referred by the store
+const (
+	uniqueViolation = "23505"
+)
+
+// isUniqueViolation reports whether the error is due to a unique constraint
+func isUniqueViolation(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == uniqueViolation
+}
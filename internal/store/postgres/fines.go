@@ -0,0 +1,249 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/test/library-app/internal/config"
+	"github.com/test/library-app/internal/constants"
+	"github.com/test/library-app/internal/logger"
+	"github.com/test/library-app/internal/model"
+	"github.com/test/library-app/internal/policy"
+)
+
+// fineColumns lists the columns of a fine selected from fineTables in the order scanFine reads them
+const fineColumns = `f.id, f.loan_id, COALESCE(l.member_id, 0), l.title, f.amount_in_cents, f.paid_in_cents,
+	f.status, f.accrued_at, f.settled_at`
+
+// fineTables joins the fines aliased as f with their loans aliased as l
+func fineTables() string {
+	return fmt.Sprintf(`%s f JOIN %s l ON l.id=f.loan_id`, config.PostgresConfig.FinesTableName, config.PostgresConfig.LoansTableName)
+}
+
+// scanFine scans a row selected with fineColumns
+func scanFine(row pgx.Row) (*model.Fine, error) {
+	var fine model.Fine
+	var accruedAt time.Time
+	var settledAt *time.Time
+	err := row.Scan(&fine.ID, &fine.LoanID, &fine.MemberID, &fine.Title, &fine.AmountInCents, &fine.PaidInCents,
+		&fine.Status, &accruedAt, &settledAt)
+	if err != nil {
+		return nil, err
+	}
+	fine.AccruedAt = accruedAt.Unix()
+	if settledAt != nil {
+		fine.SettledAt = settledAt.Unix()
+	}
+	return &fine, nil
+}
+
+// lockFine retreves a fine by its ID locking it within the transaction
+func lockFine(ctx context.Context, tx pgx.Tx, fineID int) (*model.Fine, error) {
+	query := fmt.Sprintf(`SELECT %s FROM %s WHERE f.id=$1 FOR UPDATE OF f`, fineColumns, fineTables())
+	fine, err := scanFine(tx.QueryRow(ctx, query, fineID))
+	if err != nil {
+		logger.FromContext(ctx).Errorf("Failed to scan the requested fine: %d. Error: %v", fineID, err)
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("failed to find fine: %d. %w", fineID, model.ErrNotFound)
+		}
+		return nil, err
+	}
+	return fine, nil
+}
+
+// memberOwes sums the outstanding fines of the member
+func memberOwes(ctx context.Context, tx pgx.Tx, memberID int) (int64, error) {
+	var owed int64
+	query := fmt.Sprintf(`SELECT
+		COALESCE(SUM(GREATEST(f.amount_in_cents - f.paid_in_cents, 0)), 0)
+		FROM %s
+		WHERE l.member_id=$1 AND f.status IN ($2, $3)
+	`, fineTables())
+	if err := tx.QueryRow(ctx, query, memberID, constants.FineAccruing, constants.FineUnpaid).Scan(&owed); err != nil {
+		logger.FromContext(ctx).Errorf("failed to sum the fines of member %d. Error: %v", memberID, err)
+		return 0, err
+	}
+	return owed, nil
+}
+
+// fineLoan updates the fine of the loan locked by the transaction as of now, fining the loan once past the grace period.
+// Returning finalises the fine so it stops accruing, waived fines are left as they are
+func fineLoan(ctx context.Context, tx pgx.Tx, loanID int, returnDate time.Time, now time.Time, returning bool) error {
+	amount := policy.FineAmount(returnDate.Unix(), now)
+	if amount == 0 {
+		return nil
+	}
+	status := constants.FineAccruing
+	if returning {
+		status = constants.FineUnpaid
+	}
+	// a fine paid in full while accruing is settled on return
+	query := fmt.Sprintf(`INSERT
+		INTO %s AS f
+		(loan_id, amount_in_cents, status, accrued_at)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (loan_id) DO UPDATE SET
+			amount_in_cents=EXCLUDED.amount_in_cents,
+			accrued_at=EXCLUDED.accrued_at,
+			status=CASE WHEN EXCLUDED.status=$5 AND f.paid_in_cents >= EXCLUDED.amount_in_cents THEN $6 ELSE EXCLUDED.status END,
+			settled_at=CASE WHEN EXCLUDED.status=$5 AND f.paid_in_cents >= EXCLUDED.amount_in_cents THEN EXCLUDED.accrued_at END
+		WHERE f.status=$7
+	`, config.PostgresConfig.FinesTableName)
+	_, err := tx.Exec(ctx, query, loanID, amount, status, now, constants.FineUnpaid, constants.FinePaid, constants.FineAccruing)
+	if err != nil {
+		logger.FromContext(ctx).Errorf("failed to fine loan %d. Error: %v", loanID, err)
+		return err
+	}
+	return nil
+}
+
+// AccrueFines marks the loans not returned by now overdue and accrues their fines, returns the No of overdue loans
+func (p *PostgresDB) AccrueFines(ctx context.Context, now time.Time) (int, error) {
+	tx, err := p.DB.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		logger.FromContext(ctx).Errorf("failed to begin transaction. Error: %v", err)
+		return 0, err
+	}
+	defer tx.Rollback(ctx)
+	// loans being returned meanwhile are left to the return
+	query := fmt.Sprintf(`SELECT
+		id, status, return_date, extensions
+		FROM %s
+		WHERE status<>$1 AND return_date < $2
+		FOR UPDATE SKIP LOCKED
+	`, config.PostgresConfig.LoansTableName)
+	rows, err := tx.Query(ctx, query, constants.Closed, now)
+	if err != nil {
+		logger.FromContext(ctx).Errorf("failed to fetch overdue loans. Error: %v", err)
+		return 0, err
+	}
+	type overdueLoan struct {
+		ID         int
+		Status     string
+		ReturnDate time.Time
+		Extensions int
+	}
+	loans, err := pgx.CollectRows(rows, pgx.RowToStructByPos[overdueLoan])
+	if err != nil {
+		logger.FromContext(ctx).Errorf("failed to scan overdue loans. Error: %v", err)
+		return 0, err
+	}
+	query = fmt.Sprintf(`UPDATE %s SET status=$1, version=version + 1 WHERE id=$2`, config.PostgresConfig.LoansTableName)
+	for _, loan := range loans {
+		if loan.Status == constants.Active {
+			if _, err = tx.Exec(ctx, query, constants.Overdue, loan.ID); err != nil {
+				logger.FromContext(ctx).Errorf("failed to mark loan %d overdue. Error: %v", loan.ID, err)
+				return 0, err
+			}
+			before := &model.LoanState{Status: loan.Status, ReturnDate: loan.ReturnDate.Unix(), Extensions: loan.Extensions}
+			after := &model.LoanState{Status: constants.Overdue, ReturnDate: loan.ReturnDate.Unix(), Extensions: loan.Extensions}
+			if err = recordEvent(ctx, tx, loan.ID, constants.LoanOverdue, before, after, now); err != nil {
+				return 0, err
+			}
+		}
+		if err = fineLoan(ctx, tx, loan.ID, loan.ReturnDate, now, false); err != nil {
+			return 0, err
+		}
+	}
+	if err = tx.Commit(ctx); err != nil {
+		logger.FromContext(ctx).Errorf("failed to commit transaction of accruing fines. Error: %v", err)
+		return 0, err
+	}
+	return len(loans), nil
+}
+
+// GetMemberFines retreves the fines of a member in the order fined along with the total owed
+func (p *PostgresDB) GetMemberFines(ctx context.Context, memberID int) (*model.MemberFines, error) {
+	if _, err := p.GetMember(ctx, memberID); err != nil {
+		return nil, err
+	}
+	query := fmt.Sprintf(`SELECT %s FROM %s WHERE l.member_id=$1 ORDER BY f.id`, fineColumns, fineTables())
+	rows, err := p.DB.Query(ctx, query, memberID)
+	if err != nil {
+		logger.FromContext(ctx).Errorf("Failed to fetch fines of member %d. Error: %v", memberID, err)
+		return nil, err
+	}
+	defer rows.Close()
+	fines := &model.MemberFines{MemberID: memberID, Fines: make([]*model.Fine, 0)}
+	for rows.Next() {
+		fine, err := scanFine(rows)
+		if err != nil {
+			logger.FromContext(ctx).Errorf("Failed to scan fine fetched from DB. Error: %v", err)
+			continue
+		}
+		fines.Fines = append(fines.Fines, fine)
+		fines.OutstandingInCents += fine.Outstanding()
+	}
+	return fines, nil
+}
+
+// settleFine locks a fine still open for paying or waiving, applies the change and stores it
+func (p *PostgresDB) settleFine(ctx context.Context, fineID int, change func(fine *model.Fine) error) (*model.Fine, error) {
+	tx, err := p.DB.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		logger.FromContext(ctx).Errorf("failed to begin transaction. Error: %v", err)
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+	fine, err := lockFine(ctx, tx, fineID)
+	if err != nil {
+		return nil, err
+	}
+	if fine.Status == constants.FinePaid || fine.Status == constants.FineWaived {
+		return nil, fmt.Errorf("fine %d is %s. %w", fineID, fine.Status, model.ErrConflict)
+	}
+	if err = change(fine); err != nil {
+		return nil, err
+	}
+	var settledAt *time.Time
+	if fine.SettledAt != 0 {
+		t := time.Unix(fine.SettledAt, 0)
+		settledAt = &t
+	}
+	query := fmt.Sprintf(`UPDATE
+		%s SET paid_in_cents=$1, status=$2, settled_at=$3
+		WHERE id=$4
+	`, config.PostgresConfig.FinesTableName)
+	if _, err = tx.Exec(ctx, query, fine.PaidInCents, fine.Status, settledAt, fineID); err != nil {
+		logger.FromContext(ctx).Errorf("failed to update fine %d. Error: %v", fineID, err)
+		return nil, err
+	}
+	if err = tx.Commit(ctx); err != nil {
+		logger.FromContext(ctx).Errorf("failed to commit transaction of settling fine. Error: %v", err)
+		return nil, err
+	}
+	return fine, nil
+}
+
+// PayFine pays the amount of a fine, the outstanding amount when zero. Refused when paying more than owed
+func (p *PostgresDB) PayFine(ctx context.Context, fineID int, amount int64) (*model.Fine, error) {
+	return p.settleFine(ctx, fineID, func(fine *model.Fine) error {
+		outstanding := fine.Outstanding()
+		if amount == 0 {
+			amount = outstanding
+		}
+		if amount == 0 || amount > outstanding {
+			return fmt.Errorf("fine %d has %d cents outstanding, can't pay %d. %w", fineID, outstanding, amount, model.ErrConflict)
+		}
+		fine.PaidInCents += amount
+		// accruing fines may grow till returned
+		if fine.Status == constants.FineUnpaid && fine.Outstanding() == 0 {
+			fine.Status = constants.FinePaid
+			fine.SettledAt = time.Now().Unix()
+		}
+		return nil
+	})
+}
+
+// WaiveFine waives an accruing or unpaid fine, nothing more is owed for it
+func (p *PostgresDB) WaiveFine(ctx context.Context, fineID int) (*model.Fine, error) {
+	return p.settleFine(ctx, fineID, func(fine *model.Fine) error {
+		fine.Status = constants.FineWaived
+		fine.SettledAt = time.Now().Unix()
+		return nil
+	})
+}
@@ -0,0 +1,66 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/test/library-app/internal/audit"
+	"github.com/test/library-app/internal/config"
+	"github.com/test/library-app/internal/logger"
+	"github.com/test/library-app/internal/model"
+)
+
+// recordEvent appends the transition of the loan from the state before to the state after made by the actor of the context
+func recordEvent(ctx context.Context, tx pgx.Tx, loanID int, eventType string, before, after *model.LoanState, now time.Time) error {
+	query := fmt.Sprintf(`INSERT
+		INTO %s
+		(loan_id, type, actor, at, old_state, new_state)
+		VALUES ($1, $2, $3, $4, $5, $6)
+	`, config.PostgresConfig.LoanEventsTableName)
+	if _, err := tx.Exec(ctx, query, loanID, eventType, audit.Actor(ctx), now, before, after); err != nil {
+		logger.FromContext(ctx).Errorf("failed to record %s event of loan %d. Error: %v", eventType, loanID, err)
+		return err
+	}
+	return nil
+}
+
+// GetLoanHistory retreves the transitions of a loan in the order made
+func (p *PostgresDB) GetLoanHistory(ctx context.Context, loanID int) ([]*model.LoanEvent, error) {
+	var id int
+	query := fmt.Sprintf(`SELECT id FROM %s WHERE id=$1`, config.PostgresConfig.LoansTableName)
+	if err := p.DB.QueryRow(ctx, query, loanID).Scan(&id); err != nil {
+		logger.FromContext(ctx).Errorf("failed to find loan %d. Error: %v", loanID, err)
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("failed to find loan: %d. %w", loanID, model.ErrNotFound)
+		}
+		return nil, err
+	}
+	query = fmt.Sprintf(`SELECT
+		id, loan_id, type, actor, at, old_state, new_state
+		FROM %s
+		WHERE loan_id=$1
+		ORDER BY id
+	`, config.PostgresConfig.LoanEventsTableName)
+	rows, err := p.DB.Query(ctx, query, loanID)
+	if err != nil {
+		logger.FromContext(ctx).Errorf("Failed to fetch history of loan %d. Error: %v", loanID, err)
+		return nil, err
+	}
+	defer rows.Close()
+	events := make([]*model.LoanEvent, 0)
+	for rows.Next() {
+		var event model.LoanEvent
+		var at time.Time
+		if err := rows.Scan(&event.ID, &event.LoanID, &event.Type, &event.Actor, &at, &event.Old, &event.New); err != nil {
+			logger.FromContext(ctx).Errorf("Failed to scan loan event fetched from DB. Error: %v", err)
+			return nil, err
+		}
+		event.At = at.Unix()
+		events = append(events, &event)
+	}
+	return events, rows.Err()
+}
@@ -0,0 +1,102 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/test/library-app/internal/config"
+	"github.com/test/library-app/internal/logger"
+	"github.com/test/library-app/internal/model"
+)
+
+// scanIdempotencyRecord scans a row of the idempotency keys table
+func scanIdempotencyRecord(row pgx.Row) (*model.IdempotencyRecord, error) {
+	var rec model.IdempotencyRecord
+	var expiresAt time.Time
+	if err := row.Scan(&rec.Key, &rec.Fingerprint, &rec.StatusCode, &rec.Header, &rec.Body, &expiresAt); err != nil {
+		return nil, err
+	}
+	rec.ExpiresAt = expiresAt.Unix()
+	return &rec, nil
+}
+
+// ReserveIdempotencyKey reserves the key of the record for its request till the record expires, nil is returned once reserved.
+// The record of the key is returned instead while the key is reserved or completed and not expired by now
+func (p *PostgresDB) ReserveIdempotencyKey(ctx context.Context, rec *model.IdempotencyRecord, now time.Time) (*model.IdempotencyRecord, error) {
+	// an expired key is taken over, the conflicting insert returns no row otherwise
+	query := fmt.Sprintf(`INSERT
+		INTO %s AS k (key, fingerprint, status_code, header, body, expires_at)
+		VALUES ($1, $2, 0, '{}', NULL, $3)
+		ON CONFLICT (key) DO UPDATE
+		SET fingerprint=EXCLUDED.fingerprint, status_code=0, header='{}', body=NULL, expires_at=EXCLUDED.expires_at
+		WHERE k.expires_at <= $4
+		RETURNING k.key
+	`, config.PostgresConfig.IdempotencyKeysTableName)
+	var key string
+	err := p.DB.QueryRow(ctx, query, rec.Key, rec.Fingerprint, time.Unix(rec.ExpiresAt, 0), now).Scan(&key)
+	if err == nil {
+		return nil, nil
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		logger.FromContext(ctx).Errorf("failed to reserve idempotency key %s. Error: %v", rec.Key, err)
+		return nil, err
+	}
+	query = fmt.Sprintf(`SELECT
+		key, fingerprint, status_code, header, body, expires_at
+		FROM %s
+		WHERE key=$1
+	`, config.PostgresConfig.IdempotencyKeysTableName)
+	stored, err := scanIdempotencyRecord(p.DB.QueryRow(ctx, query, rec.Key))
+	if err != nil {
+		logger.FromContext(ctx).Errorf("failed to fetch idempotency key %s. Error: %v", rec.Key, err)
+		// released by the request it was reserved for in between
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("idempotency key %s got released meanwhile. %w", rec.Key, model.ErrConflict)
+		}
+		return nil, err
+	}
+	return stored, nil
+}
+
+// CompleteIdempotencyKey stores the response of the request the key of the record is reserved for
+func (p *PostgresDB) CompleteIdempotencyKey(ctx context.Context, rec *model.IdempotencyRecord) error {
+	query := fmt.Sprintf(`UPDATE
+		%s SET status_code=$2, header=$3, body=$4, expires_at=$5
+		WHERE key=$1
+	`, config.PostgresConfig.IdempotencyKeysTableName)
+	header := rec.Header
+	if header == nil {
+		header = map[string]string{}
+	}
+	if _, err := p.DB.Exec(ctx, query, rec.Key, rec.StatusCode, header, rec.Body, time.Unix(rec.ExpiresAt, 0)); err != nil {
+		logger.FromContext(ctx).Errorf("failed to complete idempotency key %s. Error: %v", rec.Key, err)
+		return err
+	}
+	return nil
+}
+
+// ReleaseIdempotencyKey drops the reservation of a key so the request can be retried
+func (p *PostgresDB) ReleaseIdempotencyKey(ctx context.Context, key string) error {
+	// completed keys are kept till they expire
+	query := fmt.Sprintf(`DELETE FROM %s WHERE key=$1 AND status_code=0`, config.PostgresConfig.IdempotencyKeysTableName)
+	if _, err := p.DB.Exec(ctx, query, key); err != nil {
+		logger.FromContext(ctx).Errorf("failed to release idempotency key %s. Error: %v", key, err)
+		return err
+	}
+	return nil
+}
+
+// ExpireIdempotencyKeys drops the keys expired by now, returns the No of keys dropped
+func (p *PostgresDB) ExpireIdempotencyKeys(ctx context.Context, now time.Time) (int, error) {
+	query := fmt.Sprintf(`DELETE FROM %s WHERE expires_at <= $1`, config.PostgresConfig.IdempotencyKeysTableName)
+	tag, err := p.DB.Exec(ctx, query, now)
+	if err != nil {
+		logger.FromContext(ctx).Errorf("failed to drop expired idempotency keys. Error: %v", err)
+		return 0, err
+	}
+	return int(tag.RowsAffected()), nil
+}
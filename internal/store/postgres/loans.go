@@ -0,0 +1,71 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/test/library-app/internal/config"
+	"github.com/test/library-app/internal/logger"
+	"github.com/test/library-app/internal/model"
+)
+
+// loanColumns lists the columns of the loans table aliased as l in the order scanLoan reads them
+const loanColumns = `l.id, COALESCE(l.book_id, 0), COALESCE(l.copy_id, 0), l.barcode, l.title, COALESCE(l.member_id, 0),
+	l.name_of_borrower, l.loan_date, l.return_date, l.status, l.extensions, l.version`
+
+// scanLoan scans a row selected with loanColumns
+func scanLoan(row pgx.Row) (*model.LoanDetails, error) {
+	var loan model.LoanDetails
+	var loanDate, returnDate time.Time
+	err := row.Scan(&loan.ID, &loan.BookID, &loan.CopyID, &loan.Barcode, &loan.Title, &loan.MemberID,
+		&loan.NameOfBorrower, &loanDate, &returnDate, &loan.Status, &loan.Extensions, &loan.Version)
+	if err != nil {
+		return nil, err
+	}
+	loan.LoanDate = loanDate.Unix()
+	loan.ReturnDate = returnDate.Unix()
+	return &loan, nil
+}
+
+// GetLoan retreves a loan by its ID
+func (p *PostgresDB) GetLoan(ctx context.Context, loanID int) (*model.LoanDetails, error) {
+	query := fmt.Sprintf(`SELECT %s FROM %s l WHERE l.id=$1`, loanColumns, config.PostgresConfig.LoansTableName)
+	loan, err := scanLoan(p.DB.QueryRow(ctx, query, loanID))
+	if err != nil {
+		logger.FromContext(ctx).Errorf("Failed to scan the requested loan: %d. Error: %v", loanID, err)
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("failed to find loan: %d. %w", loanID, model.ErrNotFound)
+		}
+		return nil, err
+	}
+	return loan, nil
+}
+
+// GetLoansByBorrower retreves the active and past loans of a member in the order loaned
+func (p *PostgresDB) GetLoansByBorrower(ctx context.Context, memberID int) ([]*model.LoanDetails, error) {
+	if _, err := p.GetMember(ctx, memberID); err != nil {
+		return nil, err
+	}
+	// served by the member_id index of the loans
+	query := fmt.Sprintf(`SELECT %s FROM %s l WHERE l.member_id=$1 ORDER BY l.id`, loanColumns, config.PostgresConfig.LoansTableName)
+	rows, err := p.DB.Query(ctx, query, memberID)
+	if err != nil {
+		logger.FromContext(ctx).Errorf("Failed to fetch loans of member %d. Error: %v", memberID, err)
+		return nil, err
+	}
+	defer rows.Close()
+	loans := make([]*model.LoanDetails, 0)
+	for rows.Next() {
+		loan, err := scanLoan(rows)
+		if err != nil {
+			logger.FromContext(ctx).Errorf("Failed to scan loan details fetched from DB. Error: %v", err)
+			continue
+		}
+		loans = append(loans, loan)
+	}
+	return loans, nil
+}
@@ -0,0 +1,279 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/test/library-app/internal/config"
+	"github.com/test/library-app/internal/constants"
+	"github.com/test/library-app/internal/logger"
+	"github.com/test/library-app/internal/model"
+	"github.com/test/library-app/internal/policy"
+)
+
+// memberColumns lists the columns of the members table aliased as m in the order scanMember reads them
+const memberColumns = `m.id, m.name, COALESCE(m.email, ''), m.card_number, m.tier, m.status, m.joined_at`
+
+var memberSortKeys = map[string]sortKey{
+	constants.SortByID:   {expr: "m.id"},
+	constants.SortByName: {expr: "LOWER(m.name)", text: true},
+}
+
+// scanMember scans a row selected with memberColumns
+func scanMember(row pgx.Row) (*model.Member, error) {
+	var member model.Member
+	var joinedAt time.Time
+	if err := row.Scan(&member.ID, &member.Name, &member.Email, &member.CardNumber, &member.Tier, &member.Status, &joinedAt); err != nil {
+		return nil, err
+	}
+	member.JoinedAt = joinedAt.Unix()
+	return &member, nil
+}
+
+// AddMember registers a member, generating the card number when missed
+func (p *PostgresDB) AddMember(ctx context.Context, det *model.Member) (int, error) {
+	// taking the id up front as the generated card number derives from it
+	query := fmt.Sprintf(`SELECT nextval(pg_get_serial_sequence('%s', 'id'))`, config.PostgresConfig.MembersTableName)
+	if err := p.DB.QueryRow(ctx, query).Scan(&det.ID); err != nil {
+		logger.FromContext(ctx).Errorf("failed to take the next member id. Error: %v", err)
+		return 0, err
+	}
+	if det.CardNumber == "" {
+		det.CardNumber = model.CardNumber(det.ID)
+	}
+	if det.Tier == "" {
+		det.Tier = constants.DefaultTier
+	}
+	if det.Status == "" {
+		det.Status = constants.MemberActive
+	}
+	if det.JoinedAt == 0 {
+		det.JoinedAt = time.Now().Unix()
+	}
+	query = fmt.Sprintf(`INSERT
+		INTO %s
+		(id, name, email, card_number, tier, status, joined_at)
+		VALUES ($1, $2, NULLIF($3, ''), $4, $5, $6, $7)
+	`, config.PostgresConfig.MembersTableName)
+	_, err := p.DB.Exec(ctx, query, det.ID, det.Name, det.Email, det.CardNumber, det.Tier, det.Status, time.Unix(det.JoinedAt, 0))
+	if err != nil {
+		logger.FromContext(ctx).Errorf("failed to insert member %s. Error: %v", det.Name, err)
+		if isUniqueViolation(err) {
+			return 0, fmt.Errorf("member with email '%s' or card number '%s' already presents. %w", det.Email, det.CardNumber, model.ErrAlreadyExists)
+		}
+		return 0, err
+	}
+	return det.ID, nil
+}
+
+// GetMember retreves a member by its ID
+func (p *PostgresDB) GetMember(ctx context.Context, memberID int) (*model.Member, error) {
+	query := fmt.Sprintf(`SELECT %s FROM %s m WHERE m.id=$1`, memberColumns, config.PostgresConfig.MembersTableName)
+	member, err := scanMember(p.DB.QueryRow(ctx, query, memberID))
+	if err != nil {
+		logger.FromContext(ctx).Errorf("Failed to scan the requested member: %d. Error: %v", memberID, err)
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("failed to find member: %d. %w", memberID, model.ErrNotFound)
+		}
+		return nil, fmt.Errorf("failed to find member: %d", memberID)
+	}
+	return member, nil
+}
+
+// GetAllMembers retreves a page of the members passing the filters of the query
+func (p *PostgresDB) GetAllMembers(ctx context.Context, query *model.MemberQuery) (*model.MemberPage, error) {
+	var f filter
+	if query.Name != "" {
+		f.add("strpos(LOWER(m.name), LOWER($%d)) > 0", query.Name)
+	}
+	if query.Tier != "" {
+		f.add("m.tier=$%d", query.Tier)
+	}
+	if query.Status != "" {
+		f.add("m.status=$%d", query.Status)
+	}
+	key, ok := memberSortKeys[query.SortBy]
+	if !ok {
+		key = memberSortKeys[constants.SortByID]
+	}
+	orderBy := paginate(&f, key, "m.id", &query.PageQuery)
+	sqlQuery := fmt.Sprintf(`SELECT
+		%s
+		FROM %s m
+		%s
+		%s
+	`, memberColumns, config.PostgresConfig.MembersTableName, f.where(), orderBy)
+	rows, err := p.DB.Query(ctx, sqlQuery, f.args...)
+	if err != nil {
+		logger.FromContext(ctx).Errorf("Failed to fetch members. Error: %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+	members := make([]*model.Member, 0)
+	for rows.Next() {
+		member, err := scanMember(rows)
+		if err != nil {
+			logger.FromContext(ctx).Errorf("Failed to scan member fetched from DB. Error: %v", err)
+			continue
+		}
+		members = append(members, member)
+	}
+	page := &model.MemberPage{Members: members}
+	if limit := pageLimit(&query.PageQuery); len(members) > limit {
+		page.Members = members[:limit]
+		last := members[limit-1]
+		cursor := &model.Cursor{SortBy: query.SortBy, Desc: query.Order == constants.OrderDesc, ID: last.ID}
+		if query.SortBy == constants.SortByName {
+			cursor.Text = last.Name
+		}
+		page.NextCursor = cursor.Encode()
+	}
+	return page, nil
+}
+
+// UpdateMember updates the non empty name, email, card number, tier and status of a member
+func (p *PostgresDB) UpdateMember(ctx context.Context, memberID int, det *model.Member) (*model.Member, error) {
+	query := fmt.Sprintf(`UPDATE
+		%s m SET
+		name=COALESCE(NULLIF($1, ''), m.name),
+		email=COALESCE(NULLIF($2, ''), m.email),
+		card_number=COALESCE(NULLIF($3, ''), m.card_number),
+		tier=COALESCE(NULLIF($4, ''), m.tier),
+		status=COALESCE(NULLIF($5, ''), m.status)
+		WHERE m.id=$6
+		RETURNING %s
+	`, config.PostgresConfig.MembersTableName, memberColumns)
+	member, err := scanMember(p.DB.QueryRow(ctx, query, det.Name, det.Email, det.CardNumber, det.Tier, det.Status, memberID))
+	if err != nil {
+		logger.FromContext(ctx).Errorf("failed to update member: %d. Error: %v", memberID, err)
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("failed to find member: %d. %w", memberID, model.ErrNotFound)
+		}
+		if isUniqueViolation(err) {
+			return nil, fmt.Errorf("member with email '%s' or card number '%s' already presents. %w", det.Email, det.CardNumber, model.ErrAlreadyExists)
+		}
+		return nil, err
+	}
+	return member, nil
+}
+
+// DeleteMember removes a member cancelling its holds, refused while the member has active loans or owes fines
+func (p *PostgresDB) DeleteMember(ctx context.Context, memberID int) error {
+	tx, err := p.DB.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		logger.FromContext(ctx).Errorf("failed to begin transaction. Error: %v", err)
+		return err
+	}
+	defer tx.Rollback(ctx)
+	// locking the member so no loan gets added meanwhile
+	var id int
+	query := fmt.Sprintf(`SELECT id FROM %s WHERE id=$1 FOR UPDATE`, config.PostgresConfig.MembersTableName)
+	if err = tx.QueryRow(ctx, query, memberID).Scan(&id); err != nil {
+		logger.FromContext(ctx).Errorf("failed to lock member: %d. Error: %v", memberID, err)
+		if errors.Is(err, sql.ErrNoRows) {
+			return fmt.Errorf("failed to find member: %d. %w", memberID, model.ErrNotFound)
+		}
+		return err
+	}
+	var activeLoans int
+	query = fmt.Sprintf(`SELECT COUNT(*) FROM %s WHERE member_id=$1 AND status<>$2`, config.PostgresConfig.LoansTableName)
+	if err = tx.QueryRow(ctx, query, memberID, constants.Closed).Scan(&activeLoans); err != nil {
+		logger.FromContext(ctx).Errorf("failed to count active loans of member: %d. Error: %v", memberID, err)
+		return err
+	}
+	if activeLoans > 0 {
+		return fmt.Errorf("member %d has %d active loans. %w", memberID, activeLoans, model.ErrConflict)
+	}
+	owed, err := memberOwes(ctx, tx, memberID)
+	if err != nil {
+		return err
+	}
+	if owed > 0 {
+		return fmt.Errorf("member %d owes %d cents of fines. %w", memberID, owed, model.ErrConflict)
+	}
+	// cancelling the holds passes the copies set aside on before they get deleted along with the member
+	query = fmt.Sprintf(`SELECT id FROM %s WHERE member_id=$1 AND status IN ($2, $3) FOR UPDATE`, config.PostgresConfig.HoldsTableName)
+	rows, err := tx.Query(ctx, query, memberID, constants.HoldWaiting, constants.HoldReady)
+	if err != nil {
+		logger.FromContext(ctx).Errorf("failed to fetch holds of member: %d. Error: %v", memberID, err)
+		return err
+	}
+	holdIDs, err := pgx.CollectRows(rows, pgx.RowTo[int])
+	if err != nil {
+		return err
+	}
+	for _, holdID := range holdIDs {
+		if err = closeHold(ctx, tx, holdID, constants.HoldCancelled, time.Now()); err != nil {
+			return err
+		}
+	}
+	query = fmt.Sprintf(`DELETE FROM %s WHERE id=$1`, config.PostgresConfig.MembersTableName)
+	if _, err = tx.Exec(ctx, query, memberID); err != nil {
+		logger.FromContext(ctx).Errorf("failed to delete member: %d. Error: %v", memberID, err)
+		return err
+	}
+	if err = tx.Commit(ctx); err != nil {
+		logger.FromContext(ctx).Errorf("failed to commit transaction of deleting member. Error: %v", err)
+		return err
+	}
+	return nil
+}
+
+// memberStanding locks the borrowing member, serializing its loans and keeping it from being deleted meanwhile,
+// and gathers its active loans, holds and unpaid fines
+func memberStanding(ctx context.Context, tx pgx.Tx, memberID int) (*policy.Standing, error) {
+	query := fmt.Sprintf(`SELECT %s FROM %s m WHERE m.id=$1 FOR UPDATE`, memberColumns, config.PostgresConfig.MembersTableName)
+	member, err := scanMember(tx.QueryRow(ctx, query, memberID))
+	if err != nil {
+		logger.FromContext(ctx).Errorf("failed to find member %d to loan. Error: %v", memberID, err)
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("failed to find member: %d. %w", memberID, model.ErrNotFound)
+		}
+		return nil, err
+	}
+	standing := &policy.Standing{Member: member}
+	query = fmt.Sprintf(`SELECT id, book_id, title, return_date FROM %s WHERE member_id=$1 AND status<>$2`, config.PostgresConfig.LoansTableName)
+	rows, err := tx.Query(ctx, query, memberID, constants.Closed)
+	if err != nil {
+		logger.FromContext(ctx).Errorf("failed to fetch active loans of member %d. Error: %v", memberID, err)
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var loan model.LoanDetails
+		var returnDate time.Time
+		if err := rows.Scan(&loan.ID, &loan.BookID, &loan.Title, &returnDate); err != nil {
+			return nil, err
+		}
+		loan.ReturnDate = returnDate.Unix()
+		standing.ActiveLoans = append(standing.ActiveLoans, &loan)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	query = fmt.Sprintf(`SELECT id, book_id FROM %s WHERE member_id=$1 AND status IN ($2, $3)`, config.PostgresConfig.HoldsTableName)
+	rows, err = tx.Query(ctx, query, memberID, constants.HoldWaiting, constants.HoldReady)
+	if err != nil {
+		logger.FromContext(ctx).Errorf("failed to fetch active holds of member %d. Error: %v", memberID, err)
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var hold model.Hold
+		if err := rows.Scan(&hold.ID, &hold.BookID); err != nil {
+			return nil, err
+		}
+		standing.ActiveHolds = append(standing.ActiveHolds, &hold)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	if standing.UnpaidFines, err = memberOwes(ctx, tx, memberID); err != nil {
+		return nil, err
+	}
+	return standing, nil
+}
@@ -0,0 +1,269 @@
+package postgres
+
+import (
+	"context"
+	"embed"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/test/library-app/internal/config"
+	"github.com/test/library-app/internal/logger"
+)
+
+// migrationFiles holds the schema migrations named <version>_<name>.up.sql and <version>_<name>.down.sql
+//
+//go:embed migrations/*.sql
+var migrationFiles embed.FS
+
+// migrationLockID identifies the advisory lock serializing migrations across app instances
+const migrationLockID int64 = 0x6c6962726172792d // "library-"
+
+type migration struct {
+	version int
+	name    string
+	up      string
+	down    string
+}
+
+// MigrationStatus represents a migration known to the app or applied to the database
+type MigrationStatus struct {
+	Version   int
+	Name      string
+	AppliedAt time.Time // zero while pending
+	Unknown   bool      // applied by a newer app
+}
+
+// loadMigrations reads the embedded migrations in version order,
+// the table names are filled in from the config
+func loadMigrations() ([]*migration, error) {
+	entries, err := migrationFiles.ReadDir("migrations")
+	if err != nil {
+		return nil, err
+	}
+	tables := map[string]string{
+		"Books":           config.PostgresConfig.BooksTableName,
+		"Copies":          config.PostgresConfig.CopiesTableName,
+		"Loans":           config.PostgresConfig.LoansTableName,
+		"Members":         config.PostgresConfig.MembersTableName,
+		"Holds":           config.PostgresConfig.HoldsTableName,
+		"Fines":           config.PostgresConfig.FinesTableName,
+		"LoanEvents":      config.PostgresConfig.LoanEventsTableName,
+		"IdempotencyKeys": config.PostgresConfig.IdempotencyKeysTableName,
+		"APIKeys":         config.PostgresConfig.APIKeysTableName,
+	}
+	byVersion := make(map[int]*migration)
+	for _, entry := range entries {
+		fileName := entry.Name()
+		base, direction, ok := strings.Cut(strings.TrimSuffix(fileName, ".sql"), ".")
+		if !ok || (direction != "up" && direction != "down") {
+			return nil, fmt.Errorf("migration %s isn't named <version>_<name>.(up|down).sql", fileName)
+		}
+		v, name, _ := strings.Cut(base, "_")
+		version, err := strconv.Atoi(v)
+		if err != nil {
+			return nil, fmt.Errorf("migration %s has invalid version. Error: %w", fileName, err)
+		}
+		data, err := migrationFiles.ReadFile("migrations/" + fileName)
+		if err != nil {
+			return nil, err
+		}
+		tmpl, err := template.New(fileName).Option("missingkey=error").Parse(string(data))
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse migration %s. Error: %w", fileName, err)
+		}
+		var sql strings.Builder
+		if err := tmpl.Execute(&sql, tables); err != nil {
+			return nil, fmt.Errorf("failed to render migration %s. Error: %w", fileName, err)
+		}
+		m, ok := byVersion[version]
+		if !ok {
+			m = &migration{version: version, name: name}
+			byVersion[version] = m
+		}
+		if direction == "up" {
+			m.up = sql.String()
+		} else {
+			m.down = sql.String()
+		}
+	}
+	migrations := make([]*migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if m.up == "" || m.down == "" {
+			return nil, fmt.Errorf("migration %d_%s misses its up or down script", m.version, m.name)
+		}
+		migrations = append(migrations, m)
+	}
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].version < migrations[j].version
+	})
+	return migrations, nil
+}
+
+// withMigrationLock runs fn on a connection holding the migration advisory lock,
+// the table tracking the applied migrations is created when missed
+func (p *PostgresDB) withMigrationLock(ctx context.Context, fn func(conn *pgxpool.Conn, migrations []*migration) error) error {
+	migrations, err := loadMigrations()
+	if err != nil {
+		return err
+	}
+	conn, err := p.DB.Acquire(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Release()
+	// blocks while another instance migrates
+	if _, err := conn.Exec(ctx, "SELECT pg_advisory_lock($1)", migrationLockID); err != nil {
+		return fmt.Errorf("failed to take the migration lock. Error: %w", err)
+	}
+	defer func() {
+		if _, err := conn.Exec(context.Background(), "SELECT pg_advisory_unlock($1)", migrationLockID); err != nil {
+			logger.FromContext(ctx).Errorf("Failed to release the migration lock. Error: %v", err)
+		}
+	}()
+	query := fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s (
+		version INT PRIMARY KEY,
+		name VARCHAR(255) NOT NULL,
+		applied_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+	)`, config.PostgresConfig.MigrationsTableName)
+	if _, err := conn.Exec(ctx, query); err != nil {
+		return fmt.Errorf("failed to create %s. Error: %w", config.PostgresConfig.MigrationsTableName, err)
+	}
+	return fn(conn, migrations)
+}
+
+// appliedMigrations retreves the applied migrations by version
+func appliedMigrations(ctx context.Context, conn *pgxpool.Conn) (map[int]*MigrationStatus, error) {
+	query := fmt.Sprintf(`SELECT version, name, applied_at FROM %s`, config.PostgresConfig.MigrationsTableName)
+	rows, err := conn.Query(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	applied := make(map[int]*MigrationStatus)
+	for rows.Next() {
+		var status MigrationStatus
+		if err := rows.Scan(&status.Version, &status.Name, &status.AppliedAt); err != nil {
+			return nil, err
+		}
+		applied[status.Version] = &status
+	}
+	return applied, rows.Err()
+}
+
+// runMigration runs a migration script and records it in one transaction
+func runMigration(ctx context.Context, conn *pgxpool.Conn, script string, record string, args ...any) error {
+	tx, err := conn.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+	// without arguments the script runs through the simple protocol which allows several statements
+	if _, err := tx.Exec(ctx, script); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(ctx, record, args...); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+// MigrateUp applies the pending migrations in version order, returns the no of applied migrations
+func (p *PostgresDB) MigrateUp(ctx context.Context) (int, error) {
+	count := 0
+	err := p.withMigrationLock(ctx, func(conn *pgxpool.Conn, migrations []*migration) error {
+		applied, err := appliedMigrations(ctx, conn)
+		if err != nil {
+			return err
+		}
+		record := fmt.Sprintf(`INSERT INTO %s (version, name) VALUES ($1, $2)`, config.PostgresConfig.MigrationsTableName)
+		for _, m := range migrations {
+			if _, ok := applied[m.version]; ok {
+				continue
+			}
+			logger.FromContext(ctx).Infof("Applying migration %d_%s", m.version, m.name)
+			if err := runMigration(ctx, conn, m.up, record, m.version, m.name); err != nil {
+				return fmt.Errorf("failed to apply migration %d_%s. Error: %w", m.version, m.name, err)
+			}
+			count++
+		}
+		return nil
+	})
+	return count, err
+}
+
+// MigrateDown reverts the last steps applied migrations, returns the no of reverted migrations
+func (p *PostgresDB) MigrateDown(ctx context.Context, steps int) (int, error) {
+	count := 0
+	err := p.withMigrationLock(ctx, func(conn *pgxpool.Conn, migrations []*migration) error {
+		applied, err := appliedMigrations(ctx, conn)
+		if err != nil {
+			return err
+		}
+		record := fmt.Sprintf(`DELETE FROM %s WHERE version=$1`, config.PostgresConfig.MigrationsTableName)
+		for i := len(migrations) - 1; i >= 0 && count < steps; i-- {
+			m := migrations[i]
+			if _, ok := applied[m.version]; !ok {
+				continue
+			}
+			logger.FromContext(ctx).Infof("Reverting migration %d_%s", m.version, m.name)
+			if err := runMigration(ctx, conn, m.down, record, m.version); err != nil {
+				return fmt.Errorf("failed to revert migration %d_%s. Error: %w", m.version, m.name, err)
+			}
+			count++
+		}
+		return nil
+	})
+	return count, err
+}
+
+// MigrationStatus lists the known and applied migrations in version order
+func (p *PostgresDB) MigrationStatus(ctx context.Context) ([]*MigrationStatus, error) {
+	var statuses []*MigrationStatus
+	err := p.withMigrationLock(ctx, func(conn *pgxpool.Conn, migrations []*migration) error {
+		applied, err := appliedMigrations(ctx, conn)
+		if err != nil {
+			return err
+		}
+		for _, m := range migrations {
+			status := &MigrationStatus{Version: m.version, Name: m.name}
+			if a, ok := applied[m.version]; ok {
+				status.AppliedAt = a.AppliedAt
+				delete(applied, m.version)
+			}
+			statuses = append(statuses, status)
+		}
+		for _, a := range applied {
+			a.Unknown = true
+			statuses = append(statuses, a)
+		}
+		sort.Slice(statuses, func(i, j int) bool {
+			return statuses[i].Version < statuses[j].Version
+		})
+		return nil
+	})
+	return statuses, err
+}
+
+// verifyMigrations fails when migrations are pending
+func (p *PostgresDB) verifyMigrations(ctx context.Context) error {
+	statuses, err := p.MigrationStatus(ctx)
+	if err != nil {
+		return err
+	}
+	pending := 0
+	for _, status := range statuses {
+		if status.AppliedAt.IsZero() {
+			pending++
+		}
+	}
+	if pending > 0 {
+		return fmt.Errorf("%d migrations are pending, run `migrate up`", pending)
+	}
+	return nil
+}
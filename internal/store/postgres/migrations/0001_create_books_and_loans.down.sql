@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS {{.Loans}};
+DROP TABLE IF EXISTS {{.Books}};
@@ -0,0 +1,15 @@
+-- IF NOT EXISTS adopts databases created before migrations were tracked
+CREATE TABLE IF NOT EXISTS {{.Books}} (
+	id SERIAL PRIMARY KEY,
+	title VARCHAR(255) NOT NULL UNIQUE,
+	available_copies INT NOT NULL CHECK (available_copies >= 0)
+);
+
+CREATE TABLE IF NOT EXISTS {{.Loans}} (
+	id SERIAL PRIMARY KEY,
+	title VARCHAR(256) NOT NULL,
+	name_of_borrower VARCHAR(256) NOT NULL,
+	loan_date TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	return_date TIMESTAMP NOT NULL,
+	status VARCHAR(100) NOT NULL
+);
@@ -0,0 +1,16 @@
+ALTER TABLE {{.Loans}} DROP COLUMN book_id;
+
+DROP INDEX {{.Books}}_lower_title_idx;
+
+ALTER TABLE {{.Books}}
+	DROP COLUMN isbn,
+	DROP COLUMN authors,
+	DROP COLUMN publisher,
+	DROP COLUMN publication_year,
+	DROP COLUMN language,
+	DROP COLUMN subjects,
+	DROP COLUMN edition,
+	DROP COLUMN description;
+
+-- fails while several books share a title
+ALTER TABLE {{.Books}} ADD CONSTRAINT {{.Books}}_title_key UNIQUE (title);
@@ -0,0 +1,18 @@
+-- titles aren't unique anymore, books are identified by their id and optionally ISBN
+ALTER TABLE {{.Books}} DROP CONSTRAINT IF EXISTS {{.Books}}_title_key;
+
+ALTER TABLE {{.Books}}
+	ADD COLUMN isbn VARCHAR(13) UNIQUE,
+	ADD COLUMN authors TEXT[] NOT NULL DEFAULT '{}',
+	ADD COLUMN publisher VARCHAR(255) NOT NULL DEFAULT '',
+	ADD COLUMN publication_year INT NOT NULL DEFAULT 0,
+	ADD COLUMN language VARCHAR(35) NOT NULL DEFAULT '',
+	ADD COLUMN subjects TEXT[] NOT NULL DEFAULT '{}',
+	ADD COLUMN edition VARCHAR(255) NOT NULL DEFAULT '',
+	ADD COLUMN description TEXT NOT NULL DEFAULT '';
+
+CREATE INDEX {{.Books}}_lower_title_idx ON {{.Books}} (LOWER(title));
+
+ALTER TABLE {{.Loans}} ADD COLUMN book_id INT REFERENCES {{.Books}}(id) ON DELETE SET NULL;
+
+UPDATE {{.Loans}} l SET book_id = b.id FROM {{.Books}} b WHERE LOWER(b.title) = LOWER(l.title);
@@ -0,0 +1,10 @@
+ALTER TABLE {{.Books}} ADD COLUMN available_copies INT NOT NULL DEFAULT 0 CHECK (available_copies >= 0);
+
+UPDATE {{.Books}} b SET available_copies =
+	(SELECT COUNT(*) FROM {{.Copies}} c WHERE c.book_id = b.id AND c.status = 'available');
+
+ALTER TABLE {{.Loans}}
+	DROP COLUMN copy_id,
+	DROP COLUMN barcode;
+
+DROP TABLE {{.Copies}};
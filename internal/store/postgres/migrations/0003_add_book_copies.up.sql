@@ -0,0 +1,38 @@
+CREATE TABLE {{.Copies}} (
+	id SERIAL PRIMARY KEY,
+	book_id INT NOT NULL REFERENCES {{.Books}}(id) ON DELETE CASCADE,
+	barcode VARCHAR(64) NOT NULL UNIQUE,
+	shelf_location VARCHAR(64) NOT NULL DEFAULT '',
+	condition VARCHAR(32) NOT NULL DEFAULT 'good',
+	acquisition_date TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	status VARCHAR(32) NOT NULL DEFAULT 'available'
+);
+
+CREATE INDEX {{.Copies}}_book_id_status_idx ON {{.Copies}} (book_id, status);
+
+ALTER TABLE {{.Loans}}
+	ADD COLUMN copy_id INT REFERENCES {{.Copies}}(id) ON DELETE SET NULL,
+	ADD COLUMN barcode VARCHAR(64) NOT NULL DEFAULT '';
+
+-- every counted copy becomes an available copy with a generated barcode
+INSERT INTO {{.Copies}} (book_id, barcode, shelf_location)
+	SELECT b.id, b.id || '-' || lpad(n::text, 4, '0'), 'STACKS'
+	FROM {{.Books}} b, generate_series(1, b.available_copies) n;
+
+-- and every active loan holds one more copy numbered after them
+WITH on_loan AS (
+	SELECT l.id AS loan_id,
+		l.book_id,
+		l.book_id || '-' || lpad((b.available_copies + row_number() OVER (PARTITION BY l.book_id ORDER BY l.id))::text, 4, '0') AS barcode
+	FROM {{.Loans}} l JOIN {{.Books}} b ON b.id = l.book_id
+	WHERE l.status = 'active'
+), added AS (
+	INSERT INTO {{.Copies}} (book_id, barcode, shelf_location, status)
+	SELECT book_id, barcode, 'STACKS', 'on_loan' FROM on_loan
+	RETURNING id, barcode
+)
+UPDATE {{.Loans}} l SET copy_id = a.id, barcode = a.barcode
+	FROM on_loan o JOIN added a ON a.barcode = o.barcode
+	WHERE l.id = o.loan_id;
+
+ALTER TABLE {{.Books}} DROP COLUMN available_copies;
@@ -0,0 +1,4 @@
+DROP TRIGGER {{.Books}}_search_vector_trigger ON {{.Books}};
+DROP FUNCTION {{.Books}}_search_vector_update();
+DROP INDEX {{.Books}}_search_vector_idx;
+ALTER TABLE {{.Books}} DROP COLUMN search_vector;
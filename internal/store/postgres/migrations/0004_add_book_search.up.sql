@@ -0,0 +1,21 @@
+ALTER TABLE {{.Books}} ADD COLUMN search_vector TSVECTOR NOT NULL DEFAULT '';
+
+CREATE INDEX {{.Books}}_search_vector_idx ON {{.Books}} USING GIN (search_vector);
+
+-- array_to_string isn't immutable so the search vector is kept up to date by a trigger instead of a generated column
+CREATE FUNCTION {{.Books}}_search_vector_update() RETURNS trigger AS $$
+BEGIN
+	new.search_vector :=
+		setweight(to_tsvector('english', coalesce(new.title, '')), 'A') ||
+		setweight(to_tsvector('english', array_to_string(new.authors, ' ')), 'B') ||
+		setweight(to_tsvector('english', array_to_string(new.subjects, ' ')), 'C') ||
+		setweight(to_tsvector('english', coalesce(new.description, '')), 'D');
+	RETURN new;
+END
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER {{.Books}}_search_vector_trigger BEFORE INSERT OR UPDATE ON {{.Books}}
+	FOR EACH ROW EXECUTE FUNCTION {{.Books}}_search_vector_update();
+
+-- indexing the existing books
+UPDATE {{.Books}} SET title = title;
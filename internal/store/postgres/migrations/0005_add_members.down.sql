@@ -0,0 +1,3 @@
+ALTER TABLE {{.Loans}} DROP COLUMN member_id;
+
+DROP TABLE {{.Members}};
@@ -0,0 +1,26 @@
+CREATE TABLE {{.Members}} (
+	id SERIAL PRIMARY KEY,
+	name VARCHAR(256) NOT NULL,
+	email VARCHAR(320),
+	card_number VARCHAR(64) NOT NULL UNIQUE,
+	tier VARCHAR(32) NOT NULL DEFAULT 'standard',
+	status VARCHAR(32) NOT NULL DEFAULT 'active',
+	joined_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE UNIQUE INDEX {{.Members}}_lower_email_idx ON {{.Members}} (LOWER(email));
+CREATE INDEX {{.Members}}_lower_name_idx ON {{.Members}} (LOWER(name));
+
+-- loans keep the name of the member when borrowed as history
+ALTER TABLE {{.Loans}} ADD COLUMN member_id INT REFERENCES {{.Members}}(id) ON DELETE SET NULL;
+
+CREATE INDEX {{.Loans}}_member_id_idx ON {{.Loans}} (member_id);
+
+-- borrowers of earlier loans become members, one per name
+INSERT INTO {{.Members}} (name, card_number)
+	SELECT name_of_borrower, 'LEGACY-' || row_number() OVER (ORDER BY name_of_borrower)
+	FROM (SELECT DISTINCT name_of_borrower FROM {{.Loans}}) borrowers;
+
+UPDATE {{.Loans}} l SET member_id = m.id
+	FROM {{.Members}} m
+	WHERE m.name = l.name_of_borrower AND m.card_number LIKE 'LEGACY-%';
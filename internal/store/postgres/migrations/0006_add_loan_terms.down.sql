@@ -0,0 +1,3 @@
+ALTER TABLE {{.Loans}} DROP COLUMN extensions;
+
+ALTER TABLE {{.Books}} DROP COLUMN category;
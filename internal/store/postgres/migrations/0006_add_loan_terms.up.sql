@@ -0,0 +1,4 @@
+-- lending category of a book, picks the loan period and extension rules
+ALTER TABLE {{.Books}} ADD COLUMN category VARCHAR(64) NOT NULL DEFAULT '';
+
+ALTER TABLE {{.Loans}} ADD COLUMN extensions INT NOT NULL DEFAULT 0;
@@ -0,0 +1,4 @@
+-- copies set aside for ready holds go back on the shelf
+UPDATE {{.Copies}} SET status = 'available' WHERE status = 'on_hold';
+
+DROP TABLE {{.Holds}};
@@ -0,0 +1,16 @@
+CREATE TABLE {{.Holds}} (
+	id SERIAL PRIMARY KEY,
+	member_id INT NOT NULL REFERENCES {{.Members}}(id) ON DELETE CASCADE,
+	book_id INT NOT NULL REFERENCES {{.Books}}(id) ON DELETE CASCADE,
+	copy_id INT REFERENCES {{.Copies}}(id) ON DELETE SET NULL,
+	status VARCHAR(32) NOT NULL DEFAULT 'waiting',
+	placed_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	ready_at TIMESTAMP,
+	expires_at TIMESTAMP
+);
+
+-- a member holds a book once at a time
+CREATE UNIQUE INDEX {{.Holds}}_active_member_book_idx ON {{.Holds}} (member_id, book_id) WHERE status IN ('waiting', 'ready');
+-- the queue of a book
+CREATE INDEX {{.Holds}}_waiting_book_idx ON {{.Holds}} (book_id, placed_at, id) WHERE status = 'waiting';
+CREATE INDEX {{.Holds}}_ready_expires_at_idx ON {{.Holds}} (expires_at) WHERE status = 'ready';
@@ -0,0 +1,5 @@
+DROP INDEX {{.Loans}}_open_return_date_idx;
+DROP TABLE {{.Fines}};
+
+-- overdue loans become active again
+UPDATE {{.Loans}} SET status = 'active' WHERE status = 'overdue';
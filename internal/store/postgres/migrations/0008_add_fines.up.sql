@@ -0,0 +1,13 @@
+CREATE TABLE {{.Fines}} (
+	id SERIAL PRIMARY KEY,
+	loan_id INT NOT NULL UNIQUE REFERENCES {{.Loans}}(id) ON DELETE CASCADE,
+	amount_in_cents BIGINT NOT NULL DEFAULT 0,
+	paid_in_cents BIGINT NOT NULL DEFAULT 0,
+	status VARCHAR(32) NOT NULL DEFAULT 'accruing',
+	accrued_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	settled_at TIMESTAMP
+);
+
+CREATE INDEX {{.Fines}}_open_idx ON {{.Fines}} (loan_id) WHERE status IN ('accruing', 'unpaid');
+-- the overdue job looks up the loans past their return date
+CREATE INDEX {{.Loans}}_open_return_date_idx ON {{.Loans}} (return_date) WHERE status <> 'closed';
@@ -0,0 +1,2 @@
+DROP TABLE {{.LoanEvents}};
+DROP FUNCTION {{.LoanEvents}}_append_only();
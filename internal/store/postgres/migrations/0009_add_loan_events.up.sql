@@ -0,0 +1,29 @@
+CREATE TABLE {{.LoanEvents}} (
+	id SERIAL PRIMARY KEY,
+	loan_id INT NOT NULL REFERENCES {{.Loans}}(id),
+	type VARCHAR(32) NOT NULL,
+	actor VARCHAR(256) NOT NULL,
+	at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	old_state JSONB,
+	new_state JSONB NOT NULL
+);
+
+CREATE INDEX {{.LoanEvents}}_loan_id_idx ON {{.LoanEvents}} (loan_id, id);
+
+-- loan events are append only
+CREATE FUNCTION {{.LoanEvents}}_append_only() RETURNS trigger AS $$
+BEGIN
+	RAISE EXCEPTION 'loan events are append only';
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER {{.LoanEvents}}_append_only_trigger
+	BEFORE UPDATE OR DELETE ON {{.LoanEvents}}
+	FOR EACH ROW EXECUTE FUNCTION {{.LoanEvents}}_append_only();
+
+-- earlier loans start their history with the creation
+INSERT INTO {{.LoanEvents}} (loan_id, type, actor, at, new_state)
+	SELECT id, 'created', 'system', COALESCE(loan_date, CURRENT_TIMESTAMP),
+		jsonb_build_object('status', status, 'return_date', EXTRACT(EPOCH FROM return_date)::BIGINT, 'extensions', extensions)
+	FROM {{.Loans}}
+	ORDER BY id;
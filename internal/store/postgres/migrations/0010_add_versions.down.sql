@@ -0,0 +1,2 @@
+ALTER TABLE {{.Loans}} DROP COLUMN version;
+ALTER TABLE {{.Books}} DROP COLUMN version;
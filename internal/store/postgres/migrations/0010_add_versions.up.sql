@@ -0,0 +1,3 @@
+-- versions of the books and loans, bumped by every change for optimistic concurrency
+ALTER TABLE {{.Books}} ADD COLUMN version INT NOT NULL DEFAULT 1;
+ALTER TABLE {{.Loans}} ADD COLUMN version INT NOT NULL DEFAULT 1;
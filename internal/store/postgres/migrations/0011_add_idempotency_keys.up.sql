@@ -0,0 +1,12 @@
+-- requests made with an Idempotency-Key, status_code is 0 while the request is in progress
+CREATE TABLE {{.IdempotencyKeys}} (
+	key VARCHAR(255) PRIMARY KEY,
+	fingerprint VARCHAR(64) NOT NULL,
+	status_code INT NOT NULL DEFAULT 0,
+	header JSONB NOT NULL DEFAULT '{}',
+	body BYTEA,
+	expires_at TIMESTAMP NOT NULL
+);
+
+-- the expiry job drops the keys past their expiry
+CREATE INDEX {{.IdempotencyKeys}}_expires_at_idx ON {{.IdempotencyKeys}} (expires_at);
@@ -0,0 +1,11 @@
+-- keys of the integrating systems, only the SHA-256 of a key is stored
+CREATE TABLE {{.APIKeys}} (
+	id SERIAL PRIMARY KEY,
+	name VARCHAR(256) NOT NULL UNIQUE,
+	prefix VARCHAR(16) NOT NULL,
+	hash VARCHAR(64) NOT NULL UNIQUE,
+	scopes TEXT[] NOT NULL DEFAULT '{}',
+	requests_per_minute INT NOT NULL DEFAULT 0,
+	created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	revoked_at TIMESTAMP
+);
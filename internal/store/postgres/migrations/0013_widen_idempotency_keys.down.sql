@@ -0,0 +1 @@
+ALTER TABLE {{.IdempotencyKeys}} ALTER COLUMN key TYPE VARCHAR(255);
@@ -0,0 +1,2 @@
+-- the keys are scoped to the callers, the subject of the caller prefixes the Idempotency-Key
+ALTER TABLE {{.IdempotencyKeys}} ALTER COLUMN key TYPE TEXT;
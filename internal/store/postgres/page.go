@@ -0,0 +1,224 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/test/library-app/internal/config"
+	"github.com/test/library-app/internal/constants"
+	"github.com/test/library-app/internal/logger"
+	"github.com/test/library-app/internal/model"
+)
+
+// epoch gives the unix epoch of a timestamp column the way time.Unix reads it
+func epoch(column string) string {
+	return fmt.Sprintf("FLOOR(EXTRACT(EPOCH FROM %s))::bigint", column)
+}
+
+// filter collects the conditions and arguments of a WHERE clause
+type filter struct {
+	conds []string
+	args  []any
+}
+
+// add adds a condition, its %d verbs are replaced with the placeholders of the args
+func (f *filter) add(cond string, args ...any) {
+	placeholders := make([]any, len(args))
+	for i, arg := range args {
+		f.args = append(f.args, arg)
+		placeholders[i] = len(f.args)
+	}
+	f.conds = append(f.conds, fmt.Sprintf(cond, placeholders...))
+}
+
+func (f *filter) where() string {
+	if len(f.conds) == 0 {
+		return ""
+	}
+	return "WHERE " + strings.Join(f.conds, " AND ")
+}
+
+// sortKey is the expression a listing is sorted by, text keys compare case insensitively
+type sortKey struct {
+	expr string
+	text bool
+}
+
+var bookSortKeys = map[string]sortKey{
+	constants.SortByID:              {expr: "b.id"},
+	constants.SortByTitle:           {expr: "LOWER(b.title)", text: true},
+	constants.SortByPublicationYear: {expr: "b.publication_year"},
+}
+
+var loanSortKeys = map[string]sortKey{
+	constants.SortByID:         {expr: "l.id"},
+	constants.SortByBorrower:   {expr: "LOWER(l.name_of_borrower)", text: true},
+	constants.SortByLoanDate:   {expr: epoch("l.loan_date")},
+	constants.SortByReturnDate: {expr: epoch("l.return_date")},
+}
+
+// pageLimit gives the no of items in the page
+func pageLimit(query *model.PageQuery) int {
+	if query.Limit <= 0 {
+		return constants.DefaultPageLimit
+	}
+	return query.Limit
+}
+
+// paginate adds the cursor condition to the filter and gives the ORDER BY and LIMIT clauses,
+// one row more than the limit is fetched to know whether more rows follow
+func paginate(f *filter, key sortKey, idColumn string, query *model.PageQuery) string {
+	order, cmp := "ASC", ">"
+	if query.Order == constants.OrderDesc {
+		order, cmp = "DESC", "<"
+	}
+	if after := query.After; after != nil {
+		switch {
+		case key.expr == idColumn:
+			f.add(fmt.Sprintf("%s %s $%%d", idColumn, cmp), after.ID)
+		case key.text:
+			f.add(fmt.Sprintf("(%s, %s) %s (LOWER($%%d), $%%d)", key.expr, idColumn, cmp), after.Text, after.ID)
+		default:
+			f.add(fmt.Sprintf("(%s, %s) %s ($%%d, $%%d)", key.expr, idColumn, cmp), after.Num, after.ID)
+		}
+	}
+	if key.expr == idColumn {
+		return fmt.Sprintf("ORDER BY %s %s LIMIT %d", idColumn, order, pageLimit(query)+1)
+	}
+	return fmt.Sprintf("ORDER BY %s %s, %s %s LIMIT %d", key.expr, order, idColumn, order, pageLimit(query)+1)
+}
+
+// GetAllBookDetails retreves a page of the books passing the filters of the query
+func (p *PostgresDB) GetAllBookDetails(ctx context.Context, query *model.BookQuery) (*model.BookPage, error) {
+	var f filter
+	if query.Title != "" {
+		f.add("strpos(LOWER(b.title), LOWER($%d)) > 0", query.Title)
+	}
+	if query.Author != "" {
+		f.add("EXISTS (SELECT 1 FROM unnest(b.authors) a WHERE strpos(LOWER(a), LOWER($%d)) > 0)", query.Author)
+	}
+	if query.Available != nil {
+		available := fmt.Sprintf("EXISTS (SELECT 1 FROM %s c WHERE c.book_id=b.id AND c.status='%s')",
+			config.PostgresConfig.CopiesTableName, constants.CopyAvailable)
+		if !*query.Available {
+			available = "NOT " + available
+		}
+		f.add(available)
+	}
+	key, ok := bookSortKeys[query.SortBy]
+	if !ok {
+		key = bookSortKeys[constants.SortByID]
+	}
+	orderBy := paginate(&f, key, "b.id", &query.PageQuery)
+	sqlQuery := fmt.Sprintf(`SELECT
+		%s
+		FROM %s b
+		%s
+		%s
+	`, bookColumns(), config.PostgresConfig.BooksTableName, f.where(), orderBy)
+	rows, err := p.DB.Query(ctx, sqlQuery, f.args...)
+	if err != nil {
+		logger.FromContext(ctx).Errorf("Failed to fetch books. Error: %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+	books := make([]*model.BookDetails, 0)
+	for rows.Next() {
+		book, err := scanBook(rows)
+		if err != nil {
+			logger.FromContext(ctx).Errorf("Failed to scan bookdetails fetched from DB. Error: %v", err)
+			continue
+		}
+		books = append(books, book)
+	}
+	page := &model.BookPage{Books: books}
+	if limit := pageLimit(&query.PageQuery); len(books) > limit {
+		page.Books = books[:limit]
+		last := books[limit-1]
+		cursor := &model.Cursor{SortBy: query.SortBy, Desc: query.Order == constants.OrderDesc, ID: last.ID}
+		switch query.SortBy {
+		case constants.SortByTitle:
+			cursor.Text = last.Title
+		case constants.SortByPublicationYear:
+			cursor.Num = int64(last.PublicationYear)
+		}
+		page.NextCursor = cursor.Encode()
+	}
+	return page, nil
+}
+
+// GetAllLoans retreves a page of the loans passing the filters of the query
+func (p *PostgresDB) GetAllLoans(ctx context.Context, query *model.LoanQuery) (*model.LoanPage, error) {
+	var f filter
+	if query.Status != "" {
+		f.add("l.status=$%d", query.Status)
+	}
+	if query.MemberID != 0 {
+		f.add("l.member_id=$%d", query.MemberID)
+	}
+	if query.Borrower != "" {
+		f.add("LOWER(l.name_of_borrower)=LOWER($%d)", query.Borrower)
+	}
+	if query.Title != "" {
+		f.add("strpos(LOWER(l.title), LOWER($%d)) > 0", query.Title)
+	}
+	if query.Overdue {
+		f.add(fmt.Sprintf("l.status<>$%%d AND %s < $%%d", epoch("l.return_date")), constants.Closed, time.Now().Unix())
+	}
+	if query.LoanedFrom != 0 {
+		f.add(epoch("l.loan_date")+" >= $%d", query.LoanedFrom)
+	}
+	if query.LoanedTo != 0 {
+		f.add(epoch("l.loan_date")+" <= $%d", query.LoanedTo)
+	}
+	if query.DueFrom != 0 {
+		f.add(epoch("l.return_date")+" >= $%d", query.DueFrom)
+	}
+	if query.DueTo != 0 {
+		f.add(epoch("l.return_date")+" <= $%d", query.DueTo)
+	}
+	key, ok := loanSortKeys[query.SortBy]
+	if !ok {
+		key = loanSortKeys[constants.SortByID]
+	}
+	orderBy := paginate(&f, key, "l.id", &query.PageQuery)
+	sqlQuery := fmt.Sprintf(`SELECT
+		%s
+		FROM %s l
+		%s
+		%s
+	`, loanColumns, config.PostgresConfig.LoansTableName, f.where(), orderBy)
+	rows, err := p.DB.Query(ctx, sqlQuery, f.args...)
+	if err != nil {
+		logger.FromContext(ctx).Errorf("Failed to fetch loans. Error: %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+	loans := make([]*model.LoanDetails, 0)
+	for rows.Next() {
+		loan, err := scanLoan(rows)
+		if err != nil {
+			logger.FromContext(ctx).Errorf("Failed to scan loan details fetched from DB. Error: %v", err)
+			continue
+		}
+		loans = append(loans, loan)
+	}
+	page := &model.LoanPage{Loans: loans}
+	if limit := pageLimit(&query.PageQuery); len(loans) > limit {
+		page.Loans = loans[:limit]
+		last := loans[limit-1]
+		cursor := &model.Cursor{SortBy: query.SortBy, Desc: query.Order == constants.OrderDesc, ID: last.ID}
+		switch query.SortBy {
+		case constants.SortByBorrower:
+			cursor.Text = last.NameOfBorrower
+		case constants.SortByLoanDate:
+			cursor.Num = last.LoanDate
+		case constants.SortByReturnDate:
+			cursor.Num = last.ReturnDate
+		}
+		page.NextCursor = cursor.Encode()
+	}
+	return page, nil
+}
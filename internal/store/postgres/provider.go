@@ -9,7 +9,32 @@ import (
 	"github.com/test/library-app/internal/logger"
 )
 
+// InitPostgresStore connects to postgres and applies the pending migrations,
+// only verifies none are pending when MigrateOnStart is off
 func InitPostgresStore() (*PostgresDB, error) {
+	db, err := Connect()
+	if err != nil {
+		return nil, err
+	}
+	ctx := context.Background()
+	if config.PostgresConfig.MigrateOnStart {
+		count, err := db.MigrateUp(ctx)
+		if err != nil {
+			logger.Errorf("Failed to migrate postgres. Error: %v", err)
+			db.Close()
+			return nil, err
+		}
+		logger.Infof("Applied %d migrations", count)
+	} else if err := db.verifyMigrations(ctx); err != nil {
+		logger.Errorf("Failed to verify postgres migrations. Error: %v", err)
+		db.Close()
+		return nil, err
+	}
+	return db, nil
+}
+
+// Connect connects to postgres without touching the schema
+func Connect() (*PostgresDB, error) {
 	query := url.Values{}
 	query.Add("application_name", config.CommonConfig.AppName)
 	query.Add("client_encoding", "utf-8")
@@ -21,13 +46,15 @@ func InitPostgresStore() (*PostgresDB, error) {
 		Path:     "/" + config.PostgresConfig.DBName,
 		RawQuery: query.Encode(),
 	}
-	logger.Infof("Connecting to postgres: %s", u.String())
+	logger.Infof("Connecting to postgres: %s", u.Redacted())
 	poolConfig, err := pgxpool.ParseConfig(u.String())
 	if err != nil {
 		logger.Errorf("Failed to parse postgres config. Error: %v", err)
 		return nil, err
 	}
 	poolConfig.MaxConns = 10
+	// tracing the queries under the spans of the store operations
+	poolConfig.ConnConfig.Tracer = queryTracer{}
 	ctx := context.Background()
 	pool, err := pgxpool.NewWithConfig(ctx, poolConfig)
 	if err != nil {
@@ -0,0 +1,82 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/test/library-app/internal/config"
+	"github.com/test/library-app/internal/constants"
+	"github.com/test/library-app/internal/logger"
+	"github.com/test/library-app/internal/model"
+)
+
+// SearchBooks retreves the books best matching the query across title, authors, subjects and description,
+// ranked by the weighted search vector kept up to date by a trigger on the books table
+func (p *PostgresDB) SearchBooks(ctx context.Context, query string, limit int) ([]*model.BookSearchResult, error) {
+	headline := fmt.Sprintf("StartSel=%s, StopSel=%s, HighlightAll=true", constants.HighlightStart, constants.HighlightStop)
+	sqlQuery := fmt.Sprintf(`SELECT
+		%[1]s,
+		ts_rank(b.search_vector, q) AS score,
+		ts_headline('english', b.title, q, '%[3]s'),
+		ts_headline('english', array_to_string(b.authors, ', '), q, '%[3]s'),
+		ts_headline('english', array_to_string(b.subjects, ', '), q, '%[3]s'),
+		ts_headline('english', b.description, q, '%[3]s')
+		FROM %[2]s b, websearch_to_tsquery('english', $1) q
+		WHERE b.search_vector @@ q
+		ORDER BY score DESC, b.id
+		LIMIT $2
+	`, bookColumns(), config.PostgresConfig.BooksTableName, headline)
+	rows, err := p.DB.Query(ctx, sqlQuery, query, limit)
+	if err != nil {
+		logger.FromContext(ctx).Errorf("Failed to search books with query: %s. Error: %v", query, err)
+		return nil, err
+	}
+	defer rows.Close()
+	results := make([]*model.BookSearchResult, 0)
+	for rows.Next() {
+		var book model.BookDetails
+		var result model.BookSearchResult
+		var title, authors, subjects, description string
+		err := rows.Scan(
+			&book.ID,
+			&book.ISBN,
+			&book.Title,
+			&book.Authors,
+			&book.Publisher,
+			&book.PublicationYear,
+			&book.Language,
+			&book.Subjects,
+			&book.Edition,
+			&book.Description,
+			&book.Category,
+			&book.AvailableCopies,
+			&book.TotalCopies,
+			&book.Version,
+			&result.Score,
+			&title,
+			&authors,
+			&subjects,
+			&description,
+		)
+		if err != nil {
+			logger.FromContext(ctx).Errorf("Failed to scan search result fetched from DB. Error: %v", err)
+			continue
+		}
+		result.Book = &book
+		result.Highlights = make(map[string]string)
+		// fields without a match are left out
+		for field, text := range map[string]string{
+			constants.SearchFieldTitle:       title,
+			constants.SearchFieldAuthors:     authors,
+			constants.SearchFieldSubjects:    subjects,
+			constants.SearchFieldDescription: description,
+		} {
+			if strings.Contains(text, constants.HighlightStart) {
+				result.Highlights[field] = text
+			}
+		}
+		results = append(results, &result)
+	}
+	return results, nil
+}
@@ -13,310 +13,607 @@ import (
 	"github.com/test/library-app/internal/constants"
 	"github.com/test/library-app/internal/logger"
 	"github.com/test/library-app/internal/model"
+	"github.com/test/library-app/internal/policy"
 )
 
 type PostgresDB struct {
 	DB *pgxpool.Pool
 }
 
-// GetBookDetails retreves book details from store
+// bookColumns lists the columns of the books table aliased as b in the order scanBook reads them,
+// copy counts are derived from the copies table
+func bookColumns() string {
+	return fmt.Sprintf(`b.id,
+		COALESCE(b.isbn, ''),
+		b.title,
+		b.authors,
+		b.publisher,
+		b.publication_year,
+		b.language,
+		b.subjects,
+		b.edition,
+		b.description,
+		b.category,
+		(SELECT COUNT(*) FROM %[1]s c WHERE c.book_id=b.id AND c.status='%[2]s'),
+		(SELECT COUNT(*) FROM %[1]s c WHERE c.book_id=b.id AND c.status<>'%[3]s'),
+		b.version`,
+		config.PostgresConfig.CopiesTableName, constants.CopyAvailable, constants.CopyWithdrawn)
+}
+
+// scanBook scans a row selected with bookColumns
+func scanBook(row pgx.Row) (*model.BookDetails, error) {
+	var book model.BookDetails
+	err := row.Scan(
+		&book.ID,
+		&book.ISBN,
+		&book.Title,
+		&book.Authors,
+		&book.Publisher,
+		&book.PublicationYear,
+		&book.Language,
+		&book.Subjects,
+		&book.Edition,
+		&book.Description,
+		&book.Category,
+		&book.AvailableCopies,
+		&book.TotalCopies,
+		&book.Version,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &book, nil
+}
+
+// GetBookDetails retreves the oldest book with the title from store
 func (p *PostgresDB) GetBookDetails(ctx context.Context, title string) (*model.BookDetails, error) {
 	query := fmt.Sprintf(`SELECT 
-		title, 
-		available_copies 
-		FROM %s
-		WHERE LOWER(title)=LOWER($1)
-	`, config.PostgresConfig.BooksTableName)
-	row := p.DB.QueryRow(ctx, query, title)
-	var bookTitle string
-	var avalilableCopies int
-	err := row.Scan(&bookTitle, &avalilableCopies)
+		%s
+		FROM %s b
+		WHERE LOWER(b.title)=LOWER($1)
+		ORDER BY b.id
+		LIMIT 1
+	`, bookColumns(), config.PostgresConfig.BooksTableName)
+	book, err := scanBook(p.DB.QueryRow(ctx, query, title))
 	if err != nil {
-		logger.Errorf("Failed to scan the requested title: %s. Error: %v", title, err)
+		logger.FromContext(ctx).Errorf("Failed to scan the requested title: %s. Error: %v", title, err)
 		if errors.Is(err, sql.ErrNoRows) {
 			return nil, fmt.Errorf("failed to find the title: %s. %w", title, model.ErrNotFound)
 		}
 		return nil, fmt.Errorf("failed to find the title: %s", title)
 	}
-	return &model.BookDetails{
-		Title:           bookTitle,
-		AvailableCopies: avalilableCopies,
-	}, nil
+	return book, nil
 }
 
-// GetAllBookDetails retreves book details from store
-func (p *PostgresDB) GetAllBookDetails(ctx context.Context) ([]*model.BookDetails, error) {
+// GetBookDetailsByID retreves book details by its ID from store
+func (p *PostgresDB) GetBookDetailsByID(ctx context.Context, bookID int) (*model.BookDetails, error) {
 	query := fmt.Sprintf(`SELECT 
-		title, 
-		available_copies 
-		FROM %s
-	`, config.PostgresConfig.BooksTableName)
-	rows, err := p.DB.Query(ctx, query)
+		%s
+		FROM %s b
+		WHERE b.id=$1
+	`, bookColumns(), config.PostgresConfig.BooksTableName)
+	book, err := scanBook(p.DB.QueryRow(ctx, query, bookID))
 	if err != nil {
-		logger.Errorf("Failed to fetch books. Error: %v", err)
+		logger.FromContext(ctx).Errorf("Failed to scan the requested book: %d. Error: %v", bookID, err)
 		if errors.Is(err, sql.ErrNoRows) {
-			return nil, fmt.Errorf("failed to fetch books. %w", model.ErrNotFound)
+			return nil, fmt.Errorf("failed to find book: %d. %w", bookID, model.ErrNotFound)
 		}
-		return nil, err
+		return nil, fmt.Errorf("failed to find book: %d", bookID)
+	}
+	return book, nil
+}
+
+// AddBook adds a new book with TotalCopies available copies to the catalog
+func (p *PostgresDB) AddBook(ctx context.Context, det *model.BookDetails) (int, error) {
+	tx, err := p.DB.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		logger.FromContext(ctx).Errorf("failed to begin transaction. Error: %v", err)
+		return 0, err
 	}
-	defer rows.Close()
-	books := make([]*model.BookDetails, 0)
-	for rows.Next() {
-		var book model.BookDetails
-		if err := rows.Scan(&book.Title, &book.AvailableCopies); err != nil {
-			logger.Errorf("Failed to scan bookdetails fetched from DB. Error: %v", err)
-			continue
+	defer tx.Rollback(ctx)
+	query := fmt.Sprintf(`INSERT
+		INTO %s
+		(isbn, title, authors, publisher, publication_year, language, subjects, edition, description, category)
+		VALUES (NULLIF($1, ''), $2, $3, $4, $5, $6, $7, $8, $9, $10)
+		RETURNING id
+	`, config.PostgresConfig.BooksTableName)
+	err = tx.QueryRow(ctx, query,
+		det.ISBN, det.Title, nonNil(det.Authors), det.Publisher, det.PublicationYear,
+		det.Language, nonNil(det.Subjects), det.Edition, det.Description, det.Category,
+	).Scan(&det.ID)
+	if err != nil {
+		logger.FromContext(ctx).Errorf("failed to insert into books. Error: %v", err)
+		if isUniqueViolation(err) {
+			return 0, fmt.Errorf("book with isbn '%s' already presents. %w", det.ISBN, model.ErrAlreadyExists)
 		}
-		books = append(books, &book)
+		return 0, err
+	}
+	copies := make([]*model.BookCopy, det.TotalCopies)
+	for i := range copies {
+		copies[i] = &model.BookCopy{BookID: det.ID}
+	}
+	if err = addCopies(ctx, tx, copies); err != nil {
+		return 0, err
+	}
+	if err = tx.Commit(ctx); err != nil {
+		logger.FromContext(ctx).Errorf("failed to commit transaction of adding book. Error: %v", err)
+		return 0, err
 	}
+	det.AvailableCopies = det.TotalCopies
+	det.Version = 1
+	return det.ID, nil
+}
 
-	return books, nil
+// UpdateBook replaces the details of a book based on its version, any version when 0
+func (p *PostgresDB) UpdateBook(ctx context.Context, bookID int, version int, det *model.BookDetails) (*model.BookDetails, error) {
+	query := fmt.Sprintf(`UPDATE
+		%s SET isbn=NULLIF($1, ''), title=$2, authors=$3, publisher=$4, publication_year=$5,
+		language=$6, subjects=$7, edition=$8, description=$9, category=$10, version=version + 1
+		WHERE id=$11 AND ($12=0 OR version=$12)
+	`, config.PostgresConfig.BooksTableName)
+	tag, err := p.DB.Exec(ctx, query,
+		det.ISBN, det.Title, nonNil(det.Authors), det.Publisher, det.PublicationYear,
+		det.Language, nonNil(det.Subjects), det.Edition, det.Description, det.Category,
+		bookID, version,
+	)
+	if err != nil {
+		logger.FromContext(ctx).Errorf("failed to update book: %d. Error: %v", bookID, err)
+		if isUniqueViolation(err) {
+			return nil, fmt.Errorf("book with isbn '%s' already presents. %w", det.ISBN, model.ErrAlreadyExists)
+		}
+		return nil, err
+	}
+	if tag.RowsAffected() == 0 {
+		// either missed or moved on to another version
+		book, err := p.GetBookDetailsByID(ctx, bookID)
+		if err != nil {
+			return nil, err
+		}
+		logger.FromContext(ctx).Errorf("requested book: %d is at version %d, not %d", bookID, book.Version, version)
+		return nil, fmt.Errorf("book %d is at version %d, not %d. %w", bookID, book.Version, version, model.ErrPreconditionFailed)
+	}
+	// copy counts are derived from the copies, fetching them along with the updated details
+	return p.GetBookDetailsByID(ctx, bookID)
 }
 
-// GetAllLoans retreves all loan details from store
-func (p *PostgresDB) GetAllLoans(ctx context.Context) ([]*model.LoanDetails, error) {
-	query := fmt.Sprintf(`SELECT 
-		id,
-		title, 
-		name_of_borrower,
-		loan_date,
-		return_date,
-		status
-		FROM %s
-	`, config.PostgresConfig.LoansTableName)
-	rows, err := p.DB.Query(ctx, query)
+// UpdateBookCopies adds copies with generated barcodes or withdraws available copies of a book based on its version, any version when 0
+func (p *PostgresDB) UpdateBookCopies(ctx context.Context, bookID int, version int, delta int) (*model.BookDetails, error) {
+	tx, err := p.DB.BeginTx(ctx, pgx.TxOptions{})
 	if err != nil {
-		logger.Errorf("Failed to fetch loans. Error: %v", err)
-		if errors.Is(err, sql.ErrNoRows) {
-			return nil, fmt.Errorf("failed to fetch books. %w", model.ErrNotFound)
+		logger.FromContext(ctx).Errorf("failed to begin transaction. Error: %v", err)
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+	if err = lockBook(ctx, tx, bookID); err != nil {
+		return nil, err
+	}
+	// bumping the version first, the book stays locked till committed
+	query := fmt.Sprintf(`UPDATE %s SET version=version + 1 WHERE id=$1 AND ($2=0 OR version=$2)`, config.PostgresConfig.BooksTableName)
+	tag, err := tx.Exec(ctx, query, bookID, version)
+	if err != nil {
+		logger.FromContext(ctx).Errorf("failed to bump version of book: %d. Error: %v", bookID, err)
+		return nil, err
+	}
+	if tag.RowsAffected() == 0 {
+		logger.FromContext(ctx).Errorf("requested book: %d isn't at version %d", bookID, version)
+		return nil, fmt.Errorf("book %d isn't at version %d. %w", bookID, version, model.ErrPreconditionFailed)
+	}
+	if delta > 0 {
+		copies := make([]*model.BookCopy, delta)
+		for i := range copies {
+			copies[i] = &model.BookCopy{BookID: bookID}
+		}
+		if err = addCopies(ctx, tx, copies); err != nil {
+			return nil, err
 		}
+	}
+	if delta < 0 {
+		query := fmt.Sprintf(`UPDATE
+			%[1]s SET status=$1
+			WHERE id IN (
+				SELECT id FROM %[1]s WHERE book_id=$2 AND status=$3 ORDER BY id LIMIT $4 FOR UPDATE
+			)
+		`, config.PostgresConfig.CopiesTableName)
+		tag, err := tx.Exec(ctx, query, constants.CopyWithdrawn, bookID, constants.CopyAvailable, -delta)
+		if err != nil {
+			logger.FromContext(ctx).Errorf("failed to withdraw copies of book: %d. Error: %v", bookID, err)
+			return nil, err
+		}
+		if tag.RowsAffected() < int64(-delta) {
+			logger.FromContext(ctx).Errorf("not enough copies of book: %d to withdraw", bookID)
+			return nil, fmt.Errorf("not enough copies of book: %d to withdraw. %w", bookID, model.ErrConflict)
+		}
+	}
+	if err = tx.Commit(ctx); err != nil {
+		logger.FromContext(ctx).Errorf("failed to commit transaction of updating book copies. Error: %v", err)
 		return nil, err
 	}
-	defer rows.Close()
-	loans := make([]*model.LoanDetails, 0)
-	for rows.Next() {
-		var loan model.LoanDetails
-		var loanDate time.Time
-		var returnDate time.Time
-		if err := rows.Scan(&loan.ID, &loan.Title, &loan.NameOfBorrower, &loanDate, &returnDate, &loan.Status); err != nil {
-			logger.Errorf("Failed to scan bookdetails fetched from DB. Error: %v", err)
-			continue
+	return p.GetBookDetailsByID(ctx, bookID)
+}
+
+// DeleteBook removes a book from the catalog, refused while it has active loans
+func (p *PostgresDB) DeleteBook(ctx context.Context, bookID int) error {
+	tx, err := p.DB.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		logger.FromContext(ctx).Errorf("failed to begin transaction. Error: %v", err)
+		return err
+	}
+	defer tx.Rollback(ctx)
+	// locking the book row so no loan gets added while deleting
+	if err = lockBook(ctx, tx, bookID); err != nil {
+		return err
+	}
+	var activeLoans int
+	query := fmt.Sprintf(`SELECT COUNT(*) FROM %s WHERE book_id=$1 AND status<>$2`, config.PostgresConfig.LoansTableName)
+	err = tx.QueryRow(ctx, query, bookID, constants.Closed).Scan(&activeLoans)
+	if err != nil {
+		logger.FromContext(ctx).Errorf("failed to count active loans of book: %d. Error: %v", bookID, err)
+		return err
+	}
+	if activeLoans > 0 {
+		logger.FromContext(ctx).Errorf("book: %d has %d active loans", bookID, activeLoans)
+		return fmt.Errorf("book %d has active loans. %w", bookID, model.ErrConflict)
+	}
+	// copies are deleted along with the book, closed loans keep their title and barcode
+	// while book_id and copy_id are set to null by the foreign keys
+	query = fmt.Sprintf(`DELETE FROM %s WHERE id=$1`, config.PostgresConfig.BooksTableName)
+	if _, err = tx.Exec(ctx, query, bookID); err != nil {
+		logger.FromContext(ctx).Errorf("failed to delete book: %d. Error: %v", bookID, err)
+		return err
+	}
+	if err = tx.Commit(ctx); err != nil {
+		logger.FromContext(ctx).Errorf("failed to commit transaction of deleting book. Error: %v", err)
+		return err
+	}
+	return nil
+}
+
+// bookForLoan resolves the loaned book by the copy barcode, its ID, or by its title when both are absent
+func (p *PostgresDB) bookForLoan(ctx context.Context, det *model.LoanDetails) (*model.BookDetails, error) {
+	if det.Barcode != "" {
+		bookCopy, err := p.GetBookCopy(ctx, det.Barcode)
+		if err != nil {
+			return nil, err
 		}
-		loan.LoanDate = loanDate.Unix()
-		loan.ReturnDate = returnDate.Unix()
-		loans = append(loans, &loan)
+		det.BookID = bookCopy.BookID
+	}
+	if det.BookID != 0 {
+		return p.GetBookDetailsByID(ctx, det.BookID)
 	}
+	// fetching two rows is enough to find out whether the title is ambiguous
+	query := fmt.Sprintf(`SELECT
+		%s
+		FROM %s b
+		WHERE LOWER(b.title)=LOWER($1)
+		LIMIT 2
+	`, bookColumns(), config.PostgresConfig.BooksTableName)
+	rows, err := p.DB.Query(ctx, query, det.Title)
+	if err != nil {
+		logger.FromContext(ctx).Errorf("failed to fetch requested title from books table. Error: %v", err)
+		return nil, err
+	}
+	books, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (*model.BookDetails, error) {
+		return scanBook(row)
+	})
+	if err != nil {
+		logger.FromContext(ctx).Errorf("failed to scan requested title from books table. Error: %v", err)
+		return nil, err
+	}
+	switch len(books) {
+	case 0:
+		return nil, fmt.Errorf("failed to find the title: %s. %w", det.Title, model.ErrNotFound)
+	case 1:
+		return books[0], nil
+	default:
+		return nil, fmt.Errorf("books share the title '%s', book_id is required. %w", det.Title, model.ErrConflict)
+	}
+}
 
-	return loans, nil
+// nonNil keeps nil slices from being stored as NULL arrays
+func nonNil(vals []string) []string {
+	if vals == nil {
+		return []string{}
+	}
+	return vals
 }
 
 // AddLoan adds the loan details to store
 func (p *PostgresDB) AddLoan(ctx context.Context, det *model.LoanDetails) (int, error) {
-	// checking available copies are there or not for the requested book title
-	query := fmt.Sprintf(`SELECT available_copies FROM %s WHERE LOWER(title)=LOWER($1)`, config.PostgresConfig.BooksTableName)
-	var avalilableCopies int
-	err := p.DB.QueryRow(ctx, query, det.Title).Scan(&avalilableCopies)
+	// checking available copies are there or not for the requested book
+	book, err := p.bookForLoan(ctx, det)
 	if err != nil {
-		logger.Errorf("failed to fetch requested title from books table. Error: %v", err)
-		if errors.Is(err, sql.ErrNoRows) {
-			return 0, fmt.Errorf("failed to find the title: %s. %w", det.Title, model.ErrNotFound)
-		}
 		return 0, err
 	}
-	// if available copies are zero returning the error
-	if avalilableCopies == 0 {
-		logger.Errorf("not enough copies of requested title %v", det.Title)
-		return 0, fmt.Errorf("not enough copies of requested title %v. %w", det.Title, model.ErrNotFound)
-	}
+	det.BookID = book.ID
+	det.Title = book.Title
 
 	tx, err := p.DB.BeginTx(ctx, pgx.TxOptions{})
 	if err != nil {
-		logger.Errorf("failed to begin transaction. Error: %v", err)
+		logger.FromContext(ctx).Errorf("failed to begin transaction. Error: %v", err)
 		return 0, err
 	}
 	defer tx.Rollback(ctx)
+	standing, err := memberStanding(ctx, tx, det.MemberID)
+	if err != nil {
+		return 0, err
+	}
+	det.NameOfBorrower = standing.Member.Name
+	if err = policy.CheckLoan(standing, det, time.Now()); err != nil {
+		return 0, err
+	}
+	if det.LoanDate == 0 {
+		det.LoanDate = time.Now().Unix()
+	}
+	if det.ReturnDate == 0 {
+		det.ReturnDate = time.Unix(det.LoanDate, 0).Add(policy.Terms(standing.Member.Tier, book.Category).Period).Unix()
+	}
+	// the copy set aside for the member comes first
+	held, err := takeReadyHold(ctx, tx, det)
+	if err != nil {
+		return 0, err
+	}
+	// locking the copy to loan, concurrent loans skip it
+	var copyStatus string
+	switch {
+	case held:
+	case det.Barcode != "":
+		query := fmt.Sprintf(`SELECT id, status FROM %s WHERE barcode=$1 FOR UPDATE`, config.PostgresConfig.CopiesTableName)
+		err = tx.QueryRow(ctx, query, det.Barcode).Scan(&det.CopyID, &copyStatus)
+		if err != nil {
+			logger.FromContext(ctx).Errorf("failed to lock copy %s to loan. Error: %v", det.Barcode, err)
+			if errors.Is(err, sql.ErrNoRows) {
+				return 0, fmt.Errorf("failed to find copy: %s. %w", det.Barcode, model.ErrNotFound)
+			}
+			return 0, err
+		}
+		if copyStatus != constants.CopyAvailable {
+			logger.FromContext(ctx).Errorf("requested copy %s is %s", det.Barcode, copyStatus)
+			return 0, fmt.Errorf("copy with barcode '%s' is %s. %w", det.Barcode, copyStatus, model.ErrConflict)
+		}
+	default:
+		query := fmt.Sprintf(`SELECT
+			id, barcode
+			FROM %s
+			WHERE book_id=$1 AND status=$2
+			ORDER BY id
+			LIMIT 1
+			FOR UPDATE SKIP LOCKED
+		`, config.PostgresConfig.CopiesTableName)
+		err = tx.QueryRow(ctx, query, det.BookID, constants.CopyAvailable).Scan(&det.CopyID, &det.Barcode)
+		if err != nil {
+			logger.FromContext(ctx).Errorf("failed to lock a copy of title %v to loan. Error: %v", det.Title, err)
+			if errors.Is(err, sql.ErrNoRows) {
+				return 0, fmt.Errorf("not enough copies of requested title %v. %w", det.Title, model.ErrNotFound)
+			}
+			return 0, err
+		}
+	}
+	// taking the copy off the shelf
+	query := fmt.Sprintf(`UPDATE %s SET status=$1 WHERE id=$2`, config.PostgresConfig.CopiesTableName)
+	if _, err = tx.Exec(ctx, query, constants.CopyOnLoan, det.CopyID); err != nil {
+		logger.FromContext(ctx).Errorf("failed to update status of copy %s. Error: %v", det.Barcode, err)
+		return 0, err
+	}
 	// id := GetUniqueIncrementedID()
 	lastInsertId := 0
 	// inserting in to loans table
 	query = fmt.Sprintf(`INSERT
 		INTO %s
-		(title, name_of_borrower, return_date, status)
-		VALUES ($1, $2, $3, $4)
+		(book_id, copy_id, barcode, title, member_id, name_of_borrower, loan_date, return_date, status)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
 		RETURNING id
 	`, config.PostgresConfig.LoansTableName)
-	err = tx.QueryRow(ctx, query, det.Title, det.NameOfBorrower, time.Unix(det.ReturnDate, 0), det.Status).Scan(&lastInsertId)
+	err = tx.QueryRow(ctx, query, det.BookID, det.CopyID, det.Barcode, det.Title, det.MemberID, det.NameOfBorrower, time.Unix(det.LoanDate, 0), time.Unix(det.ReturnDate, 0), det.Status).Scan(&lastInsertId)
 	if err != nil {
-		logger.Errorf("failed to insert into loan. Error: %v", err)
+		logger.FromContext(ctx).Errorf("failed to insert into loan. Error: %v", err)
 		return 0, err
 	}
 	det.ID = lastInsertId
-
-	// updating the available copies
-	query = fmt.Sprintf(`UPDATE
-		%s SET available_copies=$1 WHERE LOWER(title)=LOWER($2)
-	`, config.PostgresConfig.BooksTableName)
-	_, err = tx.Exec(ctx, query, avalilableCopies-1, det.Title)
-	if err != nil {
-		logger.Errorf("failed to update avaialble_copies count in to books. Error: %v", err)
+	after := &model.LoanState{Status: det.Status, ReturnDate: det.ReturnDate}
+	if err = recordEvent(ctx, tx, det.ID, constants.LoanCreated, nil, after, time.Now()); err != nil {
 		return 0, err
 	}
+
 	// committing the transaction after all db actions completed successfully
 	if err = tx.Commit(ctx); err != nil {
-		logger.Errorf("failed to commit transaction. Error: %v", err)
+		logger.FromContext(ctx).Errorf("failed to commit transaction. Error: %v", err)
 		return 0, err
 	}
+	det.Version = 1
 	return det.ID, nil
 }
 
-// Extends the loan
-func (p *PostgresDB) ExtendLoan(ctx context.Context, loanID int) (*model.LoanDetails, error) {
+// ExtendLoan extends the return date by the extension of the loan terms, refused once overdue or extended the most times
+func (p *PostgresDB) ExtendLoan(ctx context.Context, loanID int, version int) (*model.LoanDetails, error) {
 	det := model.LoanDetails{
 		ID: loanID,
 	}
+	tx, err := p.DB.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		logger.FromContext(ctx).Errorf("Failed to begin transaction. Error: %v", err)
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+	// locking the loan so concurrent extensions are counted, terms of deleted members and books fall back to the defaults
 	query := fmt.Sprintf(`SELECT
-		name_of_borrower,
-		title,
-		status 
-	FROM %s 
-		WHERE id=$1 
-	`, config.PostgresConfig.LoansTableName)
-	err := p.DB.QueryRow(ctx, query, loanID).Scan(&det.NameOfBorrower, &det.Title, &det.Status)
+		COALESCE(l.member_id, 0),
+		l.name_of_borrower,
+		COALESCE(l.book_id, 0),
+		COALESCE(l.copy_id, 0),
+		l.barcode,
+		l.title,
+		l.loan_date,
+		l.return_date,
+		l.status,
+		l.extensions,
+		l.version,
+		COALESCE(m.tier, $2),
+		COALESCE(b.category, '')
+	FROM %s l
+		LEFT JOIN %s m ON m.id=l.member_id
+		LEFT JOIN %s b ON b.id=l.book_id
+		WHERE l.id=$1
+		FOR UPDATE OF l
+	`, config.PostgresConfig.LoansTableName, config.PostgresConfig.MembersTableName, config.PostgresConfig.BooksTableName)
+	var loanDate, returnDate time.Time
+	var tier, category string
+	err = tx.QueryRow(ctx, query, loanID, constants.DefaultTier).Scan(
+		&det.MemberID, &det.NameOfBorrower, &det.BookID, &det.CopyID, &det.Barcode, &det.Title,
+		&loanDate, &returnDate, &det.Status, &det.Extensions, &det.Version, &tier, &category,
+	)
 	if err != nil {
-		logger.Errorf("failed to find a requested loan: %d to extend", loanID)
+		logger.FromContext(ctx).Errorf("failed to find a requested loan: %d to extend", loanID)
 		if errors.Is(err, sql.ErrNoRows) {
 			return nil, fmt.Errorf("failed to find loan: %d. %w", loanID, model.ErrNotFound)
 		}
-		return nil, fmt.Errorf("failed to find a requested loan: %d to extend. %w", loanID, model.ErrNotFound)
+		return nil, err
 	}
 	if det.Status == constants.Closed {
-		logger.Errorf("requested loan: %d already closed", loanID)
+		logger.FromContext(ctx).Errorf("requested loan: %d already closed", loanID)
 		return nil, fmt.Errorf("requested loan: %d already closed", loanID)
 	}
-
-	tx, err := p.DB.BeginTx(ctx, pgx.TxOptions{})
-	if err != nil {
-		logger.Errorf("Failed to begin transaction. Error: %v", err)
+	if version != 0 && version != det.Version {
+		logger.FromContext(ctx).Errorf("requested loan: %d is at version %d, not %d", loanID, det.Version, version)
+		return nil, fmt.Errorf("loan %d is at version %d, not %d. %w", loanID, det.Version, version, model.ErrPreconditionFailed)
+	}
+	terms := policy.Terms(tier, category)
+	if err = policy.CheckExtension(&det, terms); err != nil {
 		return nil, err
 	}
-	defer tx.Rollback(ctx)
 	// updating the return date
+	before := &model.LoanState{Status: det.Status, ReturnDate: returnDate.Unix(), Extensions: det.Extensions}
+	returnDate = returnDate.Add(terms.Extension)
 	query = fmt.Sprintf(`UPDATE
-	%s SET return_date=return_date + interval '3 weeks'
-	WHERE id=$1
+	%s SET return_date=$1, extensions=extensions + 1, version=version + 1
+	WHERE id=$2
 	`, config.PostgresConfig.LoansTableName)
-	_, err = tx.Exec(ctx, query, loanID)
-	if err != nil {
-		logger.Errorf("Failed to execute update query for extending loan. Error: %v", err)
-		// if update failed with doesn't exists returning loan not found
-		if errors.Is(err, sql.ErrNoRows) {
-			return nil, fmt.Errorf("failed to find loan: %d. %w", loanID, model.ErrNotFound)
-		}
+	if _, err = tx.Exec(ctx, query, returnDate, loanID); err != nil {
+		logger.FromContext(ctx).Errorf("Failed to execute update query for extending loan. Error: %v", err)
 		return nil, err
 	}
-	// fetching the updated return date
-	query = fmt.Sprintf(`SELECT
-		return_date 
-	FROM %s 
-		WHERE id=$1 
-	`, config.PostgresConfig.LoansTableName)
-	var returnDate time.Time
-	err = tx.QueryRow(ctx, query, loanID).Scan(&returnDate)
-	if err != nil {
-		logger.Errorf("failed to find a requested loan: %d to extend", loanID)
-		return nil, fmt.Errorf("failed to find a requested loan: %d to extend. %w", loanID, model.ErrNotFound)
+	after := &model.LoanState{Status: det.Status, ReturnDate: returnDate.Unix(), Extensions: det.Extensions + 1}
+	if err = recordEvent(ctx, tx, loanID, constants.LoanExtended, before, after, time.Now()); err != nil {
+		return nil, err
 	}
 	if err = tx.Commit(ctx); err != nil {
-		logger.Errorf("Failed to commit transaction of extending loan. Error: %v", err)
+		logger.FromContext(ctx).Errorf("Failed to commit transaction of extending loan. Error: %v", err)
 		return nil, err
 	}
+	det.LoanDate = loanDate.Unix()
 	det.ReturnDate = returnDate.Unix()
+	det.Extensions++
+	det.Version++
 	return &det, nil
 }
 
 // Retunrs a book
-func (p *PostgresDB) ReturnBook(ctx context.Context, loanID int) (*model.LoanDetails, error) {
+func (p *PostgresDB) ReturnBook(ctx context.Context, loanID int, version int) (*model.LoanDetails, error) {
 	det := model.LoanDetails{
 		ID: loanID,
 	}
 	query := fmt.Sprintf(`SELECT
+		COALESCE(member_id, 0),
 		name_of_borrower,
+		COALESCE(book_id, 0),
+		COALESCE(copy_id, 0),
+		barcode,
 		title,
-		status 
+		status,
+		extensions
 	FROM %s 
 		WHERE id=$1 
 	`, config.PostgresConfig.LoansTableName)
-	err := p.DB.QueryRow(ctx, query, loanID).Scan(&det.NameOfBorrower, &det.Title, &det.Status)
+	err := p.DB.QueryRow(ctx, query, loanID).Scan(&det.MemberID, &det.NameOfBorrower, &det.BookID, &det.CopyID, &det.Barcode, &det.Title, &det.Status, &det.Extensions)
 	if err != nil {
-		logger.Errorf("failed to find a requested loan: %d to extend", loanID)
+		logger.FromContext(ctx).Errorf("failed to find a requested loan: %d to extend", loanID)
 		if errors.Is(err, sql.ErrNoRows) {
 			return nil, fmt.Errorf("failed to find loan: %d. %w", loanID, model.ErrNotFound)
 		}
 		return nil, fmt.Errorf("failed to find a requested loan: %d to extend. %w", loanID, model.ErrNotFound)
 	}
 	if det.Status == constants.Closed {
-		logger.Errorf("requested loan: %d already closed", loanID)
+		logger.FromContext(ctx).Errorf("requested loan: %d already closed", loanID)
 		return nil, fmt.Errorf("requested loan: %d already closed", loanID)
 	}
 
 	tx, err := p.DB.BeginTx(ctx, pgx.TxOptions{})
 	if err != nil {
-		logger.Errorf("Failed to begin transaction. Error: %v", err)
+		logger.FromContext(ctx).Errorf("Failed to begin transaction. Error: %v", err)
 		return nil, err
 	}
 	defer tx.Rollback(ctx)
-	// fetching title from loan
-	var title string
+	// fetching copy from loan, locking it against the overdue job
+	var copyID int
+	var returnDate time.Time
 	query = fmt.Sprintf(`SELECT
-		title
+		COALESCE(copy_id, 0), return_date, status, version
 	FROM
 		%s
 		WHERE id=$1
+		FOR UPDATE
 	`, config.PostgresConfig.LoansTableName)
-	err = tx.QueryRow(ctx, query, loanID).Scan(&title)
+	err = tx.QueryRow(ctx, query, loanID).Scan(&copyID, &returnDate, &det.Status, &det.Version)
 	if err != nil {
-		logger.Errorf("Failed to execute get loan. Error: %v", err)
+		logger.FromContext(ctx).Errorf("Failed to execute get loan. Error: %v", err)
 		if errors.Is(err, sql.ErrNoRows) {
 			return nil, fmt.Errorf("failed to find loan: %d. %w", loanID, model.ErrNotFound)
 		}
 		return nil, err
 	}
+	// returned meanwhile
+	if det.Status == constants.Closed {
+		logger.FromContext(ctx).Errorf("requested loan: %d already closed", loanID)
+		return nil, fmt.Errorf("requested loan: %d already closed", loanID)
+	}
+	if version != 0 && version != det.Version {
+		logger.FromContext(ctx).Errorf("requested loan: %d is at version %d, not %d", loanID, det.Version, version)
+		return nil, fmt.Errorf("loan %d is at version %d, not %d. %w", loanID, det.Version, version, model.ErrPreconditionFailed)
+	}
 
 	// deleting the loan
 	query = fmt.Sprintf(`UPDATE
 		%s
-		SET status=$1
+		SET status=$1, version=version + 1
 		WHERE id=$2
 	`, config.PostgresConfig.LoansTableName)
 	_, err = tx.Exec(ctx, query, constants.Closed, loanID)
 	if err != nil {
-		logger.Errorf("Failed to execute update query for extending loan. Error: %v", err)
+		logger.FromContext(ctx).Errorf("Failed to execute update query for extending loan. Error: %v", err)
 		if errors.Is(err, sql.ErrNoRows) {
 			return nil, fmt.Errorf("failed to find loan: %d. %w", loanID, model.ErrNotFound)
 		}
 		return nil, err
 	}
-	query = fmt.Sprintf(`UPDATE
-		%s SET available_copies=available_copies+1
-	WHERE 
-		LOWER(title)=LOWER($1)
-	`,
-		config.PostgresConfig.BooksTableName)
-	_, err = tx.Exec(ctx, query, title)
-	if err != nil {
-		logger.Errorf("Failed to update  query for extending loan. Error: %v", err)
-		if errors.Is(err, sql.ErrNoRows) {
-			return nil, fmt.Errorf("failed to find book: %d. %w", loanID, model.ErrNotFound)
-		}
+	now := time.Now()
+	before := &model.LoanState{Status: det.Status, ReturnDate: returnDate.Unix(), Extensions: det.Extensions}
+	after := &model.LoanState{Status: constants.Closed, ReturnDate: returnDate.Unix(), Extensions: det.Extensions}
+	if err = recordEvent(ctx, tx, loanID, constants.LoanReturned, before, after, now); err != nil {
+		return nil, err
+	}
+	// the fine stops accruing once returned
+	if err = fineLoan(ctx, tx, loanID, returnDate, now, true); err != nil {
 		return nil, err
 	}
+	// setting the copy aside for the next hold or putting it back on the shelf
+	if copyID != 0 {
+		if err = shelveCopy(ctx, tx, det.BookID, copyID, now); err != nil {
+			return nil, err
+		}
+	}
 	if err = tx.Commit(ctx); err != nil {
-		logger.Errorf("Failed to commit transaction of returning a book. Error: %v", err)
+		logger.FromContext(ctx).Errorf("Failed to commit transaction of returning a book. Error: %v", err)
 		return nil, err
 	}
 	det.Status = constants.Closed
+	det.Version++
 	return &det, nil
 }
 
+// Ping acquires a connection of the pool and pings postgres over it
+func (p *PostgresDB) Ping(ctx context.Context) error {
+	return p.DB.Ping(ctx)
+}
+
 func (p *PostgresDB) Close() error {
 	logger.Infof("Closing the postgress connection pool")
 	p.DB.Close()
@@ -0,0 +1,29 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/test/library-app/internal/config"
+	"github.com/test/library-app/internal/constants"
+	"github.com/test/library-app/internal/logger"
+	"github.com/test/library-app/internal/model"
+)
+
+// GetCirculationStats counts the open and overdue loans and the books with no copy available as of now
+func (p *PostgresDB) GetCirculationStats(ctx context.Context, now time.Time) (*model.CirculationStats, error) {
+	query := fmt.Sprintf(`SELECT
+		(SELECT COUNT(*) FROM %[1]s WHERE status<>'%[3]s'),
+		(SELECT COUNT(*) FROM %[1]s WHERE status<>'%[3]s' AND return_date<$1),
+		(SELECT COUNT(*) FROM %[2]s b WHERE NOT EXISTS (
+			SELECT 1 FROM %[4]s c WHERE c.book_id=b.id AND c.status='%[5]s'))
+	`, config.PostgresConfig.LoansTableName, config.PostgresConfig.BooksTableName, constants.Closed,
+		config.PostgresConfig.CopiesTableName, constants.CopyAvailable)
+	var stats model.CirculationStats
+	if err := p.DB.QueryRow(ctx, query, now).Scan(&stats.OpenLoans, &stats.OverdueLoans, &stats.UnavailableTitles); err != nil {
+		logger.FromContext(ctx).Errorf("failed to count the circulation stats. Error: %v", err)
+		return nil, err
+	}
+	return &stats, nil
+}
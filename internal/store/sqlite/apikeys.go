@@ -0,0 +1,104 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/test/library-app/internal/logger"
+	"github.com/test/library-app/internal/model"
+)
+
+// apiKeyColumns lists the columns of the api keys table in the order scanAPIKey reads them
+const apiKeyColumns = `id, name, prefix, hash, scopes, requests_per_minute, created_at, revoked_at`
+
+// scanAPIKey scans a row selected with apiKeyColumns
+func scanAPIKey(row scanner) (*model.APIKey, error) {
+	var key model.APIKey
+	if err := row.Scan(&key.ID, &key.Name, &key.Prefix, &key.Hash, (*stringList)(&key.Scopes), &key.RequestsPerMinute, &key.CreatedAt, &key.RevokedAt); err != nil {
+		return nil, err
+	}
+	return &key, nil
+}
+
+// AddAPIKey stores an issued api key, refused with ErrAlreadyExists when its name or hash is taken
+func (s *SQLiteDB) AddAPIKey(ctx context.Context, key *model.APIKey) (int, error) {
+	if key.CreatedAt == 0 {
+		key.CreatedAt = time.Now().Unix()
+	}
+	query := `INSERT
+		INTO api_keys (name, prefix, hash, scopes, requests_per_minute, created_at)
+		VALUES (?1, ?2, ?3, ?4, ?5, ?6)
+	`
+	res, err := s.DB.ExecContext(ctx, query, key.Name, key.Prefix, key.Hash, stringList(key.Scopes), key.RequestsPerMinute, key.CreatedAt)
+	if err != nil {
+		logger.FromContext(ctx).Errorf("failed to insert api key %s. Error: %v", key.Name, err)
+		if isUniqueViolation(err) {
+			return 0, fmt.Errorf("api key named '%s' already presents. %w", key.Name, model.ErrAlreadyExists)
+		}
+		return 0, err
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+	key.ID = int(id)
+	return key.ID, nil
+}
+
+// GetAPIKeyByHash retreves the api key with the hash whether revoked or not
+func (s *SQLiteDB) GetAPIKeyByHash(ctx context.Context, hash string) (*model.APIKey, error) {
+	key, err := scanAPIKey(s.DB.QueryRowContext(ctx, `SELECT `+apiKeyColumns+` FROM api_keys WHERE hash=?1`, hash))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("api key isn't presents. %w", model.ErrNotFound)
+		}
+		logger.FromContext(ctx).Errorf("failed to fetch api key. Error: %v", err)
+		return nil, err
+	}
+	return key, nil
+}
+
+// GetAllAPIKeys retreves the api keys in the order issued
+func (s *SQLiteDB) GetAllAPIKeys(ctx context.Context) ([]*model.APIKey, error) {
+	rows, err := s.DB.QueryContext(ctx, `SELECT `+apiKeyColumns+` FROM api_keys ORDER BY id`)
+	if err != nil {
+		logger.FromContext(ctx).Errorf("failed to fetch api keys. Error: %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+	keys := make([]*model.APIKey, 0)
+	for rows.Next() {
+		key, err := scanAPIKey(rows)
+		if err != nil {
+			logger.FromContext(ctx).Errorf("failed to scan api key. Error: %v", err)
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+	return keys, rows.Err()
+}
+
+// RevokeAPIKey revokes an api key as of now, refused with ErrConflict once revoked
+func (s *SQLiteDB) RevokeAPIKey(ctx context.Context, keyID int, now time.Time) (*model.APIKey, error) {
+	query := `UPDATE api_keys SET revoked_at=?2 WHERE id=?1 AND revoked_at=0 RETURNING ` + apiKeyColumns
+	key, err := scanAPIKey(s.DB.QueryRowContext(ctx, query, keyID, now.Unix()))
+	if err == nil {
+		return key, nil
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		logger.FromContext(ctx).Errorf("failed to revoke api key %d. Error: %v", keyID, err)
+		return nil, err
+	}
+	// telling a missed key from a revoked one
+	if _, err := scanAPIKey(s.DB.QueryRowContext(ctx, `SELECT `+apiKeyColumns+` FROM api_keys WHERE id=?1`, keyID)); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("api key %d isn't presents. %w", keyID, model.ErrNotFound)
+		}
+		logger.FromContext(ctx).Errorf("failed to fetch api key %d. Error: %v", keyID, err)
+		return nil, err
+	}
+	return nil, fmt.Errorf("api key %d is revoked already. %w", keyID, model.ErrConflict)
+}
@@ -0,0 +1,220 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/test/library-app/internal/constants"
+	"github.com/test/library-app/internal/logger"
+	"github.com/test/library-app/internal/model"
+)
+
+// copyColumns lists the copies table columns in the order scanCopy reads them
+const copyColumns = `id,
+		book_id,
+		barcode,
+		shelf_location,
+		condition,
+		acquisition_date,
+		status`
+
+// scanCopy scans a row selected with copyColumns
+func scanCopy(row scanner) (*model.BookCopy, error) {
+	var bookCopy model.BookCopy
+	err := row.Scan(
+		&bookCopy.ID,
+		&bookCopy.BookID,
+		&bookCopy.Barcode,
+		&bookCopy.ShelfLocation,
+		&bookCopy.Condition,
+		&bookCopy.AcquisitionDate,
+		&bookCopy.Status,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &bookCopy, nil
+}
+
+// findBook checks the book presents, the immediate transaction holds the write lock already
+func findBook(ctx context.Context, tx *sql.Tx, bookID int) error {
+	var id int
+	err := tx.QueryRowContext(ctx, `SELECT id FROM books WHERE id=?1`, bookID).Scan(&id)
+	if err != nil {
+		logger.FromContext(ctx).Errorf("failed to find book: %d. Error: %v", bookID, err)
+		if errors.Is(err, sql.ErrNoRows) {
+			return fmt.Errorf("failed to find book: %d. %w", bookID, model.ErrNotFound)
+		}
+		return err
+	}
+	return nil
+}
+
+// addCopies inserts copies of a book within the transaction, generating the missed barcodes
+func addCopies(ctx context.Context, tx *sql.Tx, copies []*model.BookCopy) error {
+	if len(copies) == 0 {
+		return nil
+	}
+	bookID := copies[0].BookID
+	var n int
+	if err := tx.QueryRowContext(ctx, `SELECT COUNT(*) FROM book_copies WHERE book_id=?1`, bookID).Scan(&n); err != nil {
+		logger.FromContext(ctx).Errorf("failed to count copies of book: %d. Error: %v", bookID, err)
+		return err
+	}
+	existsQuery := `SELECT EXISTS (SELECT 1 FROM book_copies WHERE barcode=?1)`
+	insertQuery := `INSERT
+		INTO book_copies
+		(book_id, barcode, shelf_location, condition, acquisition_date, status)
+		VALUES (?1, ?2, ?3, ?4, ?5, ?6)
+		RETURNING id
+	`
+	for _, det := range copies {
+		// skipping barcodes taken by copies added with explicit barcodes
+		for det.Barcode == "" {
+			n++
+			var taken bool
+			if err := tx.QueryRowContext(ctx, existsQuery, model.CopyBarcode(bookID, n)).Scan(&taken); err != nil {
+				logger.FromContext(ctx).Errorf("failed to check barcode of book: %d. Error: %v", bookID, err)
+				return err
+			}
+			if !taken {
+				det.Barcode = model.CopyBarcode(bookID, n)
+			}
+		}
+		if det.Condition == "" {
+			det.Condition = constants.DefaultCondition
+		}
+		if det.AcquisitionDate == 0 {
+			det.AcquisitionDate = time.Now().Unix()
+		}
+		if det.Status == "" {
+			det.Status = constants.CopyAvailable
+		}
+		err := tx.QueryRowContext(ctx, insertQuery,
+			det.BookID, det.Barcode, det.ShelfLocation, det.Condition, det.AcquisitionDate, det.Status,
+		).Scan(&det.ID)
+		if err != nil {
+			logger.FromContext(ctx).Errorf("failed to insert into copies. Error: %v", err)
+			if isUniqueViolation(err) {
+				return fmt.Errorf("copy with barcode '%s' already presents. %w", det.Barcode, model.ErrAlreadyExists)
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+// AddBookCopy adds a physical copy to a book, generating the barcode when missed
+func (s *SQLiteDB) AddBookCopy(ctx context.Context, det *model.BookCopy) (int, error) {
+	tx, err := s.DB.BeginTx(ctx, nil)
+	if err != nil {
+		logger.FromContext(ctx).Errorf("failed to begin transaction. Error: %v", err)
+		return 0, err
+	}
+	defer tx.Rollback()
+	if err = findBook(ctx, tx, det.BookID); err != nil {
+		return 0, err
+	}
+	det.Status = constants.CopyAvailable
+	if err = addCopies(ctx, tx, []*model.BookCopy{det}); err != nil {
+		return 0, err
+	}
+	if err = tx.Commit(); err != nil {
+		logger.FromContext(ctx).Errorf("failed to commit transaction of adding copy. Error: %v", err)
+		return 0, err
+	}
+	return det.ID, nil
+}
+
+// GetBookCopies retreves all copies of a book
+func (s *SQLiteDB) GetBookCopies(ctx context.Context, bookID int) ([]*model.BookCopy, error) {
+	if _, err := s.GetBookDetailsByID(ctx, bookID); err != nil {
+		return nil, err
+	}
+	query := fmt.Sprintf(`SELECT %s FROM book_copies WHERE book_id=?1 ORDER BY id`, copyColumns)
+	rows, err := s.DB.QueryContext(ctx, query, bookID)
+	if err != nil {
+		logger.FromContext(ctx).Errorf("Failed to fetch copies of book: %d. Error: %v", bookID, err)
+		return nil, err
+	}
+	defer rows.Close()
+	copies := make([]*model.BookCopy, 0)
+	for rows.Next() {
+		bookCopy, err := scanCopy(rows)
+		if err != nil {
+			logger.FromContext(ctx).Errorf("Failed to scan copy fetched from DB. Error: %v", err)
+			continue
+		}
+		copies = append(copies, bookCopy)
+	}
+	return copies, rows.Err()
+}
+
+// GetBookCopy retreves a copy by its barcode
+func (s *SQLiteDB) GetBookCopy(ctx context.Context, barcode string) (*model.BookCopy, error) {
+	query := fmt.Sprintf(`SELECT %s FROM book_copies WHERE barcode=?1`, copyColumns)
+	bookCopy, err := scanCopy(s.DB.QueryRowContext(ctx, query, barcode))
+	if err != nil {
+		logger.FromContext(ctx).Errorf("Failed to scan the requested copy: %s. Error: %v", barcode, err)
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("failed to find copy: %s. %w", barcode, model.ErrNotFound)
+		}
+		return nil, err
+	}
+	return bookCopy, nil
+}
+
+// UpdateBookCopy updates the non empty shelf location, condition and status of a copy
+func (s *SQLiteDB) UpdateBookCopy(ctx context.Context, barcode string, det *model.BookCopy) (*model.BookCopy, error) {
+	tx, err := s.DB.BeginTx(ctx, nil)
+	if err != nil {
+		logger.FromContext(ctx).Errorf("failed to begin transaction. Error: %v", err)
+		return nil, err
+	}
+	defer tx.Rollback()
+	query := fmt.Sprintf(`SELECT %s FROM book_copies WHERE barcode=?1`, copyColumns)
+	bookCopy, err := scanCopy(tx.QueryRowContext(ctx, query, barcode))
+	if err != nil {
+		logger.FromContext(ctx).Errorf("failed to find a requested copy: %s to update. Error: %v", barcode, err)
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("failed to find copy: %s. %w", barcode, model.ErrNotFound)
+		}
+		return nil, err
+	}
+	if det.Status != "" && det.Status != bookCopy.Status && (bookCopy.Status == constants.CopyOnLoan || bookCopy.Status == constants.CopyOnHold) {
+		// loaned copies change status only by returning them, held copies by loaning or releasing the hold
+		logger.FromContext(ctx).Errorf("requested copy: %s is %s", barcode, bookCopy.Status)
+		return nil, fmt.Errorf("copy with barcode '%s' is %s. %w", barcode, bookCopy.Status, model.ErrConflict)
+	}
+	if det.ShelfLocation != "" {
+		bookCopy.ShelfLocation = det.ShelfLocation
+	}
+	if det.Condition != "" {
+		bookCopy.Condition = det.Condition
+	}
+	if det.AcquisitionDate != 0 {
+		bookCopy.AcquisitionDate = det.AcquisitionDate
+	}
+	if det.Status != "" {
+		bookCopy.Status = det.Status
+	}
+	query = `UPDATE
+		book_copies SET shelf_location=?1, condition=?2, acquisition_date=?3, status=?4
+		WHERE id=?5
+	`
+	_, err = tx.ExecContext(ctx, query,
+		bookCopy.ShelfLocation, bookCopy.Condition, bookCopy.AcquisitionDate, bookCopy.Status, bookCopy.ID,
+	)
+	if err != nil {
+		logger.FromContext(ctx).Errorf("failed to update copy: %s. Error: %v", barcode, err)
+		return nil, err
+	}
+	if err = tx.Commit(); err != nil {
+		logger.FromContext(ctx).Errorf("failed to commit transaction of updating copy. Error: %v", err)
+		return nil, err
+	}
+	return bookCopy, nil
+}
@@ -0,0 +1,228 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/test/library-app/internal/constants"
+	"github.com/test/library-app/internal/logger"
+	"github.com/test/library-app/internal/model"
+	"github.com/test/library-app/internal/policy"
+)
+
+// fineColumns lists the columns of a fine selected from fineTables in the order scanFine reads them
+const fineColumns = `f.id, f.loan_id, COALESCE(l.member_id, 0), l.title, f.amount_in_cents, f.paid_in_cents,
+	f.status, f.accrued_at, COALESCE(f.settled_at, 0)`
+
+// fineTables joins the fines aliased as f with their loans aliased as l
+const fineTables = `fines f JOIN loans l ON l.id=f.loan_id`
+
+// scanFine scans a row selected with fineColumns
+func scanFine(row scanner) (*model.Fine, error) {
+	var fine model.Fine
+	err := row.Scan(&fine.ID, &fine.LoanID, &fine.MemberID, &fine.Title, &fine.AmountInCents, &fine.PaidInCents,
+		&fine.Status, &fine.AccruedAt, &fine.SettledAt)
+	if err != nil {
+		return nil, err
+	}
+	return &fine, nil
+}
+
+// findFine retreves a fine by its ID within the transaction
+func findFine(ctx context.Context, tx *sql.Tx, fineID int) (*model.Fine, error) {
+	query := fmt.Sprintf(`SELECT %s FROM %s WHERE f.id=?1`, fineColumns, fineTables)
+	fine, err := scanFine(tx.QueryRowContext(ctx, query, fineID))
+	if err != nil {
+		logger.FromContext(ctx).Errorf("Failed to scan the requested fine: %d. Error: %v", fineID, err)
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("failed to find fine: %d. %w", fineID, model.ErrNotFound)
+		}
+		return nil, err
+	}
+	return fine, nil
+}
+
+// memberOwes sums the outstanding fines of the member
+func memberOwes(ctx context.Context, tx *sql.Tx, memberID int) (int64, error) {
+	var owed int64
+	query := fmt.Sprintf(`SELECT
+		COALESCE(SUM(MAX(f.amount_in_cents - f.paid_in_cents, 0)), 0)
+		FROM %s
+		WHERE l.member_id=?1 AND f.status IN (?2, ?3)
+	`, fineTables)
+	if err := tx.QueryRowContext(ctx, query, memberID, constants.FineAccruing, constants.FineUnpaid).Scan(&owed); err != nil {
+		logger.FromContext(ctx).Errorf("failed to sum the fines of member %d. Error: %v", memberID, err)
+		return 0, err
+	}
+	return owed, nil
+}
+
+// fineLoan updates the fine of the loan as of now, fining the loan once past the grace period.
+// Returning finalises the fine so it stops accruing, waived fines are left as they are
+func fineLoan(ctx context.Context, tx *sql.Tx, loanID int, returnDate int64, now time.Time, returning bool) error {
+	amount := policy.FineAmount(returnDate, now)
+	if amount == 0 {
+		return nil
+	}
+	status := constants.FineAccruing
+	if returning {
+		status = constants.FineUnpaid
+	}
+	// a fine paid in full while accruing is settled on return
+	query := `INSERT
+		INTO fines AS f
+		(loan_id, amount_in_cents, status, accrued_at)
+		VALUES (?1, ?2, ?3, ?4)
+		ON CONFLICT (loan_id) DO UPDATE SET
+			amount_in_cents=excluded.amount_in_cents,
+			accrued_at=excluded.accrued_at,
+			status=CASE WHEN excluded.status=?5 AND f.paid_in_cents >= excluded.amount_in_cents THEN ?6 ELSE excluded.status END,
+			settled_at=CASE WHEN excluded.status=?5 AND f.paid_in_cents >= excluded.amount_in_cents THEN excluded.accrued_at END
+		WHERE f.status=?7
+	`
+	_, err := tx.ExecContext(ctx, query, loanID, amount, status, now.Unix(), constants.FineUnpaid, constants.FinePaid, constants.FineAccruing)
+	if err != nil {
+		logger.FromContext(ctx).Errorf("failed to fine loan %d. Error: %v", loanID, err)
+		return err
+	}
+	return nil
+}
+
+// AccrueFines marks the loans not returned by now overdue and accrues their fines, returns the No of overdue loans
+func (s *SQLiteDB) AccrueFines(ctx context.Context, now time.Time) (int, error) {
+	tx, err := s.DB.BeginTx(ctx, nil)
+	if err != nil {
+		logger.FromContext(ctx).Errorf("failed to begin transaction. Error: %v", err)
+		return 0, err
+	}
+	defer tx.Rollback()
+	query := fmt.Sprintf(`SELECT %s FROM loans l WHERE l.status<>?1 AND l.return_date < ?2`, loanColumns)
+	rows, err := tx.QueryContext(ctx, query, constants.Closed, now.Unix())
+	if err != nil {
+		logger.FromContext(ctx).Errorf("failed to fetch overdue loans. Error: %v", err)
+		return 0, err
+	}
+	loans := make([]*model.LoanDetails, 0)
+	for rows.Next() {
+		loan, err := scanLoan(rows)
+		if err != nil {
+			rows.Close()
+			logger.FromContext(ctx).Errorf("failed to scan overdue loans. Error: %v", err)
+			return 0, err
+		}
+		loans = append(loans, loan)
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return 0, err
+	}
+	for _, loan := range loans {
+		if loan.Status == constants.Active {
+			if _, err = tx.ExecContext(ctx, `UPDATE loans SET status=?1, version=version + 1 WHERE id=?2`, constants.Overdue, loan.ID); err != nil {
+				logger.FromContext(ctx).Errorf("failed to mark loan %d overdue. Error: %v", loan.ID, err)
+				return 0, err
+			}
+			before := &model.LoanState{Status: loan.Status, ReturnDate: loan.ReturnDate, Extensions: loan.Extensions}
+			after := &model.LoanState{Status: constants.Overdue, ReturnDate: loan.ReturnDate, Extensions: loan.Extensions}
+			if err = recordEvent(ctx, tx, loan.ID, constants.LoanOverdue, before, after, now); err != nil {
+				return 0, err
+			}
+		}
+		if err = fineLoan(ctx, tx, loan.ID, loan.ReturnDate, now, false); err != nil {
+			return 0, err
+		}
+	}
+	if err = tx.Commit(); err != nil {
+		logger.FromContext(ctx).Errorf("failed to commit transaction of accruing fines. Error: %v", err)
+		return 0, err
+	}
+	return len(loans), nil
+}
+
+// GetMemberFines retreves the fines of a member in the order fined along with the total owed
+func (s *SQLiteDB) GetMemberFines(ctx context.Context, memberID int) (*model.MemberFines, error) {
+	if _, err := s.GetMember(ctx, memberID); err != nil {
+		return nil, err
+	}
+	query := fmt.Sprintf(`SELECT %s FROM %s WHERE l.member_id=?1 ORDER BY f.id`, fineColumns, fineTables)
+	rows, err := s.DB.QueryContext(ctx, query, memberID)
+	if err != nil {
+		logger.FromContext(ctx).Errorf("Failed to fetch fines of member %d. Error: %v", memberID, err)
+		return nil, err
+	}
+	defer rows.Close()
+	fines := &model.MemberFines{MemberID: memberID, Fines: make([]*model.Fine, 0)}
+	for rows.Next() {
+		fine, err := scanFine(rows)
+		if err != nil {
+			logger.FromContext(ctx).Errorf("Failed to scan fine fetched from DB. Error: %v", err)
+			continue
+		}
+		fines.Fines = append(fines.Fines, fine)
+		fines.OutstandingInCents += fine.Outstanding()
+	}
+	return fines, rows.Err()
+}
+
+// settleFine finds a fine still open for paying or waiving, applies the change and stores it
+func (s *SQLiteDB) settleFine(ctx context.Context, fineID int, change func(fine *model.Fine) error) (*model.Fine, error) {
+	tx, err := s.DB.BeginTx(ctx, nil)
+	if err != nil {
+		logger.FromContext(ctx).Errorf("failed to begin transaction. Error: %v", err)
+		return nil, err
+	}
+	defer tx.Rollback()
+	fine, err := findFine(ctx, tx, fineID)
+	if err != nil {
+		return nil, err
+	}
+	if fine.Status == constants.FinePaid || fine.Status == constants.FineWaived {
+		return nil, fmt.Errorf("fine %d is %s. %w", fineID, fine.Status, model.ErrConflict)
+	}
+	if err = change(fine); err != nil {
+		return nil, err
+	}
+	settledAt := sql.NullInt64{Int64: fine.SettledAt, Valid: fine.SettledAt != 0}
+	query := `UPDATE fines SET paid_in_cents=?1, status=?2, settled_at=?3 WHERE id=?4`
+	if _, err = tx.ExecContext(ctx, query, fine.PaidInCents, fine.Status, settledAt, fineID); err != nil {
+		logger.FromContext(ctx).Errorf("failed to update fine %d. Error: %v", fineID, err)
+		return nil, err
+	}
+	if err = tx.Commit(); err != nil {
+		logger.FromContext(ctx).Errorf("failed to commit transaction of settling fine. Error: %v", err)
+		return nil, err
+	}
+	return fine, nil
+}
+
+// PayFine pays the amount of a fine, the outstanding amount when zero. Refused when paying more than owed
+func (s *SQLiteDB) PayFine(ctx context.Context, fineID int, amount int64) (*model.Fine, error) {
+	return s.settleFine(ctx, fineID, func(fine *model.Fine) error {
+		outstanding := fine.Outstanding()
+		if amount == 0 {
+			amount = outstanding
+		}
+		if amount == 0 || amount > outstanding {
+			return fmt.Errorf("fine %d has %d cents outstanding, can't pay %d. %w", fineID, outstanding, amount, model.ErrConflict)
+		}
+		fine.PaidInCents += amount
+		// accruing fines may grow till returned
+		if fine.Status == constants.FineUnpaid && fine.Outstanding() == 0 {
+			fine.Status = constants.FinePaid
+			fine.SettledAt = time.Now().Unix()
+		}
+		return nil
+	})
+}
+
+// WaiveFine waives an accruing or unpaid fine, nothing more is owed for it
+func (s *SQLiteDB) WaiveFine(ctx context.Context, fineID int) (*model.Fine, error) {
+	return s.settleFine(ctx, fineID, func(fine *model.Fine) error {
+		fine.Status = constants.FineWaived
+		fine.SettledAt = time.Now().Unix()
+		return nil
+	})
+}
@@ -0,0 +1,101 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/test/library-app/internal/audit"
+	"github.com/test/library-app/internal/logger"
+	"github.com/test/library-app/internal/model"
+)
+
+// encodeState stores a loan state as a JSON object, NULL when missed
+func encodeState(state *model.LoanState) (sql.NullString, error) {
+	if state == nil {
+		return sql.NullString{}, nil
+	}
+	data, err := json.Marshal(state)
+	if err != nil {
+		return sql.NullString{}, err
+	}
+	return sql.NullString{String: string(data), Valid: true}, nil
+}
+
+// decodeState reads a loan state stored by encodeState
+func decodeState(data sql.NullString) (*model.LoanState, error) {
+	if !data.Valid {
+		return nil, nil
+	}
+	var state model.LoanState
+	if err := json.Unmarshal([]byte(data.String), &state); err != nil {
+		return nil, err
+	}
+	return &state, nil
+}
+
+// recordEvent appends the transition of the loan from the state before to the state after made by the actor of the context
+func recordEvent(ctx context.Context, tx *sql.Tx, loanID int, eventType string, before, after *model.LoanState, now time.Time) error {
+	oldState, err := encodeState(before)
+	if err != nil {
+		return err
+	}
+	newState, err := encodeState(after)
+	if err != nil {
+		return err
+	}
+	query := `INSERT
+		INTO loan_events
+		(loan_id, type, actor, at, old_state, new_state)
+		VALUES (?1, ?2, ?3, ?4, ?5, ?6)
+	`
+	if _, err = tx.ExecContext(ctx, query, loanID, eventType, audit.Actor(ctx), now.Unix(), oldState, newState); err != nil {
+		logger.FromContext(ctx).Errorf("failed to record %s event of loan %d. Error: %v", eventType, loanID, err)
+		return err
+	}
+	return nil
+}
+
+// GetLoanHistory retreves the transitions of a loan in the order made
+func (s *SQLiteDB) GetLoanHistory(ctx context.Context, loanID int) ([]*model.LoanEvent, error) {
+	var id int
+	if err := s.DB.QueryRowContext(ctx, `SELECT id FROM loans WHERE id=?1`, loanID).Scan(&id); err != nil {
+		logger.FromContext(ctx).Errorf("failed to find loan %d. Error: %v", loanID, err)
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("failed to find loan: %d. %w", loanID, model.ErrNotFound)
+		}
+		return nil, err
+	}
+	query := `SELECT
+		id, loan_id, type, actor, at, old_state, new_state
+		FROM loan_events
+		WHERE loan_id=?1
+		ORDER BY id
+	`
+	rows, err := s.DB.QueryContext(ctx, query, loanID)
+	if err != nil {
+		logger.FromContext(ctx).Errorf("Failed to fetch history of loan %d. Error: %v", loanID, err)
+		return nil, err
+	}
+	defer rows.Close()
+	events := make([]*model.LoanEvent, 0)
+	for rows.Next() {
+		var event model.LoanEvent
+		var oldState, newState sql.NullString
+		if err := rows.Scan(&event.ID, &event.LoanID, &event.Type, &event.Actor, &event.At, &oldState, &newState); err != nil {
+			logger.FromContext(ctx).Errorf("Failed to scan loan event fetched from DB. Error: %v", err)
+			return nil, err
+		}
+		if event.Old, err = decodeState(oldState); err != nil {
+			return nil, err
+		}
+		if event.New, err = decodeState(newState); err != nil {
+			return nil, err
+		}
+		events = append(events, &event)
+	}
+	return events, rows.Err()
+}
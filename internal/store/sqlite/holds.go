@@ -0,0 +1,269 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/test/library-app/internal/config"
+	"github.com/test/library-app/internal/constants"
+	"github.com/test/library-app/internal/logger"
+	"github.com/test/library-app/internal/model"
+	"github.com/test/library-app/internal/policy"
+)
+
+// holdColumns lists the columns of a hold selected from holdTables in the order scanHold reads them
+const holdColumns = `h.id, h.member_id, h.book_id, b.title, COALESCE(h.copy_id, 0), COALESCE(c.barcode, ''),
+	h.status, h.placed_at, COALESCE(h.ready_at, 0), COALESCE(h.expires_at, 0)`
+
+// holdTables joins the holds aliased as h with the title of the book and the barcode of the copy set aside
+const holdTables = `holds h
+		JOIN books b ON b.id=h.book_id
+		LEFT JOIN book_copies c ON c.id=h.copy_id`
+
+// scanHold scans a row selected with holdColumns
+func scanHold(row scanner) (*model.Hold, error) {
+	var hold model.Hold
+	err := row.Scan(&hold.ID, &hold.MemberID, &hold.BookID, &hold.Title, &hold.CopyID, &hold.Barcode,
+		&hold.Status, &hold.PlacedAt, &hold.ReadyAt, &hold.ExpiresAt)
+	if err != nil {
+		return nil, err
+	}
+	return &hold, nil
+}
+
+// getHold retreves a hold by its ID
+func (s *SQLiteDB) getHold(ctx context.Context, holdID int) (*model.Hold, error) {
+	query := fmt.Sprintf(`SELECT %s FROM %s WHERE h.id=?1`, holdColumns, holdTables)
+	hold, err := scanHold(s.DB.QueryRowContext(ctx, query, holdID))
+	if err != nil {
+		logger.FromContext(ctx).Errorf("Failed to scan the requested hold: %d. Error: %v", holdID, err)
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("failed to find hold: %d. %w", holdID, model.ErrNotFound)
+		}
+		return nil, err
+	}
+	return hold, nil
+}
+
+// shelveCopy sets a copy coming back aside for the next waiting hold of its book,
+// or puts it on the shelf when none waits
+func shelveCopy(ctx context.Context, tx *sql.Tx, bookID, copyID int, now time.Time) error {
+	var holdID int
+	query := `SELECT
+		id
+		FROM holds
+		WHERE book_id=?1 AND status=?2
+		ORDER BY placed_at, id
+		LIMIT 1
+	`
+	err := tx.QueryRowContext(ctx, query, bookID, constants.HoldWaiting).Scan(&holdID)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		logger.FromContext(ctx).Errorf("failed to find the next hold of book %d. Error: %v", bookID, err)
+		return err
+	}
+	copyStatus := constants.CopyAvailable
+	if err == nil {
+		expiresAt := now.Add(time.Duration(config.PolicyConfig.HoldPickupInDays) * 24 * time.Hour)
+		query = `UPDATE
+			holds SET status=?1, copy_id=?2, ready_at=?3, expires_at=?4
+			WHERE id=?5
+		`
+		if _, err = tx.ExecContext(ctx, query, constants.HoldReady, copyID, now.Unix(), expiresAt.Unix(), holdID); err != nil {
+			logger.FromContext(ctx).Errorf("failed to set copy %d aside for hold %d. Error: %v", copyID, holdID, err)
+			return err
+		}
+		copyStatus = constants.CopyOnHold
+	}
+	if _, err = tx.ExecContext(ctx, `UPDATE book_copies SET status=?1 WHERE id=?2`, copyStatus, copyID); err != nil {
+		logger.FromContext(ctx).Errorf("failed to update status of copy %d. Error: %v", copyID, err)
+		return err
+	}
+	return nil
+}
+
+// closeHold closes an active hold with the status, passing its copy on when set aside
+func closeHold(ctx context.Context, tx *sql.Tx, holdID int, status string, now time.Time) error {
+	// only ready holds have a copy set aside
+	var bookID, copyID int
+	query := `UPDATE
+		holds SET status=?1
+		WHERE id=?2
+		RETURNING book_id, COALESCE(copy_id, 0)
+	`
+	if err := tx.QueryRowContext(ctx, query, status, holdID).Scan(&bookID, &copyID); err != nil {
+		logger.FromContext(ctx).Errorf("failed to close hold %d. Error: %v", holdID, err)
+		return err
+	}
+	if copyID != 0 {
+		return shelveCopy(ctx, tx, bookID, copyID, now)
+	}
+	return nil
+}
+
+// AddHold places a hold at the end of the queue of a book with no copy available
+func (s *SQLiteDB) AddHold(ctx context.Context, det *model.Hold) (int, error) {
+	book, err := s.bookForLoan(ctx, &model.LoanDetails{BookID: det.BookID, Title: det.Title})
+	if err != nil {
+		return 0, err
+	}
+	det.BookID = book.ID
+	det.Title = book.Title
+	tx, err := s.DB.BeginTx(ctx, nil)
+	if err != nil {
+		logger.FromContext(ctx).Errorf("failed to begin transaction. Error: %v", err)
+		return 0, err
+	}
+	defer tx.Rollback()
+	standing, err := memberStanding(ctx, tx, det.MemberID)
+	if err != nil {
+		return 0, err
+	}
+	if err = policy.CheckHold(standing, det); err != nil {
+		return 0, err
+	}
+	if err = findBook(ctx, tx, det.BookID); err != nil {
+		return 0, err
+	}
+	var available int
+	query := `SELECT COUNT(*) FROM book_copies WHERE book_id=?1 AND status=?2`
+	if err = tx.QueryRowContext(ctx, query, det.BookID, constants.CopyAvailable).Scan(&available); err != nil {
+		logger.FromContext(ctx).Errorf("failed to count available copies of book %d. Error: %v", det.BookID, err)
+		return 0, err
+	}
+	if available > 0 {
+		return 0, fmt.Errorf("book %d has %d copies available to borrow. %w", det.BookID, available, model.ErrConflict)
+	}
+	det.PlacedAt = time.Now().Unix()
+	query = `INSERT
+		INTO holds
+		(member_id, book_id, status, placed_at)
+		VALUES (?1, ?2, ?3, ?4)
+		RETURNING id
+	`
+	if err = tx.QueryRowContext(ctx, query, det.MemberID, det.BookID, constants.HoldWaiting, det.PlacedAt).Scan(&det.ID); err != nil {
+		logger.FromContext(ctx).Errorf("failed to insert hold. Error: %v", err)
+		if isUniqueViolation(err) {
+			return 0, fmt.Errorf("member %d already holds book %d. %w", det.MemberID, det.BookID, model.ErrAlreadyExists)
+		}
+		return 0, err
+	}
+	if err = tx.Commit(); err != nil {
+		logger.FromContext(ctx).Errorf("failed to commit transaction of placing hold. Error: %v", err)
+		return 0, err
+	}
+	det.Status = constants.HoldWaiting
+	return det.ID, nil
+}
+
+// GetMemberHolds retreves the holds of a member in the order placed
+func (s *SQLiteDB) GetMemberHolds(ctx context.Context, memberID int) ([]*model.Hold, error) {
+	if _, err := s.GetMember(ctx, memberID); err != nil {
+		return nil, err
+	}
+	query := fmt.Sprintf(`SELECT %s FROM %s WHERE h.member_id=?1 ORDER BY h.id`, holdColumns, holdTables)
+	rows, err := s.DB.QueryContext(ctx, query, memberID)
+	if err != nil {
+		logger.FromContext(ctx).Errorf("Failed to fetch holds of member %d. Error: %v", memberID, err)
+		return nil, err
+	}
+	defer rows.Close()
+	holds := make([]*model.Hold, 0)
+	for rows.Next() {
+		hold, err := scanHold(rows)
+		if err != nil {
+			logger.FromContext(ctx).Errorf("Failed to scan hold fetched from DB. Error: %v", err)
+			continue
+		}
+		holds = append(holds, hold)
+	}
+	return holds, rows.Err()
+}
+
+// CancelHold cancels a waiting or ready hold, the copy set aside passes on to the next hold
+func (s *SQLiteDB) CancelHold(ctx context.Context, holdID int) (*model.Hold, error) {
+	tx, err := s.DB.BeginTx(ctx, nil)
+	if err != nil {
+		logger.FromContext(ctx).Errorf("failed to begin transaction. Error: %v", err)
+		return nil, err
+	}
+	defer tx.Rollback()
+	var status string
+	if err = tx.QueryRowContext(ctx, `SELECT status FROM holds WHERE id=?1`, holdID).Scan(&status); err != nil {
+		logger.FromContext(ctx).Errorf("failed to find hold: %d. Error: %v", holdID, err)
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("failed to find hold: %d. %w", holdID, model.ErrNotFound)
+		}
+		return nil, err
+	}
+	if status != constants.HoldWaiting && status != constants.HoldReady {
+		return nil, fmt.Errorf("hold %d is %s. %w", holdID, status, model.ErrConflict)
+	}
+	if err = closeHold(ctx, tx, holdID, constants.HoldCancelled, time.Now()); err != nil {
+		return nil, err
+	}
+	if err = tx.Commit(); err != nil {
+		logger.FromContext(ctx).Errorf("failed to commit transaction of cancelling hold. Error: %v", err)
+		return nil, err
+	}
+	return s.getHold(ctx, holdID)
+}
+
+// ExpireHolds expires the ready holds not picked up by now, their copies pass on to the next holds
+func (s *SQLiteDB) ExpireHolds(ctx context.Context, now time.Time) (int, error) {
+	tx, err := s.DB.BeginTx(ctx, nil)
+	if err != nil {
+		logger.FromContext(ctx).Errorf("failed to begin transaction. Error: %v", err)
+		return 0, err
+	}
+	defer tx.Rollback()
+	ids, err := queryIDs(ctx, tx, `SELECT id FROM holds WHERE status=?1 AND expires_at <= ?2`, constants.HoldReady, now.Unix())
+	if err != nil {
+		logger.FromContext(ctx).Errorf("failed to fetch expired holds. Error: %v", err)
+		return 0, err
+	}
+	for _, id := range ids {
+		if err = closeHold(ctx, tx, id, constants.HoldExpired, now); err != nil {
+			return 0, err
+		}
+		logger.FromContext(ctx).Infof("Hold %d expired", id)
+	}
+	if err = tx.Commit(); err != nil {
+		logger.FromContext(ctx).Errorf("failed to commit transaction of expiring holds. Error: %v", err)
+		return 0, err
+	}
+	return len(ids), nil
+}
+
+// takeReadyHold fulfills the ready hold of the member on the book of the loan, taking the copy set aside.
+// Returns false when the member has no ready hold or asked for another copy
+func takeReadyHold(ctx context.Context, tx *sql.Tx, det *model.LoanDetails) (bool, error) {
+	var holdID, copyID int
+	var barcode string
+	query := `SELECT
+		h.id, c.id, c.barcode
+		FROM holds h
+		JOIN book_copies c ON c.id=h.copy_id
+		WHERE h.member_id=?1 AND h.book_id=?2 AND h.status=?3
+	`
+	err := tx.QueryRowContext(ctx, query, det.MemberID, det.BookID, constants.HoldReady).Scan(&holdID, &copyID, &barcode)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		logger.FromContext(ctx).Errorf("failed to find the ready hold of member %d. Error: %v", det.MemberID, err)
+		return false, err
+	}
+	if det.Barcode != "" && det.Barcode != barcode {
+		return false, nil
+	}
+	if _, err = tx.ExecContext(ctx, `UPDATE holds SET status=?1 WHERE id=?2`, constants.HoldFulfilled, holdID); err != nil {
+		logger.FromContext(ctx).Errorf("failed to fulfill hold %d. Error: %v", holdID, err)
+		return false, err
+	}
+	det.CopyID = copyID
+	det.Barcode = barcode
+	return true, nil
+}
@@ -0,0 +1,102 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/test/library-app/internal/logger"
+	"github.com/test/library-app/internal/model"
+)
+
+// scanIdempotencyRecord scans a row of the idempotency keys table
+func scanIdempotencyRecord(row scanner) (*model.IdempotencyRecord, error) {
+	var rec model.IdempotencyRecord
+	var header string
+	if err := row.Scan(&rec.Key, &rec.Fingerprint, &rec.StatusCode, &header, &rec.Body, &rec.ExpiresAt); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal([]byte(header), &rec.Header); err != nil {
+		return nil, err
+	}
+	return &rec, nil
+}
+
+// ReserveIdempotencyKey reserves the key of the record for its request till the record expires, nil is returned once reserved.
+// The record of the key is returned instead while the key is reserved or completed and not expired by now
+func (s *SQLiteDB) ReserveIdempotencyKey(ctx context.Context, rec *model.IdempotencyRecord, now time.Time) (*model.IdempotencyRecord, error) {
+	// an expired key is taken over, the conflicting insert returns no row otherwise
+	query := `INSERT
+		INTO idempotency_keys (key, fingerprint, status_code, header, body, expires_at)
+		VALUES (?1, ?2, 0, '{}', NULL, ?3)
+		ON CONFLICT (key) DO UPDATE
+		SET fingerprint=excluded.fingerprint, status_code=0, header='{}', body=NULL, expires_at=excluded.expires_at
+		WHERE expires_at <= ?4
+		RETURNING key
+	`
+	var key string
+	err := s.DB.QueryRowContext(ctx, query, rec.Key, rec.Fingerprint, rec.ExpiresAt, now.Unix()).Scan(&key)
+	if err == nil {
+		return nil, nil
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		logger.FromContext(ctx).Errorf("failed to reserve idempotency key %s. Error: %v", rec.Key, err)
+		return nil, err
+	}
+	query = `SELECT key, fingerprint, status_code, header, body, expires_at FROM idempotency_keys WHERE key=?1`
+	stored, err := scanIdempotencyRecord(s.DB.QueryRowContext(ctx, query, rec.Key))
+	if err != nil {
+		logger.FromContext(ctx).Errorf("failed to fetch idempotency key %s. Error: %v", rec.Key, err)
+		// released by the request it was reserved for in between
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("idempotency key %s got released meanwhile. %w", rec.Key, model.ErrConflict)
+		}
+		return nil, err
+	}
+	return stored, nil
+}
+
+// CompleteIdempotencyKey stores the response of the request the key of the record is reserved for
+func (s *SQLiteDB) CompleteIdempotencyKey(ctx context.Context, rec *model.IdempotencyRecord) error {
+	header := rec.Header
+	if header == nil {
+		header = map[string]string{}
+	}
+	data, err := json.Marshal(header)
+	if err != nil {
+		return err
+	}
+	query := `UPDATE idempotency_keys SET status_code=?2, header=?3, body=?4, expires_at=?5 WHERE key=?1`
+	if _, err = s.DB.ExecContext(ctx, query, rec.Key, rec.StatusCode, string(data), rec.Body, rec.ExpiresAt); err != nil {
+		logger.FromContext(ctx).Errorf("failed to complete idempotency key %s. Error: %v", rec.Key, err)
+		return err
+	}
+	return nil
+}
+
+// ReleaseIdempotencyKey drops the reservation of a key so the request can be retried
+func (s *SQLiteDB) ReleaseIdempotencyKey(ctx context.Context, key string) error {
+	// completed keys are kept till they expire
+	if _, err := s.DB.ExecContext(ctx, `DELETE FROM idempotency_keys WHERE key=?1 AND status_code=0`, key); err != nil {
+		logger.FromContext(ctx).Errorf("failed to release idempotency key %s. Error: %v", key, err)
+		return err
+	}
+	return nil
+}
+
+// ExpireIdempotencyKeys drops the keys expired by now, returns the No of keys dropped
+func (s *SQLiteDB) ExpireIdempotencyKeys(ctx context.Context, now time.Time) (int, error) {
+	res, err := s.DB.ExecContext(ctx, `DELETE FROM idempotency_keys WHERE expires_at <= ?1`, now.Unix())
+	if err != nil {
+		logger.FromContext(ctx).Errorf("failed to drop expired idempotency keys. Error: %v", err)
+		return 0, err
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+	return int(affected), nil
+}
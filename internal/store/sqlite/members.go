@@ -0,0 +1,277 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/test/library-app/internal/constants"
+	"github.com/test/library-app/internal/logger"
+	"github.com/test/library-app/internal/model"
+	"github.com/test/library-app/internal/policy"
+)
+
+// memberColumns lists the columns of the members table aliased as m in the order scanMember reads them
+const memberColumns = `m.id, m.name, COALESCE(m.email, ''), m.card_number, m.tier, m.status, m.joined_at`
+
+var memberSortKeys = map[string]sortKey{
+	constants.SortByID:   {expr: "m.id"},
+	constants.SortByName: {expr: "LOWER(m.name)", text: true},
+}
+
+// scanMember scans a row selected with memberColumns
+func scanMember(row scanner) (*model.Member, error) {
+	var member model.Member
+	if err := row.Scan(&member.ID, &member.Name, &member.Email, &member.CardNumber, &member.Tier, &member.Status, &member.JoinedAt); err != nil {
+		return nil, err
+	}
+	return &member, nil
+}
+
+// AddMember registers a member, generating the card number when missed
+func (s *SQLiteDB) AddMember(ctx context.Context, det *model.Member) (int, error) {
+	tx, err := s.DB.BeginTx(ctx, nil)
+	if err != nil {
+		logger.FromContext(ctx).Errorf("failed to begin transaction. Error: %v", err)
+		return 0, err
+	}
+	defer tx.Rollback()
+	// taking the id up front as the generated card number derives from it
+	query := `SELECT COALESCE((SELECT seq FROM sqlite_sequence WHERE name='members'), 0) + 1`
+	if err = tx.QueryRowContext(ctx, query).Scan(&det.ID); err != nil {
+		logger.FromContext(ctx).Errorf("failed to take the next member id. Error: %v", err)
+		return 0, err
+	}
+	if det.CardNumber == "" {
+		det.CardNumber = model.CardNumber(det.ID)
+	}
+	if det.Tier == "" {
+		det.Tier = constants.DefaultTier
+	}
+	if det.Status == "" {
+		det.Status = constants.MemberActive
+	}
+	if det.JoinedAt == 0 {
+		det.JoinedAt = time.Now().Unix()
+	}
+	query = `INSERT
+		INTO members
+		(id, name, email, card_number, tier, status, joined_at)
+		VALUES (?1, ?2, NULLIF(?3, ''), ?4, ?5, ?6, ?7)
+	`
+	_, err = tx.ExecContext(ctx, query, det.ID, det.Name, det.Email, det.CardNumber, det.Tier, det.Status, det.JoinedAt)
+	if err != nil {
+		logger.FromContext(ctx).Errorf("failed to insert member %s. Error: %v", det.Name, err)
+		if isUniqueViolation(err) {
+			return 0, fmt.Errorf("member with email '%s' or card number '%s' already presents. %w", det.Email, det.CardNumber, model.ErrAlreadyExists)
+		}
+		return 0, err
+	}
+	if err = tx.Commit(); err != nil {
+		logger.FromContext(ctx).Errorf("failed to commit transaction of adding member. Error: %v", err)
+		return 0, err
+	}
+	return det.ID, nil
+}
+
+// GetMember retreves a member by its ID
+func (s *SQLiteDB) GetMember(ctx context.Context, memberID int) (*model.Member, error) {
+	query := fmt.Sprintf(`SELECT %s FROM members m WHERE m.id=?1`, memberColumns)
+	member, err := scanMember(s.DB.QueryRowContext(ctx, query, memberID))
+	if err != nil {
+		logger.FromContext(ctx).Errorf("Failed to scan the requested member: %d. Error: %v", memberID, err)
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("failed to find member: %d. %w", memberID, model.ErrNotFound)
+		}
+		return nil, fmt.Errorf("failed to find member: %d", memberID)
+	}
+	return member, nil
+}
+
+// GetAllMembers retreves a page of the members passing the filters of the query
+func (s *SQLiteDB) GetAllMembers(ctx context.Context, query *model.MemberQuery) (*model.MemberPage, error) {
+	var f filter
+	if query.Name != "" {
+		f.add("instr(LOWER(m.name), LOWER(?%d)) > 0", query.Name)
+	}
+	if query.Tier != "" {
+		f.add("m.tier=?%d", query.Tier)
+	}
+	if query.Status != "" {
+		f.add("m.status=?%d", query.Status)
+	}
+	key, ok := memberSortKeys[query.SortBy]
+	if !ok {
+		key = memberSortKeys[constants.SortByID]
+	}
+	orderBy := paginate(&f, key, "m.id", &query.PageQuery)
+	sqlQuery := fmt.Sprintf(`SELECT
+		%s
+		FROM members m
+		%s
+		%s
+	`, memberColumns, f.where(), orderBy)
+	rows, err := s.DB.QueryContext(ctx, sqlQuery, f.args...)
+	if err != nil {
+		logger.FromContext(ctx).Errorf("Failed to fetch members. Error: %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+	members := make([]*model.Member, 0)
+	for rows.Next() {
+		member, err := scanMember(rows)
+		if err != nil {
+			logger.FromContext(ctx).Errorf("Failed to scan member fetched from DB. Error: %v", err)
+			continue
+		}
+		members = append(members, member)
+	}
+	page := &model.MemberPage{Members: members}
+	if limit := pageLimit(&query.PageQuery); len(members) > limit {
+		page.Members = members[:limit]
+		last := members[limit-1]
+		cursor := &model.Cursor{SortBy: query.SortBy, Desc: query.Order == constants.OrderDesc, ID: last.ID}
+		if query.SortBy == constants.SortByName {
+			cursor.Text = last.Name
+		}
+		page.NextCursor = cursor.Encode()
+	}
+	return page, nil
+}
+
+// UpdateMember updates the non empty name, email, card number, tier and status of a member
+func (s *SQLiteDB) UpdateMember(ctx context.Context, memberID int, det *model.Member) (*model.Member, error) {
+	// RETURNING takes no table alias
+	query := `UPDATE
+		members SET
+		name=COALESCE(NULLIF(?1, ''), name),
+		email=COALESCE(NULLIF(?2, ''), email),
+		card_number=COALESCE(NULLIF(?3, ''), card_number),
+		tier=COALESCE(NULLIF(?4, ''), tier),
+		status=COALESCE(NULLIF(?5, ''), status)
+		WHERE id=?6
+		RETURNING id, name, COALESCE(email, ''), card_number, tier, status, joined_at
+	`
+	member, err := scanMember(s.DB.QueryRowContext(ctx, query, det.Name, det.Email, det.CardNumber, det.Tier, det.Status, memberID))
+	if err != nil {
+		logger.FromContext(ctx).Errorf("failed to update member: %d. Error: %v", memberID, err)
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("failed to find member: %d. %w", memberID, model.ErrNotFound)
+		}
+		if isUniqueViolation(err) {
+			return nil, fmt.Errorf("member with email '%s' or card number '%s' already presents. %w", det.Email, det.CardNumber, model.ErrAlreadyExists)
+		}
+		return nil, err
+	}
+	return member, nil
+}
+
+// DeleteMember removes a member cancelling its holds, refused while the member has active loans or owes fines
+func (s *SQLiteDB) DeleteMember(ctx context.Context, memberID int) error {
+	tx, err := s.DB.BeginTx(ctx, nil)
+	if err != nil {
+		logger.FromContext(ctx).Errorf("failed to begin transaction. Error: %v", err)
+		return err
+	}
+	defer tx.Rollback()
+	var id int
+	if err = tx.QueryRowContext(ctx, `SELECT id FROM members WHERE id=?1`, memberID).Scan(&id); err != nil {
+		logger.FromContext(ctx).Errorf("failed to find member: %d. Error: %v", memberID, err)
+		if errors.Is(err, sql.ErrNoRows) {
+			return fmt.Errorf("failed to find member: %d. %w", memberID, model.ErrNotFound)
+		}
+		return err
+	}
+	var activeLoans int
+	query := `SELECT COUNT(*) FROM loans WHERE member_id=?1 AND status<>?2`
+	if err = tx.QueryRowContext(ctx, query, memberID, constants.Closed).Scan(&activeLoans); err != nil {
+		logger.FromContext(ctx).Errorf("failed to count active loans of member: %d. Error: %v", memberID, err)
+		return err
+	}
+	if activeLoans > 0 {
+		return fmt.Errorf("member %d has %d active loans. %w", memberID, activeLoans, model.ErrConflict)
+	}
+	owed, err := memberOwes(ctx, tx, memberID)
+	if err != nil {
+		return err
+	}
+	if owed > 0 {
+		return fmt.Errorf("member %d owes %d cents of fines. %w", memberID, owed, model.ErrConflict)
+	}
+	// cancelling the holds passes the copies set aside on before they get deleted along with the member
+	query = `SELECT id FROM holds WHERE member_id=?1 AND status IN (?2, ?3)`
+	holdIDs, err := queryIDs(ctx, tx, query, memberID, constants.HoldWaiting, constants.HoldReady)
+	if err != nil {
+		logger.FromContext(ctx).Errorf("failed to fetch holds of member: %d. Error: %v", memberID, err)
+		return err
+	}
+	for _, holdID := range holdIDs {
+		if err = closeHold(ctx, tx, holdID, constants.HoldCancelled, time.Now()); err != nil {
+			return err
+		}
+	}
+	if _, err = tx.ExecContext(ctx, `DELETE FROM members WHERE id=?1`, memberID); err != nil {
+		logger.FromContext(ctx).Errorf("failed to delete member: %d. Error: %v", memberID, err)
+		return err
+	}
+	if err = tx.Commit(); err != nil {
+		logger.FromContext(ctx).Errorf("failed to commit transaction of deleting member. Error: %v", err)
+		return err
+	}
+	return nil
+}
+
+// memberStanding gathers the active loans, holds and unpaid fines of the borrowing member,
+// the immediate transaction keeps the member from changing meanwhile
+func memberStanding(ctx context.Context, tx *sql.Tx, memberID int) (*policy.Standing, error) {
+	query := fmt.Sprintf(`SELECT %s FROM members m WHERE m.id=?1`, memberColumns)
+	member, err := scanMember(tx.QueryRowContext(ctx, query, memberID))
+	if err != nil {
+		logger.FromContext(ctx).Errorf("failed to find member %d to loan. Error: %v", memberID, err)
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("failed to find member: %d. %w", memberID, model.ErrNotFound)
+		}
+		return nil, err
+	}
+	standing := &policy.Standing{Member: member}
+	query = `SELECT id, COALESCE(book_id, 0), title, return_date FROM loans WHERE member_id=?1 AND status<>?2`
+	rows, err := tx.QueryContext(ctx, query, memberID, constants.Closed)
+	if err != nil {
+		logger.FromContext(ctx).Errorf("failed to fetch active loans of member %d. Error: %v", memberID, err)
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var loan model.LoanDetails
+		if err := rows.Scan(&loan.ID, &loan.BookID, &loan.Title, &loan.ReturnDate); err != nil {
+			return nil, err
+		}
+		standing.ActiveLoans = append(standing.ActiveLoans, &loan)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	query = `SELECT id, book_id FROM holds WHERE member_id=?1 AND status IN (?2, ?3)`
+	rows, err = tx.QueryContext(ctx, query, memberID, constants.HoldWaiting, constants.HoldReady)
+	if err != nil {
+		logger.FromContext(ctx).Errorf("failed to fetch active holds of member %d. Error: %v", memberID, err)
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var hold model.Hold
+		if err := rows.Scan(&hold.ID, &hold.BookID); err != nil {
+			return nil, err
+		}
+		standing.ActiveHolds = append(standing.ActiveHolds, &hold)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	if standing.UnpaidFines, err = memberOwes(ctx, tx, memberID); err != nil {
+		return nil, err
+	}
+	return standing, nil
+}
@@ -0,0 +1,50 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	_ "embed"
+	"net/url"
+
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/test/library-app/internal/config"
+	"github.com/test/library-app/internal/logger"
+)
+
+// schema creates the tables, indexes and triggers missed by the database
+//
+//go:embed schema.sql
+var schema string
+
+// InitSQLiteStore opens the database file, creating it along with its schema when missed
+func InitSQLiteStore() (*SQLiteDB, error) {
+	// transactions take the write lock up front so the writes are serialized without deadlocking,
+	// the write-ahead journal keeps the readers going meanwhile
+	query := url.Values{}
+	query.Add("_txlock", "immediate")
+	query.Add("_journal_mode", "WAL")
+	query.Add("_foreign_keys", "on")
+	query.Add("_busy_timeout", "5000")
+	dsn := "file:" + config.SQLiteConfig.SQLitePath + "?" + query.Encode()
+	logger.Infof("Opening sqlite: %s", dsn)
+	db, err := sql.Open("sqlite3", dsn)
+	if err != nil {
+		logger.Errorf("Failed to open sqlite. Error: %v", err)
+		return nil, err
+	}
+	ctx := context.Background()
+	if err = db.PingContext(ctx); err != nil {
+		logger.Errorf("Failed to ping to opened sqlite. Error: %v", err)
+		db.Close()
+		return nil, err
+	}
+	if _, err = db.ExecContext(ctx, schema); err != nil {
+		logger.Errorf("Failed to create the sqlite schema. Error: %v", err)
+		db.Close()
+		return nil, err
+	}
+	logger.Infof("Opened sqlite successfully")
+	return &SQLiteDB{
+		DB: db,
+	}, nil
+}
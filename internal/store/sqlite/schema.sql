@@ -0,0 +1,165 @@
+-- the schema is created on start, IF NOT EXISTS keeps it as it is on later starts.
+-- Dates are unix epochs and lists are JSON arrays
+CREATE TABLE IF NOT EXISTS books (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	isbn TEXT UNIQUE,
+	title TEXT NOT NULL,
+	authors TEXT NOT NULL DEFAULT '[]',
+	publisher TEXT NOT NULL DEFAULT '',
+	publication_year INTEGER NOT NULL DEFAULT 0,
+	language TEXT NOT NULL DEFAULT '',
+	subjects TEXT NOT NULL DEFAULT '[]',
+	edition TEXT NOT NULL DEFAULT '',
+	description TEXT NOT NULL DEFAULT '',
+	category TEXT NOT NULL DEFAULT '',
+	version INTEGER NOT NULL DEFAULT 1
+);
+
+CREATE INDEX IF NOT EXISTS books_lower_title_idx ON books (LOWER(title));
+
+-- full text index of the books, the docid is the book id. Porter stemming matches the word forms
+CREATE VIRTUAL TABLE IF NOT EXISTS books_fts USING fts4 (title, authors, subjects, description, tokenize=porter);
+
+CREATE TRIGGER IF NOT EXISTS books_fts_insert AFTER INSERT ON books BEGIN
+	INSERT INTO books_fts (docid, title, authors, subjects, description) VALUES (
+		new.id,
+		new.title,
+		(SELECT COALESCE(group_concat(value, ', '), '') FROM json_each(new.authors)),
+		(SELECT COALESCE(group_concat(value, ', '), '') FROM json_each(new.subjects)),
+		new.description
+	);
+END;
+
+-- bumping the version alone leaves the index as it is
+CREATE TRIGGER IF NOT EXISTS books_fts_update AFTER UPDATE OF title, authors, subjects, description ON books BEGIN
+	DELETE FROM books_fts WHERE docid = old.id;
+	INSERT INTO books_fts (docid, title, authors, subjects, description) VALUES (
+		new.id,
+		new.title,
+		(SELECT COALESCE(group_concat(value, ', '), '') FROM json_each(new.authors)),
+		(SELECT COALESCE(group_concat(value, ', '), '') FROM json_each(new.subjects)),
+		new.description
+	);
+END;
+
+CREATE TRIGGER IF NOT EXISTS books_fts_delete AFTER DELETE ON books BEGIN
+	DELETE FROM books_fts WHERE docid = old.id;
+END;
+
+CREATE TABLE IF NOT EXISTS book_copies (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	book_id INTEGER NOT NULL REFERENCES books(id) ON DELETE CASCADE,
+	barcode TEXT NOT NULL UNIQUE,
+	shelf_location TEXT NOT NULL DEFAULT '',
+	condition TEXT NOT NULL DEFAULT 'good',
+	acquisition_date INTEGER NOT NULL,
+	status TEXT NOT NULL DEFAULT 'available'
+);
+
+CREATE INDEX IF NOT EXISTS book_copies_book_id_status_idx ON book_copies (book_id, status);
+
+CREATE TABLE IF NOT EXISTS members (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	name TEXT NOT NULL,
+	email TEXT,
+	card_number TEXT NOT NULL UNIQUE,
+	tier TEXT NOT NULL DEFAULT 'standard',
+	status TEXT NOT NULL DEFAULT 'active',
+	joined_at INTEGER NOT NULL
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS members_lower_email_idx ON members (LOWER(email));
+CREATE INDEX IF NOT EXISTS members_lower_name_idx ON members (LOWER(name));
+
+-- loans keep the title, barcode and name of the member when borrowed as history
+CREATE TABLE IF NOT EXISTS loans (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	book_id INTEGER REFERENCES books(id) ON DELETE SET NULL,
+	copy_id INTEGER REFERENCES book_copies(id) ON DELETE SET NULL,
+	barcode TEXT NOT NULL DEFAULT '',
+	title TEXT NOT NULL,
+	member_id INTEGER REFERENCES members(id) ON DELETE SET NULL,
+	name_of_borrower TEXT NOT NULL,
+	loan_date INTEGER NOT NULL,
+	return_date INTEGER NOT NULL,
+	status TEXT NOT NULL,
+	extensions INTEGER NOT NULL DEFAULT 0,
+	version INTEGER NOT NULL DEFAULT 1
+);
+
+CREATE INDEX IF NOT EXISTS loans_member_id_idx ON loans (member_id);
+-- the overdue job looks up the loans past their return date
+CREATE INDEX IF NOT EXISTS loans_open_return_date_idx ON loans (return_date) WHERE status <> 'closed';
+
+CREATE TABLE IF NOT EXISTS holds (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	member_id INTEGER NOT NULL REFERENCES members(id) ON DELETE CASCADE,
+	book_id INTEGER NOT NULL REFERENCES books(id) ON DELETE CASCADE,
+	copy_id INTEGER REFERENCES book_copies(id) ON DELETE SET NULL,
+	status TEXT NOT NULL DEFAULT 'waiting',
+	placed_at INTEGER NOT NULL,
+	ready_at INTEGER,
+	expires_at INTEGER
+);
+
+-- a member holds a book once at a time
+CREATE UNIQUE INDEX IF NOT EXISTS holds_active_member_book_idx ON holds (member_id, book_id) WHERE status IN ('waiting', 'ready');
+-- the queue of a book
+CREATE INDEX IF NOT EXISTS holds_waiting_book_idx ON holds (book_id, placed_at, id) WHERE status = 'waiting';
+CREATE INDEX IF NOT EXISTS holds_ready_expires_at_idx ON holds (expires_at) WHERE status = 'ready';
+
+CREATE TABLE IF NOT EXISTS fines (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	loan_id INTEGER NOT NULL UNIQUE REFERENCES loans(id) ON DELETE CASCADE,
+	amount_in_cents INTEGER NOT NULL DEFAULT 0,
+	paid_in_cents INTEGER NOT NULL DEFAULT 0,
+	status TEXT NOT NULL DEFAULT 'accruing',
+	accrued_at INTEGER NOT NULL,
+	settled_at INTEGER
+);
+
+CREATE TABLE IF NOT EXISTS loan_events (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	loan_id INTEGER NOT NULL REFERENCES loans(id),
+	type TEXT NOT NULL,
+	actor TEXT NOT NULL,
+	at INTEGER NOT NULL,
+	old_state TEXT,
+	new_state TEXT NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS loan_events_loan_id_idx ON loan_events (loan_id, id);
+
+-- loan events are append only
+CREATE TRIGGER IF NOT EXISTS loan_events_no_update BEFORE UPDATE ON loan_events BEGIN
+	SELECT RAISE(ABORT, 'loan events are append only');
+END;
+
+CREATE TRIGGER IF NOT EXISTS loan_events_no_delete BEFORE DELETE ON loan_events BEGIN
+	SELECT RAISE(ABORT, 'loan events are append only');
+END;
+
+-- requests made with an Idempotency-Key, status_code is 0 while the request is in progress
+CREATE TABLE IF NOT EXISTS idempotency_keys (
+	key TEXT PRIMARY KEY,
+	fingerprint TEXT NOT NULL,
+	status_code INTEGER NOT NULL DEFAULT 0,
+	header TEXT NOT NULL DEFAULT '{}',
+	body BLOB,
+	expires_at INTEGER NOT NULL
+);
+
+-- the expiry job drops the keys past their expiry
+CREATE INDEX IF NOT EXISTS idempotency_keys_expires_at_idx ON idempotency_keys (expires_at);
+
+-- keys of the integrating systems, only the SHA-256 of a key is stored. revoked_at is 0 while usable
+CREATE TABLE IF NOT EXISTS api_keys (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	name TEXT NOT NULL UNIQUE,
+	prefix TEXT NOT NULL,
+	hash TEXT NOT NULL UNIQUE,
+	scopes TEXT NOT NULL DEFAULT '[]',
+	requests_per_minute INTEGER NOT NULL DEFAULT 0,
+	created_at INTEGER NOT NULL,
+	revoked_at INTEGER NOT NULL DEFAULT 0
+);
@@ -0,0 +1,121 @@
+package sqlite
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/test/library-app/internal/constants"
+	"github.com/test/library-app/internal/logger"
+	"github.com/test/library-app/internal/model"
+)
+
+// searchFields are the columns of the books_fts table in order, a match in the title ranks over the one in the description
+var searchFields = []struct {
+	name   string
+	weight float64
+}{
+	{constants.SearchFieldTitle, 1.0},
+	{constants.SearchFieldAuthors, 0.4},
+	{constants.SearchFieldSubjects, 0.2},
+	{constants.SearchFieldDescription, 0.1},
+}
+
+// stopWords are too common to be searched for
+var stopWords = map[string]bool{
+	"a": true, "an": true, "and": true, "are": true, "as": true, "at": true, "be": true, "by": true,
+	"for": true, "from": true, "in": true, "is": true, "it": true, "of": true, "on": true, "or": true,
+	"the": true, "to": true, "with": true,
+}
+
+// matchQuery turns the query into a full text query matching all of its words,
+// quoting each so the query syntax of the words is taken literally
+func matchQuery(query string) string {
+	words := strings.FieldsFunc(strings.ToLower(query), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	terms := make([]string, 0, len(words))
+	for _, word := range words {
+		if !stopWords[word] {
+			terms = append(terms, `"`+word+`"`)
+		}
+	}
+	return strings.Join(terms, " ")
+}
+
+// matchScore weighs the hits of the phrases in each column given by matchinfo with the 'x' format
+func matchScore(info []byte) float64 {
+	var score float64
+	// three 32 bit integers per phrase and column, the hits in the row comes first
+	for i := 0; i+12 <= len(info); i += 12 {
+		col := (i / 12) % len(searchFields)
+		hits := float64(binary.NativeEndian.Uint32(info[i:]))
+		score += searchFields[col].weight * hits / (hits + 1)
+	}
+	return score
+}
+
+// SearchBooks retreves the books best matching the query across title, authors, subjects and description,
+// ranked by the weighted hits in the full text index kept up to date by triggers on the books table
+func (s *SQLiteDB) SearchBooks(ctx context.Context, query string, limit int) ([]*model.BookSearchResult, error) {
+	match := matchQuery(query)
+	results := make([]*model.BookSearchResult, 0)
+	if match == "" {
+		return results, nil
+	}
+	snippets := make([]string, len(searchFields))
+	for i := range searchFields {
+		snippets[i] = fmt.Sprintf("snippet(books_fts, '%s', '%s', '...', %d, 64)", constants.HighlightStart, constants.HighlightStop, i)
+	}
+	sqlQuery := fmt.Sprintf(`SELECT
+		%s,
+		matchinfo(books_fts, 'x'),
+		%s
+		FROM books_fts
+		JOIN books b ON b.id=books_fts.docid
+		WHERE books_fts MATCH ?1
+	`, bookColumns, strings.Join(snippets, ",\n\t\t"))
+	rows, err := s.DB.QueryContext(ctx, sqlQuery, match)
+	if err != nil {
+		logger.FromContext(ctx).Errorf("Failed to search books with query: %s. Error: %v", query, err)
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var info []byte
+		highlights := make([]string, len(searchFields))
+		extra := []any{&info}
+		for i := range highlights {
+			extra = append(extra, &highlights[i])
+		}
+		book, err := scanBook(rows, extra...)
+		if err != nil {
+			logger.FromContext(ctx).Errorf("Failed to scan search result fetched from DB. Error: %v", err)
+			continue
+		}
+		result := &model.BookSearchResult{Book: book, Score: matchScore(info), Highlights: make(map[string]string)}
+		// fields without a match are left out
+		for i, text := range highlights {
+			if strings.Contains(text, constants.HighlightStart) {
+				result.Highlights[searchFields[i].name] = text
+			}
+		}
+		results = append(results, result)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	sort.SliceStable(results, func(i, j int) bool {
+		if results[i].Score != results[j].Score {
+			return results[i].Score > results[j].Score
+		}
+		return results[i].Book.ID < results[j].Book.ID
+	})
+	if len(results) > limit {
+		results = results[:limit]
+	}
+	return results, nil
+}
@@ -0,0 +1,559 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/test/library-app/internal/constants"
+	"github.com/test/library-app/internal/logger"
+	"github.com/test/library-app/internal/model"
+	"github.com/test/library-app/internal/policy"
+)
+
+type SQLiteDB struct {
+	DB *sql.DB
+}
+
+// scanner is a row of *sql.Row or *sql.Rows
+type scanner interface {
+	Scan(dest ...any) error
+}
+
+// queryIDs fetches the ids selected by the query within the transaction, closing the rows before the ids get changed
+func queryIDs(ctx context.Context, tx *sql.Tx, query string, args ...any) ([]int, error) {
+	rows, err := tx.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	ids := make([]int, 0)
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+// stringList stores a list of strings as a JSON array
+type stringList []string
+
+func (s stringList) Value() (driver.Value, error) {
+	if s == nil {
+		return "[]", nil
+	}
+	data, err := json.Marshal([]string(s))
+	return string(data), err
+}
+
+func (s *stringList) Scan(src any) error {
+	switch val := src.(type) {
+	case string:
+		return json.Unmarshal([]byte(val), (*[]string)(s))
+	case []byte:
+		return json.Unmarshal(val, (*[]string)(s))
+	default:
+		return fmt.Errorf("can't scan %T into a list of strings", src)
+	}
+}
+
+// bookColumns lists the columns of the books table aliased as b in the order scanBook reads them,
+// copy counts are derived from the copies table
+var bookColumns = fmt.Sprintf(`b.id,
+		COALESCE(b.isbn, ''),
+		b.title,
+		b.authors,
+		b.publisher,
+		b.publication_year,
+		b.language,
+		b.subjects,
+		b.edition,
+		b.description,
+		b.category,
+		(SELECT COUNT(*) FROM book_copies c WHERE c.book_id=b.id AND c.status='%s'),
+		(SELECT COUNT(*) FROM book_copies c WHERE c.book_id=b.id AND c.status<>'%s'),
+		b.version`,
+	constants.CopyAvailable, constants.CopyWithdrawn)
+
+// scanBook scans a row selected with bookColumns, followed by the extra columns
+func scanBook(row scanner, extra ...any) (*model.BookDetails, error) {
+	var book model.BookDetails
+	dest := []any{
+		&book.ID,
+		&book.ISBN,
+		&book.Title,
+		(*stringList)(&book.Authors),
+		&book.Publisher,
+		&book.PublicationYear,
+		&book.Language,
+		(*stringList)(&book.Subjects),
+		&book.Edition,
+		&book.Description,
+		&book.Category,
+		&book.AvailableCopies,
+		&book.TotalCopies,
+		&book.Version,
+	}
+	if err := row.Scan(append(dest, extra...)...); err != nil {
+		return nil, err
+	}
+	return &book, nil
+}
+
+// GetBookDetails retreves the oldest book with the title from store
+func (s *SQLiteDB) GetBookDetails(ctx context.Context, title string) (*model.BookDetails, error) {
+	query := fmt.Sprintf(`SELECT
+		%s
+		FROM books b
+		WHERE LOWER(b.title)=LOWER(?1)
+		ORDER BY b.id
+		LIMIT 1
+	`, bookColumns)
+	book, err := scanBook(s.DB.QueryRowContext(ctx, query, title))
+	if err != nil {
+		logger.FromContext(ctx).Errorf("Failed to scan the requested title: %s. Error: %v", title, err)
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("failed to find the title: %s. %w", title, model.ErrNotFound)
+		}
+		return nil, fmt.Errorf("failed to find the title: %s", title)
+	}
+	return book, nil
+}
+
+// GetBookDetailsByID retreves book details by its ID from store
+func (s *SQLiteDB) GetBookDetailsByID(ctx context.Context, bookID int) (*model.BookDetails, error) {
+	query := fmt.Sprintf(`SELECT %s FROM books b WHERE b.id=?1`, bookColumns)
+	book, err := scanBook(s.DB.QueryRowContext(ctx, query, bookID))
+	if err != nil {
+		logger.FromContext(ctx).Errorf("Failed to scan the requested book: %d. Error: %v", bookID, err)
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("failed to find book: %d. %w", bookID, model.ErrNotFound)
+		}
+		return nil, fmt.Errorf("failed to find book: %d", bookID)
+	}
+	return book, nil
+}
+
+// AddBook adds a new book with TotalCopies available copies to the catalog
+func (s *SQLiteDB) AddBook(ctx context.Context, det *model.BookDetails) (int, error) {
+	tx, err := s.DB.BeginTx(ctx, nil)
+	if err != nil {
+		logger.FromContext(ctx).Errorf("failed to begin transaction. Error: %v", err)
+		return 0, err
+	}
+	defer tx.Rollback()
+	query := `INSERT
+		INTO books
+		(isbn, title, authors, publisher, publication_year, language, subjects, edition, description, category)
+		VALUES (NULLIF(?1, ''), ?2, ?3, ?4, ?5, ?6, ?7, ?8, ?9, ?10)
+		RETURNING id
+	`
+	err = tx.QueryRowContext(ctx, query,
+		det.ISBN, det.Title, stringList(det.Authors), det.Publisher, det.PublicationYear,
+		det.Language, stringList(det.Subjects), det.Edition, det.Description, det.Category,
+	).Scan(&det.ID)
+	if err != nil {
+		logger.FromContext(ctx).Errorf("failed to insert into books. Error: %v", err)
+		if isUniqueViolation(err) {
+			return 0, fmt.Errorf("book with isbn '%s' already presents. %w", det.ISBN, model.ErrAlreadyExists)
+		}
+		return 0, err
+	}
+	copies := make([]*model.BookCopy, det.TotalCopies)
+	for i := range copies {
+		copies[i] = &model.BookCopy{BookID: det.ID}
+	}
+	if err = addCopies(ctx, tx, copies); err != nil {
+		return 0, err
+	}
+	if err = tx.Commit(); err != nil {
+		logger.FromContext(ctx).Errorf("failed to commit transaction of adding book. Error: %v", err)
+		return 0, err
+	}
+	det.AvailableCopies = det.TotalCopies
+	det.Version = 1
+	return det.ID, nil
+}
+
+// UpdateBook replaces the details of a book based on its version, any version when 0
+func (s *SQLiteDB) UpdateBook(ctx context.Context, bookID int, version int, det *model.BookDetails) (*model.BookDetails, error) {
+	query := `UPDATE
+		books SET isbn=NULLIF(?1, ''), title=?2, authors=?3, publisher=?4, publication_year=?5,
+		language=?6, subjects=?7, edition=?8, description=?9, category=?10, version=version + 1
+		WHERE id=?11 AND (?12=0 OR version=?12)
+	`
+	res, err := s.DB.ExecContext(ctx, query,
+		det.ISBN, det.Title, stringList(det.Authors), det.Publisher, det.PublicationYear,
+		det.Language, stringList(det.Subjects), det.Edition, det.Description, det.Category,
+		bookID, version,
+	)
+	if err != nil {
+		logger.FromContext(ctx).Errorf("failed to update book: %d. Error: %v", bookID, err)
+		if isUniqueViolation(err) {
+			return nil, fmt.Errorf("book with isbn '%s' already presents. %w", det.ISBN, model.ErrAlreadyExists)
+		}
+		return nil, err
+	}
+	if affected, _ := res.RowsAffected(); affected == 0 {
+		// either missed or moved on to another version
+		book, err := s.GetBookDetailsByID(ctx, bookID)
+		if err != nil {
+			return nil, err
+		}
+		logger.FromContext(ctx).Errorf("requested book: %d is at version %d, not %d", bookID, book.Version, version)
+		return nil, fmt.Errorf("book %d is at version %d, not %d. %w", bookID, book.Version, version, model.ErrPreconditionFailed)
+	}
+	// copy counts are derived from the copies, fetching them along with the updated details
+	return s.GetBookDetailsByID(ctx, bookID)
+}
+
+// UpdateBookCopies adds copies with generated barcodes or withdraws available copies of a book based on its version, any version when 0
+func (s *SQLiteDB) UpdateBookCopies(ctx context.Context, bookID int, version int, delta int) (*model.BookDetails, error) {
+	tx, err := s.DB.BeginTx(ctx, nil)
+	if err != nil {
+		logger.FromContext(ctx).Errorf("failed to begin transaction. Error: %v", err)
+		return nil, err
+	}
+	defer tx.Rollback()
+	if err = findBook(ctx, tx, bookID); err != nil {
+		return nil, err
+	}
+	res, err := tx.ExecContext(ctx, `UPDATE books SET version=version + 1 WHERE id=?1 AND (?2=0 OR version=?2)`, bookID, version)
+	if err != nil {
+		logger.FromContext(ctx).Errorf("failed to bump version of book: %d. Error: %v", bookID, err)
+		return nil, err
+	}
+	if affected, _ := res.RowsAffected(); affected == 0 {
+		logger.FromContext(ctx).Errorf("requested book: %d isn't at version %d", bookID, version)
+		return nil, fmt.Errorf("book %d isn't at version %d. %w", bookID, version, model.ErrPreconditionFailed)
+	}
+	if delta > 0 {
+		copies := make([]*model.BookCopy, delta)
+		for i := range copies {
+			copies[i] = &model.BookCopy{BookID: bookID}
+		}
+		if err = addCopies(ctx, tx, copies); err != nil {
+			return nil, err
+		}
+	}
+	if delta < 0 {
+		query := `UPDATE
+			book_copies SET status=?1
+			WHERE id IN (
+				SELECT id FROM book_copies WHERE book_id=?2 AND status=?3 ORDER BY id LIMIT ?4
+			)
+		`
+		res, err := tx.ExecContext(ctx, query, constants.CopyWithdrawn, bookID, constants.CopyAvailable, -delta)
+		if err != nil {
+			logger.FromContext(ctx).Errorf("failed to withdraw copies of book: %d. Error: %v", bookID, err)
+			return nil, err
+		}
+		if affected, _ := res.RowsAffected(); affected < int64(-delta) {
+			logger.FromContext(ctx).Errorf("not enough copies of book: %d to withdraw", bookID)
+			return nil, fmt.Errorf("not enough copies of book: %d to withdraw. %w", bookID, model.ErrConflict)
+		}
+	}
+	if err = tx.Commit(); err != nil {
+		logger.FromContext(ctx).Errorf("failed to commit transaction of updating book copies. Error: %v", err)
+		return nil, err
+	}
+	return s.GetBookDetailsByID(ctx, bookID)
+}
+
+// DeleteBook removes a book from the catalog, refused while it has active loans
+func (s *SQLiteDB) DeleteBook(ctx context.Context, bookID int) error {
+	tx, err := s.DB.BeginTx(ctx, nil)
+	if err != nil {
+		logger.FromContext(ctx).Errorf("failed to begin transaction. Error: %v", err)
+		return err
+	}
+	defer tx.Rollback()
+	if err = findBook(ctx, tx, bookID); err != nil {
+		return err
+	}
+	var activeLoans int
+	query := `SELECT COUNT(*) FROM loans WHERE book_id=?1 AND status<>?2`
+	if err = tx.QueryRowContext(ctx, query, bookID, constants.Closed).Scan(&activeLoans); err != nil {
+		logger.FromContext(ctx).Errorf("failed to count active loans of book: %d. Error: %v", bookID, err)
+		return err
+	}
+	if activeLoans > 0 {
+		logger.FromContext(ctx).Errorf("book: %d has %d active loans", bookID, activeLoans)
+		return fmt.Errorf("book %d has active loans. %w", bookID, model.ErrConflict)
+	}
+	// copies are deleted along with the book, closed loans keep their title and barcode
+	// while book_id and copy_id are set to null by the foreign keys
+	if _, err = tx.ExecContext(ctx, `DELETE FROM books WHERE id=?1`, bookID); err != nil {
+		logger.FromContext(ctx).Errorf("failed to delete book: %d. Error: %v", bookID, err)
+		return err
+	}
+	if err = tx.Commit(); err != nil {
+		logger.FromContext(ctx).Errorf("failed to commit transaction of deleting book. Error: %v", err)
+		return err
+	}
+	return nil
+}
+
+// bookForLoan resolves the loaned book by the copy barcode, its ID, or by its title when both are absent
+func (s *SQLiteDB) bookForLoan(ctx context.Context, det *model.LoanDetails) (*model.BookDetails, error) {
+	if det.Barcode != "" {
+		bookCopy, err := s.GetBookCopy(ctx, det.Barcode)
+		if err != nil {
+			return nil, err
+		}
+		det.BookID = bookCopy.BookID
+	}
+	if det.BookID != 0 {
+		return s.GetBookDetailsByID(ctx, det.BookID)
+	}
+	// fetching two rows is enough to find out whether the title is ambiguous
+	query := fmt.Sprintf(`SELECT %s FROM books b WHERE LOWER(b.title)=LOWER(?1) LIMIT 2`, bookColumns)
+	rows, err := s.DB.QueryContext(ctx, query, det.Title)
+	if err != nil {
+		logger.FromContext(ctx).Errorf("failed to fetch requested title from books table. Error: %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+	books := make([]*model.BookDetails, 0, 2)
+	for rows.Next() {
+		book, err := scanBook(rows)
+		if err != nil {
+			logger.FromContext(ctx).Errorf("failed to scan requested title from books table. Error: %v", err)
+			return nil, err
+		}
+		books = append(books, book)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	switch len(books) {
+	case 0:
+		return nil, fmt.Errorf("failed to find the title: %s. %w", det.Title, model.ErrNotFound)
+	case 1:
+		return books[0], nil
+	default:
+		return nil, fmt.Errorf("books share the title '%s', book_id is required. %w", det.Title, model.ErrConflict)
+	}
+}
+
+// AddLoan adds the loan details to store
+func (s *SQLiteDB) AddLoan(ctx context.Context, det *model.LoanDetails) (int, error) {
+	book, err := s.bookForLoan(ctx, det)
+	if err != nil {
+		return 0, err
+	}
+	det.BookID = book.ID
+	det.Title = book.Title
+
+	tx, err := s.DB.BeginTx(ctx, nil)
+	if err != nil {
+		logger.FromContext(ctx).Errorf("failed to begin transaction. Error: %v", err)
+		return 0, err
+	}
+	defer tx.Rollback()
+	standing, err := memberStanding(ctx, tx, det.MemberID)
+	if err != nil {
+		return 0, err
+	}
+	det.NameOfBorrower = standing.Member.Name
+	if err = policy.CheckLoan(standing, det, time.Now()); err != nil {
+		return 0, err
+	}
+	if det.LoanDate == 0 {
+		det.LoanDate = time.Now().Unix()
+	}
+	if det.ReturnDate == 0 {
+		det.ReturnDate = time.Unix(det.LoanDate, 0).Add(policy.Terms(standing.Member.Tier, book.Category).Period).Unix()
+	}
+	// the copy set aside for the member comes first
+	held, err := takeReadyHold(ctx, tx, det)
+	if err != nil {
+		return 0, err
+	}
+	var copyStatus string
+	switch {
+	case held:
+	case det.Barcode != "":
+		query := `SELECT id, status FROM book_copies WHERE barcode=?1`
+		err = tx.QueryRowContext(ctx, query, det.Barcode).Scan(&det.CopyID, &copyStatus)
+		if err != nil {
+			logger.FromContext(ctx).Errorf("failed to find copy %s to loan. Error: %v", det.Barcode, err)
+			if errors.Is(err, sql.ErrNoRows) {
+				return 0, fmt.Errorf("failed to find copy: %s. %w", det.Barcode, model.ErrNotFound)
+			}
+			return 0, err
+		}
+		if copyStatus != constants.CopyAvailable {
+			logger.FromContext(ctx).Errorf("requested copy %s is %s", det.Barcode, copyStatus)
+			return 0, fmt.Errorf("copy with barcode '%s' is %s. %w", det.Barcode, copyStatus, model.ErrConflict)
+		}
+	default:
+		query := `SELECT id, barcode FROM book_copies WHERE book_id=?1 AND status=?2 ORDER BY id LIMIT 1`
+		err = tx.QueryRowContext(ctx, query, det.BookID, constants.CopyAvailable).Scan(&det.CopyID, &det.Barcode)
+		if err != nil {
+			logger.FromContext(ctx).Errorf("failed to find a copy of title %v to loan. Error: %v", det.Title, err)
+			if errors.Is(err, sql.ErrNoRows) {
+				return 0, fmt.Errorf("not enough copies of requested title %v. %w", det.Title, model.ErrNotFound)
+			}
+			return 0, err
+		}
+	}
+	// taking the copy off the shelf
+	if _, err = tx.ExecContext(ctx, `UPDATE book_copies SET status=?1 WHERE id=?2`, constants.CopyOnLoan, det.CopyID); err != nil {
+		logger.FromContext(ctx).Errorf("failed to update status of copy %s. Error: %v", det.Barcode, err)
+		return 0, err
+	}
+	query := `INSERT
+		INTO loans
+		(book_id, copy_id, barcode, title, member_id, name_of_borrower, loan_date, return_date, status)
+		VALUES (?1, ?2, ?3, ?4, ?5, ?6, ?7, ?8, ?9)
+		RETURNING id
+	`
+	err = tx.QueryRowContext(ctx, query,
+		det.BookID, det.CopyID, det.Barcode, det.Title, det.MemberID, det.NameOfBorrower, det.LoanDate, det.ReturnDate, det.Status,
+	).Scan(&det.ID)
+	if err != nil {
+		logger.FromContext(ctx).Errorf("failed to insert into loan. Error: %v", err)
+		return 0, err
+	}
+	after := &model.LoanState{Status: det.Status, ReturnDate: det.ReturnDate}
+	if err = recordEvent(ctx, tx, det.ID, constants.LoanCreated, nil, after, time.Now()); err != nil {
+		return 0, err
+	}
+	if err = tx.Commit(); err != nil {
+		logger.FromContext(ctx).Errorf("failed to commit transaction. Error: %v", err)
+		return 0, err
+	}
+	det.Version = 1
+	return det.ID, nil
+}
+
+// ExtendLoan extends the return date by the extension of the loan terms, refused once overdue or extended the most times
+func (s *SQLiteDB) ExtendLoan(ctx context.Context, loanID int, version int) (*model.LoanDetails, error) {
+	tx, err := s.DB.BeginTx(ctx, nil)
+	if err != nil {
+		logger.FromContext(ctx).Errorf("Failed to begin transaction. Error: %v", err)
+		return nil, err
+	}
+	defer tx.Rollback()
+	// terms of deleted members and books fall back to the defaults
+	query := fmt.Sprintf(`SELECT
+		%s,
+		COALESCE(m.tier, ?2),
+		COALESCE(b.category, '')
+		FROM loans l
+		LEFT JOIN members m ON m.id=l.member_id
+		LEFT JOIN books b ON b.id=l.book_id
+		WHERE l.id=?1
+	`, loanColumns)
+	var tier, category string
+	loan, err := scanLoan(tx.QueryRowContext(ctx, query, loanID, constants.DefaultTier), &tier, &category)
+	if err != nil {
+		logger.FromContext(ctx).Errorf("failed to find a requested loan: %d to extend", loanID)
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("failed to find loan: %d. %w", loanID, model.ErrNotFound)
+		}
+		return nil, err
+	}
+	if loan.Status == constants.Closed {
+		logger.FromContext(ctx).Errorf("requested loan: %d already closed", loanID)
+		return nil, fmt.Errorf("requested loan: %d already closed", loanID)
+	}
+	if version != 0 && version != loan.Version {
+		logger.FromContext(ctx).Errorf("requested loan: %d is at version %d, not %d", loanID, loan.Version, version)
+		return nil, fmt.Errorf("loan %d is at version %d, not %d. %w", loanID, loan.Version, version, model.ErrPreconditionFailed)
+	}
+	terms := policy.Terms(tier, category)
+	if err = policy.CheckExtension(loan, terms); err != nil {
+		return nil, err
+	}
+	before := &model.LoanState{Status: loan.Status, ReturnDate: loan.ReturnDate, Extensions: loan.Extensions}
+	loan.ReturnDate = time.Unix(loan.ReturnDate, 0).Add(terms.Extension).Unix()
+	loan.Extensions++
+	loan.Version++
+	query = `UPDATE loans SET return_date=?1, extensions=?2, version=?3 WHERE id=?4`
+	if _, err = tx.ExecContext(ctx, query, loan.ReturnDate, loan.Extensions, loan.Version, loanID); err != nil {
+		logger.FromContext(ctx).Errorf("Failed to execute update query for extending loan. Error: %v", err)
+		return nil, err
+	}
+	after := &model.LoanState{Status: loan.Status, ReturnDate: loan.ReturnDate, Extensions: loan.Extensions}
+	if err = recordEvent(ctx, tx, loanID, constants.LoanExtended, before, after, time.Now()); err != nil {
+		return nil, err
+	}
+	if err = tx.Commit(); err != nil {
+		logger.FromContext(ctx).Errorf("Failed to commit transaction of extending loan. Error: %v", err)
+		return nil, err
+	}
+	return loan, nil
+}
+
+// ReturnBook closes the loan finalising its fine, the copy is set aside for the next waiting hold of the book or put on the shelf
+func (s *SQLiteDB) ReturnBook(ctx context.Context, loanID int, version int) (*model.LoanDetails, error) {
+	tx, err := s.DB.BeginTx(ctx, nil)
+	if err != nil {
+		logger.FromContext(ctx).Errorf("Failed to begin transaction. Error: %v", err)
+		return nil, err
+	}
+	defer tx.Rollback()
+	query := fmt.Sprintf(`SELECT %s FROM loans l WHERE l.id=?1`, loanColumns)
+	loan, err := scanLoan(tx.QueryRowContext(ctx, query, loanID))
+	if err != nil {
+		logger.FromContext(ctx).Errorf("failed to find a requested loan: %d to return", loanID)
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("failed to find loan: %d. %w", loanID, model.ErrNotFound)
+		}
+		return nil, err
+	}
+	if loan.Status == constants.Closed {
+		logger.FromContext(ctx).Errorf("requested loan: %d already closed", loanID)
+		return nil, fmt.Errorf("requested loan: %d already closed", loanID)
+	}
+	if version != 0 && version != loan.Version {
+		logger.FromContext(ctx).Errorf("requested loan: %d is at version %d, not %d", loanID, loan.Version, version)
+		return nil, fmt.Errorf("loan %d is at version %d, not %d. %w", loanID, loan.Version, version, model.ErrPreconditionFailed)
+	}
+	if _, err = tx.ExecContext(ctx, `UPDATE loans SET status=?1, version=version + 1 WHERE id=?2`, constants.Closed, loanID); err != nil {
+		logger.FromContext(ctx).Errorf("Failed to execute update query for returning loan. Error: %v", err)
+		return nil, err
+	}
+	now := time.Now()
+	before := &model.LoanState{Status: loan.Status, ReturnDate: loan.ReturnDate, Extensions: loan.Extensions}
+	loan.Status = constants.Closed
+	loan.Version++
+	after := &model.LoanState{Status: loan.Status, ReturnDate: loan.ReturnDate, Extensions: loan.Extensions}
+	if err = recordEvent(ctx, tx, loanID, constants.LoanReturned, before, after, now); err != nil {
+		return nil, err
+	}
+	// the fine stops accruing once returned
+	if err = fineLoan(ctx, tx, loanID, loan.ReturnDate, now, true); err != nil {
+		return nil, err
+	}
+	// setting the copy aside for the next hold or putting it back on the shelf
+	if loan.CopyID != 0 {
+		if err = shelveCopy(ctx, tx, loan.BookID, loan.CopyID, now); err != nil {
+			return nil, err
+		}
+	}
+	if err = tx.Commit(); err != nil {
+		logger.FromContext(ctx).Errorf("Failed to commit transaction of returning a book. Error: %v", err)
+		return nil, err
+	}
+	return loan, nil
+}
+
+// Ping verifies the database file is still open
+func (s *SQLiteDB) Ping(ctx context.Context) error {
+	return s.DB.PingContext(ctx)
+}
+
+func (s *SQLiteDB) Close() error {
+	logger.Infof("Closing the sqlite database")
+	return s.DB.Close()
+}
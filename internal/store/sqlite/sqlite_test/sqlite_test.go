@@ -0,0 +1,508 @@
+package sqlitetest
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/test/library-app/internal/audit"
+	"github.com/test/library-app/internal/config"
+	"github.com/test/library-app/internal/constants"
+	"github.com/test/library-app/internal/model"
+	"github.com/test/library-app/internal/store/sqlite"
+)
+
+var ctx context.Context
+
+func TestMain(m *testing.M) {
+	ctx = context.Background()
+	// loading configuration for the borrowing policy
+	config.LoadConfig()
+	m.Run()
+}
+
+// newStore opens a store on a database file of its own under the temp dir of the test
+func newStore(t *testing.T) *sqlite.SQLiteDB {
+	config.SQLiteConfig.SQLitePath = filepath.Join(t.TempDir(), "library.db")
+	store, err := sqlite.InitSQLiteStore()
+	assert.Nil(t, err)
+	t.Cleanup(func() { store.Close() })
+	return store
+}
+
+func TestInitSQLiteStore(t *testing.T) {
+	store := newStore(t)
+	bookID, err := store.AddBook(ctx, &model.BookDetails{Title: "Sapiens", Authors: []string{"Yuval Noah Harari"}, TotalCopies: 1})
+	assert.Nil(t, err)
+	assert.Nil(t, store.Close())
+
+	// reopening keeps the schema and the data
+	store, err = sqlite.InitSQLiteStore()
+	assert.Nil(t, err)
+	defer store.Close()
+	book, err := store.GetBookDetailsByID(ctx, bookID)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"Yuval Noah Harari"}, book.Authors)
+	assert.Equal(t, 1, book.AvailableCopies)
+}
+
+func TestBooks(t *testing.T) {
+	store := newStore(t)
+
+	// success case
+	bookID, err := store.AddBook(ctx, &model.BookDetails{ISBN: "9780441172719", Title: "Dune", TotalCopies: 2})
+	assert.Nil(t, err)
+	book, err := store.GetBookDetails(ctx, "DUNE")
+	assert.Nil(t, err)
+	assert.Equal(t, bookID, book.ID)
+	assert.Equal(t, 2, book.TotalCopies)
+	copies, err := store.GetBookCopies(ctx, bookID)
+	assert.Nil(t, err)
+	assert.Len(t, copies, 2)
+	assert.Equal(t, model.CopyBarcode(bookID, 1), copies[0].Barcode)
+	book, err = store.UpdateBook(ctx, bookID, 0, &model.BookDetails{ISBN: "9780441172719", Title: "Dune", Edition: "2nd"})
+	assert.Nil(t, err)
+	assert.Equal(t, "2nd", book.Edition)
+	book, err = store.UpdateBookCopies(ctx, bookID, 0, -1)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, book.AvailableCopies)
+	assert.Equal(t, 1, book.TotalCopies)
+
+	// failure case
+	_, err = store.AddBook(ctx, &model.BookDetails{ISBN: "9780441172719", Title: "Dune Messiah"})
+	assert.ErrorIs(t, err, model.ErrAlreadyExists)
+	_, err = store.UpdateBookCopies(ctx, bookID, 0, -2)
+	assert.ErrorIs(t, err, model.ErrConflict)
+	_, err = store.UpdateBook(ctx, 1000, 0, &model.BookDetails{Title: "Dune"})
+	assert.ErrorIs(t, err, model.ErrNotFound)
+	_, err = store.GetBookCopy(ctx, "NOPE")
+	assert.ErrorIs(t, err, model.ErrNotFound)
+
+	// books with active loans aren't deleted
+	memberID, err := store.AddMember(ctx, &model.Member{Name: "Ann"})
+	assert.Nil(t, err)
+	loanID, err := store.AddLoan(ctx, &model.LoanDetails{MemberID: memberID, BookID: bookID, Status: constants.Active})
+	assert.Nil(t, err)
+	assert.ErrorIs(t, store.DeleteBook(ctx, bookID), model.ErrConflict)
+	_, err = store.ReturnBook(ctx, loanID, 0)
+	assert.Nil(t, err)
+	assert.Nil(t, store.DeleteBook(ctx, bookID))
+	_, err = store.GetBookDetailsByID(ctx, bookID)
+	assert.ErrorIs(t, err, model.ErrNotFound)
+	// closed loans keep their title
+	loan, err := store.GetLoan(ctx, loanID)
+	assert.Nil(t, err)
+	assert.Equal(t, "Dune", loan.Title)
+	assert.Equal(t, 0, loan.BookID)
+}
+
+func TestGetAllBookDetails(t *testing.T) {
+	store := newStore(t)
+	for _, book := range []*model.BookDetails{
+		{Title: "Sapiens", Authors: []string{"Yuval Noah Harari"}, PublicationYear: 2011, TotalCopies: 1},
+		{Title: "Animal Farm", Authors: []string{"George Orwell"}, PublicationYear: 1945, TotalCopies: 1},
+		{Title: "Alchemist", Authors: []string{"Paulo Coelho"}, PublicationYear: 1988},
+	} {
+		_, err := store.AddBook(ctx, book)
+		assert.Nil(t, err)
+	}
+
+	// paging through titles
+	titles := []string{}
+	query := &model.BookQuery{PageQuery: model.PageQuery{Limit: 2, SortBy: constants.SortByTitle}}
+	for {
+		page, err := store.GetAllBookDetails(ctx, query)
+		assert.Nil(t, err)
+		for _, book := range page.Books {
+			titles = append(titles, book.Title)
+		}
+		if page.NextCursor == "" {
+			break
+		}
+		query.After, err = model.DecodeCursor(page.NextCursor)
+		assert.Nil(t, err)
+	}
+	assert.Equal(t, []string{"Alchemist", "Animal Farm", "Sapiens"}, titles)
+
+	// filters
+	page, err := store.GetAllBookDetails(ctx, &model.BookQuery{Author: "ORWELL"})
+	assert.Nil(t, err)
+	assert.Len(t, page.Books, 1)
+	assert.Equal(t, "Animal Farm", page.Books[0].Title)
+	available := false
+	page, err = store.GetAllBookDetails(ctx, &model.BookQuery{Available: &available})
+	assert.Nil(t, err)
+	assert.Len(t, page.Books, 1)
+	assert.Equal(t, "Alchemist", page.Books[0].Title)
+}
+
+func TestSearchBooks(t *testing.T) {
+	store := newStore(t)
+	_, err := store.AddBook(ctx, &model.BookDetails{Title: "Alchemist", Authors: []string{"Paulo Coelho"}})
+	assert.Nil(t, err)
+	_, err = store.AddBook(ctx, &model.BookDetails{Title: "Animal Farm", Subjects: []string{"satire"}})
+	assert.Nil(t, err)
+
+	// success case
+	results, err := store.SearchBooks(ctx, "coelho", 10)
+	assert.Nil(t, err)
+	assert.Len(t, results, 1)
+	assert.Equal(t, "Alchemist", results[0].Book.Title)
+	assert.Equal(t, "Paulo <mark>Coelho</mark>", results[0].Highlights[constants.SearchFieldAuthors])
+
+	// title matches rank above subject matches
+	bookID, err := store.AddBook(ctx, &model.BookDetails{Title: "Satire", Subjects: []string{"essays"}})
+	assert.Nil(t, err)
+	results, err = store.SearchBooks(ctx, "satire", 10)
+	assert.Nil(t, err)
+	assert.Len(t, results, 2)
+	assert.Equal(t, bookID, results[0].Book.ID)
+	assert.Equal(t, "Animal Farm", results[1].Book.Title)
+
+	// updates are re-indexed
+	_, err = store.UpdateBook(ctx, bookID, 0, &model.BookDetails{Title: "Essays"})
+	assert.Nil(t, err)
+	results, err = store.SearchBooks(ctx, "satire", 10)
+	assert.Nil(t, err)
+	assert.Len(t, results, 1)
+
+	// failure case: every word must match, query syntax is taken literally
+	results, err = store.SearchBooks(ctx, "coelho orwell", 10)
+	assert.Nil(t, err)
+	assert.Empty(t, results)
+	results, err = store.SearchBooks(ctx, `paulo NOT coelho`, 10)
+	assert.Nil(t, err)
+	assert.Empty(t, results)
+}
+
+func TestMembers(t *testing.T) {
+	store := newStore(t)
+
+	// success case
+	memberID, err := store.AddMember(ctx, &model.Member{Name: "Ann", Email: "ann@example.com"})
+	assert.Nil(t, err)
+	member, err := store.GetMember(ctx, memberID)
+	assert.Nil(t, err)
+	assert.Equal(t, model.CardNumber(memberID), member.CardNumber)
+	assert.Equal(t, constants.DefaultTier, member.Tier)
+	page, err := store.GetAllMembers(ctx, &model.MemberQuery{Name: "ANN"})
+	assert.Nil(t, err)
+	assert.Len(t, page.Members, 1)
+	member, err = store.UpdateMember(ctx, memberID, &model.Member{Tier: constants.TierPremium})
+	assert.Nil(t, err)
+	assert.Equal(t, "ann@example.com", member.Email)
+
+	// failure case: emails are unique ignoring the case
+	_, err = store.AddMember(ctx, &model.Member{Name: "Ann", Email: "ANN@example.com"})
+	assert.ErrorIs(t, err, model.ErrAlreadyExists)
+	_, err = store.UpdateMember(ctx, 1000, &model.Member{Name: "Bob"})
+	assert.ErrorIs(t, err, model.ErrNotFound)
+
+	assert.Nil(t, store.DeleteMember(ctx, memberID))
+	_, err = store.GetMember(ctx, memberID)
+	assert.ErrorIs(t, err, model.ErrNotFound)
+	// ids aren't reused
+	otherID, err := store.AddMember(ctx, &model.Member{Name: "Bob"})
+	assert.Nil(t, err)
+	assert.Greater(t, otherID, memberID)
+}
+
+func TestLoans(t *testing.T) {
+	store := newStore(t)
+	bookID, err := store.AddBook(ctx, &model.BookDetails{Title: "Sapiens", TotalCopies: 1})
+	assert.Nil(t, err)
+	memberID, err := store.AddMember(ctx, &model.Member{Name: "Gina"})
+	assert.Nil(t, err)
+	librarianCtx := audit.WithActor(ctx, "librarian-1")
+
+	// success case
+	loanID, err := store.AddLoan(librarianCtx, &model.LoanDetails{MemberID: memberID, Title: "sapiens", Status: constants.Active})
+	assert.Nil(t, err)
+	loan, err := store.ExtendLoan(librarianCtx, loanID, 0)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, loan.Extensions)
+	book, err := store.GetBookDetailsByID(ctx, bookID)
+	assert.Nil(t, err)
+	assert.Equal(t, 0, book.AvailableCopies)
+
+	// failure case: no copy left, unknown loan
+	otherID, err := store.AddMember(ctx, &model.Member{Name: "Hana"})
+	assert.Nil(t, err)
+	_, err = store.AddLoan(ctx, &model.LoanDetails{MemberID: otherID, BookID: bookID, Status: constants.Active})
+	assert.ErrorIs(t, err, model.ErrNotFound)
+	_, err = store.ExtendLoan(ctx, 1000, 0)
+	assert.ErrorIs(t, err, model.ErrNotFound)
+
+	// marked overdue by the background job
+	overdue, err := store.AccrueFines(ctx, time.Unix(loan.ReturnDate, 0).Add(time.Hour))
+	assert.Nil(t, err)
+	assert.Equal(t, 1, overdue)
+	loan, err = store.ReturnBook(librarianCtx, loanID, 0)
+	assert.Nil(t, err)
+	assert.Equal(t, constants.Closed, loan.Status)
+	_, err = store.ReturnBook(ctx, loanID, 0)
+	assert.NotNil(t, err)
+
+	events, err := store.GetLoanHistory(ctx, loanID)
+	assert.Nil(t, err)
+	types := []string{}
+	for _, event := range events {
+		types = append(types, event.Type)
+	}
+	assert.Equal(t, []string{constants.LoanCreated, constants.LoanExtended, constants.LoanOverdue, constants.LoanReturned}, types)
+	assert.Nil(t, events[0].Old)
+	assert.Equal(t, "librarian-1", events[1].Actor)
+	assert.Equal(t, 1, events[1].New.Extensions)
+	assert.Equal(t, constants.ActorSystem, events[2].Actor)
+	assert.Equal(t, constants.Closed, events[3].New.Status)
+
+	loans, err := store.GetLoansByBorrower(ctx, memberID)
+	assert.Nil(t, err)
+	assert.Len(t, loans, 1)
+	page, err := store.GetAllLoans(ctx, &model.LoanQuery{Status: constants.Closed})
+	assert.Nil(t, err)
+	assert.Len(t, page.Loans, 1)
+	_, err = store.GetLoanHistory(ctx, 1000)
+	assert.ErrorIs(t, err, model.ErrNotFound)
+}
+
+func TestVersions(t *testing.T) {
+	store := newStore(t)
+	bookID, err := store.AddBook(ctx, &model.BookDetails{Title: "Middlemarch", TotalCopies: 1})
+	assert.Nil(t, err)
+	memberID, err := store.AddMember(ctx, &model.Member{Name: "Ida"})
+	assert.Nil(t, err)
+
+	// success case: each update moves the book on to the next version
+	book, err := store.UpdateBook(ctx, bookID, 1, &model.BookDetails{Title: "Middlemarch"})
+	assert.Nil(t, err)
+	assert.Equal(t, 2, book.Version)
+	book, err = store.UpdateBookCopies(ctx, bookID, 2, 1)
+	assert.Nil(t, err)
+	assert.Equal(t, 3, book.Version)
+
+	// failure case: updating a stale version
+	_, err = store.UpdateBook(ctx, bookID, 2, &model.BookDetails{Title: "Middlemarch (Annotated)"})
+	assert.ErrorIs(t, err, model.ErrPreconditionFailed)
+	_, err = store.UpdateBookCopies(ctx, bookID, 2, 1)
+	assert.ErrorIs(t, err, model.ErrPreconditionFailed)
+	book, err = store.GetBookDetailsByID(ctx, bookID)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, book.TotalCopies)
+	_, err = store.UpdateBook(ctx, 1000, 2, &model.BookDetails{Title: "Nothing"})
+	assert.ErrorIs(t, err, model.ErrNotFound)
+
+	loanID, err := store.AddLoan(ctx, &model.LoanDetails{MemberID: memberID, BookID: bookID, Status: constants.Active})
+	assert.Nil(t, err)
+	loan, err := store.ExtendLoan(ctx, loanID, 1)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, loan.Version)
+	// marking the loan overdue moves it on as well
+	_, err = store.AccrueFines(ctx, time.Unix(loan.ReturnDate, 0).Add(time.Hour))
+	assert.Nil(t, err)
+	_, err = store.ReturnBook(ctx, loanID, 2)
+	assert.ErrorIs(t, err, model.ErrPreconditionFailed)
+	loan, err = store.ReturnBook(ctx, loanID, 3)
+	assert.Nil(t, err)
+	assert.Equal(t, 4, loan.Version)
+	loan, err = store.GetLoan(ctx, loanID)
+	assert.Nil(t, err)
+	assert.Equal(t, 4, loan.Version)
+}
+
+func TestCirculationStats(t *testing.T) {
+	store := newStore(t)
+	bookID, err := store.AddBook(ctx, &model.BookDetails{Title: "Dune", TotalCopies: 1})
+	assert.Nil(t, err)
+	_, err = store.AddBook(ctx, &model.BookDetails{Title: "Emma", TotalCopies: 2})
+	assert.Nil(t, err)
+	memberID, err := store.AddMember(ctx, &model.Member{Name: "Ann"})
+	assert.Nil(t, err)
+	_, err = store.AddLoan(ctx, &model.LoanDetails{MemberID: memberID, BookID: bookID, Status: constants.Active})
+	assert.Nil(t, err)
+
+	// success case: the loan is open, its book has no copy left
+	stats, err := store.GetCirculationStats(ctx, time.Now())
+	assert.Nil(t, err)
+	assert.Equal(t, model.CirculationStats{OpenLoans: 1, OverdueLoans: 0, UnavailableTitles: 1}, *stats)
+
+	// success case: overdue once past its return date
+	stats, err = store.GetCirculationStats(ctx, time.Now().AddDate(0, 0, 60))
+	assert.Nil(t, err)
+	assert.Equal(t, 1, stats.OverdueLoans)
+}
+
+func TestAPIKeys(t *testing.T) {
+	store := newStore(t)
+	key := &model.APIKey{Name: "kiosk-east", Prefix: "lib_abcdef", Hash: "hash-1", Scopes: []string{"catalog:read", "circulation:write"}, RequestsPerMinute: 60}
+
+	// success case: issued keys are found by their hash
+	id, err := store.AddAPIKey(ctx, key)
+	assert.Nil(t, err)
+	found, err := store.GetAPIKeyByHash(ctx, "hash-1")
+	assert.Nil(t, err)
+	assert.Equal(t, id, found.ID)
+	assert.Equal(t, []string{"catalog:read", "circulation:write"}, found.Scopes)
+	assert.Equal(t, 60, found.RequestsPerMinute)
+	assert.Equal(t, "hash-1", found.Hash)
+
+	// failure case: the name is taken
+	_, err = store.AddAPIKey(ctx, &model.APIKey{Name: "kiosk-east", Hash: "hash-2"})
+	assert.ErrorIs(t, err, model.ErrAlreadyExists)
+
+	// success case: revoked keys are kept
+	revoked, err := store.RevokeAPIKey(ctx, id, time.Now())
+	assert.Nil(t, err)
+	assert.NotZero(t, revoked.RevokedAt)
+	keys, err := store.GetAllAPIKeys(ctx)
+	assert.Nil(t, err)
+	assert.Len(t, keys, 1)
+	assert.NotZero(t, keys[0].RevokedAt)
+
+	// failure cases: revoked once only, unknown keys
+	_, err = store.RevokeAPIKey(ctx, id, time.Now())
+	assert.ErrorIs(t, err, model.ErrConflict)
+	_, err = store.RevokeAPIKey(ctx, id+1, time.Now())
+	assert.ErrorIs(t, err, model.ErrNotFound)
+	_, err = store.GetAPIKeyByHash(ctx, "hash-2")
+	assert.ErrorIs(t, err, model.ErrNotFound)
+}
+
+func TestIdempotencyKeys(t *testing.T) {
+	store := newStore(t)
+	now := time.Now()
+	rec := &model.IdempotencyRecord{Key: "key-1", Fingerprint: "abc", ExpiresAt: now.Add(time.Minute).Unix()}
+
+	// success case: reserved by the first request, in progress for its retries
+	stored, err := store.ReserveIdempotencyKey(ctx, rec, now)
+	assert.Nil(t, err)
+	assert.Nil(t, stored)
+	stored, err = store.ReserveIdempotencyKey(ctx, rec, now)
+	assert.Nil(t, err)
+	assert.Equal(t, 0, stored.StatusCode)
+
+	// released keys are reserved again
+	assert.Nil(t, store.ReleaseIdempotencyKey(ctx, rec.Key))
+	stored, err = store.ReserveIdempotencyKey(ctx, rec, now)
+	assert.Nil(t, err)
+	assert.Nil(t, stored)
+
+	// completed keys are replayed and aren't released
+	completed := *rec
+	completed.StatusCode = 201
+	completed.Body = []byte(`{"id":1}`)
+	completed.Header = map[string]string{"Content-Type": "application/json"}
+	assert.Nil(t, store.CompleteIdempotencyKey(ctx, &completed))
+	assert.Nil(t, store.ReleaseIdempotencyKey(ctx, rec.Key))
+	stored, err = store.ReserveIdempotencyKey(ctx, rec, now)
+	assert.Nil(t, err)
+	assert.Equal(t, 201, stored.StatusCode)
+	assert.Equal(t, `{"id":1}`, string(stored.Body))
+	assert.Equal(t, "application/json", stored.Header["Content-Type"])
+
+	// failure case: expired keys are dropped and taken over
+	expired, err := store.ExpireIdempotencyKeys(ctx, now.Add(2*time.Minute))
+	assert.Nil(t, err)
+	assert.Equal(t, 1, expired)
+	stored, err = store.ReserveIdempotencyKey(ctx, rec, now.Add(2*time.Minute))
+	assert.Nil(t, err)
+	assert.Nil(t, stored)
+}
+
+func TestHolds(t *testing.T) {
+	store := newStore(t)
+	bookID, err := store.AddBook(ctx, &model.BookDetails{Title: "Beloved", TotalCopies: 1})
+	assert.Nil(t, err)
+	memberIDs := map[string]int{}
+	for _, name := range []string{"john", "jane", "adam"} {
+		memberIDs[name], err = store.AddMember(ctx, &model.Member{Name: name})
+		assert.Nil(t, err)
+	}
+
+	// failure case: a copy is on the shelf
+	_, err = store.AddHold(ctx, &model.Hold{MemberID: memberIDs["jane"], BookID: bookID})
+	assert.ErrorIs(t, err, model.ErrConflict)
+
+	loanID, err := store.AddLoan(ctx, &model.LoanDetails{MemberID: memberIDs["john"], BookID: bookID, Status: constants.Active})
+	assert.Nil(t, err)
+
+	// success case: holds queue up in the order placed
+	_, err = store.AddHold(ctx, &model.Hold{MemberID: memberIDs["jane"], Title: "beloved"})
+	assert.Nil(t, err)
+	adamHoldID, err := store.AddHold(ctx, &model.Hold{MemberID: memberIDs["adam"], BookID: bookID})
+	assert.Nil(t, err)
+	_, err = store.AddHold(ctx, &model.Hold{MemberID: memberIDs["jane"], BookID: bookID})
+	assert.ErrorIs(t, err, model.ErrAlreadyExists)
+
+	// the returned copy is set aside for the longest waiting hold
+	_, err = store.ReturnBook(ctx, loanID, 0)
+	assert.Nil(t, err)
+	holds, err := store.GetMemberHolds(ctx, memberIDs["jane"])
+	assert.Nil(t, err)
+	assert.Equal(t, constants.HoldReady, holds[0].Status)
+	bookCopy, err := store.GetBookCopy(ctx, holds[0].Barcode)
+	assert.Nil(t, err)
+	assert.Equal(t, constants.CopyOnHold, bookCopy.Status)
+
+	// expiring passes the copy on to the next hold
+	expired, err := store.ExpireHolds(ctx, time.Now().Add(time.Duration(config.PolicyConfig.HoldPickupInDays+1)*24*time.Hour))
+	assert.Nil(t, err)
+	assert.Equal(t, 1, expired)
+
+	// borrowing fulfills the ready hold with the copy set aside
+	_, err = store.AddLoan(ctx, &model.LoanDetails{MemberID: memberIDs["adam"], Title: "beloved", Status: constants.Active})
+	assert.Nil(t, err)
+	holds, err = store.GetMemberHolds(ctx, memberIDs["adam"])
+	assert.Nil(t, err)
+	assert.Equal(t, adamHoldID, holds[0].ID)
+	assert.Equal(t, constants.HoldFulfilled, holds[0].Status)
+
+	// failure case: unknown hold
+	_, err = store.CancelHold(ctx, 1000)
+	assert.ErrorIs(t, err, model.ErrNotFound)
+}
+
+func TestFines(t *testing.T) {
+	store := newStore(t)
+	_, err := store.AddBook(ctx, &model.BookDetails{Title: "Sapiens", TotalCopies: 1})
+	assert.Nil(t, err)
+	memberID, err := store.AddMember(ctx, &model.Member{Name: "Fred"})
+	assert.Nil(t, err)
+	now := time.Now()
+	loanID, err := store.AddLoan(ctx, &model.LoanDetails{MemberID: memberID, Title: "sapiens", ReturnDate: now.Add(-3 * 24 * time.Hour).Unix(), Status: constants.Active})
+	assert.Nil(t, err)
+
+	// success case: overdue loans accrue fines
+	_, err = store.AccrueFines(ctx, now)
+	assert.Nil(t, err)
+	fines, err := store.GetMemberFines(ctx, memberID)
+	assert.Nil(t, err)
+	assert.Len(t, fines.Fines, 1)
+	fine := fines.Fines[0]
+	assert.Equal(t, constants.FineAccruing, fine.Status)
+	assert.Equal(t, 3*config.PolicyConfig.FinePerDayInCents, fine.AmountInCents)
+	fine, err = store.PayFine(ctx, fine.ID, 25)
+	assert.Nil(t, err)
+	assert.Equal(t, constants.FineAccruing, fine.Status)
+
+	// returning finalises the fine, members owing fines aren't deleted
+	_, err = store.ReturnBook(ctx, loanID, 0)
+	assert.Nil(t, err)
+	fines, err = store.GetMemberFines(ctx, memberID)
+	assert.Nil(t, err)
+	assert.Equal(t, constants.FineUnpaid, fines.Fines[0].Status)
+	assert.Equal(t, fine.AmountInCents-25, fines.OutstandingInCents)
+	assert.ErrorIs(t, store.DeleteMember(ctx, memberID), model.ErrConflict)
+	fine, err = store.PayFine(ctx, fine.ID, 0)
+	assert.Nil(t, err)
+	assert.Equal(t, constants.FinePaid, fine.Status)
+	assert.NotZero(t, fine.SettledAt)
+
+	// failure case: settled fines
+	_, err = store.WaiveFine(ctx, fine.ID)
+	assert.ErrorIs(t, err, model.ErrConflict)
+	_, err = store.PayFine(ctx, 1000, 0)
+	assert.ErrorIs(t, err, model.ErrNotFound)
+}
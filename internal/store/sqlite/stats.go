@@ -0,0 +1,28 @@
+package sqlite
+
+import (
+	"context"
+	"time"
+
+	"github.com/test/library-app/internal/constants"
+	"github.com/test/library-app/internal/logger"
+	"github.com/test/library-app/internal/model"
+)
+
+// GetCirculationStats counts the open and overdue loans and the books with no copy available as of now
+func (s *SQLiteDB) GetCirculationStats(ctx context.Context, now time.Time) (*model.CirculationStats, error) {
+	query := `SELECT
+		(SELECT COUNT(*) FROM loans WHERE status<>?1),
+		(SELECT COUNT(*) FROM loans WHERE status<>?1 AND return_date<?2),
+		(SELECT COUNT(*) FROM books b WHERE NOT EXISTS (
+			SELECT 1 FROM book_copies c WHERE c.book_id=b.id AND c.status=?3))
+	`
+	var stats model.CirculationStats
+	err := s.DB.QueryRowContext(ctx, query, constants.Closed, now.Unix(), constants.CopyAvailable).
+		Scan(&stats.OpenLoans, &stats.OverdueLoans, &stats.UnavailableTitles)
+	if err != nil {
+		logger.FromContext(ctx).Errorf("failed to count the circulation stats. Error: %v", err)
+		return nil, err
+	}
+	return &stats, nil
+}
@@ -3,36 +3,135 @@ package store
 import (
 	"context"
 	"fmt"
+	"time"
 
 	"github.com/test/library-app/internal/config"
 	"github.com/test/library-app/internal/constants"
+	"github.com/test/library-app/internal/metrics"
 	"github.com/test/library-app/internal/model"
 	"github.com/test/library-app/internal/store/local"
 	"github.com/test/library-app/internal/store/postgres"
+	"github.com/test/library-app/internal/store/sqlite"
 )
 
 type Store interface {
-	// GetBookDetails retreves book details from store
+	// GetBookDetails retreves the oldest book with the title from store
 	GetBookDetails(ctx context.Context, title string) (*model.BookDetails, error)
-	// GetAllBookDetails retreves book details from store
-	GetAllBookDetails(ctx context.Context) ([]*model.BookDetails, error)
-	// GetAllLoans retreves all loan details from store
-	GetAllLoans(ctx context.Context) ([]*model.LoanDetails, error)
-	// AddLoan adds the loan details to store
+	// GetBookDetailsByID retreves book details by its ID from store
+	GetBookDetailsByID(ctx context.Context, bookID int) (*model.BookDetails, error)
+	// GetAllBookDetails retreves a page of the books passing the filters of the query, in its sort order
+	GetAllBookDetails(ctx context.Context, query *model.BookQuery) (*model.BookPage, error)
+	// SearchBooks retreves the books best matching the query across title, authors, subjects and description
+	SearchBooks(ctx context.Context, query string, limit int) ([]*model.BookSearchResult, error)
+	// AddBook adds a new book with TotalCopies available copies to the catalog
+	AddBook(ctx context.Context, det *model.BookDetails) (int, error)
+	// UpdateBook replaces the details of a book based on its version, any version when 0.
+	// Refused with ErrPreconditionFailed once the book moved on to another version
+	UpdateBook(ctx context.Context, bookID int, version int, det *model.BookDetails) (*model.BookDetails, error)
+	// UpdateBookCopies adds copies with generated barcodes or withdraws available copies of a book based on its version,
+	// any version when 0. Refused with ErrPreconditionFailed once the book moved on to another version
+	UpdateBookCopies(ctx context.Context, bookID int, version int, delta int) (*model.BookDetails, error)
+	// AddBookCopy adds a physical copy to a book, generating the barcode when missed
+	AddBookCopy(ctx context.Context, det *model.BookCopy) (int, error)
+	// GetBookCopies retreves all copies of a book
+	GetBookCopies(ctx context.Context, bookID int) ([]*model.BookCopy, error)
+	// GetBookCopy retreves a copy by its barcode
+	GetBookCopy(ctx context.Context, barcode string) (*model.BookCopy, error)
+	// UpdateBookCopy updates the non empty shelf location, condition and status of a copy
+	UpdateBookCopy(ctx context.Context, barcode string, det *model.BookCopy) (*model.BookCopy, error)
+	// DeleteBook removes a book from the catalog, refused while it has active loans
+	DeleteBook(ctx context.Context, bookID int) error
+	// AddMember registers a member, generating the card number when missed
+	AddMember(ctx context.Context, det *model.Member) (int, error)
+	// GetMember retreves a member by its ID
+	GetMember(ctx context.Context, memberID int) (*model.Member, error)
+	// GetAllMembers retreves a page of the members passing the filters of the query, in its sort order
+	GetAllMembers(ctx context.Context, query *model.MemberQuery) (*model.MemberPage, error)
+	// UpdateMember updates the non empty name, email, card number, tier and status of a member
+	UpdateMember(ctx context.Context, memberID int, det *model.Member) (*model.Member, error)
+	// DeleteMember removes a member cancelling its holds, refused while the member has active loans or owes fines
+	DeleteMember(ctx context.Context, memberID int) error
+	// AddHold places a hold at the end of the queue of a book with no copy available.
+	// Refused with a *model.RefusalError when the borrowing policy isn't met
+	AddHold(ctx context.Context, det *model.Hold) (int, error)
+	// GetMemberHolds retreves the holds of a member in the order placed
+	GetMemberHolds(ctx context.Context, memberID int) ([]*model.Hold, error)
+	// CancelHold cancels a waiting or ready hold, the copy set aside passes on to the next hold
+	CancelHold(ctx context.Context, holdID int) (*model.Hold, error)
+	// ExpireHolds expires the ready holds not picked up by now, their copies pass on to the next holds
+	ExpireHolds(ctx context.Context, now time.Time) (int, error)
+	// AccrueFines marks the loans not returned by now overdue and accrues their fines, returns the No of overdue loans
+	AccrueFines(ctx context.Context, now time.Time) (int, error)
+	// GetMemberFines retreves the fines of a member in the order fined along with the total owed
+	GetMemberFines(ctx context.Context, memberID int) (*model.MemberFines, error)
+	// PayFine pays the amount of a fine, the outstanding amount when zero. Refused with ErrConflict when paying more than owed
+	PayFine(ctx context.Context, fineID int, amount int64) (*model.Fine, error)
+	// WaiveFine waives an accruing or unpaid fine, nothing more is owed for it
+	WaiveFine(ctx context.Context, fineID int) (*model.Fine, error)
+	// GetLoan retreves a loan by its ID
+	GetLoan(ctx context.Context, loanID int) (*model.LoanDetails, error)
+	// GetLoansByBorrower retreves the active and past loans of a member in the order loaned
+	GetLoansByBorrower(ctx context.Context, memberID int) ([]*model.LoanDetails, error)
+	// GetAllLoans retreves a page of the loans passing the filters of the query, in its sort order
+	GetAllLoans(ctx context.Context, query *model.LoanQuery) (*model.LoanPage, error)
+	// AddLoan adds the loan details to store, the return date is given by the loan terms when missed.
+	// Refused with a *model.RefusalError when the borrowing policy isn't met
 	AddLoan(ctx context.Context, det *model.LoanDetails) (int, error)
-	// Extends the loan
-	ExtendLoan(ctx context.Context, loanID int) (*model.LoanDetails, error)
-	// Retunrs a book
-	ReturnBook(ctx context.Context, loanID int) (*model.LoanDetails, error)
+	// ExtendLoan extends the return date by the extension of the loan terms, refused with a *model.RefusalError once overdue or extended the most times.
+	// Refused with ErrPreconditionFailed unless version is the current version of the loan or 0
+	ExtendLoan(ctx context.Context, loanID int, version int) (*model.LoanDetails, error)
+	// ReturnBook closes the loan finalising its fine, the copy is set aside for the next waiting hold of the book or put on the shelf.
+	// Refused with ErrPreconditionFailed unless version is the current version of the loan or 0
+	ReturnBook(ctx context.Context, loanID int, version int) (*model.LoanDetails, error)
+	// GetLoanHistory retreves the transitions of a loan in the order made, each naming the actor carried by the context of the change
+	GetLoanHistory(ctx context.Context, loanID int) ([]*model.LoanEvent, error)
+	// ReserveIdempotencyKey reserves the key of the record for its request till the record expires, nil is returned once reserved.
+	// The record of the key is returned instead while the key is reserved or completed and not expired by now
+	ReserveIdempotencyKey(ctx context.Context, rec *model.IdempotencyRecord, now time.Time) (*model.IdempotencyRecord, error)
+	// CompleteIdempotencyKey stores the response of the request the key of the record is reserved for
+	CompleteIdempotencyKey(ctx context.Context, rec *model.IdempotencyRecord) error
+	// ReleaseIdempotencyKey drops the reservation of a key so the request can be retried
+	ReleaseIdempotencyKey(ctx context.Context, key string) error
+	// ExpireIdempotencyKeys drops the keys expired by now, returns the No of keys dropped
+	ExpireIdempotencyKeys(ctx context.Context, now time.Time) (int, error)
+	// GetCirculationStats counts the open and overdue loans and the books with no copy available as of now
+	GetCirculationStats(ctx context.Context, now time.Time) (*model.CirculationStats, error)
+	// AddAPIKey stores an issued api key, refused with ErrAlreadyExists when its name or hash is taken
+	AddAPIKey(ctx context.Context, key *model.APIKey) (int, error)
+	// GetAPIKeyByHash retreves the api key with the hash whether revoked or not
+	GetAPIKeyByHash(ctx context.Context, hash string) (*model.APIKey, error)
+	// GetAllAPIKeys retreves the api keys in the order issued
+	GetAllAPIKeys(ctx context.Context) ([]*model.APIKey, error)
+	// RevokeAPIKey revokes an api key as of now, refused with ErrConflict once revoked
+	RevokeAPIKey(ctx context.Context, keyID int, now time.Time) (*model.APIKey, error)
+	// Ping verifies the store serves the requests, failing once it can't reach its database or refuses writes
+	Ping(ctx context.Context) error
 	Close() error
 }
 
+// NewStore initializes the configured store, its operations observed by the metrics and traced
 func NewStore() (Store, error) {
 	switch config.CommonConfig.StoreType {
 	case constants.LocalStore:
-		return local.InitLocalStore()
+		s, err := local.InitLocalStore()
+		if err != nil {
+			return nil, err
+		}
+		return newTracedStore(newMeteredStore(s)), nil
 	case constants.PostgresStore:
-		return postgres.InitPostgresStore()
+		s, err := postgres.InitPostgresStore()
+		if err != nil {
+			return nil, err
+		}
+		// exposing the stats of the connection pool
+		metrics.RegisterPool(s.DB)
+		return newTracedStore(newMeteredStore(s)), nil
+	case constants.SQLiteStore:
+		s, err := sqlite.InitSQLiteStore()
+		if err != nil {
+			return nil, err
+		}
+		return newTracedStore(newMeteredStore(s)), nil
 	default:
 		return nil, fmt.Errorf("unknown Store configured: %v", config.CommonConfig.StoreType)
 	}
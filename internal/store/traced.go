@@ -0,0 +1,308 @@
+package store
+
+import (
+	"context"
+	"time"
+
+	"github.com/test/library-app/internal/config"
+	"github.com/test/library-app/internal/metrics"
+	"github.com/test/library-app/internal/model"
+	"github.com/test/library-app/internal/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// tracedStore runs every operation of the store it wraps in a span of its own,
+// a child of the span of the request and the parent of the spans of its queries
+type tracedStore struct {
+	next Store
+}
+
+// newTracedStore wraps the store with the spans of its operations
+func newTracedStore(s Store) Store {
+	return &tracedStore{next: s}
+}
+
+// startSpan starts the span of an operation of the store under the span in the context
+func startSpan(ctx context.Context, operation string) (context.Context, trace.Span) {
+	return tracing.Tracer().Start(ctx, "store."+operation, trace.WithAttributes(
+		attribute.String("store.operation", operation),
+		attribute.String("store.type", config.CommonConfig.StoreType),
+	))
+}
+
+// endSpan ends the span of an operation once it returns with the error, only the internal errors fail the span,
+// the refusals and the missing records are expected answers
+func endSpan(span trace.Span, err *error) {
+	if *err != nil {
+		kind := metrics.ErrorKind(*err)
+		span.SetAttributes(attribute.String("error.type", kind))
+		span.RecordError(*err)
+		if kind == "internal" {
+			span.SetStatus(codes.Error, (*err).Error())
+		}
+	}
+	span.End()
+}
+
+func (t *tracedStore) GetBookDetails(ctx context.Context, title string) (res *model.BookDetails, err error) {
+	ctx, span := startSpan(ctx, "GetBookDetails")
+	defer endSpan(span, &err)
+	return t.next.GetBookDetails(ctx, title)
+}
+
+func (t *tracedStore) GetBookDetailsByID(ctx context.Context, bookID int) (res *model.BookDetails, err error) {
+	ctx, span := startSpan(ctx, "GetBookDetailsByID")
+	defer endSpan(span, &err)
+	return t.next.GetBookDetailsByID(ctx, bookID)
+}
+
+func (t *tracedStore) GetAllBookDetails(ctx context.Context, query *model.BookQuery) (res *model.BookPage, err error) {
+	ctx, span := startSpan(ctx, "GetAllBookDetails")
+	defer endSpan(span, &err)
+	return t.next.GetAllBookDetails(ctx, query)
+}
+
+func (t *tracedStore) SearchBooks(ctx context.Context, query string, limit int) (res []*model.BookSearchResult, err error) {
+	ctx, span := startSpan(ctx, "SearchBooks")
+	defer endSpan(span, &err)
+	return t.next.SearchBooks(ctx, query, limit)
+}
+
+func (t *tracedStore) AddBook(ctx context.Context, det *model.BookDetails) (res int, err error) {
+	ctx, span := startSpan(ctx, "AddBook")
+	defer endSpan(span, &err)
+	return t.next.AddBook(ctx, det)
+}
+
+func (t *tracedStore) UpdateBook(ctx context.Context, bookID int, version int, det *model.BookDetails) (res *model.BookDetails, err error) {
+	ctx, span := startSpan(ctx, "UpdateBook")
+	defer endSpan(span, &err)
+	return t.next.UpdateBook(ctx, bookID, version, det)
+}
+
+func (t *tracedStore) UpdateBookCopies(ctx context.Context, bookID int, version int, delta int) (res *model.BookDetails, err error) {
+	ctx, span := startSpan(ctx, "UpdateBookCopies")
+	defer endSpan(span, &err)
+	return t.next.UpdateBookCopies(ctx, bookID, version, delta)
+}
+
+func (t *tracedStore) AddBookCopy(ctx context.Context, det *model.BookCopy) (res int, err error) {
+	ctx, span := startSpan(ctx, "AddBookCopy")
+	defer endSpan(span, &err)
+	return t.next.AddBookCopy(ctx, det)
+}
+
+func (t *tracedStore) GetBookCopies(ctx context.Context, bookID int) (res []*model.BookCopy, err error) {
+	ctx, span := startSpan(ctx, "GetBookCopies")
+	defer endSpan(span, &err)
+	return t.next.GetBookCopies(ctx, bookID)
+}
+
+func (t *tracedStore) GetBookCopy(ctx context.Context, barcode string) (res *model.BookCopy, err error) {
+	ctx, span := startSpan(ctx, "GetBookCopy")
+	defer endSpan(span, &err)
+	return t.next.GetBookCopy(ctx, barcode)
+}
+
+func (t *tracedStore) UpdateBookCopy(ctx context.Context, barcode string, det *model.BookCopy) (res *model.BookCopy, err error) {
+	ctx, span := startSpan(ctx, "UpdateBookCopy")
+	defer endSpan(span, &err)
+	return t.next.UpdateBookCopy(ctx, barcode, det)
+}
+
+func (t *tracedStore) DeleteBook(ctx context.Context, bookID int) (err error) {
+	ctx, span := startSpan(ctx, "DeleteBook")
+	defer endSpan(span, &err)
+	err = t.next.DeleteBook(ctx, bookID)
+	return err
+}
+
+func (t *tracedStore) AddMember(ctx context.Context, det *model.Member) (res int, err error) {
+	ctx, span := startSpan(ctx, "AddMember")
+	defer endSpan(span, &err)
+	return t.next.AddMember(ctx, det)
+}
+
+func (t *tracedStore) GetMember(ctx context.Context, memberID int) (res *model.Member, err error) {
+	ctx, span := startSpan(ctx, "GetMember")
+	defer endSpan(span, &err)
+	return t.next.GetMember(ctx, memberID)
+}
+
+func (t *tracedStore) GetAllMembers(ctx context.Context, query *model.MemberQuery) (res *model.MemberPage, err error) {
+	ctx, span := startSpan(ctx, "GetAllMembers")
+	defer endSpan(span, &err)
+	return t.next.GetAllMembers(ctx, query)
+}
+
+func (t *tracedStore) UpdateMember(ctx context.Context, memberID int, det *model.Member) (res *model.Member, err error) {
+	ctx, span := startSpan(ctx, "UpdateMember")
+	defer endSpan(span, &err)
+	return t.next.UpdateMember(ctx, memberID, det)
+}
+
+func (t *tracedStore) DeleteMember(ctx context.Context, memberID int) (err error) {
+	ctx, span := startSpan(ctx, "DeleteMember")
+	defer endSpan(span, &err)
+	err = t.next.DeleteMember(ctx, memberID)
+	return err
+}
+
+func (t *tracedStore) AddHold(ctx context.Context, det *model.Hold) (res int, err error) {
+	ctx, span := startSpan(ctx, "AddHold")
+	defer endSpan(span, &err)
+	return t.next.AddHold(ctx, det)
+}
+
+func (t *tracedStore) GetMemberHolds(ctx context.Context, memberID int) (res []*model.Hold, err error) {
+	ctx, span := startSpan(ctx, "GetMemberHolds")
+	defer endSpan(span, &err)
+	return t.next.GetMemberHolds(ctx, memberID)
+}
+
+func (t *tracedStore) CancelHold(ctx context.Context, holdID int) (res *model.Hold, err error) {
+	ctx, span := startSpan(ctx, "CancelHold")
+	defer endSpan(span, &err)
+	return t.next.CancelHold(ctx, holdID)
+}
+
+func (t *tracedStore) ExpireHolds(ctx context.Context, now time.Time) (res int, err error) {
+	ctx, span := startSpan(ctx, "ExpireHolds")
+	defer endSpan(span, &err)
+	return t.next.ExpireHolds(ctx, now)
+}
+
+func (t *tracedStore) AccrueFines(ctx context.Context, now time.Time) (res int, err error) {
+	ctx, span := startSpan(ctx, "AccrueFines")
+	defer endSpan(span, &err)
+	return t.next.AccrueFines(ctx, now)
+}
+
+func (t *tracedStore) GetMemberFines(ctx context.Context, memberID int) (res *model.MemberFines, err error) {
+	ctx, span := startSpan(ctx, "GetMemberFines")
+	defer endSpan(span, &err)
+	return t.next.GetMemberFines(ctx, memberID)
+}
+
+func (t *tracedStore) PayFine(ctx context.Context, fineID int, amount int64) (res *model.Fine, err error) {
+	ctx, span := startSpan(ctx, "PayFine")
+	defer endSpan(span, &err)
+	return t.next.PayFine(ctx, fineID, amount)
+}
+
+func (t *tracedStore) WaiveFine(ctx context.Context, fineID int) (res *model.Fine, err error) {
+	ctx, span := startSpan(ctx, "WaiveFine")
+	defer endSpan(span, &err)
+	return t.next.WaiveFine(ctx, fineID)
+}
+
+func (t *tracedStore) GetLoan(ctx context.Context, loanID int) (res *model.LoanDetails, err error) {
+	ctx, span := startSpan(ctx, "GetLoan")
+	defer endSpan(span, &err)
+	return t.next.GetLoan(ctx, loanID)
+}
+
+func (t *tracedStore) GetLoansByBorrower(ctx context.Context, memberID int) (res []*model.LoanDetails, err error) {
+	ctx, span := startSpan(ctx, "GetLoansByBorrower")
+	defer endSpan(span, &err)
+	return t.next.GetLoansByBorrower(ctx, memberID)
+}
+
+func (t *tracedStore) GetAllLoans(ctx context.Context, query *model.LoanQuery) (res *model.LoanPage, err error) {
+	ctx, span := startSpan(ctx, "GetAllLoans")
+	defer endSpan(span, &err)
+	return t.next.GetAllLoans(ctx, query)
+}
+
+func (t *tracedStore) AddLoan(ctx context.Context, det *model.LoanDetails) (res int, err error) {
+	ctx, span := startSpan(ctx, "AddLoan")
+	defer endSpan(span, &err)
+	return t.next.AddLoan(ctx, det)
+}
+
+func (t *tracedStore) ExtendLoan(ctx context.Context, loanID int, version int) (res *model.LoanDetails, err error) {
+	ctx, span := startSpan(ctx, "ExtendLoan")
+	defer endSpan(span, &err)
+	return t.next.ExtendLoan(ctx, loanID, version)
+}
+
+func (t *tracedStore) ReturnBook(ctx context.Context, loanID int, version int) (res *model.LoanDetails, err error) {
+	ctx, span := startSpan(ctx, "ReturnBook")
+	defer endSpan(span, &err)
+	return t.next.ReturnBook(ctx, loanID, version)
+}
+
+func (t *tracedStore) GetLoanHistory(ctx context.Context, loanID int) (res []*model.LoanEvent, err error) {
+	ctx, span := startSpan(ctx, "GetLoanHistory")
+	defer endSpan(span, &err)
+	return t.next.GetLoanHistory(ctx, loanID)
+}
+
+func (t *tracedStore) ReserveIdempotencyKey(ctx context.Context, rec *model.IdempotencyRecord, now time.Time) (res *model.IdempotencyRecord, err error) {
+	ctx, span := startSpan(ctx, "ReserveIdempotencyKey")
+	defer endSpan(span, &err)
+	return t.next.ReserveIdempotencyKey(ctx, rec, now)
+}
+
+func (t *tracedStore) CompleteIdempotencyKey(ctx context.Context, rec *model.IdempotencyRecord) (err error) {
+	ctx, span := startSpan(ctx, "CompleteIdempotencyKey")
+	defer endSpan(span, &err)
+	err = t.next.CompleteIdempotencyKey(ctx, rec)
+	return err
+}
+
+func (t *tracedStore) ReleaseIdempotencyKey(ctx context.Context, key string) (err error) {
+	ctx, span := startSpan(ctx, "ReleaseIdempotencyKey")
+	defer endSpan(span, &err)
+	err = t.next.ReleaseIdempotencyKey(ctx, key)
+	return err
+}
+
+func (t *tracedStore) ExpireIdempotencyKeys(ctx context.Context, now time.Time) (res int, err error) {
+	ctx, span := startSpan(ctx, "ExpireIdempotencyKeys")
+	defer endSpan(span, &err)
+	return t.next.ExpireIdempotencyKeys(ctx, now)
+}
+
+func (t *tracedStore) GetCirculationStats(ctx context.Context, now time.Time) (res *model.CirculationStats, err error) {
+	ctx, span := startSpan(ctx, "GetCirculationStats")
+	defer endSpan(span, &err)
+	return t.next.GetCirculationStats(ctx, now)
+}
+
+func (t *tracedStore) AddAPIKey(ctx context.Context, key *model.APIKey) (res int, err error) {
+	ctx, span := startSpan(ctx, "AddAPIKey")
+	defer endSpan(span, &err)
+	return t.next.AddAPIKey(ctx, key)
+}
+
+func (t *tracedStore) GetAPIKeyByHash(ctx context.Context, hash string) (res *model.APIKey, err error) {
+	ctx, span := startSpan(ctx, "GetAPIKeyByHash")
+	defer endSpan(span, &err)
+	return t.next.GetAPIKeyByHash(ctx, hash)
+}
+
+func (t *tracedStore) GetAllAPIKeys(ctx context.Context) (res []*model.APIKey, err error) {
+	ctx, span := startSpan(ctx, "GetAllAPIKeys")
+	defer endSpan(span, &err)
+	return t.next.GetAllAPIKeys(ctx)
+}
+
+func (t *tracedStore) RevokeAPIKey(ctx context.Context, keyID int, now time.Time) (res *model.APIKey, err error) {
+	ctx, span := startSpan(ctx, "RevokeAPIKey")
+	defer endSpan(span, &err)
+	return t.next.RevokeAPIKey(ctx, keyID, now)
+}
+
+func (t *tracedStore) Ping(ctx context.Context) (err error) {
+	ctx, span := startSpan(ctx, "Ping")
+	defer endSpan(span, &err)
+	err = t.next.Ping(ctx)
+	return err
+}
+
+func (t *tracedStore) Close() error {
+	return t.next.Close()
+}
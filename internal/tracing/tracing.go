@@ -0,0 +1,63 @@
+package tracing
+
+import (
+	"context"
+
+	"github.com/test/library-app/internal/config"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// instrumentation names the tracer of the spans of the app
+const instrumentation = "github.com/test/library-app"
+
+// Tracer is the tracer the spans of the requests, the store operations and the queries are started with
+func Tracer() trace.Tracer {
+	return otel.Tracer(instrumentation)
+}
+
+// Init installs the tracer provider exporting the spans to the OTLP collector of the config,
+// the spans are dropped while tracing is disabled. The shutdown returned flushes the pending spans
+func Init(ctx context.Context) (func(context.Context) error, error) {
+	// the traces of the callers are continued from the traceparent header
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+	if !config.TracingConfig.TracingEnabled {
+		return func(context.Context) error { return nil }, nil
+	}
+
+	opts := []otlptracehttp.Option{otlptracehttp.WithEndpoint(config.TracingConfig.OTLPEndpoint)}
+	if config.TracingConfig.OTLPInsecure {
+		opts = append(opts, otlptracehttp.WithInsecure())
+	}
+	if len(config.TracingConfig.OTLPHeaders) > 0 {
+		opts = append(opts, otlptracehttp.WithHeaders(config.TracingConfig.OTLPHeaders))
+	}
+	exporter, err := otlptracehttp.New(ctx, opts...)
+	if err != nil {
+		return nil, err
+	}
+	sampler := sdktrace.ParentBased(sdktrace.TraceIDRatioBased(config.TracingConfig.TraceSampleRatio))
+	provider := newProvider(sdktrace.WithBatcher(exporter), sdktrace.WithSampler(sampler))
+	otel.SetTracerProvider(provider)
+	return provider.Shutdown, nil
+}
+
+// UseExporter installs a tracer provider sampling every trace and handing each span to the exporter as it ends,
+// the tests read the spans back from an in-memory exporter
+func UseExporter(exporter sdktrace.SpanExporter) *sdktrace.TracerProvider {
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	provider := newProvider(sdktrace.WithSyncer(exporter), sdktrace.WithSampler(sdktrace.AlwaysSample()))
+	otel.SetTracerProvider(provider)
+	return provider
+}
+
+// newProvider creates the tracer provider of the spans of the app named by the AppName
+func newProvider(opts ...sdktrace.TracerProviderOption) *sdktrace.TracerProvider {
+	res := resource.NewSchemaless(semconv.ServiceName(config.CommonConfig.AppName))
+	return sdktrace.NewTracerProvider(append(opts, sdktrace.WithResource(res))...)
+}
@@ -0,0 +1,51 @@
+package tracingtest
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/test/library-app/internal/config"
+	"github.com/test/library-app/internal/tracing"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+)
+
+func TestMain(m *testing.M) {
+	config.LoadConfig()
+	m.Run()
+}
+
+func TestInit(t *testing.T) {
+	// success case: no exporter while tracing is disabled
+	config.TracingConfig.TracingEnabled = false
+	shutdown, err := tracing.Init(context.Background())
+	assert.Nil(t, err)
+	assert.Nil(t, shutdown(context.Background()))
+
+	// success case: the exporter doesn't connect to the collector till the spans are sent
+	config.TracingConfig.TracingEnabled = true
+	config.TracingConfig.OTLPHeaders = map[string]string{"authorization": "Bearer token"}
+	defer func() { config.TracingConfig.TracingEnabled = false }()
+	shutdown, err = tracing.Init(context.Background())
+	assert.Nil(t, err)
+	assert.Nil(t, shutdown(context.Background()))
+}
+
+func TestUseExporter(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	provider := tracing.UseExporter(exporter)
+	defer provider.Shutdown(context.Background())
+
+	ctx, parent := tracing.Tracer().Start(context.Background(), "parent")
+	_, child := tracing.Tracer().Start(ctx, "child")
+	child.End()
+	parent.End()
+
+	// success case: the spans are exported as they end, named by the app
+	spans := exporter.GetSpans()
+	assert.Len(t, spans, 2)
+	assert.Equal(t, "child", spans[0].Name)
+	assert.Equal(t, spans[1].SpanContext.SpanID(), spans[0].Parent.SpanID())
+	assert.Contains(t, spans[1].Resource.Attributes(), semconv.ServiceName(config.CommonConfig.AppName))
+}
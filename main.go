@@ -14,10 +14,16 @@ import (
 	swaggerFiles "github.com/swaggo/files"
 	ginSwagger "github.com/swaggo/gin-swagger"
 	_ "github.com/test/library-app/docs"
+	"github.com/test/library-app/internal/auth"
 	"github.com/test/library-app/internal/config"
 	"github.com/test/library-app/internal/handler"
+	"github.com/test/library-app/internal/health"
+	"github.com/test/library-app/internal/jobs"
 	"github.com/test/library-app/internal/logger"
+	"github.com/test/library-app/internal/metrics"
 	"github.com/test/library-app/internal/store"
+	"github.com/test/library-app/internal/store/postgres"
+	"github.com/test/library-app/internal/tracing"
 )
 
 // @title 		Library App
@@ -25,6 +31,16 @@ import (
 // @description Handles the digital library operation
 // @host 		localhost:3000
 // @basePath 	/api/v1
+//
+// @securityDefinitions.apikey	BearerAuth
+// @in 							header
+// @name 						Authorization
+// @description 				Bearer JWT of a librarian or a member, required once AuthEnabled
+//
+// @securityDefinitions.apikey	APIKeyAuth
+// @in 							header
+// @name 						X-API-Key
+// @description 				Key of an integrating system, held to its scopes and quota
 func main() {
 	// loads config if any error in reading config panics the appl
 	err := config.LoadConfig()
@@ -33,11 +49,30 @@ func main() {
 	}
 
 	// configures logger for an app
-	logger.InitLogger()
+	if err := logger.InitLogger(); err != nil {
+		log.Panicf("%v", err)
+	}
 	logger.Infof("Hello this is library-app")
 
+	// `app migrate ...` manages the postgres schema instead of serving
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		if err := postgres.RunMigrateCommand(context.Background(), os.Args[2:], os.Stdout); err != nil {
+			logger.Errorf("Failed to migrate. Error: %v", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	// exporting the spans of the requests down to their queries once tracing is enabled
+	shutdownTracing, err := tracing.Init(context.Background())
+	if err != nil {
+		logger.Panicf("failed to initialize tracing. Error:%v", err)
+	}
+
 	// initializing the gin router
 	router := gin.Default()
+	// the stores read the actor of the loan history from the request context
+	router.ContextWithFallback = true
 
 	// Initializing the store
 	store, err := store.NewStore()
@@ -46,22 +81,99 @@ func main() {
 	}
 	defer store.Close()
 
+	// verifies the bearer tokens once authentication is enabled
+	var authn *auth.Authenticator
+	if config.AuthConfig.AuthEnabled {
+		authn, err = auth.NewAuthenticator()
+		if err != nil {
+			logger.Panicf("failed to initialize authentication. Error:%v", err)
+		}
+	} else {
+		logger.Warnf("authentication is disabled, every request acts as a librarian")
+	}
+
+	// checks of the dependencies the readiness of the app depends on
+	checks := health.NewRegistry(time.Duration(config.CommonConfig.HealthTimeoutInSec) * time.Second)
+	checks.Register(config.CommonConfig.StoreType, store.Ping)
+
 	// Actual handler to handles the requests
-	handler := handler.NewHandler(store)
+	handler := handler.NewHandler(store, authn, checks)
+	// tagging, tracing and observing every request, the unmatched ones too
+	router.Use(handler.RequestID, handler.Tracing, handler.Metrics)
 	// to handle liveness and readyness requests
 	router.GET("/live", handler.Live)
 	router.GET("/health", handler.Health)
+	// to serve the metrics to prometheus
+	router.GET("/metrics", gin.WrapH(metrics.Handler()))
 	// to serve swagger files
 	router.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
-	bookRouter := router.Group("/api/v1")
+	bookRouter := router.Group("/api/v1", handler.Authenticate, handler.Quota, handler.Actor, handler.Idempotency)
 	{
 		bookRouter.GET("/book", handler.GetAllBooks)
+		bookRouter.GET("/book/search", handler.SearchBooks)
 		bookRouter.GET("/book/:title", handler.GetBook)
+		bookRouter.GET("/book/id/:id", handler.GetBookByID)
+		bookRouter.GET("/book/id/:id/copy", handler.GetBookCopies)
+		bookRouter.POST("/book/id/:id/copy", handler.Librarian, handler.AddBookCopy)
+		bookRouter.GET("/copy/:barcode", handler.GetBookCopy)
+		bookRouter.PATCH("/copy/:barcode", handler.Librarian, handler.UpdateBookCopy)
+		bookRouter.POST("/book", handler.Librarian, handler.AddBook)
+		bookRouter.PUT("/book/:id", handler.Librarian, handler.UpdateBook)
+		bookRouter.PATCH("/book/:id", handler.Librarian, handler.UpdateBookCopies)
+		bookRouter.DELETE("/book/:id", handler.Librarian, handler.DeleteBook)
+		bookRouter.GET("/member", handler.Librarian, handler.GetAllMembers)
+		bookRouter.POST("/member", handler.Librarian, handler.AddMember)
+		bookRouter.GET("/member/:id", handler.GetMember)
+		bookRouter.PUT("/member/:id", handler.Librarian, handler.UpdateMember)
+		bookRouter.DELETE("/member/:id", handler.Librarian, handler.DeleteMember)
+		bookRouter.GET("/member/:id/loans", handler.GetMemberLoans)
+		bookRouter.GET("/member/:id/holds", handler.GetMemberHolds)
+		bookRouter.POST("/hold", handler.PlaceHold)
+		bookRouter.DELETE("/hold/:id", handler.CancelHold)
+		bookRouter.GET("/member/:id/fines", handler.GetMemberFines)
+		bookRouter.POST("/fine/:id/pay", handler.Librarian, handler.PayFine)
+		bookRouter.POST("/fine/:id/waive", handler.Librarian, handler.WaiveFine)
 		bookRouter.GET("/loan", handler.GetAllLoans)
 		bookRouter.POST("/loan", handler.LoanBook)
 		bookRouter.POST("/loan/extend/:id", handler.ExtendLoan)
 		bookRouter.POST("/loan/return/:id", handler.ReturnBook)
+		bookRouter.GET("/loan/:id", handler.GetLoan)
+		bookRouter.GET("/loan/:id/history", handler.GetLoanHistory)
+		bookRouter.GET("/apikey", handler.Admin, handler.GetAllAPIKeys)
+		bookRouter.POST("/apikey", handler.Admin, handler.IssueAPIKey)
+		bookRouter.DELETE("/apikey/:id", handler.Admin, handler.RevokeAPIKey)
+		bookRouter.GET("/log/level", handler.Admin, handler.GetLogLevel)
+		bookRouter.PUT("/log/level", handler.Admin, handler.SetLogLevel)
+	}
+
+	// starting the background jobs
+	runner := jobs.NewRunner()
+	runner.Every("expire-holds", time.Duration(config.CommonConfig.HoldExpiryInSec)*time.Second, func(ctx context.Context, now time.Time) error {
+		_, err := store.ExpireHolds(ctx, now)
+		return err
+	})
+	runner.Every("accrue-fines", time.Duration(config.CommonConfig.OverdueCheckInSec)*time.Second, func(ctx context.Context, now time.Time) error {
+		_, err := store.AccrueFines(ctx, now)
+		return err
+	})
+	// the gauges start off current rather than waiting for the first tick
+	refreshCirculation := func(ctx context.Context, now time.Time) error {
+		stats, err := store.GetCirculationStats(ctx, now)
+		if err != nil {
+			return err
+		}
+		metrics.SetCirculation(stats)
+		return nil
 	}
+	if err := refreshCirculation(context.Background(), time.Now()); err != nil {
+		logger.Errorf("Failed to refresh the circulation stats. Error: %v", err)
+	}
+	runner.Every("circulation-stats", time.Duration(config.CommonConfig.CirculationStatsInSec)*time.Second, refreshCirculation)
+	runner.Every("expire-idempotency-keys", time.Duration(config.CommonConfig.IdempotencyExpiryInSec)*time.Second, func(ctx context.Context, now time.Time) error {
+		_, err := store.ExpireIdempotencyKeys(ctx, now)
+		return err
+	})
+	checks.Register("jobs", runner.Check)
 
 	// Attaching the request handlers, port etc to the server
 	server := http.Server{
@@ -92,6 +204,8 @@ func main() {
 		logger.Infof("Shutting down the app with signal: %v", sig)
 	}
 	logger.Infof("Server is shutting down")
+	// no longer ready, new requests are routed to the other instances
+	checks.Shutdown()
 
 	// proceeding further to cleanup any resources like servers, db connections etc
 	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
@@ -100,5 +214,11 @@ func main() {
 	if err := server.Shutdown((ctx)); err != nil {
 		logger.Errorf("Failed to shutdown the server properly. Error: %v", err)
 	}
+	// stopping the jobs before the store gets closed
+	runner.Stop()
+	// flushing the spans not exported yet
+	if err := shutdownTracing(ctx); err != nil {
+		logger.Errorf("Failed to flush the spans. Error: %v", err)
+	}
 	logger.Infof("Server exited gracefully")
 }